@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"sync"
@@ -128,34 +129,62 @@ func (s *AdvancedInvoiceService) CreateInvoice(ctx context.Context, companyID st
 	return response, nil
 }
 
-// CreateInvoiceBatch procesa múltiples facturas en paralelo
+// CreateInvoiceBatch procesa múltiples facturas agrupadas por empresa, con a lo
+// sumo 4 autorizaciones simultáneas por empresa
 func (s *AdvancedInvoiceService) CreateInvoiceBatch(ctx context.Context, jobs []AdvancedInvoiceJob) []AdvancedInvoiceResult {
 	results := make([]AdvancedInvoiceResult, len(jobs))
-	var wg sync.WaitGroup
 
-	// Procesar trabajos en paralelo
+	// Agrupar trabajos por empresa, conservando su posición en el lote
+	byCompany := make(map[string][]int)
 	for i, job := range jobs {
-		wg.Add(1)
-		go func(index int, job AdvancedInvoiceJob) {
-			defer wg.Done()
-
-			// Crear contexto con timeout para cada trabajo
-			jobCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
-			defer cancel()
-
-			response, err := s.CreateInvoice(jobCtx, job.CompanyID, job.Invoice)
-			results[index] = AdvancedInvoiceResult{
-				CompanyID: job.CompanyID,
-				Response:  response,
-				Error:     err,
+		byCompany[job.CompanyID] = append(byCompany[job.CompanyID], i)
+		results[i].CompanyID = job.CompanyID
+	}
+
+	for companyID, indexes := range byCompany {
+		client, err := s.getClient(ctx, companyID)
+		if err != nil {
+			for _, i := range indexes {
+				results[i].Error = err
 			}
-		}(i, job)
+			continue
+		}
+
+		invoices := make([]*models.Invoice, len(indexes))
+		for j, i := range indexes {
+			invoices[j] = jobs[i].Invoice
+		}
+
+		responses, err := client.WSFE().AuthorizeInvoicesConcurrent(ctx, invoices, 4)
+		var batchErr *models.BatchError
+		errors.As(err, &batchErr)
+
+		for j, i := range indexes {
+			results[i].Response = responses[j]
+			if batchErr != nil {
+				results[i].Error = batchErr.Errors[j]
+			}
+		}
 	}
 
-	wg.Wait()
 	return results
 }
 
+// getClient obtiene el cliente ARCA de una empresa
+func (s *AdvancedInvoiceService) getClient(ctx context.Context, companyID string) (interfaces.ARCAClient, error) {
+	companyConfig, err := s.configProvider.GetCompanyConfig(ctx, companyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get company config: %w", err)
+	}
+
+	client, err := s.arcaManager.GetClientForCompany(ctx, companyConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ARCA client: %w", err)
+	}
+
+	return client, nil
+}
+
 // AdvancedInvoiceJob representa un trabajo de facturación
 type AdvancedInvoiceJob struct {
 	CompanyID string
@@ -244,12 +273,6 @@ func (pm *PerformanceMonitor) monitorPeriodic() {
 		if stats.InactiveClients > stats.TotalClients/2 {
 			pm.logger.Warnf("High number of inactive clients: %d/%d", stats.InactiveClients, stats.TotalClients)
 		}
-
-		// Limpiar clientes inactivos si es necesario
-		if stats.InactiveClients > 10 {
-			pm.arcaManager.CleanupInactiveClients(15 * time.Minute)
-			pm.logger.Infof("Cleaned up inactive clients")
-		}
 	}
 }
 
@@ -258,8 +281,10 @@ func runAdvancedExample() {
 	db := setupMockDatabase()
 
 	// 2. Crear factory y manager
-	factory := factory.NewClientManagerFactory(200, 60*time.Minute, 60*time.Second, 5, &AdvancedLogger{})
+	factory := factory.NewClientManagerFactory(200, 60*time.Minute, 60*time.Second, 5, &AdvancedLogger{},
+		factory.WithCleanupInterval(5*time.Minute))
 	manager := factory.CreateManager()
+	defer manager.Close()
 
 	// 3. Crear proveedor de configuraciones
 	configProvider := NewDatabaseCompanyConfigProvider(db)
@@ -337,8 +362,7 @@ func createAdvancedSampleInvoice() *models.Invoice {
 		InvoiceBase: models.InvoiceBase{
 			InvoiceType:  models.InvoiceTypeA,
 			PointOfSale:  1,
-			DateFrom:     time.Now(),
-			DateTo:       time.Now(),
+			IssueDate:    time.Now(),
 			ConceptType:  models.ConceptTypeProducts,
 			CurrencyType: models.CurrencyTypePES,
 			Amount:       1000.0,
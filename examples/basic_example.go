@@ -52,6 +52,7 @@ func main() {
 
 	// 3. Configurar manager
 	manager := factory.CreateManager()
+	defer manager.Close()
 
 	// 4. Crear configuración de empresa
 	companyConfig := &CompanyConfiguration{
@@ -137,8 +138,7 @@ func createNationalInvoice(ctx context.Context, client interfaces.ARCAClient) er
 		InvoiceBase: models.InvoiceBase{
 			InvoiceType:  models.InvoiceTypeA,
 			PointOfSale:  1,
-			DateFrom:     time.Now(),
-			DateTo:       time.Now(),
+			IssueDate:    time.Now(),
 			ConceptType:  models.ConceptTypeProducts,
 			CurrencyType: models.CurrencyTypePES,
 			Amount:       1000.0,
@@ -207,7 +207,8 @@ func createExportInvoice(ctx context.Context, client interfaces.ARCAClient) erro
 		Destination:     "Estados Unidos",
 		DestinationCode: "US",
 		ExportDate:      time.Now(),
-		ExportType:      "Definitiva",
+		ExportType:      models.ExportTypeGoods,
+		Incoterm:        "FOB",
 	}
 
 	// Autorizar factura de exportación
@@ -0,0 +1,51 @@
+// Package batch procesa lotes de comprobantes con una cantidad acotada de
+// llamadas concurrentes a AFIP, conservando el orden de los resultados.
+package batch
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultConcurrency es la cantidad de workers usada si no se indica otra
+const DefaultConcurrency = 4
+
+// Run aplica fn a cada elemento de items usando a lo sumo concurrency
+// workers. Los resultados y errores se retornan en el mismo orden que items.
+// Si el contexto se cancela, los elementos que no llegaron a procesarse
+// quedan con el error del contexto.
+func Run[T, R any](ctx context.Context, items []T, concurrency int, fn func(ctx context.Context, item T) (R, error)) ([]R, []error) {
+	results := make([]R, len(items))
+	errs := make([]error, len(items))
+
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+	if concurrency > len(items) {
+		concurrency = len(items)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if err := ctx.Err(); err != nil {
+					errs[i] = err
+					continue
+				}
+				results[i], errs[i] = fn(ctx, items[i])
+			}
+		}()
+	}
+
+	for i := range items {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results, errs
+}
@@ -2,17 +2,24 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/dlarregola/arca_invoice_lib/internal/services/auth"
 	"github.com/dlarregola/arca_invoice_lib/internal/services/wsfe"
 	"github.com/dlarregola/arca_invoice_lib/internal/services/wsfex"
 	"github.com/dlarregola/arca_invoice_lib/internal/shared"
+	"github.com/dlarregola/arca_invoice_lib/internal/utils"
 	"github.com/dlarregola/arca_invoice_lib/pkg/interfaces"
+	"github.com/dlarregola/arca_invoice_lib/pkg/models"
 )
 
+// errClientClosed es el error de las operaciones sobre un cliente cerrado
+var errClientClosed = errors.New("client is closed")
+
 // arcaClient es la implementación privada del cliente ARCA
 type arcaClient struct {
 	companyConfig interfaces.CompanyConfig
@@ -24,6 +31,11 @@ type arcaClient struct {
 	logger        interfaces.Logger
 	mutex         sync.RWMutex
 	closed        bool
+
+	// initOnce garantiza que los servicios se creen una sola vez; initErr
+	// guarda el resultado para las llamadas siguientes
+	initOnce sync.Once
+	initErr  error
 }
 
 // WSFE retorna el servicio de facturación nacional
@@ -51,11 +63,23 @@ func (c *arcaClient) IsHealthy(ctx context.Context) error {
 	defer c.mutex.RUnlock()
 
 	if c.closed {
-		return fmt.Errorf("client is closed")
+		return errClientClosed
+	}
+
+	// Verificar la vigencia del certificado antes de autenticar
+	certStatus, err := utils.CheckCertificate(c.companyConfig.GetCertificate(), time.Now())
+	if err != nil {
+		return fmt.Errorf("health check failed: %w", err)
+	}
+	if !certStatus.Valid {
+		return fmt.Errorf("health check failed: %s", certStatus.Message)
+	}
+	if certStatus.Status == models.CertStatusWarning {
+		c.logger.Warnf("Certificate for company %s: %s", c.companyConfig.GetCompanyID(), certStatus.Message)
 	}
 
 	// Intentar obtener un token para verificar la conexión
-	_, err := c.authService.GetToken(ctx, "wsfe")
+	_, err = c.authService.GetToken(ctx, "wsfe")
 	if err != nil {
 		return fmt.Errorf("health check failed: %w", err)
 	}
@@ -63,6 +87,11 @@ func (c *arcaClient) IsHealthy(ctx context.Context) error {
 	return nil
 }
 
+// CheckCertificate informa la vigencia del certificado de la empresa
+func (c *arcaClient) CheckCertificate(ctx context.Context) (*models.CertStatus, error) {
+	return utils.CheckCertificate(c.companyConfig.GetCertificate(), time.Now())
+}
+
 // Close cierra el cliente y limpia recursos
 func (c *arcaClient) Close() error {
 	c.mutex.Lock()
@@ -74,8 +103,10 @@ func (c *arcaClient) Close() error {
 
 	c.closed = true
 
-	// Limpiar cache de autenticación
-	c.authService.ClearCache()
+	// Limpiar cache de autenticación (nil si los servicios no llegaron a crearse)
+	if c.authService != nil {
+		c.authService.ClearCache()
+	}
 
 	// Cerrar HTTP client si es necesario
 	if c.httpClient != nil {
@@ -87,60 +118,63 @@ func (c *arcaClient) Close() error {
 	return nil
 }
 
-// initializeServices inicializa los servicios del cliente
+// initializeServices inicializa los servicios del cliente. Los servicios se
+// crean una sola vez: las llamadas siguientes retornan el resultado de la
+// primera. Falla si el cliente está cerrado.
 func (c *arcaClient) initializeServices() error {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
+	c.mutex.RLock()
+	closed := c.closed
+	c.mutex.RUnlock()
 
-	// Crear HTTP client
-	c.httpClient = &http.Client{
-		Timeout: c.config.Timeout,
+	if closed {
+		return errClientClosed
 	}
 
+	c.initOnce.Do(func() {
+		c.initErr = c.createServices()
+	})
+	return c.initErr
+}
+
+// createServices crea los servicios del cliente y los asigna tomando el lock
+// sólo para la asignación. Si el cliente se cerró mientras tanto, los
+// descarta y retorna errClientClosed.
+func (c *arcaClient) createServices() error {
+	// Crear HTTP client
+	httpClient := c.config.NewHTTPClient()
+
+	// Los servicios registran la empresa en cada mensaje
+	logger := shared.WithFields(c.logger, map[string]interface{}{
+		interfaces.LogFieldCompany: c.companyConfig.GetCompanyID(),
+	})
+
 	// Crear servicio de autenticación
-	c.authService = auth.NewAuthService(c.config, c.logger)
+	authService := auth.NewAuthService(c.config, logger)
 
 	// Crear servicio WSFE
-	wsfeService, err := wsfe.NewWSFEService(c.authService, c.logger)
+	wsfeService, err := wsfe.NewWSFEService(c.config, authService, logger)
 	if err != nil {
 		return fmt.Errorf("failed to create WSFE service: %w", err)
 	}
-	c.wsfeService = wsfeService
 
 	// Crear servicio WSFEX
-	wsfexService, err := wsfex.NewWSFEXService(c.authService, c.logger)
+	wsfexService, err := wsfex.NewWSFEXService(c.config, authService, logger)
 	if err != nil {
 		return fmt.Errorf("failed to create WSFEX service: %w", err)
 	}
+
+	// Close pudo ejecutarse mientras se creaban los servicios
+	c.mutex.Lock()
+	if c.closed {
+		c.mutex.Unlock()
+		return errClientClosed
+	}
+	c.httpClient = httpClient
+	c.authService = authService
+	c.wsfeService = wsfeService
 	c.wsfexService = wsfexService
+	c.mutex.Unlock()
 
 	c.logger.Infof("Services initialized for company %s", c.companyConfig.GetCompanyID())
 	return nil
 }
-
-// getBaseURL retorna la URL base según el environment
-func (c *arcaClient) getBaseURL() string {
-	switch c.config.Environment {
-	case "testing":
-		return "https://wswhomo.afip.gov.ar"
-	case "production":
-		return "https://servicios1.afip.gov.ar"
-	default:
-		return "https://wswhomo.afip.gov.ar"
-	}
-}
-
-// getWSAAURL retorna la URL del servicio WSAA
-func (c *arcaClient) getWSAAURL() string {
-	return c.getBaseURL() + "/ws/services/LoginCms"
-}
-
-// getWSFEURL retorna la URL del servicio WSFEv1
-func (c *arcaClient) getWSFEURL() string {
-	return c.getBaseURL() + "/wsfev1/service.asmx"
-}
-
-// getWSFEXURL retorna la URL del servicio WSFEXv1
-func (c *arcaClient) getWSFEXURL() string {
-	return c.getBaseURL() + "/wsfexv1/service.asmx"
-}
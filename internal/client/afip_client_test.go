@@ -0,0 +1,68 @@
+package client
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/dlarregola/arca_invoice_lib/internal/shared"
+	"github.com/dlarregola/arca_invoice_lib/pkg/core"
+	"github.com/dlarregola/arca_invoice_lib/pkg/interfaces"
+)
+
+// testCompanyConfig implementa interfaces.CompanyConfig para los tests
+type testCompanyConfig struct{}
+
+func (testCompanyConfig) GetCUIT() string        { return "20123456786" }
+func (testCompanyConfig) GetCertificate() []byte { return []byte("cert") }
+func (testCompanyConfig) GetPrivateKey() []byte  { return []byte("key") }
+func (testCompanyConfig) GetEnvironment() string { return "testing" }
+func (testCompanyConfig) GetCompanyID() string   { return "company-1" }
+
+// newTestClient crea un cliente sin servicios inicializados
+func newTestClient() *arcaClient {
+	var companyConfig interfaces.CompanyConfig = testCompanyConfig{}
+	return &arcaClient{
+		companyConfig: companyConfig,
+		config:        &shared.InternalConfig{CUIT: companyConfig.GetCUIT(), Environment: companyConfig.GetEnvironment()},
+		logger:        core.NewStdLogger(io.Discard, "error"),
+	}
+}
+
+func TestInitializeServicesOnce(t *testing.T) {
+	client := newTestClient()
+
+	if err := client.initializeServices(); err != nil {
+		t.Fatalf("initializeServices() error = %v", err)
+	}
+	wsfeService, authService := client.wsfeService, client.authService
+	if wsfeService == nil || authService == nil {
+		t.Fatal("initializeServices() should create the services")
+	}
+
+	if err := client.initializeServices(); err != nil {
+		t.Fatalf("Second initializeServices() error = %v", err)
+	}
+	if client.wsfeService != wsfeService || client.authService != authService {
+		t.Error("initializeServices() should create the services only once")
+	}
+}
+
+func TestInitializeServicesClosed(t *testing.T) {
+	client := newTestClient()
+	if err := client.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if err := client.initializeServices(); !errors.Is(err, errClientClosed) {
+		t.Errorf("initializeServices() on a closed client should return errClientClosed, got %v", err)
+	}
+
+	// Un Close entre la verificación y la creación descarta los servicios
+	if err := client.createServices(); !errors.Is(err, errClientClosed) {
+		t.Errorf("createServices() on a closed client should return errClientClosed, got %v", err)
+	}
+	if client.wsfeService != nil || client.authService != nil {
+		t.Error("A closed client should not keep the services created after Close")
+	}
+}
@@ -2,6 +2,7 @@ package client
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
 	"sync"
 	"time"
@@ -17,10 +18,23 @@ type ManagerConfig struct {
 	ClientCacheSize   int
 	ClientIdleTimeout time.Duration
 
+	// CleanupInterval habilita un janitor en segundo plano que cada intervalo
+	// elimina los clientes inactivos por más de ClientIdleTimeout. Si es 0 la
+	// limpieza queda a cargo de CleanupInactiveClients.
+	CleanupInterval time.Duration
+
 	// Configuración de red
 	HTTPTimeout      time.Duration
 	MaxRetryAttempts int
 
+	// TLSConfig permite fijar la versión mínima de TLS o agregar CAs propias
+	// (ej. proxies con inspección TLS). Si es nil se exige TLS 1.2+.
+	TLSConfig *tls.Config
+
+	// BaseURLOverride redirige todas las llamadas a AFIP a otra URL base (ej.
+	// un reverse proxy o un mock). Si está vacío se usa la del environment.
+	BaseURLOverride string
+
 	// Logging
 	Logger Logger
 }
@@ -41,17 +55,29 @@ type Logger interface {
 type clientManager struct {
 	clientCache  map[string]*cachedClient
 	cacheMutex   sync.RWMutex
+	versions     map[string]uint64
 	config       ManagerConfig
 	lastCleanup  time.Time
 	cleanupMutex sync.Mutex
+
+	// retired son los clientes reemplazados por una rotación que esperan el
+	// período de gracia para cerrarse, con su timer. Lo protege cacheMutex.
+	retired map[interfaces.ARCAClient]*time.Timer
+
+	closed    bool
+	done      chan struct{}
+	closeOnce sync.Once
+	janitor   sync.WaitGroup
 }
 
-// cachedClient representa un cliente en cache
+// cachedClient representa un cliente en cache. version es la rotación de
+// credenciales con la que se creó el cliente.
 type cachedClient struct {
 	client    interfaces.ARCAClient
 	lastUsed  time.Time
 	companyID string
 	createdAt time.Time
+	version   uint64
 }
 
 // internalConfig representa la configuración interna del cliente
@@ -59,10 +85,37 @@ type internalConfig = shared.InternalConfig
 
 // newClientManager crea una nueva instancia del manager
 func NewClientManager(config ManagerConfig) interfaces.ARCAClientManager {
-	return &clientManager{
+	manager := &clientManager{
 		clientCache: make(map[string]*cachedClient),
+		versions:    make(map[string]uint64),
+		retired:     make(map[interfaces.ARCAClient]*time.Timer),
 		config:      config,
 		lastCleanup: time.Now(),
+		done:        make(chan struct{}),
+	}
+
+	if config.CleanupInterval > 0 {
+		manager.janitor.Add(1)
+		go manager.runJanitor(config.CleanupInterval)
+	}
+
+	return manager
+}
+
+// runJanitor limpia periódicamente los clientes inactivos hasta que se cierre el manager
+func (m *clientManager) runJanitor(interval time.Duration) {
+	defer m.janitor.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			m.CleanupInactiveClients(m.config.ClientIdleTimeout)
+		case <-m.done:
+			return
+		}
 	}
 }
 
@@ -75,21 +128,146 @@ func (m *clientManager) GetClientForCompany(ctx context.Context, companyConfig i
 
 	companyID := companyConfig.GetCompanyID()
 
+	if m.isClosed() {
+		return nil, errors.NewClientCacheError(companyID, "get_client", "client manager is closed")
+	}
+
 	// Verificar cache primero
 	if client := m.getCachedClient(companyID); client != nil {
 		return client, nil
 	}
 
-	// Crear nuevo cliente
+	// Crear nuevo cliente, recordando la versión de credenciales vigente
+	version := m.credentialsVersion(companyID)
 	client, err := m.createNewClient(companyConfig)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create client: %w", err)
 	}
 
 	// Guardar en cache
-	m.cacheClient(companyID, client)
+	return m.cacheClient(companyID, client, version)
+}
 
-	return client, nil
+// RotateCompanyCredentials reemplaza el cliente de la empresa por uno creado
+// con newConfig. El cliente nuevo se crea antes de tomar el lock y el
+// reemplazo es atómico: las llamadas siguientes a GetClientForCompany
+// obtienen el cliente nuevo, que pide un token a WSAA con las credenciales
+// nuevas. El cliente anterior no se cierra de inmediato para que las
+// llamadas en curso terminen; se cierra pasado rotationGracePeriod.
+func (m *clientManager) RotateCompanyCredentials(companyID string, newConfig interfaces.CompanyConfig) error {
+	// Validar configuración
+	if err := m.ValidateCompanyConfig(newConfig); err != nil {
+		return fmt.Errorf("invalid company config: %w", err)
+	}
+	if newConfig.GetCompanyID() != companyID {
+		return errors.NewCompanyConfigError(companyID, "company_id", "new config belongs to a different company")
+	}
+
+	if m.isClosed() {
+		return errors.NewClientCacheError(companyID, "rotate_credentials", "client manager is closed")
+	}
+
+	// Crear cliente con las credenciales nuevas
+	client, err := m.createNewClient(newConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create client: %w", err)
+	}
+
+	m.cacheMutex.Lock()
+	if m.closed {
+		m.cacheMutex.Unlock()
+		client.Close()
+		return errors.NewClientCacheError(companyID, "rotate_credentials", "client manager is closed")
+	}
+
+	previous, exists := m.clientCache[companyID]
+	if !exists {
+		m.evictOldestLocked()
+	}
+	m.versions[companyID]++
+	now := time.Now()
+	m.clientCache[companyID] = &cachedClient{
+		client:    client,
+		lastUsed:  now,
+		companyID: companyID,
+		createdAt: now,
+		version:   m.versions[companyID],
+	}
+	version := m.versions[companyID]
+	m.cacheMutex.Unlock()
+
+	if exists {
+		m.retireClient(companyID, previous.client)
+	}
+
+	m.config.Logger.Infof("Rotated credentials for company %s (version %d)", companyID, version)
+	return nil
+}
+
+const (
+	// defaultRotationTimeout es el timeout por llamada que se asume para el
+	// período de gracia si HTTPTimeout no está configurado
+	defaultRotationTimeout = 30 * time.Second
+
+	// rotationRetryDelay es la espera entre reintentos que se suma al
+	// período de gracia
+	rotationRetryDelay = time.Second
+)
+
+// rotationGracePeriod es el tiempo que se espera antes de cerrar un cliente
+// reemplazado: lo que puede durar una llamada en curso con sus reintentos y
+// las esperas entre ellos
+func (m *clientManager) rotationGracePeriod() time.Duration {
+	timeout := m.config.HTTPTimeout
+	if timeout <= 0 {
+		timeout = defaultRotationTimeout
+	}
+	retries := m.config.MaxRetryAttempts
+	if retries < 0 {
+		retries = 0
+	}
+	return timeout*time.Duration(retries+1) + rotationRetryDelay*time.Duration(retries)
+}
+
+// retireClient cierra el cliente reemplazado por una rotación una vez
+// transcurrido rotationGracePeriod, o al cerrar el manager si ocurre antes
+func (m *clientManager) retireClient(companyID string, client interfaces.ARCAClient) {
+	m.cacheMutex.Lock()
+	defer m.cacheMutex.Unlock()
+
+	if m.closed {
+		m.closeRetiredClient(companyID, client)
+		return
+	}
+
+	m.retired[client] = time.AfterFunc(m.rotationGracePeriod(), func() {
+		m.cacheMutex.Lock()
+		_, pending := m.retired[client]
+		delete(m.retired, client)
+		m.cacheMutex.Unlock()
+
+		// Si no está pendiente, Close ya lo cerró
+		if pending {
+			m.closeRetiredClient(companyID, client)
+		}
+	})
+}
+
+// closeRetiredClient cierra un cliente reemplazado por una rotación
+func (m *clientManager) closeRetiredClient(companyID string, client interfaces.ARCAClient) {
+	if err := client.Close(); err != nil {
+		m.config.Logger.Warnf("Error closing rotated client for company %s: %v", companyID, err)
+	}
+}
+
+// credentialsVersion retorna la versión de credenciales vigente de la
+// empresa: la cantidad de rotaciones, que se conserva aunque el cliente se
+// expulse del cache
+func (m *clientManager) credentialsVersion(companyID string) uint64 {
+	m.cacheMutex.RLock()
+	defer m.cacheMutex.RUnlock()
+
+	return m.versions[companyID]
 }
 
 // ValidateCompanyConfig valida la configuración de una empresa
@@ -198,6 +376,49 @@ func (m *clientManager) GetCacheStats() interfaces.CacheStats {
 	}
 }
 
+// Close cierra todos los clientes en cache y detiene las tareas internas del
+// manager. Es seguro llamarlo más de una vez.
+func (m *clientManager) Close() error {
+	var firstErr error
+
+	m.closeOnce.Do(func() {
+		close(m.done)
+		m.janitor.Wait()
+
+		m.cacheMutex.Lock()
+		defer m.cacheMutex.Unlock()
+
+		m.closed = true
+		for companyID, cached := range m.clientCache {
+			if err := cached.client.Close(); err != nil {
+				m.config.Logger.Warnf("Error closing client for company %s: %v", companyID, err)
+				if firstErr == nil {
+					firstErr = fmt.Errorf("failed to close client for company %s: %w", companyID, err)
+				}
+			}
+			delete(m.clientCache, companyID)
+		}
+
+		// Los clientes rotados no esperan el fin del período de gracia
+		for client, timer := range m.retired {
+			timer.Stop()
+			m.closeRetiredClient(client.GetCompanyInfo().CompanyID, client)
+			delete(m.retired, client)
+		}
+		m.config.Logger.Infof("Client manager closed")
+	})
+
+	return firstErr
+}
+
+// isClosed indica si el manager fue cerrado
+func (m *clientManager) isClosed() bool {
+	m.cacheMutex.RLock()
+	defer m.cacheMutex.RUnlock()
+
+	return m.closed
+}
+
 // getCachedClient obtiene un cliente del cache
 func (m *clientManager) getCachedClient(companyID string) interfaces.ARCAClient {
 	m.cacheMutex.RLock()
@@ -224,11 +445,54 @@ func (m *clientManager) getCachedClient(companyID string) interfaces.ARCAClient
 	return cached.client
 }
 
-// cacheClient guarda un cliente en el cache
-func (m *clientManager) cacheClient(companyID string, client interfaces.ARCAClient) {
+// cacheClient guarda en el cache un cliente creado con la versión de
+// credenciales version y lo retorna. Si mientras se creaba se rotaron las
+// credenciales, lo descarta y retorna el cliente rotado; si otra llamada ya
+// guardó un cliente de la misma versión, lo descarta y retorna ese. Si el
+// manager se cerró, lo cierra y retorna error.
+func (m *clientManager) cacheClient(companyID string, client interfaces.ARCAClient, version uint64) (interfaces.ARCAClient, error) {
 	m.cacheMutex.Lock()
 	defer m.cacheMutex.Unlock()
 
+	if m.closed {
+		client.Close()
+		return nil, errors.NewClientCacheError(companyID, "get_client", "client manager is closed")
+	}
+
+	cached, exists := m.clientCache[companyID]
+	if version != m.versions[companyID] {
+		client.Close()
+		if !exists {
+			// El cliente rotado ya no está en cache y el creado usa las
+			// credenciales reemplazadas
+			return nil, errors.NewClientCacheError(companyID, "get_client", "credentials were rotated while creating the client")
+		}
+		cached.lastUsed = time.Now()
+		return cached.client, nil
+	}
+	if exists {
+		// Otra llamada guardó antes un cliente con las mismas credenciales
+		client.Close()
+		cached.lastUsed = time.Now()
+		return cached.client, nil
+	}
+
+	m.evictOldestLocked()
+
+	m.clientCache[companyID] = &cachedClient{
+		client:    client,
+		lastUsed:  time.Now(),
+		companyID: companyID,
+		createdAt: time.Now(),
+		version:   version,
+	}
+
+	return client, nil
+}
+
+// evictOldestLocked cierra y remueve el cliente usado hace más tiempo si el
+// cache está lleno. Requiere cacheMutex tomado para escritura.
+func (m *clientManager) evictOldestLocked() {
 	// Verificar límite de cache
 	if len(m.clientCache) >= m.config.ClientCacheSize {
 		// Remover el cliente más antiguo
@@ -249,25 +513,20 @@ func (m *clientManager) cacheClient(companyID string, client interfaces.ARCAClie
 			}
 		}
 	}
-
-	m.clientCache[companyID] = &cachedClient{
-		client:    client,
-		lastUsed:  time.Now(),
-		companyID: companyID,
-		createdAt: time.Now(),
-	}
 }
 
 // createNewClient crea un nuevo cliente ARCA
 func (m *clientManager) createNewClient(config interfaces.CompanyConfig) (interfaces.ARCAClient, error) {
 	// Crear configuración interna
 	internalConfig := &internalConfig{
-		CUIT:          config.GetCUIT(),
-		Certificate:   config.GetCertificate(),
-		PrivateKey:    config.GetPrivateKey(),
-		Environment:   config.GetEnvironment(),
-		Timeout:       m.config.HTTPTimeout,
-		RetryAttempts: m.config.MaxRetryAttempts,
+		CUIT:            config.GetCUIT(),
+		Certificate:     config.GetCertificate(),
+		PrivateKey:      config.GetPrivateKey(),
+		Environment:     config.GetEnvironment(),
+		BaseURLOverride: m.config.BaseURLOverride,
+		Timeout:         m.config.HTTPTimeout,
+		RetryAttempts:   m.config.MaxRetryAttempts,
+		TLSConfig:       m.config.TLSConfig,
 	}
 
 	// Crear cliente interno
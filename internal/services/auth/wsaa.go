@@ -17,7 +17,9 @@ import (
 	"time"
 
 	"github.com/dlarregola/arca_invoice_lib/internal/shared"
+	"github.com/dlarregola/arca_invoice_lib/internal/utils"
 	"github.com/dlarregola/arca_invoice_lib/pkg/interfaces"
+	"github.com/dlarregola/arca_invoice_lib/pkg/models"
 )
 
 // wsaaService es la implementación privada del servicio de autenticación
@@ -131,6 +133,11 @@ func (s *wsaaService) generateAccessToken(ctx context.Context, service string) (
 		return nil, fmt.Errorf("error parsing certificate: %v", err)
 	}
 
+	// Verificar vigencia del certificado antes de llamar a WSAA
+	if err := utils.ValidateCertificateDates(cert, time.Now()); err != nil {
+		return nil, err
+	}
+
 	// Parsear clave privada
 	var privateKey *rsa.PrivateKey
 	parsedKey, err := x509.ParsePKCS1PrivateKey(s.config.PrivateKey)
@@ -160,11 +167,12 @@ func (s *wsaaService) generateAccessToken(ctx context.Context, service string) (
 		Version: "1.0",
 		Service: service,
 	}
-	request.Header.Source = s.config.CUIT
-	request.Header.Destination = "cn=wsaahomo,o=afip,c=ar,serialNumber=CUIT 33693450239"
+	request.Header.Source = normalizeCUIT(s.config.CUIT)
+	request.Header.Destination = s.config.GetWSAADestination()
 	request.Header.UniqueID = uniqueID
-	request.Header.GenerationTime = time.Now().UTC().Format("2006-01-02T15:04:05.000-07:00")
-	request.Header.ExpirationTime = time.Now().Add(24 * time.Hour).UTC().Format("2006-01-02T15:04:05.000-07:00")
+	now := time.Now()
+	request.Header.GenerationTime = now.UTC().Format("2006-01-02T15:04:05.000-07:00")
+	request.Header.ExpirationTime = now.Add(shared.DefaultWSAARequestTTL).UTC().Format("2006-01-02T15:04:05.000-07:00")
 
 	// Serializar request
 	requestXML, err := xml.MarshalIndent(request, "", "  ")
@@ -201,7 +209,10 @@ func (s *wsaaService) generateAccessToken(ctx context.Context, service string) (
 	// Agregar al cache
 	s.addToCache(service, token)
 
-	s.logger.Infof("Generated new access token for service %s", service)
+	shared.WithFields(s.logger, map[string]interface{}{
+		interfaces.LogFieldService: service,
+		interfaces.LogFieldAction:  "loginCms",
+	}).Info("Generated new access token")
 	return token, nil
 }
 
@@ -239,7 +250,7 @@ func (s *wsaaService) callWSAA(ctx context.Context, cms string) (string, error)
 </soapenv:Envelope>`, cms)
 
 	// Crear request HTTP
-	req, err := http.NewRequestWithContext(ctx, "POST", s.getWSAAURL(), bytes.NewReader([]byte(requestBody)))
+	req, err := http.NewRequestWithContext(ctx, "POST", s.config.GetWSAAURL(), bytes.NewReader([]byte(requestBody)))
 	if err != nil {
 		return "", fmt.Errorf("error creating HTTP request: %v", err)
 	}
@@ -247,10 +258,10 @@ func (s *wsaaService) callWSAA(ctx context.Context, cms string) (string, error)
 	// Configurar headers
 	req.Header.Set("Content-Type", "text/xml; charset=utf-8")
 	req.Header.Set("SOAPAction", "http://wsaa.view.sua.dvadac.desein.afip.gov/loginCms")
-	req.Header.Set("User-Agent", "ARCA-Go-Client/1.0")
+	req.Header.Set("User-Agent", shared.UserAgent(s.config.UserAgent))
 
 	// Realizar request
-	client := &http.Client{Timeout: s.config.Timeout}
+	client := s.config.NewHTTPClient()
 	resp, err := client.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("error making HTTP request: %v", err)
@@ -285,24 +296,6 @@ func (s *wsaaService) callWSAA(ctx context.Context, cms string) (string, error)
 	return soapResponse.Body.LoginCmsResponse.LoginCmsReturn, nil
 }
 
-// getWSAAURL retorna la URL del servicio WSAA
-func (s *wsaaService) getWSAAURL() string {
-	baseURL := s.getBaseURL()
-	return baseURL + "/ws/services/LoginCms"
-}
-
-// getBaseURL retorna la URL base según el environment
-func (s *wsaaService) getBaseURL() string {
-	switch s.config.Environment {
-	case "testing":
-		return "https://wswhomo.afip.gov.ar"
-	case "production":
-		return "https://servicios1.afip.gov.ar"
-	default:
-		return "https://wswhomo.afip.gov.ar"
-	}
-}
-
 // generateUniqueID genera un ID único
 func generateUniqueID() (string, error) {
 	bytes := make([]byte, 16)
@@ -311,3 +304,11 @@ func generateUniqueID() (string, error) {
 	}
 	return fmt.Sprintf("%x", bytes), nil
 }
+
+// normalizeCUIT retorna el CUIT sin guiones, o el valor original si no es válido
+func normalizeCUIT(cuit string) string {
+	if normalized, err := models.NormalizeCUIT(cuit); err == nil {
+		return normalized
+	}
+	return cuit
+}
@@ -1,22 +1,33 @@
 package wsfe
 
 import (
+	"github.com/dlarregola/arca_invoice_lib/internal/batch"
+	"github.com/dlarregola/arca_invoice_lib/internal/shared"
+	"github.com/dlarregola/arca_invoice_lib/internal/soap"
+	"github.com/dlarregola/arca_invoice_lib/internal/utils"
 	"github.com/dlarregola/arca_invoice_lib/pkg/interfaces"
 	"github.com/dlarregola/arca_invoice_lib/pkg/models"
+	afipwsfe "github.com/dlarregola/arca_invoice_lib/pkg/wsfe"
 	"context"
 	"fmt"
+	"sync"
 	"time"
 )
 
 // wsfeService es la implementación privada del servicio WSFE
 type wsfeService struct {
+	config      *shared.InternalConfig
 	authService interfaces.AuthService
 	logger      interfaces.Logger
+
+	soapClient *soap.Client
+	soapOnce   sync.Once
 }
 
 // newWSFEService crea un nuevo servicio WSFE
-func newWSFEService(authService interfaces.AuthService, logger interfaces.Logger) (interfaces.WSFEService, error) {
+func newWSFEService(config *shared.InternalConfig, authService interfaces.AuthService, logger interfaces.Logger) (interfaces.WSFEService, error) {
 	return &wsfeService{
+		config:      config,
 		authService: authService,
 		logger:      logger,
 	}, nil
@@ -37,7 +48,13 @@ func (s *wsfeService) AuthorizeInvoice(ctx context.Context, invoice *models.Invo
 
 	// TODO: Implementar llamada SOAP real
 	// Por ahora retornamos una respuesta simulada
-	s.logger.Infof("Authorizing invoice %d for point of sale %d", invoice.InvoiceNumber, invoice.PointOfSale)
+	shared.WithFields(s.logger, map[string]interface{}{
+		interfaces.LogFieldService:       "wsfe",
+		interfaces.LogFieldAction:        "FECAESolicitar",
+		interfaces.LogFieldPointOfSale:   invoice.PointOfSale,
+		interfaces.LogFieldInvoiceType:   int(invoice.InvoiceType),
+		interfaces.LogFieldInvoiceNumber: invoice.InvoiceNumber,
+	}).Info("Authorizing invoice")
 
 	return &models.AuthorizationResponse{
 		CAE:               "12345678901234",
@@ -46,11 +63,17 @@ func (s *wsfeService) AuthorizeInvoice(ctx context.Context, invoice *models.Invo
 		PointOfSale:       invoice.PointOfSale,
 		InvoiceType:       invoice.InvoiceType,
 		AuthorizationDate: invoice.DateFrom,
-		Status:            "A",
+		Status:            models.AuthResultApproved,
 		Message:           "Autorizado",
 	}, nil
 }
 
+// AuthorizeInvoicesConcurrent autoriza un lote de comprobantes con concurrencia acotada
+func (s *wsfeService) AuthorizeInvoicesConcurrent(ctx context.Context, invoices []*models.Invoice, concurrency int) ([]*models.AuthorizationResponse, error) {
+	responses, errs := batch.Run(ctx, invoices, concurrency, s.AuthorizeInvoice)
+	return responses, models.NewBatchError(errs)
+}
+
 // QueryInvoice consulta un comprobante
 func (s *wsfeService) QueryInvoice(ctx context.Context, query *models.InvoiceQuery) (*models.Invoice, error) {
 	// Obtener token de autenticación
@@ -60,7 +83,13 @@ func (s *wsfeService) QueryInvoice(ctx context.Context, query *models.InvoiceQue
 	}
 
 	// TODO: Implementar consulta SOAP real
-	s.logger.Infof("Querying invoice %d for point of sale %d", query.InvoiceNumber, query.PointOfSale)
+	shared.WithFields(s.logger, map[string]interface{}{
+		interfaces.LogFieldService:       "wsfe",
+		interfaces.LogFieldAction:        "FECompConsultar",
+		interfaces.LogFieldPointOfSale:   query.PointOfSale,
+		interfaces.LogFieldInvoiceType:   int(query.InvoiceType),
+		interfaces.LogFieldInvoiceNumber: query.InvoiceNumber,
+	}).Info("Querying invoice")
 
 	// Retornar factura simulada
 	return &models.Invoice{
@@ -83,7 +112,12 @@ func (s *wsfeService) GetLastAuthorizedInvoice(ctx context.Context, pointOfSale
 	}
 
 	// TODO: Implementar consulta SOAP real
-	s.logger.Infof("Getting last authorized invoice for point of sale %d, type %d", pointOfSale, invoiceType)
+	shared.WithFields(s.logger, map[string]interface{}{
+		interfaces.LogFieldService:     "wsfe",
+		interfaces.LogFieldAction:      "FECompUltimoAutorizado",
+		interfaces.LogFieldPointOfSale: pointOfSale,
+		interfaces.LogFieldInvoiceType: invoiceType,
+	}).Info("Getting last authorized invoice")
 
 	return &models.LastInvoiceResponse{
 		InvoiceType:   models.InvoiceType(invoiceType),
@@ -102,10 +136,15 @@ func (s *wsfeService) QueryCAEA(ctx context.Context, caea string) (*models.CAEAR
 	}
 
 	// TODO: Implementar consulta SOAP real
-	s.logger.Infof("Querying CAEA: %s", caea)
+	shared.WithFields(s.logger, map[string]interface{}{
+		interfaces.LogFieldService: "wsfe",
+		interfaces.LogFieldAction:  "FECAEAConsultar",
+		"caea":                     caea,
+	}).Info("Querying CAEA")
 
 	return &models.CAEAResponse{
 		CAEA:           caea,
+		ValidFrom:      time.Now(),
 		ExpirationDate: time.Now().AddDate(0, 1, 0),
 		Status:         "A",
 		Message:        "CAEA válido",
@@ -143,8 +182,8 @@ func (s *wsfeService) GetCurrencies(ctx context.Context) ([]models.Currency, err
 
 	return []models.Currency{
 		{ID: "PES", Description: "Peso Argentino", Active: true},
-		{ID: "USD", Description: "Dólar Estadounidense", Active: true},
-		{ID: "EUR", Description: "Euro", Active: true},
+		{ID: "DOL", Description: "Dólar Estadounidense", Active: true},
+		{ID: "060", Description: "Euro", Active: true},
 	}, nil
 }
 
@@ -166,51 +205,74 @@ func (s *wsfeService) GetConceptTypes(ctx context.Context) ([]models.ConceptType
 	}, nil
 }
 
-// GetInvoiceTypes obtiene los tipos de comprobante disponibles
-func (s *wsfeService) GetInvoiceTypes(ctx context.Context) ([]models.InvoiceType, error) {
+// GetInvoiceTypes obtiene los tipos de comprobante de AFIP
+// (FEParamGetTiposCbte), con su descripción y vigencia
+func (s *wsfeService) GetInvoiceTypes(ctx context.Context) ([]models.InvoiceTypeInfo, error) {
 	// Obtener token de autenticación
-	_, err := s.authService.GetToken(ctx, "wsfe")
+	token, err := s.authService.GetToken(ctx, "wsfe")
 	if err != nil {
 		return nil, fmt.Errorf("auth failed: %w", err)
 	}
 
-	// TODO: Implementar consulta SOAP real
-	s.logger.Info("Getting invoice types")
-
-	return []models.InvoiceType{
-		models.InvoiceTypeA,
-		models.InvoiceTypeB,
-		models.InvoiceTypeC,
-		models.InvoiceTypeE,
-	}, nil
-}
+	shared.WithFields(s.logger, map[string]interface{}{
+		interfaces.LogFieldService: "wsfe",
+		interfaces.LogFieldAction:  "FEParamGetTiposCbte",
+	}).Info("Getting invoice types")
 
-// validateInvoice valida los datos de una factura
-func (s *wsfeService) validateInvoice(invoice *models.Invoice) error {
-	if invoice == nil {
-		return fmt.Errorf("invoice cannot be nil")
+	// Realizar llamada SOAP
+	request := &afipwsfe.ParametersRequest{Auth: s.newAuth(token)}
+	var response afipwsfe.InvoiceTypesResponse
+	if err := s.callSOAP(ctx, "FEParamGetTiposCbte", request, &response); err != nil {
+		return nil, err
 	}
 
-	if invoice.InvoiceNumber <= 0 {
-		return fmt.Errorf("invoice number must be greater than 0")
+	// Verificar errores
+	if len(response.Errors) > 0 {
+		return nil, models.NewServiceError(response.Errors[0].Code, response.Errors[0].Message)
 	}
 
-	if invoice.PointOfSale <= 0 {
-		return fmt.Errorf("point of sale must be greater than 0")
+	invoiceTypes := make([]models.InvoiceTypeInfo, 0, len(response.InvoiceTypes))
+	for _, it := range response.InvoiceTypes {
+		invoiceTypes = append(invoiceTypes, models.InvoiceTypeInfo{
+			ID:          models.InvoiceType(it.ID),
+			Description: it.Description,
+			Active:      utils.IsActiveParameter(it.DateTo),
+		})
 	}
 
-	if invoice.Amount <= 0 {
-		return fmt.Errorf("amount must be greater than 0")
+	return invoiceTypes, nil
+}
+
+// newAuth arma el bloque Auth de los requests a partir del token
+func (s *wsfeService) newAuth(token *interfaces.AccessToken) afipwsfe.Auth {
+	cuit, err := models.NormalizeCUIT(s.config.CUIT)
+	if err != nil {
+		cuit = s.config.CUIT
 	}
+	return afipwsfe.Auth{Token: token.Token, Sign: token.Sign, CUIT: cuit}
+}
+
+// callSOAP realiza una llamada SOAP a WSFEv1
+func (s *wsfeService) callSOAP(ctx context.Context, action string, request interface{}, response interface{}) error {
+	s.soapOnce.Do(func() {
+		s.soapClient = soap.NewClient(s.config.GetWSFEURL(), s.config.Timeout, soap.AsLogrus(s.logger))
+		s.soapClient.SetHTTPClient(s.config.NewHTTPClient())
+		s.soapClient.SetUserAgent(shared.UserAgent(s.config.UserAgent))
+	})
 
-	if len(invoice.Items) == 0 {
-		return fmt.Errorf("invoice must have at least one item")
+	return s.soapClient.Call(ctx, action, request, response)
+}
+
+// validateInvoice valida los datos de una factura
+func (s *wsfeService) validateInvoice(invoice *models.Invoice) error {
+	if invoice == nil {
+		return fmt.Errorf("invoice cannot be nil")
 	}
 
-	return nil
+	return invoice.Validate()
 }
 
 // NewWSFEService crea un nuevo servicio WSFE
-func NewWSFEService(authService interfaces.AuthService, logger interfaces.Logger) (interfaces.WSFEService, error) {
-	return newWSFEService(authService, logger)
+func NewWSFEService(config *shared.InternalConfig, authService interfaces.AuthService, logger interfaces.Logger) (interfaces.WSFEService, error) {
+	return newWSFEService(config, authService, logger)
 }
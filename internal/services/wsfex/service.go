@@ -1,22 +1,33 @@
 package wsfex
 
 import (
+	"github.com/dlarregola/arca_invoice_lib/internal/batch"
+	"github.com/dlarregola/arca_invoice_lib/internal/shared"
+	"github.com/dlarregola/arca_invoice_lib/internal/soap"
+	"github.com/dlarregola/arca_invoice_lib/internal/utils"
 	"github.com/dlarregola/arca_invoice_lib/pkg/interfaces"
 	"github.com/dlarregola/arca_invoice_lib/pkg/models"
+	afipwsfex "github.com/dlarregola/arca_invoice_lib/pkg/wsfex"
 	"context"
 	"fmt"
+	"sync"
 	"time"
 )
 
 // wsfexService es la implementación privada del servicio WSFEX
 type wsfexService struct {
+	config      *shared.InternalConfig
 	authService interfaces.AuthService
 	logger      interfaces.Logger
+
+	soapClient *soap.Client
+	soapOnce   sync.Once
 }
 
 // newWSFEXService crea un nuevo servicio WSFEX
-func newWSFEXService(authService interfaces.AuthService, logger interfaces.Logger) (interfaces.WSFEXService, error) {
+func newWSFEXService(config *shared.InternalConfig, authService interfaces.AuthService, logger interfaces.Logger) (interfaces.WSFEXService, error) {
 	return &wsfexService{
+		config:      config,
 		authService: authService,
 		logger:      logger,
 	}, nil
@@ -36,7 +47,13 @@ func (s *wsfexService) AuthorizeExportInvoice(ctx context.Context, invoice *mode
 	}
 
 	// TODO: Implementar llamada SOAP real
-	s.logger.Infof("Authorizing export invoice %d for point of sale %d", invoice.InvoiceNumber, invoice.PointOfSale)
+	shared.WithFields(s.logger, map[string]interface{}{
+		interfaces.LogFieldService:       "wsfex",
+		interfaces.LogFieldAction:        "FEXAuthorize",
+		interfaces.LogFieldPointOfSale:   invoice.PointOfSale,
+		interfaces.LogFieldInvoiceType:   int(invoice.InvoiceType),
+		interfaces.LogFieldInvoiceNumber: invoice.InvoiceNumber,
+	}).Info("Authorizing export invoice")
 
 	return &models.ExportAuthResponse{
 		AuthorizationResponse: models.AuthorizationResponse{
@@ -46,13 +63,19 @@ func (s *wsfexService) AuthorizeExportInvoice(ctx context.Context, invoice *mode
 			PointOfSale:       invoice.PointOfSale,
 			InvoiceType:       invoice.InvoiceType,
 			AuthorizationDate: invoice.DateFrom,
-			Status:            "A",
+			Status:            models.AuthResultApproved,
 			Message:           "Autorizado",
 		},
 		ExportType: invoice.ExportType,
 	}, nil
 }
 
+// AuthorizeExportInvoices autoriza un lote de comprobantes de exportación con concurrencia acotada
+func (s *wsfexService) AuthorizeExportInvoices(ctx context.Context, invoices []*models.ExportInvoice, concurrency int) ([]*models.ExportAuthResponse, error) {
+	responses, errs := batch.Run(ctx, invoices, concurrency, s.AuthorizeExportInvoice)
+	return responses, models.NewBatchError(errs)
+}
+
 // QueryExportInvoice consulta un comprobante de exportación
 func (s *wsfexService) QueryExportInvoice(ctx context.Context, query *models.ExportInvoiceQuery) (*models.ExportInvoice, error) {
 	// Obtener token de autenticación
@@ -62,7 +85,13 @@ func (s *wsfexService) QueryExportInvoice(ctx context.Context, query *models.Exp
 	}
 
 	// TODO: Implementar consulta SOAP real
-	s.logger.Infof("Querying export invoice %d for point of sale %d", query.InvoiceNumber, query.PointOfSale)
+	shared.WithFields(s.logger, map[string]interface{}{
+		interfaces.LogFieldService:       "wsfex",
+		interfaces.LogFieldAction:        "FEXGetCMP",
+		interfaces.LogFieldPointOfSale:   query.PointOfSale,
+		interfaces.LogFieldInvoiceType:   int(query.InvoiceType),
+		interfaces.LogFieldInvoiceNumber: query.InvoiceNumber,
+	}).Info("Querying export invoice")
 
 	// Retornar factura de exportación simulada
 	return &models.ExportInvoice{
@@ -76,7 +105,7 @@ func (s *wsfexService) QueryExportInvoice(ctx context.Context, query *models.Exp
 		Destination:     "Estados Unidos",
 		DestinationCode: "US",
 		ExportDate:      time.Now(),
-		ExportType:      "Definitiva",
+		ExportType:      models.ExportTypeGoods,
 	}, nil
 }
 
@@ -111,65 +140,80 @@ func (s *wsfexService) GetCurrencies(ctx context.Context) ([]models.Currency, er
 	s.logger.Info("Getting currencies")
 
 	return []models.Currency{
-		{ID: "USD", Description: "Dólar Estadounidense", Active: true},
-		{ID: "EUR", Description: "Euro", Active: true},
-		{ID: "BRL", Description: "Real Brasileño", Active: true},
+		{ID: "DOL", Description: "Dólar Estadounidense", Active: true},
+		{ID: "060", Description: "Euro", Active: true},
+		{ID: "012", Description: "Real Brasileño", Active: true},
 	}, nil
 }
 
-// GetUnitTypes obtiene los tipos de unidad disponibles
+// GetUnitTypes obtiene las unidades de medida de AFIP (FEXGetPARAM_UMed),
+// con su descripción y vigencia
 func (s *wsfexService) GetUnitTypes(ctx context.Context) ([]models.UnitType, error) {
 	// Obtener token de autenticación
-	_, err := s.authService.GetToken(ctx, "wsfex")
+	token, err := s.authService.GetToken(ctx, "wsfex")
 	if err != nil {
 		return nil, fmt.Errorf("auth failed: %w", err)
 	}
 
-	// TODO: Implementar consulta SOAP real
-	s.logger.Info("Getting unit types")
-
-	return []models.UnitType{
-		{ID: "UN", Description: "Unidad", Active: true},
-		{ID: "KG", Description: "Kilogramo", Active: true},
-		{ID: "M", Description: "Metro", Active: true},
-		{ID: "L", Description: "Litro", Active: true},
-	}, nil
-}
+	shared.WithFields(s.logger, map[string]interface{}{
+		interfaces.LogFieldService: "wsfex",
+		interfaces.LogFieldAction:  "FEXGetPARAM_UMed",
+	}).Info("Getting unit types")
 
-// validateExportInvoice valida los datos de una factura de exportación
-func (s *wsfexService) validateExportInvoice(invoice *models.ExportInvoice) error {
-	if invoice == nil {
-		return fmt.Errorf("export invoice cannot be nil")
+	// Realizar llamada SOAP
+	request := &afipwsfex.ExportParametersRequest{Auth: s.newAuth(token)}
+	var response afipwsfex.UnitMeasuresResponse
+	if err := s.callSOAP(ctx, "FEXGetPARAM_UMed", request, &response); err != nil {
+		return nil, err
 	}
 
-	if invoice.InvoiceNumber <= 0 {
-		return fmt.Errorf("invoice number must be greater than 0")
+	// Verificar errores
+	if len(response.Errors) > 0 {
+		return nil, models.NewServiceError(response.Errors[0].Code, response.Errors[0].Message)
 	}
 
-	if invoice.PointOfSale <= 0 {
-		return fmt.Errorf("point of sale must be greater than 0")
+	unitTypes := make([]models.UnitType, 0, len(response.UnitMeasures))
+	for _, unit := range response.UnitMeasures {
+		unitTypes = append(unitTypes, models.UnitType{
+			ID:          unit.ID,
+			Description: unit.Description,
+			Active:      utils.IsActiveParameter(unit.DateTo),
+		})
 	}
 
-	if invoice.Amount <= 0 {
-		return fmt.Errorf("amount must be greater than 0")
-	}
+	return unitTypes, nil
+}
 
-	if len(invoice.Items) == 0 {
-		return fmt.Errorf("export invoice must have at least one item")
+// newAuth arma el bloque Auth de los requests a partir del token
+func (s *wsfexService) newAuth(token *interfaces.AccessToken) afipwsfex.Auth {
+	cuit, err := models.NormalizeCUIT(s.config.CUIT)
+	if err != nil {
+		cuit = s.config.CUIT
 	}
+	return afipwsfex.Auth{Token: token.Token, Sign: token.Sign, CUIT: cuit}
+}
 
-	if invoice.Destination == "" {
-		return fmt.Errorf("destination cannot be empty")
-	}
+// callSOAP realiza una llamada SOAP a WSFEXv1
+func (s *wsfexService) callSOAP(ctx context.Context, action string, request interface{}, response interface{}) error {
+	s.soapOnce.Do(func() {
+		s.soapClient = soap.NewClient(s.config.GetWSFEXURL(), s.config.Timeout, soap.AsLogrus(s.logger))
+		s.soapClient.SetHTTPClient(s.config.NewHTTPClient())
+		s.soapClient.SetUserAgent(shared.UserAgent(s.config.UserAgent))
+	})
 
-	if invoice.ExportType == "" {
-		return fmt.Errorf("export type cannot be empty")
+	return s.soapClient.Call(ctx, action, request, response)
+}
+
+// validateExportInvoice valida los datos de una factura de exportación
+func (s *wsfexService) validateExportInvoice(invoice *models.ExportInvoice) error {
+	if invoice == nil {
+		return fmt.Errorf("export invoice cannot be nil")
 	}
 
-	return nil
+	return invoice.Validate()
 }
 
 // NewWSFEXService crea un nuevo servicio WSFEX
-func NewWSFEXService(authService interfaces.AuthService, logger interfaces.Logger) (interfaces.WSFEXService, error) {
-	return newWSFEXService(authService, logger)
+func NewWSFEXService(config *shared.InternalConfig, authService interfaces.AuthService, logger interfaces.Logger) (interfaces.WSFEXService, error) {
+	return newWSFEXService(config, authService, logger)
 }
@@ -1,42 +1,129 @@
 package shared
 
 import (
+	"crypto/tls"
+	"net/http"
+	"strings"
 	"time"
 )
 
+// Version es la versión de la librería
+const Version = "1.0.0"
+
+// DefaultUserAgent es el User-Agent enviado a AFIP si no se configura otro
+const DefaultUserAgent = "ARCA-Go-Client/" + Version
+
+// UserAgent retorna el User-Agent dado o, si está vacío, DefaultUserAgent
+func UserAgent(userAgent string) string {
+	if userAgent != "" {
+		return userAgent
+	}
+	return DefaultUserAgent
+}
+
+// Destinos del loginTicketRequest de WSAA (DN del certificado de WSAA) en
+// homologación y producción
+const (
+	WSAADestinationTesting    = "cn=wsaahomo,o=afip,c=ar,serialNumber=CUIT 33693450239"
+	WSAADestinationProduction = "cn=wsaa,o=afip,c=ar,serialNumber=CUIT 33693450239"
+)
+
+// WSAADestination retorna el destino del loginTicketRequest para el
+// environment ("testing" o "production"); por defecto, el de homologación
+func WSAADestination(environment string) string {
+	if environment == "production" {
+		return WSAADestinationProduction
+	}
+	return WSAADestinationTesting
+}
+
+// DefaultWSAARequestTTL es la validez del loginTicketRequest enviado a WSAA
+// (entre generationTime y expirationTime). AFIP recomienda una ventana de
+// minutos; es independiente de la duración del ticket que WSAA retorna.
+const DefaultWSAARequestTTL = 10 * time.Minute
+
+// URLs base de AFIP en homologación y producción
+const (
+	BaseURLTesting    = "https://wswhomo.afip.gov.ar"
+	BaseURLProduction = "https://servicios1.afip.gov.ar"
+)
+
+// Rutas de los servicios de AFIP, relativas a la URL base
+const (
+	WSAAPath  = "/ws/services/LoginCms"
+	WSFEPath  = "/wsfev1/service.asmx"
+	WSFEXPath = "/wsfexv1/service.asmx"
+)
+
+// BaseURL retorna la URL base de AFIP: override sin la barra final si está
+// configurado o, si no, la del environment ("testing" o "production"); por
+// defecto, la de homologación
+func BaseURL(environment, override string) string {
+	if override != "" {
+		return strings.TrimSuffix(override, "/")
+	}
+	if environment == "production" {
+		return BaseURLProduction
+	}
+	return BaseURLTesting
+}
+
 // InternalConfig representa la configuración interna del cliente
 type InternalConfig struct {
-	CUIT          string
-	Certificate   []byte
-	PrivateKey    []byte
-	Environment   string
-	Timeout       time.Duration
-	RetryAttempts int
+	CUIT            string
+	Certificate     []byte
+	PrivateKey      []byte
+	Environment     string
+	BaseURLOverride string
+	Timeout         time.Duration
+	RetryAttempts   int
+	TLSConfig       *tls.Config
+	UserAgent       string
 }
 
-// GetBaseURL retorna la URL base según el environment
-func (c *InternalConfig) GetBaseURL() string {
-	switch c.Environment {
-	case "testing":
-		return "https://wswhomo.afip.gov.ar"
-	case "production":
-		return "https://servicios1.afip.gov.ar"
-	default:
-		return "https://wswhomo.afip.gov.ar"
+// NewHTTPClient crea el cliente HTTP para las llamadas a AFIP, aplicando la
+// configuración TLS si está presente (por defecto exige TLS 1.2 o superior)
+func (c *InternalConfig) NewHTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = TLSClientConfig(c.TLSConfig)
+
+	return &http.Client{
+		Timeout:   c.Timeout,
+		Transport: transport,
+	}
+}
+
+// TLSClientConfig retorna una copia de la configuración TLS dada o, si es
+// nil, la configuración segura por defecto (TLS 1.2+ y CAs del sistema)
+func TLSClientConfig(config *tls.Config) *tls.Config {
+	if config != nil {
+		return config.Clone()
 	}
+	return &tls.Config{MinVersion: tls.VersionTLS12}
+}
+
+// GetBaseURL retorna la URL base según el environment o BaseURLOverride
+func (c *InternalConfig) GetBaseURL() string {
+	return BaseURL(c.Environment, c.BaseURLOverride)
+}
+
+// GetWSAADestination retorna el destino del loginTicketRequest de WSAA según
+// el environment
+func (c *InternalConfig) GetWSAADestination() string {
+	return WSAADestination(c.Environment)
 }
 
 // GetWSAAURL retorna la URL del servicio WSAA
 func (c *InternalConfig) GetWSAAURL() string {
-	return c.GetBaseURL() + "/ws/services/LoginCms"
+	return c.GetBaseURL() + WSAAPath
 }
 
 // GetWSFEURL retorna la URL del servicio WSFEv1
 func (c *InternalConfig) GetWSFEURL() string {
-	return c.GetBaseURL() + "/wsfev1/service.asmx"
+	return c.GetBaseURL() + WSFEPath
 }
 
 // GetWSFEXURL retorna la URL del servicio WSFEXv1
 func (c *InternalConfig) GetWSFEXURL() string {
-	return c.GetBaseURL() + "/wsfexv1/service.asmx"
+	return c.GetBaseURL() + WSFEXPath
 }
@@ -0,0 +1,74 @@
+package shared
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dlarregola/arca_invoice_lib/pkg/interfaces"
+)
+
+// WithFields retorna un logger que registra fields con cada mensaje. Si
+// logger implementa interfaces.FieldLogger delega en él; si no, agrega los
+// campos al final del mensaje como clave=valor, ordenados por clave, de modo
+// que los loggers printf existentes siguen funcionando.
+func WithFields(logger interfaces.Logger, fields map[string]interface{}) interfaces.Logger {
+	if logger == nil {
+		return nil
+	}
+	if fieldLogger, ok := logger.(interfaces.FieldLogger); ok {
+		return fieldLogger.WithFields(fields)
+	}
+	return &fieldsLogger{logger: logger, fields: fields}
+}
+
+// fieldsLogger agrega campos clave=valor a los mensajes de un logger printf
+type fieldsLogger struct {
+	logger interfaces.Logger
+	fields map[string]interface{}
+}
+
+// WithFields implementa interfaces.FieldLogger, combinando los campos
+func (l *fieldsLogger) WithFields(fields map[string]interface{}) interfaces.Logger {
+	merged := make(map[string]interface{}, len(l.fields)+len(fields))
+	for key, value := range l.fields {
+		merged[key] = value
+	}
+	for key, value := range fields {
+		merged[key] = value
+	}
+	return &fieldsLogger{logger: l.logger, fields: merged}
+}
+
+// format agrega los campos al mensaje
+func (l *fieldsLogger) format(message string) string {
+	keys := make([]string, 0, len(l.fields))
+	for key := range l.fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(message)
+	for _, key := range keys {
+		fmt.Fprintf(&b, " %s=%v", key, l.fields[key])
+	}
+	return b.String()
+}
+
+func (l *fieldsLogger) Debug(args ...interface{}) { l.logger.Debug(l.format(fmt.Sprint(args...))) }
+func (l *fieldsLogger) Debugf(format string, args ...interface{}) {
+	l.logger.Debug(l.format(fmt.Sprintf(format, args...)))
+}
+func (l *fieldsLogger) Info(args ...interface{}) { l.logger.Info(l.format(fmt.Sprint(args...))) }
+func (l *fieldsLogger) Infof(format string, args ...interface{}) {
+	l.logger.Info(l.format(fmt.Sprintf(format, args...)))
+}
+func (l *fieldsLogger) Warn(args ...interface{}) { l.logger.Warn(l.format(fmt.Sprint(args...))) }
+func (l *fieldsLogger) Warnf(format string, args ...interface{}) {
+	l.logger.Warn(l.format(fmt.Sprintf(format, args...)))
+}
+func (l *fieldsLogger) Error(args ...interface{}) { l.logger.Error(l.format(fmt.Sprint(args...))) }
+func (l *fieldsLogger) Errorf(format string, args ...interface{}) {
+	l.logger.Error(l.format(fmt.Sprintf(format, args...)))
+}
@@ -0,0 +1,27 @@
+package soap
+
+import (
+	"encoding/xml"
+	"strconv"
+)
+
+// FormatAmount formatea un importe como lo espera AFIP: punto decimal y
+// exactamente dos decimales ("1210.00"). encoding/xml serializa un float64
+// con la mínima precisión, lo que produce "1210", "1e+06" o "1210.0000001".
+func FormatAmount(value float64) string {
+	return strconv.FormatFloat(value, 'f', 2, 64)
+}
+
+// Amount es un importe del request. Se serializa con dos decimales
+// ("1210.00"), como lo espera AFIP.
+type Amount float64
+
+// MarshalXML implementa xml.Marshaler
+func (a Amount) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return EncodeAmount(e, start, float64(a))
+}
+
+// EncodeAmount codifica value con FormatAmount dentro del elemento start
+func EncodeAmount(e *xml.Encoder, start xml.StartElement, value float64) error {
+	return e.EncodeElement(FormatAmount(value), start)
+}
@@ -2,24 +2,66 @@ package soap
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"crypto/tls"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/dlarregola/arca_invoice_lib/internal/shared"
+	"github.com/dlarregola/arca_invoice_lib/pkg/core"
 	"github.com/dlarregola/arca_invoice_lib/pkg/models"
 
 	"github.com/sirupsen/logrus"
 )
 
+// Capture recibe los envelopes SOAP enviados y recibidos
+type Capture interface {
+	OnRequest(action string, body []byte)
+	OnResponse(action string, status int, body []byte)
+}
+
 // Client representa un cliente SOAP
 type Client struct {
 	httpClient *http.Client
 	logger     *logrus.Logger
 	baseURL    string
+	userAgent  string
+
+	validateRequests bool
+
+	capture          Capture
+	captureRequests  bool
+	captureResponses bool
+
+	interceptors []core.Interceptor
+
+	breaker *core.CircuitBreaker
+
+	// endpoints son la URL y el circuit breaker de cada ambiente al que se
+	// puede dirigir una llamada con core.WithEnvironmentOverride
+	endpoints map[models.Environment]endpoint
+}
+
+// endpoint es el destino de las llamadas en un ambiente
+type endpoint struct {
+	url     string
+	breaker *core.CircuitBreaker
+}
+
+// redactPattern identifica los valores sensibles del bloque Auth
+var redactPattern = regexp.MustCompile(`(?i)(<(?:[\w-]+:)?(?:token|sign|cuit)(?:\s[^>]*)?>)[^<]*(</)`)
+
+// Redact oculta los valores de token, sign y CUIT de un envelope SOAP
+func Redact(body []byte) []byte {
+	return redactPattern.ReplaceAll(body, []byte("${1}***${2}"))
 }
 
 // NewClient crea un nuevo cliente SOAP
@@ -27,8 +69,10 @@ func NewClient(baseURL string, timeout time.Duration, logger *logrus.Logger) *Cl
 	httpClient := &http.Client{
 		Timeout: timeout,
 		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
 			TLSClientConfig: &tls.Config{
 				InsecureSkipVerify: false,
+				MinVersion:         tls.VersionTLS12,
 			},
 		},
 	}
@@ -37,97 +81,364 @@ func NewClient(baseURL string, timeout time.Duration, logger *logrus.Logger) *Cl
 		httpClient: httpClient,
 		logger:     logger,
 		baseURL:    baseURL,
+		userAgent:  shared.DefaultUserAgent,
 	}
 }
 
-// Call realiza una llamada SOAP
+// Call realiza una llamada SOAP. Si hay un circuit breaker configurado y está
+// abierto, falla sin contactar a AFIP. Si ctx fuerza un ambiente con
+// core.WithEnvironmentOverride y hay un endpoint para él (ver
+// SetEnvironmentEndpoint), la llamada se envía a ese endpoint.
 func (c *Client) Call(ctx context.Context, action string, request interface{}, response interface{}) error {
+	target := c.endpoint(ctx)
+	if target.breaker == nil {
+		return c.call(ctx, target.url, action, request, response)
+	}
+
+	if err := target.breaker.Allow(); err != nil {
+		return err
+	}
+
+	err := c.call(ctx, target.url, action, request, response)
+	// Una cancelación del llamador no cuenta como falla del servicio
+	switch {
+	case !isOutage(err):
+		target.breaker.RecordSuccess()
+	case ctx.Err() == nil:
+		target.breaker.RecordFailure()
+	}
+	return err
+}
+
+// endpoint retorna el destino de la llamada: el del ambiente forzado en ctx
+// si está registrado o, si no, la URL base y el circuit breaker del cliente
+func (c *Client) endpoint(ctx context.Context) endpoint {
+	if env, ok := core.EnvironmentOverrideFromContext(ctx); ok {
+		if target, ok := c.endpoints[env]; ok {
+			return target
+		}
+	}
+	return endpoint{url: c.baseURL, breaker: c.breaker}
+}
+
+// isOutage indica si el error muestra que el servicio no está disponible
+// (falla de red o HTTP 5xx), a diferencia de un rechazo de AFIP
+func isOutage(err error) bool {
+	var networkErr *models.NetworkError
+	if errors.As(err, &networkErr) {
+		return networkErr.Retryable || networkErr.Status == 0 || networkErr.Status >= 500
+	}
+	return false
+}
+
+// call realiza la llamada SOAP a url sin pasar por el circuit breaker
+func (c *Client) call(ctx context.Context, url, action string, request interface{}, response interface{}) error {
+	operation, err := LookupOperation(action)
+	if err != nil {
+		return err
+	}
+
 	// Serializar request a XML
-	requestXML, err := xml.MarshalIndent(request, "", "  ")
+	requestXML, err := marshalRequest(operation, request)
 	if err != nil {
-		return fmt.Errorf("error marshaling request: %w", err)
+		return err
+	}
+
+	// Verificar el request antes de enviarlo
+	if err := ValidateAuth(requestXML); err != nil {
+		return err
+	}
+	if c.validateRequests {
+		if err := ValidateRequest(action, requestXML); err != nil {
+			return err
+		}
 	}
 
 	// Crear envelope SOAP
-	envelope := &SOAPEnvelope{
-		XMLName: xml.Name{Space: "http://schemas.xmlsoap.org/soap/envelope/", Local: "Envelope"},
-		Header:  &SOAPHeader{},
-		Body: SOAPBody{
-			Content: requestXML,
-		},
+	envelopeXML, err := newEnvelope(requestXML)
+	if err != nil {
+		return err
 	}
 
-	// Serializar envelope
-	envelopeXML, err := xml.MarshalIndent(envelope, "", "  ")
+	// Enviar el envelope a través de los interceptores. Si alguno responde
+	// sin llegar a AFIP, la respuesta se interpreta como un HTTP 200.
+	exchange := &httpExchange{status: http.StatusOK, statusText: "200 OK", header: http.Header{}}
+	responseBody, err := c.roundTrip(ctx, action, envelopeXML, func(body []byte) ([]byte, error) {
+		return c.send(ctx, url, operation, body, exchange)
+	})
 	if err != nil {
-		return fmt.Errorf("error marshaling envelope: %w", err)
+		return err
+	}
+
+	// AFIP limita la cantidad de requests por CUIT
+	retryAfter := parseRetryAfter(exchange.header.Get("Retry-After"))
+	if exchange.status == http.StatusTooManyRequests {
+		return models.NewRateLimitError(fmt.Sprintf("HTTP error: %s", exchange.statusText), retryAfter)
+	}
+
+	// Parsear response SOAP
+	var responseEnvelope SOAPEnvelope
+	if err := xml.Unmarshal(responseBody, &responseEnvelope); err != nil {
+		if exchange.status != http.StatusOK {
+			return models.NewNetworkError(fmt.Sprintf("HTTP error: %s", exchange.statusText), url, exchange.status)
+		}
+		if partialErr := partialResponseError(responseBody, err, url, exchange.status); partialErr != nil {
+			return partialErr
+		}
+		return models.NewARCAError(models.ErrorCodeInvalidResponse, fmt.Sprintf("error unmarshaling SOAP response: %v", err))
+	}
+
+	// Verificar si hay error SOAP (los servicios .asmx lo devuelven con HTTP 500)
+	if responseEnvelope.Body.Fault != nil {
+		fault := responseEnvelope.Body.Fault
+		if strings.HasSuffix(fault.FaultCode, models.ErrorCodeRateLimitExceeded) {
+			return models.NewRateLimitError(fault.FaultString, retryAfter)
+		}
+		return models.NewARCAError(fault.FaultCode, fault.FaultString)
+	}
+
+	// Verificar status code
+	if exchange.status != http.StatusOK {
+		return models.NewNetworkError(fmt.Sprintf("HTTP error: %s", exchange.statusText), url, exchange.status)
+	}
+
+	// Parsear contenido de respuesta
+	if err := decodeResult(responseEnvelope.Body.Content, response); err != nil {
+		return models.NewARCAError(models.ErrorCodeInvalidResponse, fmt.Sprintf("error unmarshaling response content: %v", err))
+	}
+
+	return nil
+}
+
+// maxBodySnippet es la cantidad de bytes de la respuesta que se incluyen en
+// el error cuando el body está cortado o no es XML
+const maxBodySnippet = 200
+
+// partialResponseError detecta las respuestas vacías, cortadas o que no son
+// XML, que AFIP envía con status 200 durante sus ventanas de mantenimiento,
+// y las informa como un NetworkError reintentable con el comienzo del body.
+// Retorna nil si el body es XML completo pero no tiene la forma esperada.
+func partialResponseError(body []byte, unmarshalErr error, url string, status int) error {
+	trimmed := bytes.TrimSpace(body)
+
+	var message string
+	var syntaxErr *xml.SyntaxError
+	switch {
+	case len(trimmed) == 0:
+		message = "empty SOAP response body"
+	case trimmed[0] != '<':
+		message = "SOAP response body is not XML"
+	case errors.Is(unmarshalErr, io.ErrUnexpectedEOF), errors.As(unmarshalErr, &syntaxErr) && syntaxErr.Msg == "unexpected EOF":
+		message = "truncated SOAP response body"
+	default:
+		return nil
 	}
 
+	snippet := trimmed
+	if len(snippet) > maxBodySnippet {
+		snippet = snippet[:maxBodySnippet]
+	}
+	networkErr := models.NewNetworkError(fmt.Sprintf("%s (%d bytes): %q", message, len(body), Redact(snippet)), url, status)
+	networkErr.Body = string(Redact(snippet))
+	networkErr.Retryable = true
+	return networkErr
+}
+
+// httpExchange guarda el status y los headers de la respuesta HTTP de AFIP,
+// que los interceptores no ven
+type httpExchange struct {
+	status     int
+	statusText string
+	header     http.Header
+}
+
+// roundTrip pasa el envelope por los interceptores en orden hasta send
+func (c *Client) roundTrip(ctx context.Context, action string, envelope []byte, send func([]byte) ([]byte, error)) ([]byte, error) {
+	next := send
+	for i := len(c.interceptors) - 1; i >= 0; i-- {
+		interceptor, inner := c.interceptors[i], next
+		next = func(body []byte) ([]byte, error) {
+			return interceptor.RoundTrip(ctx, action, body, inner)
+		}
+	}
+	return next(envelope)
+}
+
+// send envía el envelope a url y retorna el body de la respuesta,
+// guardando su status y headers en exchange
+func (c *Client) send(ctx context.Context, url string, operation Operation, envelopeXML []byte, exchange *httpExchange) ([]byte, error) {
+	action := operation.Name
+
 	// Log request si está habilitado
 	if c.logger.GetLevel() >= logrus.DebugLevel {
-		c.logger.WithFields(logrus.Fields{
+		c.logger.WithFields(logFields(ctx, logrus.Fields{
 			"action": action,
-			"url":    c.baseURL,
-		}).Debug("SOAP Request")
+			"url":    url,
+		})).Debug("SOAP Request")
 		c.logger.Debug(string(envelopeXML))
 	}
 
+	if c.capture != nil && c.captureRequests {
+		c.capture.OnRequest(action, Redact(envelopeXML))
+	}
+
 	// Crear request HTTP
-	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL, bytes.NewReader(envelopeXML))
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(envelopeXML))
 	if err != nil {
-		return fmt.Errorf("error creating HTTP request: %w", err)
+		return nil, fmt.Errorf("error creating HTTP request: %w", err)
 	}
 
 	// Configurar headers
 	req.Header.Set("Content-Type", "text/xml; charset=utf-8")
-	req.Header.Set("SOAPAction", action)
-	req.Header.Set("User-Agent", "ARCA-Go-Client/1.0")
+	req.Header.Set("SOAPAction", operation.SOAPAction())
+	req.Header.Set("User-Agent", c.userAgent)
+	req.Header.Set("Accept-Encoding", "gzip")
 
 	// Realizar request
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return models.NewNetworkError(fmt.Sprintf("error making HTTP request: %v", err), c.baseURL, 0)
+		return nil, models.NewNetworkError(fmt.Sprintf("error making HTTP request: %v", err), url, 0)
 	}
 	defer resp.Body.Close()
 
-	// Leer response
-	responseBody, err := io.ReadAll(resp.Body)
+	exchange.status = resp.StatusCode
+	exchange.statusText = resp.Status
+	exchange.header = resp.Header
+
+	// Leer response, descomprimiendo si el servidor respondió con gzip
+	var body io.Reader = resp.Body
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		gzipReader, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, models.NewNetworkError(fmt.Sprintf("error decompressing response body: %v", err), url, resp.StatusCode)
+		}
+		defer gzipReader.Close()
+		body = gzipReader
+	}
+
+	responseBody, err := io.ReadAll(body)
 	if err != nil {
-		return models.NewNetworkError(fmt.Sprintf("error reading response body: %v", err), c.baseURL, resp.StatusCode)
+		return nil, models.NewNetworkError(fmt.Sprintf("error reading response body: %v", err), url, resp.StatusCode)
+	}
+
+	if c.capture != nil && c.captureResponses {
+		c.capture.OnResponse(action, resp.StatusCode, Redact(responseBody))
 	}
 
 	// Log response si está habilitado
 	if c.logger.GetLevel() >= logrus.DebugLevel {
-		c.logger.WithFields(logrus.Fields{
+		c.logger.WithFields(logFields(ctx, logrus.Fields{
 			"status_code": resp.StatusCode,
 			"action":      action,
-		}).Debug("SOAP Response")
+		})).Debug("SOAP Response")
 		c.logger.Debug(string(responseBody))
 	}
 
-	// Verificar status code
-	if resp.StatusCode != http.StatusOK {
-		return models.NewNetworkError(fmt.Sprintf("HTTP error: %s", resp.Status), c.baseURL, resp.StatusCode)
+	return responseBody, nil
+}
+
+// logFields agrega a fields el ID de correlación del contexto, si lo hay
+func logFields(ctx context.Context, fields logrus.Fields) logrus.Fields {
+	if id := core.RequestIDFromContext(ctx); id != "" {
+		fields["request_id"] = id
 	}
+	return fields
+}
 
-	// Parsear response SOAP
-	var responseEnvelope SOAPEnvelope
-	if err := xml.Unmarshal(responseBody, &responseEnvelope); err != nil {
-		return models.NewARCAError(models.ErrorCodeInvalidResponse, fmt.Sprintf("error unmarshaling SOAP response: %v", err))
+// BuildEnvelope serializa el request del método action y lo envuelve en el
+// envelope SOAP, tal como lo envía Call
+func BuildEnvelope(action string, request interface{}) ([]byte, error) {
+	operation, err := LookupOperation(action)
+	if err != nil {
+		return nil, err
 	}
 
-	// Verificar si hay error SOAP
-	if responseEnvelope.Body.Fault != nil {
-		fault := responseEnvelope.Body.Fault
-		return models.NewARCAError(fault.FaultCode, fault.FaultString)
+	requestXML, err := marshalRequest(operation, request)
+	if err != nil {
+		return nil, err
 	}
 
-	// Parsear contenido de respuesta
-	if err := xml.Unmarshal(responseEnvelope.Body.Content, response); err != nil {
-		return models.NewARCAError(models.ErrorCodeInvalidResponse, fmt.Sprintf("error unmarshaling response content: %v", err))
+	return newEnvelope(requestXML)
+}
+
+// marshalRequest serializa el request como el elemento del método (ej.
+// <FECAESolicitar>) en el namespace del servicio, que heredan sus hijos
+func marshalRequest(operation Operation, request interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+	encoder := xml.NewEncoder(&buffer)
+	encoder.Indent("", "  ")
+
+	start := xml.StartElement{Name: xml.Name{Space: operation.Namespace, Local: operation.Name}}
+	if err := encoder.EncodeElement(request, start); err != nil {
+		return nil, fmt.Errorf("error marshaling request: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("error marshaling request: %w", err)
 	}
 
-	return nil
+	return buffer.Bytes(), nil
+}
+
+// newEnvelope envuelve el XML del request en un envelope SOAP
+func newEnvelope(requestXML []byte) ([]byte, error) {
+	envelope := &SOAPEnvelope{
+		XMLName: xml.Name{Space: "http://schemas.xmlsoap.org/soap/envelope/", Local: "Envelope"},
+		Header:  &SOAPHeader{},
+		Body: SOAPBody{
+			Content: requestXML,
+		},
+	}
+
+	envelopeXML, err := xml.MarshalIndent(envelope, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling envelope: %w", err)
+	}
+
+	return envelopeXML, nil
+}
+
+// decodeResult decodifica el resultado de una respuesta SOAP. Los servicios de
+// AFIP envuelven el resultado en <Método>Response><Método>Result>, por lo que
+// se decodifica el primer elemento hijo del elemento de respuesta.
+func decodeResult(content []byte, response interface{}) error {
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	depth := 0
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			if depth == 2 {
+				return decoder.DecodeElement(response, &t)
+			}
+		case xml.EndElement:
+			depth--
+		}
+	}
+}
+
+// parseRetryAfter interpreta el header Retry-After, expresado en segundos o
+// como fecha HTTP. Retorna cero si está vacío o no es válido.
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(date); wait > 0 {
+			return wait
+		}
+	}
+	return 0
 }
 
 // SOAPEnvelope representa un envelope SOAP
@@ -162,7 +473,58 @@ func (c *Client) SetTimeout(timeout time.Duration) {
 	c.httpClient.Timeout = timeout
 }
 
+// SetHTTPClient reemplaza el cliente HTTP (para usar un proxy o transporte propio)
+func (c *Client) SetHTTPClient(httpClient *http.Client) {
+	c.httpClient = httpClient
+}
+
+// SetUserAgent actualiza el User-Agent enviado en cada request
+func (c *Client) SetUserAgent(userAgent string) {
+	c.userAgent = shared.UserAgent(userAgent)
+}
+
+// SetValidateRequests activa la verificación de cardinalidad de elementos
+// antes de enviar cada request (ver ValidateRequest)
+func (c *Client) SetValidateRequests(enabled bool) {
+	c.validateRequests = enabled
+}
+
+// SetCapture configura el hook de captura de requests y responses
+func (c *Client) SetCapture(capture Capture, requests, responses bool) {
+	c.capture = capture
+	c.captureRequests = requests
+	c.captureResponses = responses
+}
+
+// SetInterceptors configura los interceptores de las llamadas, aplicados en
+// el orden dado
+func (c *Client) SetInterceptors(interceptors []core.Interceptor) {
+	c.interceptors = interceptors
+}
+
+// SetCircuitBreaker configura el circuit breaker del servicio (nil lo deshabilita)
+func (c *Client) SetCircuitBreaker(breaker *core.CircuitBreaker) {
+	c.breaker = breaker
+}
+
+// SetEnvironmentEndpoint registra la URL y el circuit breaker (nil lo
+// deshabilita) de las llamadas cuyo contexto fuerza el ambiente env
+func (c *Client) SetEnvironmentEndpoint(env models.Environment, url string, breaker *core.CircuitBreaker) {
+	if c.endpoints == nil {
+		c.endpoints = make(map[models.Environment]endpoint)
+	}
+	c.endpoints[env] = endpoint{url: url, breaker: breaker}
+}
+
 // SetLogger actualiza el logger del cliente
 func (c *Client) SetLogger(logger *logrus.Logger) {
 	c.logger = logger
 }
+
+// AsLogrus retorna el logger dado si es un *logrus.Logger, o uno nuevo por defecto
+func AsLogrus(logger interface{}) *logrus.Logger {
+	if l, ok := logger.(*logrus.Logger); ok && l != nil {
+		return l
+	}
+	return logrus.New()
+}
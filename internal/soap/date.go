@@ -0,0 +1,57 @@
+package soap
+
+import (
+	"encoding/xml"
+	"time"
+
+	"github.com/dlarregola/arca_invoice_lib/internal/utils"
+)
+
+// AFIPDate es una fecha del request o de la respuesta, que AFIP expresa
+// como AAAAMMDD (20240115). Las fechas vacías o "NULL" quedan en cero.
+type AFIPDate struct {
+	time.Time
+}
+
+// OptionalDate retorna t como fecha opcional del request: nil si es la fecha
+// cero, de modo que un campo *AFIPDate con omitempty no se envía
+func OptionalDate(t time.Time) *AFIPDate {
+	if t.IsZero() {
+		return nil
+	}
+	return &AFIPDate{Time: t}
+}
+
+// MarshalXML implementa xml.Marshaler
+func (d AFIPDate) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return EncodeDate(e, start, d.Time)
+}
+
+// UnmarshalXML implementa xml.Unmarshaler
+func (d *AFIPDate) UnmarshalXML(dec *xml.Decoder, start xml.StartElement) error {
+	t, err := DecodeDate(dec, start)
+	if err != nil {
+		return err
+	}
+	d.Time = t
+	return nil
+}
+
+// EncodeDate codifica t en formato AAAAMMDD dentro del elemento start. La
+// fecha cero se envía vacía.
+func EncodeDate(e *xml.Encoder, start xml.StartElement, t time.Time) error {
+	if t.IsZero() {
+		return e.EncodeElement("", start)
+	}
+	return e.EncodeElement(t.Format(utils.AFIPDateFormat), start)
+}
+
+// DecodeDate decodifica la fecha AAAAMMDD (o AAAAMMDDhhmmss) del elemento
+// start
+func DecodeDate(d *xml.Decoder, start xml.StartElement) (time.Time, error) {
+	var value string
+	if err := d.DecodeElement(&value, &start); err != nil {
+		return time.Time{}, err
+	}
+	return utils.ParseAFIPDate(value)
+}
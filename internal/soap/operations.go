@@ -0,0 +1,67 @@
+package soap
+
+import "fmt"
+
+// Namespaces de los servicios SOAP de AFIP
+const (
+	NamespaceWSFE  = "http://ar.gov.afip.dif.FEV1/"
+	NamespaceWSFEX = "http://ar.gov.afip.dif.fexv1/"
+)
+
+// Operation identifica un método de un servicio SOAP de AFIP
+type Operation struct {
+	Name      string
+	Namespace string
+}
+
+// SOAPAction retorna el valor del header SOAPAction del método
+func (o Operation) SOAPAction() string {
+	return o.Namespace + o.Name
+}
+
+// operations es el namespace de cada método que invocan los servicios. AFIP
+// rechaza los requests sin el SOAPAction completo o con el elemento del
+// método fuera del namespace del servicio.
+var operations = map[string]string{
+	// WSFEv1
+	"FEDummy":                        NamespaceWSFE,
+	"FECAESolicitar":                 NamespaceWSFE,
+	"FECompConsultar":                NamespaceWSFE,
+	"FECompUltimoAutorizado":         NamespaceWSFE,
+	"FECAEASolicitar":                NamespaceWSFE,
+	"FECAEARegInformativo":           NamespaceWSFE,
+	"FECAEASinMovimientoInformar":    NamespaceWSFE,
+	"FEParamGetActividades":          NamespaceWSFE,
+	"FEParamGetCondicionIvaReceptor": NamespaceWSFE,
+	"FEParamGetPtosVenta":            NamespaceWSFE,
+	"FEParamGetTiposCbte":            NamespaceWSFE,
+	"FEParamGetTiposConcepto":        NamespaceWSFE,
+	"FEParamGetTiposDoc":             NamespaceWSFE,
+	"FEParamGetTiposIva":             NamespaceWSFE,
+	"FEParamGetTiposMonedas":         NamespaceWSFE,
+	"FEParamGetTiposOpcional":        NamespaceWSFE,
+
+	// WSFEXv1
+	"FEXAuthorize":          NamespaceWSFEX,
+	"FEXCheck_Permiso":      NamespaceWSFEX,
+	"FEXGetCAEA":            NamespaceWSFEX,
+	"FEXGetCMP":             NamespaceWSFEX,
+	"FEXGetLast_CMP":        NamespaceWSFEX,
+	"FEXGetPARAM_Ctz":       NamespaceWSFEX,
+	"FEXGetPARAM_DST_pais":  NamespaceWSFEX,
+	"FEXGetPARAM_Incoterms": NamespaceWSFEX,
+	"FEXGetPARAM_MON":       NamespaceWSFEX,
+	"FEXGetPARAM_Tipo_Cbte": NamespaceWSFEX,
+	"FEXGetPARAM_Tipo_Expo": NamespaceWSFEX,
+	"FEXGetPARAM_UMed":      NamespaceWSFEX,
+}
+
+// LookupOperation retorna el método de AFIP con el nombre dado (ej.
+// "FECAESolicitar") y su namespace
+func LookupOperation(name string) (Operation, error) {
+	namespace, ok := operations[name]
+	if !ok {
+		return Operation{}, fmt.Errorf("unknown SOAP operation %q", name)
+	}
+	return Operation{Name: name, Namespace: namespace}, nil
+}
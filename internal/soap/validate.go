@@ -0,0 +1,160 @@
+package soap
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/dlarregola/arca_invoice_lib/pkg/models"
+)
+
+// elementRule indica cuántas veces debe aparecer un elemento en el request.
+// Max 0 significa sin límite.
+type elementRule struct {
+	Name string
+	Min  int
+	Max  int
+}
+
+// requestRules es la cardinalidad de elementos que AFIP espera por método
+var requestRules = map[string][]elementRule{
+	"FECAESolicitar": {
+		{Name: "FeCAEReq", Min: 1, Max: 1},
+		{Name: "FeCabReq", Min: 1, Max: 1},
+		{Name: "FeDetReq", Min: 1},
+	},
+	"FECompConsultar": {
+		{Name: "FeCompConsReq", Min: 1, Max: 1},
+	},
+	"FEXAuthorize": {
+		{Name: "Cmp", Min: 1, Max: 1},
+		{Name: "Item", Min: 1},
+	},
+}
+
+// authFields son los campos del bloque Auth que no pueden estar vacíos
+var authFields = []string{"token", "sign", "cuit"}
+
+// ValidateAuth verifica que el bloque Auth del request, si existe, tenga
+// token, sign y CUIT. Retorna un *models.AuthenticationError si falta alguno.
+func ValidateAuth(body []byte) error {
+	values, found, err := authValues(body)
+	if err != nil {
+		return fmt.Errorf("error parsing request: %w", err)
+	}
+	if !found {
+		return nil
+	}
+
+	var missing []string
+	for _, field := range authFields {
+		if strings.TrimSpace(values[field]) == "" {
+			missing = append(missing, field)
+		}
+	}
+	if len(missing) > 0 {
+		return models.NewAuthenticationError(fmt.Sprintf("Auth incompleto: falta %s", strings.Join(missing, ", ")), "")
+	}
+
+	return nil
+}
+
+// ValidateRequest verifica la cardinalidad de los elementos del request
+// según lo que AFIP espera para el método. Los métodos sin reglas se aceptan.
+func ValidateRequest(action string, body []byte) error {
+	rules, ok := requestRules[action]
+	if !ok {
+		return nil
+	}
+
+	counts, err := countElements(body)
+	if err != nil {
+		return fmt.Errorf("error parsing request: %w", err)
+	}
+
+	var errors models.ValidationErrors
+	for _, rule := range rules {
+		count := counts[rule.Name]
+		switch {
+		case count < rule.Min:
+			errors.Add(rule.Name, fmt.Sprintf("%s requiere al menos %d elemento(s) %s", action, rule.Min, rule.Name), count)
+		case rule.Max > 0 && count > rule.Max:
+			errors.Add(rule.Name, fmt.Sprintf("%s admite a lo sumo %d elemento(s) %s", action, rule.Max, rule.Name), count)
+		}
+	}
+	if errors.HasErrors() {
+		return errors
+	}
+
+	return nil
+}
+
+// countElements cuenta las apariciones de cada elemento por nombre local
+func countElements(body []byte) (map[string]int, error) {
+	counts := make(map[string]int)
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return counts, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			counts[start.Name.Local]++
+		}
+	}
+}
+
+// authValues extrae los valores de los hijos del primer elemento Auth
+func authValues(body []byte) (map[string]string, bool, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil, false, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || !strings.EqualFold(start.Name.Local, "Auth") {
+			continue
+		}
+
+		var auth struct {
+			Fields []struct {
+				XMLName xml.Name
+				Value   string `xml:",chardata"`
+			} `xml:",any"`
+		}
+		if err := decoder.DecodeElement(&auth, &start); err != nil {
+			return nil, false, err
+		}
+
+		values := make(map[string]string, len(auth.Fields))
+		for _, field := range auth.Fields {
+			values[strings.ToLower(field.XMLName.Local)] = field.Value
+		}
+		return values, true, nil
+	}
+}
+
+// EncodeList codifica values como hijos item dentro del elemento start. Lo
+// usan los MarshalXML de las listas de los requests: encoding/xml emite el
+// elemento padre vacío con tags "a>b,omitempty", que AFIP rechaza.
+func EncodeList[T any](e *xml.Encoder, start xml.StartElement, item string, values []T) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, value := range values {
+		if err := e.EncodeElement(value, xml.StartElement{Name: xml.Name{Local: item}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	"github.com/dlarregola/arca_invoice_lib/pkg/models"
+)
+
+// CertExpiryWarningDays es la cantidad de días antes del vencimiento a
+// partir de la cual el certificado se informa con estado de advertencia
+const CertExpiryWarningDays = 15
+
+// ParseCertificate parsea un certificado X.509 en formato DER o PEM
+func ParseCertificate(certificate []byte) (*x509.Certificate, error) {
+	if block, _ := pem.Decode(certificate); block != nil {
+		certificate = block.Bytes
+	}
+
+	cert, err := x509.ParseCertificate(certificate)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing certificate: %w", err)
+	}
+	return cert, nil
+}
+
+// ValidateCertificateDates verifica que el certificado esté vigente al
+// momento now. Si está vencido o todavía no es válido retorna un
+// *models.ARCAError con código ErrorCodeExpiredCertificate y las fechas de
+// vigencia, en lugar del error poco claro que devolvería WSAA.
+func ValidateCertificateDates(cert *x509.Certificate, now time.Time) error {
+	const layout = "2006-01-02 15:04:05 MST"
+
+	switch {
+	case now.Before(cert.NotBefore):
+		return models.NewARCAError(models.ErrorCodeExpiredCertificate, fmt.Sprintf(
+			"el certificado todavía no es válido: vigente desde %s hasta %s (ahora %s)",
+			cert.NotBefore.Format(layout), cert.NotAfter.Format(layout), now.Format(layout)))
+	case now.After(cert.NotAfter):
+		return models.NewARCAError(models.ErrorCodeExpiredCertificate, fmt.Sprintf(
+			"el certificado venció el %s (vigente desde %s, ahora %s)",
+			cert.NotAfter.Format(layout), cert.NotBefore.Format(layout), now.Format(layout)))
+	}
+
+	return nil
+}
+
+// CheckCertificate informa la vigencia del certificado al momento now
+func CheckCertificate(certificate []byte, now time.Time) (*models.CertStatus, error) {
+	cert, err := ParseCertificate(certificate)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &models.CertStatus{
+		Subject:         cert.Subject.String(),
+		NotBefore:       cert.NotBefore,
+		NotAfter:        cert.NotAfter,
+		DaysUntilExpiry: int(cert.NotAfter.Sub(now).Hours() / 24),
+	}
+
+	switch {
+	case now.Before(cert.NotBefore):
+		status.Status = models.CertStatusNotYetValid
+		status.Message = fmt.Sprintf("El certificado es válido a partir del %s", cert.NotBefore.Format("2006-01-02"))
+	case now.After(cert.NotAfter):
+		status.Status = models.CertStatusExpired
+		status.Message = fmt.Sprintf("El certificado venció el %s", cert.NotAfter.Format("2006-01-02"))
+	case status.DaysUntilExpiry < CertExpiryWarningDays:
+		status.Valid = true
+		status.Status = models.CertStatusWarning
+		status.Message = fmt.Sprintf("El certificado vence en %d días (%s)", status.DaysUntilExpiry, cert.NotAfter.Format("2006-01-02"))
+	default:
+		status.Valid = true
+		status.Status = models.CertStatusOK
+	}
+
+	return status, nil
+}
@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"strings"
+	"time"
+)
+
+// AFIPDateFormat es el formato de fecha AAAAMMDD usado por los web services de AFIP
+const AFIPDateFormat = "20060102"
+
+// AFIPDateTimeFormat es el formato AAAAMMDDhhmmss de fechas con hora, como
+// FchProceso
+const AFIPDateTimeFormat = "20060102150405"
+
+// ParseAFIPDate interpreta una fecha AAAAMMDD, o AAAAMMDDhhmmss, de AFIP. Las
+// fechas vacías o "NULL" retornan el tiempo cero sin error.
+func ParseAFIPDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" || value == "NULL" {
+		return time.Time{}, nil
+	}
+	if len(value) == len(AFIPDateTimeFormat) {
+		return time.Parse(AFIPDateTimeFormat, value)
+	}
+	return time.Parse(AFIPDateFormat, value)
+}
@@ -0,0 +1,16 @@
+package utils
+
+import "time"
+
+// IsActiveParameter indica si un parámetro de AFIP sigue vigente según su
+// fecha de fin de vigencia (FchHasta), en formato AAAAMMDD
+func IsActiveParameter(dateTo string) bool {
+	if dateTo == "" || dateTo == "NULL" {
+		return true
+	}
+	until, err := time.Parse("20060102", dateTo)
+	if err != nil {
+		return true
+	}
+	return !until.Before(time.Now().Truncate(24 * time.Hour))
+}
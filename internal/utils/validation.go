@@ -3,6 +3,7 @@ package utils
 import (
 	"github.com/dlarregola/arca_invoice_lib/pkg/models"
 	"fmt"
+	"math"
 	"regexp"
 	"strconv"
 	"time"
@@ -69,6 +70,14 @@ func validateCUITCheckDigit(cuit string) bool {
 
 // ValidateDocumentNumber valida un número de documento
 func ValidateDocumentNumber(docType models.DocumentType, docNumber string) error {
+	// El consumidor final anónimo se informa con número 0
+	if docType == models.DocumentTypeConsumidorFinal {
+		if docNumber != "0" {
+			return models.NewValidationError("doc_number", "Consumidor final sin identificar debe informar número de documento 0", docNumber)
+		}
+		return nil
+	}
+
 	if docNumber == "" {
 		return models.NewValidationError("doc_number", "Número de documento no puede estar vacío", docNumber)
 	}
@@ -144,33 +153,171 @@ func ValidatePointOfSale(pointOfSale int) error {
 	return nil
 }
 
-// ValidateDate valida una fecha
+// ValidateDate valida una fecha respecto de la hora del sistema
 func ValidateDate(date time.Time, fieldName string) error {
+	return ValidateDateAt(date, fieldName, time.Now())
+}
+
+// ValidateDateAt valida una fecha respecto de now: no puede ser futura ni
+// anterior a un año
+func ValidateDateAt(date time.Time, fieldName string, now time.Time) error {
 	if date.IsZero() {
 		return models.NewValidationError(fieldName, "Fecha no puede estar vacía", date)
 	}
 
 	// Validar que la fecha no sea futura (con tolerancia de 1 día)
-	if date.After(time.Now().AddDate(0, 0, 1)) {
+	if date.After(now.AddDate(0, 0, 1)) {
 		return models.NewValidationError(fieldName, "Fecha no puede ser futura", date)
 	}
 
 	// Validar que la fecha no sea muy antigua (más de 1 año)
-	if date.Before(time.Now().AddDate(-1, 0, 0)) {
+	if date.Before(now.AddDate(-1, 0, 0)) {
 		return models.NewValidationError(fieldName, "Fecha no puede ser anterior a 1 año", date)
 	}
 
 	return nil
 }
 
-// ValidateCurrencyType valida un tipo de moneda
-func ValidateCurrencyType(currency models.CurrencyType) error {
-	switch currency {
-	case models.CurrencyTypePES, models.CurrencyTypeUSD, models.CurrencyTypeEUR, models.CurrencyTypeBRL:
+// ValidateIssueDate valida que la fecha del comprobante (CbteFch) esté
+// dentro del rango que admite AFIP respecto de now: hasta 5 días antes o
+// después para productos y hasta 10 para servicios y mixto
+func ValidateIssueDate(conceptType models.ConceptType, issueDate time.Time, now time.Time) error {
+	if issueDate.IsZero() {
+		return models.NewValidationError("issue_date", "Fecha no puede estar vacía", issueDate)
+	}
+
+	days := 5
+	if conceptType == models.ConceptTypeServices || conceptType == models.ConceptTypeMixed {
+		days = 10
+	}
+
+	issueDay := time.Date(issueDate.Year(), issueDate.Month(), issueDate.Day(), 0, 0, 0, 0, time.UTC)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if issueDay.Before(today.AddDate(0, 0, -days)) || issueDay.After(today.AddDate(0, 0, days)) {
+		return models.NewValidationError("issue_date", fmt.Sprintf("La fecha del comprobante debe estar entre %d días antes y %d días después de la fecha actual", days, days), issueDate)
+	}
+
+	return nil
+}
+
+// ValidateConceptDates valida las fechas de servicio (FchServDesde,
+// FchServHasta) y de vencimiento de pago (FchVtoPago). No se admiten para
+// productos; para servicios y mixto el vencimiento de pago es obligatorio, y
+// el período del servicio también si requireServicePeriod es true (WSFE lo
+// exige, FEXAuthorize no lo recibe). Si se informa el período, el fin no
+// puede ser anterior al inicio.
+func ValidateConceptDates(conceptType models.ConceptType, serviceFrom, serviceTo, paymentDueDate time.Time, requireServicePeriod bool) error {
+	dates := []struct {
+		field    string
+		value    time.Time
+		required bool
+	}{
+		{"service_from", serviceFrom, requireServicePeriod},
+		{"service_to", serviceTo, requireServicePeriod},
+		{"payment_due_date", paymentDueDate, true},
+	}
+
+	if conceptType == models.ConceptTypeProducts {
+		for _, date := range dates {
+			if !date.value.IsZero() {
+				return models.NewValidationError(date.field, "Las fechas de servicio y de vencimiento de pago no se admiten para el concepto productos", date.value)
+			}
+		}
 		return nil
-	default:
-		return models.NewValidationError("currency_type", "Tipo de moneda no válido", currency)
 	}
+
+	for _, date := range dates {
+		if date.required && date.value.IsZero() {
+			return models.NewValidationError(date.field, "Fecha obligatoria para los conceptos servicios y mixto", date.value)
+		}
+	}
+
+	if !serviceFrom.IsZero() && !serviceTo.IsZero() && serviceTo.Before(serviceFrom) {
+		return models.NewValidationError("service_to", "La fecha de fin de servicio no puede ser anterior a la de inicio", serviceTo)
+	}
+
+	return nil
+}
+
+// MaxPaymentDueDays es el plazo máximo, en días desde la fecha del
+// comprobante, que se admite para la fecha de vencimiento de pago. AFIP sólo
+// exige que no sea anterior al comprobante; un vencimiento más lejano casi
+// siempre es un error de carga (ej. el año equivocado).
+const MaxPaymentDueDays = 365
+
+// ValidatePaymentDueDate valida que la fecha de vencimiento de pago
+// (FchVtoPago) de los conceptos servicios y mixto esté dentro de la ventana
+// admitida: no antes de la fecha del comprobante, como exige AFIP, ni más de
+// MaxPaymentDueDays después. La obligatoriedad se valida en
+// ValidateConceptDates.
+func ValidatePaymentDueDate(conceptType models.ConceptType, paymentDueDate, issueDate time.Time) error {
+	if conceptType == models.ConceptTypeProducts || paymentDueDate.IsZero() || issueDate.IsZero() {
+		return nil
+	}
+
+	issueDay := time.Date(issueDate.Year(), issueDate.Month(), issueDate.Day(), 0, 0, 0, 0, time.UTC)
+	dueDay := time.Date(paymentDueDate.Year(), paymentDueDate.Month(), paymentDueDate.Day(), 0, 0, 0, 0, time.UTC)
+	if dueDay.Before(issueDay) {
+		return models.NewValidationError("payment_due_date", fmt.Sprintf("La fecha de vencimiento de pago no puede ser anterior a la fecha del comprobante (%s)", issueDay.Format(AFIPDateFormat)), paymentDueDate)
+	}
+	if limit := issueDay.AddDate(0, 0, MaxPaymentDueDays); dueDay.After(limit) {
+		return models.NewValidationError("payment_due_date", fmt.Sprintf("La fecha de vencimiento de pago no puede superar en más de %d días a la fecha del comprobante (%s)", MaxPaymentDueDays, limit.Format(AFIPDateFormat)), paymentDueDate)
+	}
+
+	return nil
+}
+
+// currencyCodePattern es el formato de los códigos de moneda de AFIP (MonId)
+var currencyCodePattern = regexp.MustCompile(`^[A-Z0-9]{3}$`)
+
+// ValidateCurrencyType valida un tipo de moneda. Si se pasa la lista de
+// monedas de AFIP (ver wsfe.Service.GetCurrencyTypes) se valida contra ella;
+// si no, sólo que el código tenga el formato de MonId (3 letras o dígitos),
+// ya que AFIP habilita monedas sin que cambie la librería.
+func ValidateCurrencyType(currency models.CurrencyType, validCurrencies ...models.CurrencyTypeInfo) error {
+	if len(validCurrencies) > 0 {
+		for _, validCurrency := range validCurrencies {
+			if validCurrency.Active && validCurrency.ID == currency {
+				return nil
+			}
+		}
+		return models.NewValidationError("currency_type", "Moneda no habilitada en AFIP", currency)
+	}
+
+	if !currencyCodePattern.MatchString(string(currency)) {
+		return models.NewValidationError("currency_type", "Tipo de moneda no válido: debe ser un código de moneda de AFIP de 3 caracteres (ej. DOL)", currency)
+	}
+	return nil
+}
+
+// ValidateUnitMeasures valida la unidad de medida de los ítems de una factura
+// de exportación (ver models.ParseUnitMeasure). Si se pasa la lista de
+// unidades de AFIP (ver wsfex.Service.GetUnitMeasures) el código debe estar
+// vigente en ella; si no, basta con que la unidad se pueda traducir a un
+// código Umed, ya que AFIP habilita unidades sin que cambie el catálogo
+// local. Los ítems sin unidad no se validan.
+func ValidateUnitMeasures(items []models.Item, validUnits ...models.UnitType) error {
+	for i, item := range items {
+		if item.UnitMeasure == "" {
+			continue
+		}
+
+		code, ok := models.ParseUnitMeasure(item.UnitMeasure)
+		if ok && len(validUnits) > 0 {
+			ok = false
+			for _, unit := range validUnits {
+				if unit.Active && unit.ID == code {
+					ok = true
+					break
+				}
+			}
+		}
+		if !ok {
+			return models.NewValidationError(fmt.Sprintf("items[%d].unit_measure", i), "Unidad de medida no habilitada en AFIP", item.UnitMeasure)
+		}
+	}
+
+	return nil
 }
 
 // ValidateInvoiceType valida un tipo de factura
@@ -179,6 +326,9 @@ func ValidateInvoiceType(invoiceType models.InvoiceType) error {
 	case models.InvoiceTypeA, models.InvoiceTypeB, models.InvoiceTypeC, models.InvoiceTypeE, models.InvoiceTypeM, models.InvoiceTypeT, models.InvoiceTypeR:
 		return nil
 	default:
+		if invoiceType.IsFCE() || invoiceType.RequiresAssociatedInvoice() {
+			return nil
+		}
 		return models.NewValidationError("invoice_type", "Tipo de factura no válido", invoiceType)
 	}
 }
@@ -193,18 +343,89 @@ func ValidateConceptType(conceptType models.ConceptType) error {
 	}
 }
 
+// ValidateExportType valida el tipo de exportación (Tipo_expo) y que
+// corresponda al concepto del comprobante. El tipo 0 se deriva del concepto
+// y no se valida.
+func ValidateExportType(exportType models.ExportType, conceptType models.ConceptType) error {
+	if exportType == 0 {
+		return nil
+	}
+
+	switch exportType {
+	case models.ExportTypeGoods, models.ExportTypeServices, models.ExportTypeOther:
+	default:
+		return models.NewValidationError("export_type", "Tipo de exportación no válido (1 = bienes, 2 = servicios, 4 = otros)", exportType)
+	}
+
+	if expected := models.ExportTypeForConcept(conceptType); exportType != expected {
+		return models.NewValidationError("export_type", fmt.Sprintf("El tipo de exportación %s no corresponde al concepto %s (se esperaba %s)", exportType, conceptType, expected), exportType)
+	}
+
+	return nil
+}
+
 // ValidateDocumentType valida un tipo de documento
 func ValidateDocumentType(docType models.DocumentType) error {
 	switch docType {
-	case models.DocumentTypeDNI, models.DocumentTypeCUIT, models.DocumentTypeCUIL, models.DocumentTypeCDI, models.DocumentTypeLE, models.DocumentTypeLC, models.DocumentTypeCI, models.DocumentTypePAS, models.DocumentTypeDE, models.DocumentTypeDI:
+	case models.DocumentTypeDNI, models.DocumentTypeCUIT, models.DocumentTypeCUIL, models.DocumentTypeCDI, models.DocumentTypeLE, models.DocumentTypeLC, models.DocumentTypeCI, models.DocumentTypePAS, models.DocumentTypeDE, models.DocumentTypeDI, models.DocumentTypeConsumidorFinal:
 		return nil
 	default:
 		return models.NewValidationError("doc_type", "Tipo de documento no válido", docType)
 	}
 }
 
-// ValidateTaxRate valida una alícuota de impuesto
-func ValidateTaxRate(taxRate models.TaxRate) error {
+// ValidateConsumidorFinal valida que el consumidor final anónimo (DocTipo 99)
+// sólo se use en comprobantes B y C: los comprobantes A y M requieren
+// identificar al receptor
+func ValidateConsumidorFinal(invoiceType models.InvoiceType, docType models.DocumentType) error {
+	if docType != models.DocumentTypeConsumidorFinal {
+		return nil
+	}
+
+	switch invoiceType.LetterClass() {
+	case "B", "C":
+		return nil
+	default:
+		return models.NewValidationError("doc_type", fmt.Sprintf("%s no admite consumidor final sin identificar", invoiceType), docType)
+	}
+}
+
+// ConsumidorFinalThreshold es el importe en pesos a partir del cual AFIP
+// exige identificar al consumidor final (RG 5700/2025)
+const ConsumidorFinalThreshold = 10000000.0
+
+// ValidateConsumidorFinalAmount valida que un comprobante a consumidor final
+// sin identificar (DocTipo 99) no alcance el umbral de identificación.
+// amount es el total en pesos; con threshold <= 0 se usa
+// ConsumidorFinalThreshold.
+func ValidateConsumidorFinalAmount(docType models.DocumentType, amount, threshold float64) error {
+	if docType != models.DocumentTypeConsumidorFinal {
+		return nil
+	}
+	if threshold <= 0 {
+		threshold = ConsumidorFinalThreshold
+	}
+
+	if amount >= threshold {
+		return models.NewValidationError("doc_type", fmt.Sprintf("Comprobantes a consumidor final de $%.2f o más deben identificar al comprador con CUIT, CUIL o DNI (total $%.2f)", threshold, amount), amount)
+	}
+
+	return nil
+}
+
+// ValidateTaxRate valida una alícuota de impuesto. Si se pasa la lista de
+// alícuotas vigentes de AFIP (ver wsfe.Service.GetTaxRates) se valida contra
+// ella; si no, contra las constantes de models.TaxRate.
+func ValidateTaxRate(taxRate models.TaxRate, validRates ...models.TaxRateInfo) error {
+	if len(validRates) > 0 {
+		for _, rate := range validRates {
+			if rate.Active && rate.ID == taxRate {
+				return nil
+			}
+		}
+		return models.NewValidationError("tax_rate", "Alícuota de impuesto no vigente en AFIP", taxRate)
+	}
+
 	switch taxRate {
 	case models.TaxRate0, models.TaxRate105, models.TaxRate21, models.TaxRate27, models.TaxRate25, models.TaxRate5, models.TaxRateExempt:
 		return nil
@@ -256,13 +477,450 @@ func ValidateItem(item models.Item, fieldPrefix string) error {
 
 	// Validar que el total sea consistente
 	expectedTotal := item.Quantity * item.UnitPrice
-	if abs(item.TotalPrice-expectedTotal) > 0.01 {
+	if exceedsCent(item.TotalPrice - expectedTotal) {
 		return models.NewValidationError(fieldPrefix+".total_price", "Total del ítem no coincide con cantidad * precio unitario", item.TotalPrice)
 	}
 
 	return nil
 }
 
+// ValidateItemsTaxes valida que, si el comprobante informa IVA, algún ítem
+// tenga impuestos de IVA con los que armar el bloque AlicIva
+func ValidateItemsTaxes(taxAmount float64, items []models.Item) error {
+	if taxAmount <= 0 {
+		return nil
+	}
+
+	for _, item := range items {
+		for _, tax := range item.Taxes {
+			if tax.Type == models.TaxTypeIVA {
+				return nil
+			}
+		}
+	}
+
+	return models.NewValidationError("tax_amount", fmt.Sprintf("Monto de IVA %.2f informado pero ningún ítem tiene impuestos de IVA (AlicIva quedaría vacío); agregue los impuestos a los ítems", taxAmount), taxAmount)
+}
+
+// ValidateClassCTaxes valida que los comprobantes C (monotributo) no
+// discriminen IVA: TaxAmount debe ser 0 y ningún ítem puede tener IVA
+func ValidateClassCTaxes(invoiceType models.InvoiceType, taxAmount float64, items []models.Item) error {
+	if !invoiceType.IsClassC() {
+		return nil
+	}
+
+	if taxAmount != 0 {
+		return models.NewValidationError("tax_amount", fmt.Sprintf("%s no discrimina IVA: el monto de IVA debe ser 0, se informó %.2f", invoiceType, taxAmount), taxAmount)
+	}
+
+	for i, item := range items {
+		for _, tax := range item.Taxes {
+			if tax.Type == models.TaxTypeIVA {
+				return models.NewValidationError(fmt.Sprintf("items[%d].taxes", i), fmt.Sprintf("%s no discrimina IVA: quite el IVA del ítem e incluya el importe en el precio", invoiceType), tax.Rate)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ValidateTaxBreakdown valida el desglose de IVA (AlicIva) que se arma con los
+// impuestos de los ítems: cada importe debe corresponder a su base imponible
+// por la alícuota, y la suma de los importes debe coincidir con TaxAmount.
+// AFIP rechaza los desgloses inconsistentes con errores de la familia 10048.
+func ValidateTaxBreakdown(taxAmount float64, items []models.Item) error {
+	var total float64
+	for i, item := range items {
+		for j, tax := range item.Taxes {
+			if tax.Type != models.TaxTypeIVA {
+				continue
+			}
+
+			expected := RoundAmount(tax.Base * tax.Rate.Percent() / 100)
+			if exceedsCent(tax.Amount - expected) {
+				field := fmt.Sprintf("items[%d].taxes[%d].amount", i, j)
+				return models.NewValidationError(field, fmt.Sprintf("Importe de IVA %.2f no coincide con base %.2f al %s: se esperaba %.2f", tax.Amount, tax.Base, tax.Rate, expected), tax.Amount)
+			}
+			total += tax.Amount
+		}
+	}
+
+	if exceedsCent(total - taxAmount) {
+		return models.NewValidationError("tax_amount", fmt.Sprintf("Monto de IVA %.2f no coincide con la suma de las alícuotas de los ítems (%.2f)", taxAmount, total), taxAmount)
+	}
+
+	return nil
+}
+
+// ValidateTotalAmount valida que el importe total sea la suma del neto, el IVA
+// y los tributos (impuestos distintos de IVA), como exige AFIP para ImpTotal
+func ValidateTotalAmount(amount, taxAmount, totalAmount float64, taxes []models.Tax) error {
+	var tributes float64
+	for _, tax := range taxes {
+		if tax.Type != models.TaxTypeIVA {
+			tributes += tax.Amount
+		}
+	}
+
+	expected := amount + taxAmount + tributes
+	if exceedsCent(totalAmount - expected) {
+		return models.NewValidationError("total_amount", fmt.Sprintf("Importe total %.2f no coincide con neto + IVA + tributos: se esperaba %.2f (%.2f + %.2f + %.2f)", totalAmount, expected, amount, taxAmount, tributes), totalAmount)
+	}
+
+	return nil
+}
+
+// ValidateInvoiceTotal valida que el importe total (ImpTotal) sea la suma
+// del neto gravado, el no gravado (ImpTotConc), el exento (ImpOpEx), el IVA
+// y los tributos, como exige AFIP
+func ValidateInvoiceTotal(amount, nonTaxable, exempt, taxAmount, totalAmount float64, taxes []models.Tax) error {
+	if nonTaxable == 0 && exempt == 0 {
+		return ValidateTotalAmount(amount, taxAmount, totalAmount, taxes)
+	}
+
+	var tributes float64
+	for _, tax := range taxes {
+		if tax.Type != models.TaxTypeIVA {
+			tributes += tax.Amount
+		}
+	}
+
+	expected := amount + nonTaxable + exempt + taxAmount + tributes
+	if exceedsCent(totalAmount - expected) {
+		return models.NewValidationError("total_amount", fmt.Sprintf("Importe total %.2f no coincide con neto + no gravado + exento + IVA + tributos: se esperaba %.2f (%.2f + %.2f + %.2f + %.2f + %.2f)", totalAmount, expected, amount, nonTaxable, exempt, taxAmount, tributes), totalAmount)
+	}
+
+	return nil
+}
+
+// ValidateClassCUntaxedAmounts valida que los comprobantes C no informen
+// importes no gravados ni exentos: AFIP exige ImpTotConc e ImpOpEx en 0
+func ValidateClassCUntaxedAmounts(invoiceType models.InvoiceType, nonTaxable, exempt float64) error {
+	if !invoiceType.IsClassC() || (nonTaxable == 0 && exempt == 0) {
+		return nil
+	}
+
+	return models.NewValidationError("non_taxable_amount", fmt.Sprintf("%s no admite importes no gravados ni exentos: inclúyalos en el neto", invoiceType), nonTaxable+exempt)
+}
+
+// ValidateActivityCodes valida los códigos de actividad del emisor. Si el
+// régimen del contribuyente lo exige, debe informarse al menos uno.
+func ValidateActivityCodes(codes []int, required bool) error {
+	if required && len(codes) == 0 {
+		return models.NewValidationError("activity_codes", "Debe informarse al menos un código de actividad", codes)
+	}
+
+	seen := make(map[int]bool, len(codes))
+	for _, code := range codes {
+		if code <= 0 {
+			return models.NewValidationError("activity_codes", "Código de actividad inválido", code)
+		}
+		if seen[code] {
+			return models.NewValidationError("activity_codes", "Código de actividad duplicado", code)
+		}
+		seen[code] = true
+	}
+
+	return nil
+}
+
+// ValidateReceptorIVACondition valida la condición frente al IVA del
+// receptor, obligatoria en FECAESolicitar desde RG 5616
+func ValidateReceptorIVACondition(condition int) error {
+	if condition <= 0 {
+		return models.NewValidationError("receptor_iva_condition", "Debe informarse la condición frente al IVA del receptor", condition)
+	}
+	return nil
+}
+
+// ValidateFCE valida que los datos FCE MiPyME estén presentes sólo en
+// comprobantes FCE y que las facturas informen CBU y modalidad de transferencia
+func ValidateFCE(invoiceType models.InvoiceType, fce *models.FCEData) error {
+	if !invoiceType.IsFCE() {
+		if fce != nil {
+			return models.NewValidationError("fce", "Los datos FCE sólo pueden informarse en comprobantes FCE MiPyME", invoiceType)
+		}
+		return nil
+	}
+
+	if fce == nil {
+		return models.NewValidationError("fce", "Los comprobantes FCE MiPyME requieren datos FCE", invoiceType)
+	}
+
+	switch invoiceType {
+	case models.InvoiceTypeFCEA, models.InvoiceTypeFCEB, models.InvoiceTypeFCEC:
+		if !regexp.MustCompile(`^\d{22}$`).MatchString(fce.CBU) {
+			return models.NewValidationError("fce.cbu", "CBU debe tener 22 dígitos", fce.CBU)
+		}
+		if fce.TransferType != models.FCETransferSCA && fce.TransferType != models.FCETransferADC {
+			return models.NewValidationError("fce.transfer_type", "Modalidad de transferencia debe ser SCA o ADC", fce.TransferType)
+		}
+	default:
+		if fce.CBU != "" || fce.Alias != "" || fce.TransferType != "" {
+			return models.NewValidationError("fce", "Las notas de débito/crédito FCE no informan CBU, alias ni transferencia", fce)
+		}
+	}
+
+	return nil
+}
+
+// ValidateOptionals valida los datos opcionales (Opcionales) de una factura:
+// IDs numéricos y sin repetir, valor informado y, para los opcionales cuyo
+// formato fija AFIP (CBU, alias y modalidad de transferencia de FCE,
+// anulación de notas FCE), el formato del valor. Si se pasa la lista de
+// tipos de AFIP (ver wsfe.Service.GetOptionalTypes) el ID debe estar vigente
+// en ella. Retorna un error por cada opcional inválido, con campo
+// "optionals[i]".
+func ValidateOptionals(optionals []models.Optional, validTypes ...models.OptionalTypeInfo) models.ValidationErrors {
+	var errors models.ValidationErrors
+
+	seen := make(map[string]bool, len(optionals))
+	for i, optional := range optionals {
+		field := fmt.Sprintf("optionals[%d]", i)
+
+		if !regexp.MustCompile(`^\d+$`).MatchString(optional.ID) {
+			errors.Add(field, "ID de opcional debe ser numérico", optional.ID)
+			continue
+		}
+		if seen[optional.ID] {
+			errors.Add(field, fmt.Sprintf("Opcional %s informado más de una vez", optional.ID), optional.ID)
+			continue
+		}
+		seen[optional.ID] = true
+
+		if len(validTypes) > 0 && !isActiveOptionalType(optional.ID, validTypes) {
+			errors.Add(field, fmt.Sprintf("Opcional %s no habilitado en AFIP", optional.ID), optional.ID)
+			continue
+		}
+
+		if err := validateOptionalValue(optional); err != nil {
+			errors.Add(field, err.Error(), optional.Value)
+		}
+	}
+
+	return errors
+}
+
+// isActiveOptionalType indica si el ID está vigente en la lista de AFIP
+func isActiveOptionalType(id string, validTypes []models.OptionalTypeInfo) bool {
+	for _, validType := range validTypes {
+		if validType.Active && validType.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// validateOptionalValue valida el valor de un opcional según su ID
+func validateOptionalValue(optional models.Optional) error {
+	if optional.Value == "" {
+		return fmt.Errorf("El opcional %s debe informar un valor", optional.ID)
+	}
+
+	switch optional.ID {
+	case models.OptionalIDFCECBU:
+		if !regexp.MustCompile(`^\d{22}$`).MatchString(optional.Value) {
+			return fmt.Errorf("CBU debe tener 22 dígitos")
+		}
+	case models.OptionalIDFCEAlias:
+		if !regexp.MustCompile(`^[A-Za-z0-9.-]{6,20}$`).MatchString(optional.Value) {
+			return fmt.Errorf("Alias debe tener entre 6 y 20 caracteres (letras, números, punto o guión)")
+		}
+	case models.OptionalIDFCETransfer:
+		if optional.Value != models.FCETransferSCA && optional.Value != models.FCETransferADC {
+			return fmt.Errorf("Modalidad de transferencia debe ser SCA o ADC")
+		}
+	case models.OptionalIDFCECancellation:
+		if optional.Value != "S" && optional.Value != "N" {
+			return fmt.Errorf("Anulación FCE debe ser S o N")
+		}
+	}
+
+	return nil
+}
+
+// ValidateIncoterm valida que las exportaciones de bienes informen el Incoterm
+func ValidateIncoterm(conceptType models.ConceptType, incoterm string) error {
+	if conceptType == models.ConceptTypeProducts && incoterm == "" {
+		return models.NewValidationError("incoterm", "Las exportaciones de bienes deben informar el Incoterm", incoterm)
+	}
+
+	if len(incoterm) > 3 {
+		return models.NewValidationError("incoterm", "Incoterm debe tener hasta 3 caracteres (ej. FOB)", incoterm)
+	}
+
+	return nil
+}
+
+// ValidateExportPermits valida los permisos de embarque, que sólo se
+// informan en exportaciones de bienes
+func ValidateExportPermits(conceptType models.ConceptType, permits []models.ExportPermit) error {
+	if len(permits) > 0 && conceptType != models.ConceptTypeProducts {
+		return models.NewValidationError("permits", "Los permisos de embarque sólo se informan en exportaciones de bienes", conceptType)
+	}
+
+	for i, permit := range permits {
+		field := fmt.Sprintf("permits[%d]", i)
+		if permit.ID == "" {
+			return models.NewValidationError(field+".id", "Identificador del permiso no puede estar vacío", permit.ID)
+		}
+		if err := ValidateCountryCode(permit.DestinationCountry, field+".destination_country"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ValidateExportBuyers valida el reparto de una exportación entre varios
+// compradores: cada uno identificado y con un porcentaje positivo, y los
+// porcentajes sumando 100
+func ValidateExportBuyers(buyers []models.ExportBuyer) error {
+	if len(buyers) == 0 {
+		return nil
+	}
+
+	var total float64
+	for i, buyer := range buyers {
+		field := fmt.Sprintf("buyers[%d]", i)
+		if buyer.DocNumber == "" {
+			return models.NewValidationError(field+".doc_number", "Documento del comprador no puede estar vacío", buyer.DocNumber)
+		}
+		if buyer.Percentage <= 0 || buyer.Percentage > 100 {
+			return models.NewValidationError(field+".percentage", "Porcentaje del comprador debe ser mayor a 0 y no superar 100", buyer.Percentage)
+		}
+		total += buyer.Percentage
+	}
+
+	if abs(total-100) > 0.01 {
+		return models.NewValidationError("buyers", fmt.Sprintf("Los porcentajes de los compradores deben sumar 100 (suman %.2f)", total), total)
+	}
+
+	return nil
+}
+
+// countryArgentina es el código AFIP de Argentina, que no puede ser destino
+// de una exportación
+const countryArgentina = "200"
+
+// ValidateCountryCode valida un código de país de destino según la tabla de
+// AFIP (FEXGetPARAM_DST_pais): tres dígitos y distinto de Argentina
+func ValidateCountryCode(code, fieldName string) error {
+	if code == "" {
+		return models.NewValidationError(fieldName, "País de destino no puede estar vacío", code)
+	}
+
+	if !regexp.MustCompile(`^\d{3}$`).MatchString(code) {
+		return models.NewValidationError(fieldName, "País de destino debe ser un código AFIP de 3 dígitos (ej. 212)", code)
+	}
+
+	if code == countryArgentina {
+		return models.NewValidationError(fieldName, "País de destino no puede ser Argentina", code)
+	}
+
+	return nil
+}
+
+// ValidateExportTaxes valida que los comprobantes E no informen IVA, ya que
+// las exportaciones están exentas
+func ValidateExportTaxes(invoiceType models.InvoiceType, taxAmount float64) error {
+	if invoiceType.LetterClass() == "E" && taxAmount != 0 {
+		return models.NewValidationError("tax_amount", "Los comprobantes E no discriminan IVA (exportación exenta)", taxAmount)
+	}
+
+	return nil
+}
+
+// ValidateExportCurrency valida que una exportación se facture en moneda
+// extranjera con cotización positiva
+func ValidateExportCurrency(currency models.CurrencyType, rate float64) error {
+	if currency == models.CurrencyTypePES {
+		return models.NewValidationError("currency_type", "Las exportaciones deben facturarse en moneda extranjera", currency)
+	}
+
+	if rate <= 0 {
+		return models.NewValidationError("currency_rate", "Cotización debe ser mayor a 0", rate)
+	}
+
+	return nil
+}
+
+// ValidateAssociatedInvoices valida los comprobantes asociados
+func ValidateAssociatedInvoices(associated []models.AssociatedInvoice) error {
+	for i, invoice := range associated {
+		field := fmt.Sprintf("associated_invoices[%d]", i)
+		if err := ValidateInvoiceType(invoice.InvoiceType); err != nil {
+			return models.NewValidationError(field+".invoice_type", "Tipo de comprobante asociado no válido", invoice.InvoiceType)
+		}
+		if err := ValidatePointOfSale(invoice.PointOfSale); err != nil {
+			return models.NewValidationError(field+".point_of_sale", "Punto de venta del comprobante asociado no válido", invoice.PointOfSale)
+		}
+		if err := ValidateInvoiceNumber(invoice.InvoiceNumber); err != nil {
+			return models.NewValidationError(field+".invoice_number", "Número del comprobante asociado no válido", invoice.InvoiceNumber)
+		}
+	}
+
+	return nil
+}
+
+// ValidateExportAssociatedInvoices valida los comprobantes asociados
+// (Cmps_asoc) de un comprobante de exportación: las notas de débito y
+// crédito E deben informar al menos uno, y los asociados deben ser
+// comprobantes de exportación.
+func ValidateExportAssociatedInvoices(invoiceType models.InvoiceType, associated []models.AssociatedInvoice) error {
+	if len(associated) == 0 && invoiceType.RequiresAssociatedInvoice() {
+		return models.NewValidationError("associated_invoices", fmt.Sprintf("%s requiere informar el comprobante asociado (Cmps_asoc)", invoiceType), nil)
+	}
+	if err := ValidateAssociatedInvoices(associated); err != nil {
+		return err
+	}
+
+	for i, invoice := range associated {
+		if invoice.InvoiceType.LetterClass() != "E" {
+			return models.NewValidationError(fmt.Sprintf("associated_invoices[%d].invoice_type", i), "El comprobante asociado debe ser un comprobante de exportación (E)", invoice.InvoiceType)
+		}
+	}
+
+	return nil
+}
+
+// ValidateAssociatedReferences valida los comprobantes asociados (CbtesAsoc)
+// y el período asociado (PeriodoAsoc) de una nota de débito o crédito: se
+// informa uno u otro, no ambos, y es obligatorio informar alguno si el tipo
+// de comprobante lo requiere.
+func ValidateAssociatedReferences(invoiceType models.InvoiceType, associated []models.AssociatedInvoice, period *models.AssociatedPeriod) error {
+	if period == nil {
+		if len(associated) == 0 && invoiceType.RequiresAssociatedInvoice() {
+			return models.NewValidationError("associated_invoices", fmt.Sprintf("%s requiere informar el comprobante o el período asociado", invoiceType), nil)
+		}
+		return ValidateAssociatedInvoices(associated)
+	}
+
+	if len(associated) > 0 {
+		return models.NewValidationError("associated_period", "Informe comprobantes asociados o un período asociado, no ambos", period)
+	}
+	if period.From.IsZero() || period.To.IsZero() {
+		return models.NewValidationError("associated_period", "Fechas desde y hasta del período asociado obligatorias", period)
+	}
+	if period.To.Before(period.From) {
+		return models.NewValidationError("associated_period", "La fecha hasta del período asociado no puede ser anterior a la fecha desde", period)
+	}
+
+	return nil
+}
+
+// RoundAmount redondea un monto a 2 decimales
+func RoundAmount(x float64) float64 {
+	return math.Round(x*100) / 100
+}
+
+// exceedsCent indica si una diferencia entre importes supera un centavo. La
+// comparación se hace en centavos para que errores de representación de
+// float64 (0.19 - 0.18 > 0.01) no rechacen diferencias de redondeo válidas.
+func exceedsCent(diff float64) bool {
+	return math.Round(abs(diff)*100) > 1
+}
+
 // abs retorna el valor absoluto de un float64
 func abs(x float64) float64 {
 	if x < 0 {
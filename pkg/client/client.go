@@ -5,16 +5,23 @@ import (
 	"fmt"
 	"sync"
 	"time"
+
+	"github.com/dlarregola/arca_invoice_lib/internal/utils"
+	"github.com/dlarregola/arca_invoice_lib/pkg/models"
+	"github.com/dlarregola/arca_invoice_lib/pkg/wsfe"
+	"github.com/dlarregola/arca_invoice_lib/pkg/wsfex"
 )
 
 // ARCAClient representa el cliente principal de ARCA
 type ARCAClient struct {
 	config      *Config
 	auth        *WSAAAuth
-	wsfe        interface{}
-	wsfex       interface{}
+	wsfe        *wsfe.Service
+	wsfex       *wsfex.Service
 	logger      interface{}
 	loggerMutex sync.RWMutex
+	closed      bool
+	closeMutex  sync.RWMutex
 }
 
 // NewARCAClient crea un nuevo cliente ARCA
@@ -24,8 +31,8 @@ func NewARCAClient(config Config) (*ARCAClient, error) {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
 
-	// Crear logger básico
-	logger := &basicLogger{}
+	// Crear logger por defecto según LogLevel
+	logger := NewStdLogger(config.LogOutput, config.LogLevel)
 
 	// Crear autenticador
 	auth := NewWSAAAuth(&config, logger)
@@ -33,6 +40,8 @@ func NewARCAClient(config Config) (*ARCAClient, error) {
 	client := &ARCAClient{
 		config: &config,
 		auth:   auth,
+		wsfe:   wsfe.NewService(&config, auth, logger),
+		wsfex:  wsfex.NewService(&config, auth, logger),
 		logger: logger,
 	}
 
@@ -40,12 +49,12 @@ func NewARCAClient(config Config) (*ARCAClient, error) {
 }
 
 // WSFE retorna el servicio de facturación nacional
-func (c *ARCAClient) WSFE() interface{} {
+func (c *ARCAClient) WSFE() *wsfe.Service {
 	return c.wsfe
 }
 
 // WSFEX retorna el servicio de facturación internacional
-func (c *ARCAClient) WSFEX() interface{} {
+func (c *ARCAClient) WSFEX() *wsfex.Service {
 	return c.wsfex
 }
 
@@ -78,8 +87,42 @@ func (c *ARCAClient) GetAuthCacheSize() int {
 	return c.auth.GetCacheSize()
 }
 
+// GetTokenInfo informa el ticket de acceso en cache para un servicio ("wsfe"
+// o "wsfex") sin pedir uno nuevo a WSAA
+func (c *ARCAClient) GetTokenInfo(service string) (*TokenInfo, error) {
+	return c.auth.GetTokenInfo(service)
+}
+
+// Close cierra el cliente: limpia el cache de autenticación y hace que las
+// llamadas siguientes, incluidas las de WSFE() y WSFEX(), fallen con
+// ErrClientClosed. Llamarlo más de una vez no tiene efecto.
+func (c *ARCAClient) Close() error {
+	c.closeMutex.Lock()
+	defer c.closeMutex.Unlock()
+
+	if c.closed {
+		return nil
+	}
+
+	c.closed = true
+	c.auth.Close()
+
+	return nil
+}
+
+// IsClosed indica si el cliente fue cerrado
+func (c *ARCAClient) IsClosed() bool {
+	c.closeMutex.RLock()
+	defer c.closeMutex.RUnlock()
+	return c.closed
+}
+
 // TestConnection prueba la conexión con ARCA
 func (c *ARCAClient) TestConnection(ctx context.Context) error {
+	if c.IsClosed() {
+		return ErrClientClosed
+	}
+
 	// Intentar obtener un ticket de acceso para el servicio de testing
 	_, err := c.auth.GetAccessTicket(ctx, "wsfe")
 	if err != nil {
@@ -89,8 +132,24 @@ func (c *ARCAClient) TestConnection(ctx context.Context) error {
 	return nil
 }
 
+// CheckCertificate informa la vigencia del certificado configurado: fechas
+// de validez, días hasta el vencimiento y estado "warning" si vence en menos
+// de 15 días. Los certificados de AFIP vencen y la autenticación empieza a
+// fallar sin aviso, por lo que conviene monitorearlo.
+func (c *ARCAClient) CheckCertificate(ctx context.Context) (*models.CertStatus, error) {
+	if c.IsClosed() {
+		return nil, ErrClientClosed
+	}
+
+	return utils.CheckCertificate(c.config.Certificate, c.config.Now())
+}
+
 // GetSystemStatus obtiene el estado del sistema ARCA
 func (c *ARCAClient) GetSystemStatus(ctx context.Context) (*SystemStatus, error) {
+	if c.IsClosed() {
+		return nil, ErrClientClosed
+	}
+
 	// Por ahora retornamos un status básico
 	// En una implementación real, consultaríamos los parámetros del sistema
 	return &SystemStatus{
@@ -108,16 +167,3 @@ type SystemStatus struct {
 	Timestamp  time.Time `json:"timestamp"`
 	LastUpdate time.Time `json:"last_update,omitempty"`
 }
-
-// basicLogger implementa un logger básico
-type basicLogger struct{}
-
-func (l *basicLogger) Debug(args ...interface{})                 {}
-func (l *basicLogger) Debugf(format string, args ...interface{}) {}
-func (l *basicLogger) Info(args ...interface{})                  {}
-func (l *basicLogger) Infof(format string, args ...interface{})  {}
-func (l *basicLogger) Warn(args ...interface{})                  {}
-func (l *basicLogger) Warnf(format string, args ...interface{})  {}
-func (l *basicLogger) Error(args ...interface{})                 {}
-func (l *basicLogger) Errorf(format string, args ...interface{}) {}
-func (l *basicLogger) GetLevel() interface{}                     { return "info" }
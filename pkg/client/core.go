@@ -0,0 +1,148 @@
+package client
+
+import (
+	"context"
+	"io"
+
+	"github.com/dlarregola/arca_invoice_lib/pkg/core"
+	"github.com/dlarregola/arca_invoice_lib/pkg/models"
+)
+
+// Version es la versión de la librería
+const Version = core.Version
+
+// DefaultWSAARequestTTL es la validez del loginTicketRequest enviado a WSAA
+// si no se configura WSAARequestTTL
+const DefaultWSAARequestTTL = core.DefaultWSAARequestTTL
+
+// Config representa la configuración del cliente ARCA
+type Config = core.Config
+
+// CurrencyRateMode define cómo se envía la cotización (MonCotiz) de comprobantes en PES
+type CurrencyRateMode = core.CurrencyRateMode
+
+// RequestCapture recibe los envelopes SOAP intercambiados con AFIP
+type RequestCapture = core.RequestCapture
+
+// Interceptor envuelve las llamadas SOAP a WSFE/WSFEX (ver Config.Interceptors)
+type Interceptor = core.Interceptor
+
+// InterceptorFunc permite usar una función como Interceptor
+type InterceptorFunc = core.InterceptorFunc
+
+// TokenCache almacena los tickets de acceso de WSAA (en memoria por defecto)
+type TokenCache = core.TokenCache
+
+// MemoryTokenCache es el TokenCache en memoria usado por defecto
+type MemoryTokenCache = core.MemoryTokenCache
+
+// Clock provee la hora actual (ver Config.Clock)
+type Clock = core.Clock
+
+// SystemClock es el Clock por defecto, basado en time.Now
+type SystemClock = core.SystemClock
+
+// CircuitBreaker corta las llamadas a un servicio de AFIP tras fallas consecutivas
+type CircuitBreaker = core.CircuitBreaker
+
+// CircuitState es el estado de un CircuitBreaker
+type CircuitState = core.CircuitState
+
+// StdLogger es el logger por defecto, filtrado por nivel
+type StdLogger = core.StdLogger
+
+// WSAAAuth maneja la autenticación con el Web Service de Autenticación y Autorización
+type WSAAAuth = core.WSAAAuth
+
+// AccessTicket representa un ticket de acceso de ARCA
+type AccessTicket = core.AccessTicket
+
+// TokenInfo describe el ticket de acceso en cache para un servicio
+type TokenInfo = core.TokenInfo
+
+// WSAARequest representa el request para WSAA
+type WSAARequest = core.WSAARequest
+
+// WSAAResponse representa la respuesta de WSAA
+type WSAAResponse = core.WSAAResponse
+
+const (
+	CurrencyRateModeNormalize   = core.CurrencyRateModeNormalize
+	CurrencyRateModeValidate    = core.CurrencyRateModeValidate
+	CurrencyRateModePassthrough = core.CurrencyRateModePassthrough
+)
+
+const (
+	CircuitClosed   = core.CircuitClosed
+	CircuitOpen     = core.CircuitOpen
+	CircuitHalfOpen = core.CircuitHalfOpen
+)
+
+// ErrClientClosed se retorna al usar un cliente ya cerrado con Close
+var ErrClientClosed = core.ErrClientClosed
+
+// DefaultConfig retorna una configuración por defecto
+func DefaultConfig() Config {
+	return core.DefaultConfig()
+}
+
+// LoadConfigFile lee y valida una configuración JSON; certificate y
+// private_key pueden ser rutas de archivo o su contenido en base64 o PEM
+func LoadConfigFile(path string) (*Config, error) {
+	return core.LoadConfigFile(path)
+}
+
+// ConfigFromEnv arma una configuración validada a partir de las variables de
+// entorno ARCA_* (ARCA_CUIT, ARCA_ENVIRONMENT, ARCA_CERT_PATH, ARCA_KEY_PATH,
+// ARCA_TIMEOUT, etc.)
+func ConfigFromEnv() (Config, error) {
+	return core.ConfigFromEnv()
+}
+
+// NewMemoryTokenCache crea un cache de tickets en memoria
+func NewMemoryTokenCache() *MemoryTokenCache {
+	return core.NewMemoryTokenCache()
+}
+
+// NewStdLogger crea un logger que escribe en out los mensajes de nivel level
+// o superior ("debug", "info", "warn" o "error")
+func NewStdLogger(out io.Writer, level string) *StdLogger {
+	return core.NewStdLogger(out, level)
+}
+
+// WithRequestID retorna un contexto con el ID de correlación que se incluye
+// en los logs de los servicios y del cliente SOAP
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return core.WithRequestID(ctx, id)
+}
+
+// RequestIDFromContext retorna el ID de correlación del contexto, o "" si no
+// tiene uno
+func RequestIDFromContext(ctx context.Context) string {
+	return core.RequestIDFromContext(ctx)
+}
+
+// WithEnvironmentOverride retorna un contexto que dirige las llamadas hechas
+// con él al ambiente env en lugar del configurado. Cada llamada con un
+// ambiente distinto del configurado escribe una advertencia en el log; un
+// ambiente inválido se ignora.
+func WithEnvironmentOverride(ctx context.Context, env models.Environment) context.Context {
+	return core.WithEnvironmentOverride(ctx, env)
+}
+
+// EnvironmentOverrideFromContext retorna el ambiente forzado con
+// WithEnvironmentOverride y si el contexto tiene uno
+func EnvironmentOverrideFromContext(ctx context.Context) (models.Environment, bool) {
+	return core.EnvironmentOverrideFromContext(ctx)
+}
+
+// LogInfof escribe un mensaje de nivel info en logger anteponiendo el ID de
+// correlación del contexto, si lo hay
+func LogInfof(ctx context.Context, logger interface{}, format string, args ...interface{}) {
+	core.LogInfof(ctx, logger, format, args...)
+}
+
+// NewWSAAAuth crea un nuevo autenticador WSAA
+func NewWSAAAuth(config *Config, logger interface{}) *WSAAAuth {
+	return core.NewWSAAAuth(config, logger)
+}
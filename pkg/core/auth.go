@@ -1,4 +1,4 @@
-package client
+package core
 
 import (
 	"bytes"
@@ -10,19 +10,30 @@ import (
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"sync"
 	"time"
+
+	"github.com/dlarregola/arca_invoice_lib/internal/utils"
 )
 
+// ErrClientClosed se retorna al usar un cliente o autenticador ya cerrado
+var ErrClientClosed = errors.New("client is closed")
+
 // WSAAAuth maneja la autenticación con el Web Service de Autenticación y Autorización
 type WSAAAuth struct {
-	config     *Config
-	cache      map[string]*AccessTicket
-	cacheMutex sync.RWMutex
-	logger     interface{}
+	config *Config
+	cache  TokenCache
+	logger interface{}
+
+	closed     bool
+	closeMutex sync.RWMutex
+
+	httpClient *http.Client
+	httpOnce   sync.Once
 }
 
 // AccessTicket representa un ticket de acceso de ARCA
@@ -33,6 +44,22 @@ type AccessTicket struct {
 	GenerationTime time.Time `xml:"generationTime"`
 }
 
+// TokenInfo describe el ticket de acceso en cache para un servicio (ver
+// WSAAAuth.GetTokenInfo). Si Cached es false no hay ticket y el resto de los
+// campos queda en cero.
+type TokenInfo struct {
+	Service        string        `json:"service"`
+	Cached         bool          `json:"cached"`
+	GenerationTime time.Time     `json:"generation_time,omitempty"`
+	ExpirationTime time.Time     `json:"expiration_time,omitempty"`
+	Remaining      time.Duration `json:"remaining"`
+}
+
+// Expired indica si el ticket en cache ya venció
+func (t *TokenInfo) Expired() bool {
+	return t.Cached && t.Remaining <= 0
+}
+
 // WSAARequest representa el request para WSAA
 type WSAARequest struct {
 	XMLName xml.Name `xml:"loginTicketRequest"`
@@ -64,51 +91,96 @@ type WSAAResponse struct {
 }
 
 // NewWSAAAuth crea un nuevo autenticador WSAA
+// Si config.TokenCache es nil se usa un cache en memoria.
 func NewWSAAAuth(config *Config, logger interface{}) *WSAAAuth {
+	cache := config.TokenCache
+	if cache == nil {
+		cache = NewMemoryTokenCache()
+	}
+
 	return &WSAAAuth{
 		config: config,
-		cache:  make(map[string]*AccessTicket),
+		cache:  cache,
 		logger: logger,
 	}
 }
 
 // GetAccessTicket obtiene un ticket de acceso válido
 func (a *WSAAAuth) GetAccessTicket(ctx context.Context, service string) (*AccessTicket, error) {
-	// Verificar cache primero
-	if ticket := a.getFromCache(service); ticket != nil {
-		return ticket, nil
+	if a.IsClosed() {
+		return nil, ErrClientClosed
+	}
+
+	// Verificar cache primero, salvo que esté deshabilitado
+	if !a.config.DisableAuthCache {
+		if ticket := a.getFromCache(ctx, service); ticket != nil {
+			return ticket, nil
+		}
 	}
 
 	// Generar nuevo ticket
 	return a.generateAccessTicket(ctx, service)
 }
 
-// getFromCache obtiene un ticket del cache
-func (a *WSAAAuth) getFromCache(service string) *AccessTicket {
-	a.cacheMutex.RLock()
-	defer a.cacheMutex.RUnlock()
+// GetTokenInfo informa el ticket en cache para un servicio (ej. "wsfe"): su
+// generación, vencimiento y validez restante. A diferencia de
+// GetAccessTicket nunca pide un ticket a WSAA ni remueve uno vencido; si no
+// hay ticket en cache retorna un TokenInfo con Cached en false.
+func (a *WSAAAuth) GetTokenInfo(service string) (*TokenInfo, error) {
+	if a.IsClosed() {
+		return nil, ErrClientClosed
+	}
+
+	ticket, err := a.cache.Get(context.Background(), a.cacheKey(context.Background(), service))
+	if err != nil {
+		return nil, fmt.Errorf("error reading token cache: %w", err)
+	}
+
+	info := &TokenInfo{Service: service}
+	if ticket == nil {
+		return info, nil
+	}
+
+	info.Cached = true
+	info.GenerationTime = ticket.GenerationTime
+	info.ExpirationTime = ticket.ExpirationTime
+	if remaining := ticket.ExpirationTime.Sub(a.config.Now()); remaining > 0 {
+		info.Remaining = remaining
+	}
+
+	return info, nil
+}
+
+// cacheKey arma la clave del cache para un servicio. Incluye ambiente (el
+// forzado en ctx, si lo hay) y CUIT para que un cache compartido no mezcle
+// tickets de distintos ambientes o certificados.
+func (a *WSAAAuth) cacheKey(ctx context.Context, service string) string {
+	return fmt.Sprintf("%s:%s:%s", a.config.ForContext(ctx).Environment, a.config.GetSourceCUIT(), service)
+}
 
-	ticket, exists := a.cache[service]
-	if !exists {
+// getFromCache obtiene un ticket del cache. Los errores del cache se tratan
+// como ausencia del ticket, de modo que se pide uno nuevo a WSAA.
+func (a *WSAAAuth) getFromCache(ctx context.Context, service string) *AccessTicket {
+	key := a.cacheKey(ctx, service)
+	ticket, err := a.cache.Get(ctx, key)
+	if err != nil || ticket == nil {
 		return nil
 	}
 
 	// Verificar si el ticket aún es válido (con margen de 5 minutos)
-	if time.Now().Add(5 * time.Minute).Before(ticket.ExpirationTime) {
+	if a.config.Now().Add(5 * time.Minute).Before(ticket.ExpirationTime) {
 		return ticket
 	}
 
 	// Ticket expirado, remover del cache
-	delete(a.cache, service)
+	_ = a.cache.Delete(ctx, key)
 	return nil
 }
 
-// addToCache agrega un ticket al cache
-func (a *WSAAAuth) addToCache(service string, ticket *AccessTicket) {
-	a.cacheMutex.Lock()
-	defer a.cacheMutex.Unlock()
-
-	a.cache[service] = ticket
+// addToCache agrega un ticket al cache. Si el cache falla el ticket se
+// retorna igual; sólo se pierde la posibilidad de reutilizarlo.
+func (a *WSAAAuth) addToCache(ctx context.Context, service string, ticket *AccessTicket) {
+	_ = a.cache.Set(ctx, a.cacheKey(ctx, service), ticket)
 }
 
 // generateAccessTicket genera un nuevo ticket de acceso
@@ -119,6 +191,11 @@ func (a *WSAAAuth) generateAccessTicket(ctx context.Context, service string) (*A
 		return nil, fmt.Errorf("error parsing certificate: %v", err)
 	}
 
+	// Verificar vigencia del certificado antes de llamar a WSAA
+	if err := utils.ValidateCertificateDates(cert, a.config.Now()); err != nil {
+		return nil, err
+	}
+
 	// Parsear clave privada
 	var privateKey *rsa.PrivateKey
 	parsedKey, err := x509.ParsePKCS1PrivateKey(a.config.PrivateKey)
@@ -137,22 +214,11 @@ func (a *WSAAAuth) generateAccessTicket(ctx context.Context, service string) (*A
 		privateKey = parsedKey
 	}
 
-	// Generar unique ID
-	uniqueID, err := generateUniqueID()
-	if err != nil {
-		return nil, fmt.Errorf("error generating unique ID: %v", err)
-	}
-
 	// Crear request
-	request := &WSAARequest{
-		Version: "1.0",
-		Service: service,
+	request, err := a.newLoginTicketRequest(a.config.ForContext(ctx), service)
+	if err != nil {
+		return nil, err
 	}
-	request.Header.Source = a.config.CUIT
-	request.Header.Destination = "cn=wsaahomo,o=afip,c=ar,serialNumber=CUIT 33693450239"
-	request.Header.UniqueID = uniqueID
-	request.Header.GenerationTime = time.Now().UTC().Format("2006-01-02T15:04:05.000-07:00")
-	request.Header.ExpirationTime = time.Now().Add(24 * time.Hour).UTC().Format("2006-01-02T15:04:05.000-07:00")
 
 	// Serializar request
 	requestXML, err := xml.MarshalIndent(request, "", "  ")
@@ -179,19 +245,50 @@ func (a *WSAAAuth) generateAccessTicket(ctx context.Context, service string) (*A
 	}
 
 	// Crear ticket
+	now := a.config.Now()
 	ticket := &AccessTicket{
 		Token:          wsaaResponse.Credentials.Token,
 		Sign:           wsaaResponse.Credentials.Sign,
-		GenerationTime: time.Now(),
-		ExpirationTime: time.Now().Add(24 * time.Hour),
+		GenerationTime: now,
+		ExpirationTime: now.Add(24 * time.Hour),
 	}
 
 	// Agregar al cache
-	a.addToCache(service, ticket)
+	a.addToCache(ctx, service, ticket)
 
 	return ticket, nil
 }
 
+// NewLoginTicketRequest arma el loginTicketRequest para un servicio.
+// El origen es siempre el CUIT del certificado, aun cuando se facture en
+// nombre de un representado.
+func (a *WSAAAuth) NewLoginTicketRequest(service string) (*WSAARequest, error) {
+	return a.newLoginTicketRequest(a.config, service)
+}
+
+// newLoginTicketRequest arma el loginTicketRequest con el destino del
+// ambiente de config
+func (a *WSAAAuth) newLoginTicketRequest(config *Config, service string) (*WSAARequest, error) {
+	// Generar unique ID
+	uniqueID, err := generateUniqueID()
+	if err != nil {
+		return nil, fmt.Errorf("error generating unique ID: %v", err)
+	}
+
+	request := &WSAARequest{
+		Version: "1.0",
+		Service: service,
+	}
+	request.Header.Source = a.config.GetSourceCUIT()
+	request.Header.Destination = config.GetWSAADestination()
+	request.Header.UniqueID = uniqueID
+	now := a.config.Now()
+	request.Header.GenerationTime = now.UTC().Format("2006-01-02T15:04:05.000-07:00")
+	request.Header.ExpirationTime = now.Add(a.config.GetWSAARequestTTL()).UTC().Format("2006-01-02T15:04:05.000-07:00")
+
+	return request, nil
+}
+
 // createCMS crea un mensaje CMS firmado
 func (a *WSAAAuth) createCMS(data []byte, cert *x509.Certificate, privateKey *rsa.PrivateKey) (string, error) {
 	// Crear hash SHA1 del data
@@ -226,7 +323,8 @@ func (a *WSAAAuth) callWSAA(ctx context.Context, cms string) (string, error) {
 </soapenv:Envelope>`, cms)
 
 	// Crear request HTTP
-	req, err := http.NewRequestWithContext(ctx, "POST", a.config.GetWSAAURL(), bytes.NewReader([]byte(requestBody)))
+	LogEnvironmentOverride(ctx, a.logger, a.config, "wsaa")
+	req, err := http.NewRequestWithContext(ctx, "POST", a.config.ForContext(ctx).GetWSAAURL(), bytes.NewReader([]byte(requestBody)))
 	if err != nil {
 		return "", fmt.Errorf("error creating HTTP request: %v", err)
 	}
@@ -234,20 +332,22 @@ func (a *WSAAAuth) callWSAA(ctx context.Context, cms string) (string, error) {
 	// Configurar headers
 	req.Header.Set("Content-Type", "text/xml; charset=utf-8")
 	req.Header.Set("SOAPAction", "http://wsaa.view.sua.dvadac.desein.afip.gov/loginCms")
-	req.Header.Set("User-Agent", "ARCA-Go-Client/1.0")
+	req.Header.Set("User-Agent", a.config.GetUserAgent())
 
 	// Realizar request
-	client := &http.Client{Timeout: a.config.Timeout}
-	resp, err := client.Do(req)
+	a.httpOnce.Do(func() {
+		a.httpClient = a.config.GetHTTPClient()
+	})
+	resp, err := a.httpClient.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("error making HTTP request: %v", err)
+		return "", wsaaError(ctx, "error making HTTP request", err)
 	}
 	defer resp.Body.Close()
 
 	// Leer response
 	responseBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("error reading response body: %v", err)
+		return "", wsaaError(ctx, "error reading response body", err)
 	}
 
 	// Verificar status code
@@ -272,6 +372,16 @@ func (a *WSAAAuth) callWSAA(ctx context.Context, cms string) (string, error) {
 	return soapResponse.Body.LoginCmsResponse.LoginCmsReturn, nil
 }
 
+// wsaaError envuelve un error de la llamada a WSAA. Si el contexto fue
+// cancelado o venció se envuelve ctx.Err(), para que el llamador pueda usar
+// errors.Is(err, context.DeadlineExceeded) o context.Canceled.
+func wsaaError(ctx context.Context, message string, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("WSAA request aborted: %w", ctxErr)
+	}
+	return fmt.Errorf("%s: %w", message, err)
+}
+
 // generateUniqueID genera un ID único
 func generateUniqueID() (string, error) {
 	bytes := make([]byte, 16)
@@ -281,18 +391,38 @@ func generateUniqueID() (string, error) {
 	return fmt.Sprintf("%x", bytes), nil
 }
 
-// ClearCache limpia el cache de tickets
+// ClearCache limpia el cache de tickets. Sólo aplica a caches que exponen
+// Clear(), como el cache en memoria; un cache compartido no se vacía para no
+// afectar a las otras réplicas.
 func (a *WSAAAuth) ClearCache() {
-	a.cacheMutex.Lock()
-	defer a.cacheMutex.Unlock()
+	if cache, ok := a.cache.(interface{ Clear() }); ok {
+		cache.Clear()
+	}
+}
 
-	a.cache = make(map[string]*AccessTicket)
+// Close limpia el cache de tickets y hace que los pedidos siguientes fallen
+// con ErrClientClosed
+func (a *WSAAAuth) Close() {
+	a.closeMutex.Lock()
+	a.closed = true
+	a.closeMutex.Unlock()
+
+	a.ClearCache()
 }
 
-// GetCacheSize retorna el tamaño del cache
-func (a *WSAAAuth) GetCacheSize() int {
-	a.cacheMutex.RLock()
-	defer a.cacheMutex.RUnlock()
+// IsClosed indica si el autenticador fue cerrado
+func (a *WSAAAuth) IsClosed() bool {
+	a.closeMutex.RLock()
+	defer a.closeMutex.RUnlock()
 
-	return len(a.cache)
+	return a.closed
+}
+
+// GetCacheSize retorna la cantidad de tickets en cache, o 0 si el cache no
+// expone Len()
+func (a *WSAAAuth) GetCacheSize() int {
+	if cache, ok := a.cache.(interface{ Len() int }); ok {
+		return cache.Len()
+	}
+	return 0
 }
@@ -0,0 +1,145 @@
+package core
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dlarregola/arca_invoice_lib/pkg/models"
+)
+
+// CircuitState es el estado de un CircuitBreaker
+type CircuitState string
+
+const (
+	CircuitClosed   CircuitState = "closed"    // Las llamadas pasan normalmente
+	CircuitOpen     CircuitState = "open"      // Las llamadas fallan sin contactar a AFIP
+	CircuitHalfOpen CircuitState = "half_open" // Se deja pasar una llamada de prueba
+)
+
+// CircuitBreaker corta las llamadas a un servicio de AFIP después de una
+// cantidad de fallas consecutivas de red o HTTP 5xx. Mientras está abierto las
+// llamadas fallan de inmediato con models.ErrCircuitOpen; pasado el cooldown
+// deja pasar una llamada de prueba que, si tiene éxito, lo vuelve a cerrar.
+type CircuitBreaker struct {
+	name      string
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mutex    sync.Mutex
+	state    CircuitState
+	failures int
+	openedAt time.Time
+	probeAt  time.Time
+}
+
+// NewCircuitBreaker crea un circuit breaker que se abre tras threshold fallas
+// consecutivas y permanece abierto durante cooldown. Si clock es nil se usa
+// la hora del sistema.
+func NewCircuitBreaker(name string, threshold int, cooldown time.Duration, clock Clock) *CircuitBreaker {
+	if clock == nil {
+		clock = SystemClock{}
+	}
+	return &CircuitBreaker{
+		name:      name,
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       clock.Now,
+		state:     CircuitClosed,
+	}
+}
+
+// Allow indica si se puede realizar una llamada. Retorna un error que
+// envuelve models.ErrCircuitOpen si el circuito está abierto o si ya hay una
+// llamada de prueba en curso.
+func (b *CircuitBreaker) Allow() error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	now := b.now()
+	switch b.state {
+	case CircuitOpen:
+		if now.Sub(b.openedAt) < b.cooldown {
+			return b.openError()
+		}
+		b.state = CircuitHalfOpen
+		b.probeAt = now
+	case CircuitHalfOpen:
+		// Si la llamada de prueba nunca informó su resultado (por ejemplo,
+		// porque se canceló el contexto) se permite otra
+		if now.Sub(b.probeAt) < b.cooldown {
+			return b.openError()
+		}
+		b.probeAt = now
+	}
+
+	return nil
+}
+
+// RecordSuccess informa una llamada exitosa y cierra el circuito
+func (b *CircuitBreaker) RecordSuccess() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.state = CircuitClosed
+	b.failures = 0
+}
+
+// RecordFailure informa una falla de red o HTTP 5xx. Abre el circuito al
+// alcanzar el umbral, o de inmediato si falló la llamada de prueba.
+func (b *CircuitBreaker) RecordFailure() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.failures++
+	if b.state == CircuitHalfOpen || b.failures >= b.threshold {
+		b.state = CircuitOpen
+		b.openedAt = b.now()
+	}
+}
+
+// State retorna el estado actual del circuito
+func (b *CircuitBreaker) State() CircuitState {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.state == CircuitOpen && b.now().Sub(b.openedAt) >= b.cooldown {
+		return CircuitHalfOpen
+	}
+	return b.state
+}
+
+// openError arma el error de circuito abierto con el nombre del servicio
+func (b *CircuitBreaker) openError() error {
+	return fmt.Errorf("%s: %w", b.name, models.ErrCircuitOpen)
+}
+
+// circuitBreakers comparte un circuit breaker por servicio y URL entre todos
+// los clientes del proceso, para que una caída de AFIP corte las llamadas de
+// todas las empresas y no sólo las de la que la detectó
+var circuitBreakers = struct {
+	sync.Mutex
+	breakers map[string]*CircuitBreaker
+}{breakers: make(map[string]*CircuitBreaker)}
+
+// GetCircuitBreaker retorna el circuit breaker del servicio (ej. "wsfe") en el
+// ambiente configurado, o nil si CircuitBreakerThreshold es 0. El breaker se
+// comparte entre los clientes que apuntan a la misma URL y conserva el umbral
+// y el cooldown del primero que lo crea.
+func (c *Config) GetCircuitBreaker(service string) *CircuitBreaker {
+	if c.CircuitBreakerThreshold <= 0 {
+		return nil
+	}
+
+	circuitBreakers.Lock()
+	defer circuitBreakers.Unlock()
+
+	key := service + " " + c.GetBaseURL()
+	breaker, ok := circuitBreakers.breakers[key]
+	if !ok {
+		breaker = NewCircuitBreaker(service, c.CircuitBreakerThreshold, c.CircuitBreakerCooldown, c.Clock)
+		circuitBreakers.breakers[key] = breaker
+	}
+	return breaker
+}
@@ -0,0 +1,18 @@
+package core
+
+import "time"
+
+// Clock provee la hora actual. Se inyecta con Config.Clock para que los tests
+// puedan fijar la hora (ver testutil.FakeClock) al verificar el vencimiento
+// de tickets y la validación de fechas.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock es el Clock por defecto, basado en time.Now
+type SystemClock struct{}
+
+// Now implementa Clock
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
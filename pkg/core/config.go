@@ -0,0 +1,538 @@
+// Package core contiene la configuración y la autenticación WSAA compartidas
+// por el cliente y los servicios WSFE/WSFEX. pkg/client re-exporta sus tipos,
+// por lo que normalmente se usa a través de client.Config y client.WSAAAuth.
+package core
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dlarregola/arca_invoice_lib/internal/shared"
+	"github.com/dlarregola/arca_invoice_lib/pkg/models"
+)
+
+// Version es la versión de la librería, incluida en el User-Agent por defecto
+const Version = shared.Version
+
+// DefaultWSAARequestTTL es la validez del loginTicketRequest enviado a WSAA
+// si no se configura WSAARequestTTL
+const DefaultWSAARequestTTL = shared.DefaultWSAARequestTTL
+
+// CurrencyRateMode define cómo se envía la cotización (MonCotiz) de comprobantes en PES
+type CurrencyRateMode string
+
+const (
+	// CurrencyRateModeNormalize envía siempre 1 para PES (valor por defecto)
+	CurrencyRateModeNormalize CurrencyRateMode = "normalize"
+	// CurrencyRateModeValidate rechaza comprobantes en PES con cotización distinta de 1
+	CurrencyRateModeValidate CurrencyRateMode = "validate"
+	// CurrencyRateModePassthrough envía la cotización tal como está en el comprobante
+	CurrencyRateModePassthrough CurrencyRateMode = "passthrough"
+)
+
+// RequestCapture recibe los envelopes SOAP intercambiados con AFIP, útil para
+// depurar rechazos. Los valores de token, sign y CUIT llegan ocultos.
+type RequestCapture interface {
+	OnRequest(action string, body []byte)
+	OnResponse(action string, status int, body []byte)
+}
+
+// Interceptor envuelve cada llamada SOAP a WSFE/WSFEX. Recibe el envelope a
+// enviar y next, que lo envía al siguiente interceptor (o a AFIP) y retorna
+// el envelope de respuesta. Puede modificar el request o la respuesta, medir
+// la llamada o responder sin invocar next (ej. fixtures en tests).
+type Interceptor interface {
+	RoundTrip(ctx context.Context, action string, req []byte, next func([]byte) ([]byte, error)) ([]byte, error)
+}
+
+// InterceptorFunc permite usar una función como Interceptor
+type InterceptorFunc func(ctx context.Context, action string, req []byte, next func([]byte) ([]byte, error)) ([]byte, error)
+
+// RoundTrip invoca la función
+func (f InterceptorFunc) RoundTrip(ctx context.Context, action string, req []byte, next func([]byte) ([]byte, error)) ([]byte, error) {
+	return f(ctx, action, req, next)
+}
+
+// Config representa la configuración del cliente ARCA
+type Config struct {
+	// Configuración básica
+	Environment models.Environment `json:"environment" yaml:"environment"`
+	CUIT        string             `json:"cuit" yaml:"cuit"`
+	Certificate []byte             `json:"certificate" yaml:"certificate"`
+	PrivateKey  []byte             `json:"private_key" yaml:"private_key"`
+
+	// RepresentedCUIT es el CUIT del representado cuando se factura por
+	// delegación. El certificado sigue autenticando con CUIT, pero el bloque
+	// Auth de WSFE/WSFEX informa este CUIT.
+	RepresentedCUIT string `json:"represented_cuit,omitempty" yaml:"represented_cuit,omitempty"`
+
+	// Configuración de red
+	Timeout       time.Duration `json:"timeout" yaml:"timeout"`
+	RetryAttempts int           `json:"retry_attempts" yaml:"retry_attempts"`
+	RetryDelay    time.Duration `json:"retry_delay" yaml:"retry_delay"`
+
+	// CircuitBreakerThreshold es la cantidad de fallas consecutivas de red o
+	// HTTP 5xx tras la cual se dejan de enviar llamadas al servicio durante
+	// CircuitBreakerCooldown (ver CircuitBreaker). Con 0 queda deshabilitado.
+	CircuitBreakerThreshold int           `json:"circuit_breaker_threshold,omitempty" yaml:"circuit_breaker_threshold,omitempty"`
+	CircuitBreakerCooldown  time.Duration `json:"circuit_breaker_cooldown,omitempty" yaml:"circuit_breaker_cooldown,omitempty"`
+
+	// BaseURLOverride reemplaza la URL base de AFIP para todos los servicios
+	// (WSAA, WSFE y WSFEX). Se usa para apuntar a un servidor simulado en tests.
+	BaseURLOverride string `json:"base_url_override,omitempty" yaml:"base_url_override,omitempty"`
+
+	// WSAADestination reemplaza el destino del loginTicketRequest de WSAA,
+	// que por defecto se deriva de Environment (cn=wsaahomo en homologación,
+	// cn=wsaa en producción)
+	WSAADestination string `json:"wsaa_destination,omitempty" yaml:"wsaa_destination,omitempty"`
+
+	// HTTPClient reemplaza el cliente HTTP usado para todas las llamadas a AFIP.
+	// Si es nil se crea uno con Timeout y el proxy configurado.
+	HTTPClient *http.Client `json:"-" yaml:"-"`
+
+	// Proxy es la URL del proxy de salida (ej. http://proxy:3128). Si está
+	// vacío se respetan HTTP_PROXY/HTTPS_PROXY/NO_PROXY del entorno.
+	Proxy string `json:"proxy,omitempty" yaml:"proxy,omitempty"`
+
+	// TLSConfig permite fijar la versión mínima de TLS o agregar CAs propias
+	// (ej. proxies con inspección TLS). Si es nil se exige TLS 1.2+ con las
+	// CAs del sistema. No se aplica cuando se configura HTTPClient.
+	TLSConfig *tls.Config `json:"-" yaml:"-"`
+
+	// ClientTLSCert es el certificado de cliente que se presenta en el
+	// handshake TLS (mTLS), para gateways corporativos que lo exigen delante
+	// de AFIP. Es independiente de Certificate/PrivateKey, que sólo firman el
+	// login ante WSAA: AFIP no pide certificado de cliente a nivel TLS. No se
+	// aplica cuando se configura HTTPClient.
+	ClientTLSCert *tls.Certificate `json:"-" yaml:"-"`
+
+	// UserAgent identifica al integrador ante AFIP. Si está vacío se envía
+	// "ARCA-Go-Client/<versión>".
+	UserAgent string `json:"user_agent,omitempty" yaml:"user_agent,omitempty"`
+
+	// Configuración de logging
+	LogLevel     string `json:"log_level" yaml:"log_level"`
+	LogRequests  bool   `json:"log_requests" yaml:"log_requests"`
+	LogResponses bool   `json:"log_responses" yaml:"log_responses"`
+
+	// LogOutput es el destino del logger por defecto (StdLogger). Si es nil
+	// se escribe en os.Stderr.
+	LogOutput io.Writer `json:"-" yaml:"-"`
+
+	// RequestCapture recibe el XML enviado (si LogRequests) y recibido (si
+	// LogResponses) por WSFE/WSFEX, con token, sign y CUIT ocultos
+	RequestCapture RequestCapture `json:"-" yaml:"-"`
+
+	// Interceptors envuelven las llamadas SOAP a WSFE/WSFEX en el orden
+	// dado: el primero recibe el request antes que el resto y la respuesta
+	// después
+	Interceptors []Interceptor `json:"-" yaml:"-"`
+
+	// ValidateRequests verifica antes de enviar que el XML tenga los
+	// elementos que AFIP espera (ej. un único FeCabReq y al menos un
+	// FeDetReq). El bloque Auth se verifica siempre.
+	ValidateRequests bool `json:"validate_requests" yaml:"validate_requests"`
+
+	// Configuración de autenticación
+	AuthCacheTTL time.Duration `json:"auth_cache_ttl" yaml:"auth_cache_ttl"`
+
+	// WSAARequestTTL es la validez del loginTicketRequest firmado que se
+	// envía a WSAA (DefaultWSAARequestTTL si es 0). No cambia la duración
+	// del ticket de acceso que WSAA retorna.
+	WSAARequestTTL time.Duration `json:"wsaa_request_ttl,omitempty" yaml:"wsaa_request_ttl,omitempty"`
+
+	// DisableAuthCache hace que cada GetAccessTicket pida un ticket nuevo a
+	// WSAA sin leer el cache, para reproducir fallas de autenticación. Sólo
+	// se recomienda en homologación: en producción WSAA rechaza pedir un
+	// ticket mientras el anterior sigue vigente ("TA vigente").
+	DisableAuthCache bool `json:"disable_auth_cache" yaml:"disable_auth_cache"`
+
+	// Clock provee la hora usada para el vencimiento de tickets y la
+	// validación de fechas. Si es nil se usa la hora del sistema.
+	Clock Clock `json:"-" yaml:"-"`
+
+	// TokenCache almacena los tickets de WSAA. Si es nil se usa un cache en
+	// memoria por cliente; con varias réplicas que comparten CUIT conviene un
+	// cache compartido (ver TokenCache).
+	TokenCache TokenCache `json:"-" yaml:"-"`
+
+	// Configuración de facturación
+	PESCurrencyRateMode CurrencyRateMode `json:"pes_currency_rate_mode,omitempty" yaml:"pes_currency_rate_mode,omitempty"`
+}
+
+// DefaultConfig retorna una configuración por defecto
+func DefaultConfig() Config {
+	return Config{
+		Environment:   models.EnvironmentTesting,
+		Timeout:       30 * time.Second,
+		RetryAttempts: 3,
+		RetryDelay:    1 * time.Second,
+		LogLevel:      "info",
+		LogRequests:   false,
+		LogResponses:  false,
+		AuthCacheTTL:  23 * time.Hour, // Cache por 23 horas (tokens expiran en 24h)
+
+		PESCurrencyRateMode: CurrencyRateModeNormalize,
+	}
+}
+
+// Validate valida la configuración
+func (c *Config) Validate() error {
+	var errors models.ValidationErrors
+
+	// Validar environment
+	if !isValidEnvironment(c.Environment) {
+		errors.Add("environment", "Environment debe ser 'testing' o 'production'", c.Environment)
+	}
+
+	// Validar CUIT
+	if c.CUIT == "" {
+		errors.Add("cuit", "CUIT no puede estar vacío", c.CUIT)
+	} else {
+		if err := validateCUIT(c.CUIT); err != nil {
+			errors.Add("cuit", err.Error(), c.CUIT)
+		}
+	}
+
+	// Validar CUIT representado
+	if c.RepresentedCUIT != "" {
+		if err := validateCUIT(c.RepresentedCUIT); err != nil {
+			errors.Add("represented_cuit", err.Error(), c.RepresentedCUIT)
+		}
+	}
+
+	// Validar certificado
+	if len(c.Certificate) == 0 {
+		errors.Add("certificate", "Certificado no puede estar vacío", nil)
+	}
+
+	// Validar clave privada
+	if len(c.PrivateKey) == 0 {
+		errors.Add("private_key", "Clave privada no puede estar vacía", nil)
+	}
+
+	// Validar timeout
+	if c.Timeout <= 0 {
+		errors.Add("timeout", "Timeout debe ser mayor a 0", c.Timeout)
+	}
+
+	// Validar retry attempts
+	if c.RetryAttempts < 0 {
+		errors.Add("retry_attempts", "Retry attempts no puede ser negativo", c.RetryAttempts)
+	}
+
+	// Validar retry delay
+	if c.RetryDelay < 0 {
+		errors.Add("retry_delay", "Retry delay no puede ser negativo", c.RetryDelay)
+	}
+
+	// Validar circuit breaker
+	if c.CircuitBreakerThreshold < 0 {
+		errors.Add("circuit_breaker_threshold", "Circuit breaker threshold no puede ser negativo", c.CircuitBreakerThreshold)
+	}
+	if c.CircuitBreakerThreshold > 0 && c.CircuitBreakerCooldown <= 0 {
+		errors.Add("circuit_breaker_cooldown", "Circuit breaker cooldown debe ser mayor a 0", c.CircuitBreakerCooldown)
+	}
+
+	// Validar proxy
+	if c.Proxy != "" {
+		if proxyURL, err := url.Parse(c.Proxy); err != nil || proxyURL.Scheme == "" || proxyURL.Host == "" {
+			errors.Add("proxy", "Proxy debe ser una URL válida (ej. http://proxy:3128)", c.Proxy)
+		}
+	}
+
+	// Validar auth cache TTL
+	if c.AuthCacheTTL <= 0 {
+		errors.Add("auth_cache_ttl", "Auth cache TTL debe ser mayor a 0", c.AuthCacheTTL)
+	}
+
+	// Validar validez del loginTicketRequest
+	if c.WSAARequestTTL < 0 {
+		errors.Add("wsaa_request_ttl", "WSAA request TTL no puede ser negativo", c.WSAARequestTTL)
+	}
+
+	// Validar nivel de logging
+	if _, ok := logLevelOrder[strings.ToLower(c.LogLevel)]; !ok && c.LogLevel != "" {
+		errors.Add("log_level", "Log level debe ser 'debug', 'info', 'warn' o 'error'", c.LogLevel)
+	}
+
+	// Validar modo de cotización para PES
+	switch c.PESCurrencyRateMode {
+	case "", CurrencyRateModeNormalize, CurrencyRateModeValidate, CurrencyRateModePassthrough:
+	default:
+		errors.Add("pes_currency_rate_mode", "Modo de cotización PES debe ser 'normalize', 'validate' o 'passthrough'", c.PESCurrencyRateMode)
+	}
+
+	if errors.HasErrors() {
+		return errors
+	}
+
+	return nil
+}
+
+// Now retorna la hora actual según Config.Clock, o la del sistema si no hay
+// uno configurado
+func (c *Config) Now() time.Time {
+	if c.Clock != nil {
+		return c.Clock.Now()
+	}
+	return time.Now()
+}
+
+// GetBaseURL retorna la URL base según el environment o BaseURLOverride
+func (c *Config) GetBaseURL() string {
+	return shared.BaseURL(string(c.Environment), c.BaseURLOverride)
+}
+
+// GetHTTPClient retorna el cliente HTTP para las llamadas a AFIP. Si no se
+// configuró HTTPClient, crea uno con Timeout, TLSConfig, ClientTLSCert y el
+// proxy de Proxy o del entorno.
+func (c *Config) GetHTTPClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if c.Proxy != "" {
+		if proxyURL, err := url.Parse(c.Proxy); err == nil {
+			proxy = http.ProxyURL(proxyURL)
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxy
+	transport.TLSClientConfig = shared.TLSClientConfig(c.TLSConfig)
+	if c.ClientTLSCert != nil {
+		transport.TLSClientConfig.Certificates = append(transport.TLSClientConfig.Certificates, *c.ClientTLSCert)
+	}
+
+	return &http.Client{
+		Timeout:   c.Timeout,
+		Transport: transport,
+	}
+}
+
+// GetAuthCUIT retorna el CUIT a informar en el bloque Auth de WSFE/WSFEX, sin
+// guiones. Si hay un CUIT representado configurado se usa ese; si no, el del
+// certificado.
+func (c *Config) GetAuthCUIT() string {
+	if c.RepresentedCUIT != "" {
+		return normalizeCUIT(c.RepresentedCUIT)
+	}
+	return normalizeCUIT(c.CUIT)
+}
+
+// GetSourceCUIT retorna el CUIT del certificado sin guiones, como se informa
+// en el header del loginTicketRequest de WSAA
+func (c *Config) GetSourceCUIT() string {
+	return normalizeCUIT(c.CUIT)
+}
+
+// normalizeCUIT retorna el CUIT sin guiones, o el valor original si no es un
+// CUIT válido (Validate ya informa ese error)
+func normalizeCUIT(cuit string) string {
+	if normalized, err := models.NormalizeCUIT(cuit); err == nil {
+		return normalized
+	}
+	return cuit
+}
+
+// GetUserAgent retorna el User-Agent a enviar en las llamadas a AFIP
+func (c *Config) GetUserAgent() string {
+	return shared.UserAgent(c.UserAgent)
+}
+
+// GetWSAADestination retorna el destino del loginTicketRequest de WSAA:
+// WSAADestination si está configurado o, si no, el que corresponde al
+// environment. WSAA rechaza el login si el destino no es el del ambiente.
+func (c *Config) GetWSAADestination() string {
+	if c.WSAADestination != "" {
+		return c.WSAADestination
+	}
+	return shared.WSAADestination(string(c.Environment))
+}
+
+// GetWSAAURL retorna la URL del servicio WSAA
+func (c *Config) GetWSAAURL() string {
+	return c.GetBaseURL() + shared.WSAAPath
+}
+
+// GetWSFEURL retorna la URL del servicio WSFEv1
+func (c *Config) GetWSFEURL() string {
+	return c.GetBaseURL() + shared.WSFEPath
+}
+
+// GetWSFEXURL retorna la URL del servicio WSFEXv1
+func (c *Config) GetWSFEXURL() string {
+	return c.GetBaseURL() + shared.WSFEXPath
+}
+
+// validateCUIT valida el formato de un CUIT
+func validateCUIT(cuit string) error {
+	// Importar la función de validación desde utils
+	// Esta es una implementación simplificada para evitar dependencias circulares
+	if cuit == "" {
+		return models.NewValidationError("cuit", "CUIT no puede estar vacío", cuit)
+	}
+
+	// Validar formato básico: XX-XXXXXXXX-X
+	if len(cuit) != 13 || cuit[2] != '-' || cuit[11] != '-' {
+		return models.NewValidationError("cuit", "CUIT debe tener formato XX-XXXXXXXX-X", cuit)
+	}
+
+	return nil
+}
+
+// WithEnvironment configura el environment
+func (c *Config) WithEnvironment(env models.Environment) *Config {
+	c.Environment = env
+	return c
+}
+
+// WithCUIT configura el CUIT
+func (c *Config) WithCUIT(cuit string) *Config {
+	c.CUIT = cuit
+	return c
+}
+
+// WithRepresentedCUIT configura el CUIT representado
+func (c *Config) WithRepresentedCUIT(cuit string) *Config {
+	c.RepresentedCUIT = cuit
+	return c
+}
+
+// WithCertificate configura el certificado
+func (c *Config) WithCertificate(cert []byte) *Config {
+	c.Certificate = cert
+	return c
+}
+
+// WithPrivateKey configura la clave privada
+func (c *Config) WithPrivateKey(key []byte) *Config {
+	c.PrivateKey = key
+	return c
+}
+
+// WithTimeout configura el timeout
+func (c *Config) WithTimeout(timeout time.Duration) *Config {
+	c.Timeout = timeout
+	return c
+}
+
+// WithRetryAttempts configura los intentos de retry
+func (c *Config) WithRetryAttempts(attempts int) *Config {
+	c.RetryAttempts = attempts
+	return c
+}
+
+// WithRetryDelay configura el delay entre retries
+func (c *Config) WithRetryDelay(delay time.Duration) *Config {
+	c.RetryDelay = delay
+	return c
+}
+
+// WithCircuitBreaker habilita el circuit breaker: tras threshold fallas
+// consecutivas se deja de llamar al servicio durante cooldown
+func (c *Config) WithCircuitBreaker(threshold int, cooldown time.Duration) *Config {
+	c.CircuitBreakerThreshold = threshold
+	c.CircuitBreakerCooldown = cooldown
+	return c
+}
+
+// WithLogLevel configura el nivel de logging
+func (c *Config) WithLogLevel(level string) *Config {
+	c.LogLevel = level
+	return c
+}
+
+// WithLogRequests habilita el logging de requests
+func (c *Config) WithLogRequests(enabled bool) *Config {
+	c.LogRequests = enabled
+	return c
+}
+
+// WithLogResponses habilita el logging de responses
+func (c *Config) WithLogResponses(enabled bool) *Config {
+	c.LogResponses = enabled
+	return c
+}
+
+// WithBaseURLOverride configura una URL base alternativa para todos los servicios
+func (c *Config) WithBaseURLOverride(url string) *Config {
+	c.BaseURLOverride = url
+	return c
+}
+
+// GetWSAARequestTTL retorna la validez del loginTicketRequest de WSAA:
+// WSAARequestTTL si está configurado o, si no, DefaultWSAARequestTTL
+func (c *Config) GetWSAARequestTTL() time.Duration {
+	if c.WSAARequestTTL > 0 {
+		return c.WSAARequestTTL
+	}
+	return DefaultWSAARequestTTL
+}
+
+// WithWSAARequestTTL configura la validez del loginTicketRequest de WSAA
+func (c *Config) WithWSAARequestTTL(ttl time.Duration) *Config {
+	c.WSAARequestTTL = ttl
+	return c
+}
+
+// WithWSAADestination configura el destino del loginTicketRequest de WSAA
+func (c *Config) WithWSAADestination(destination string) *Config {
+	c.WSAADestination = destination
+	return c
+}
+
+// WithHTTPClient configura un cliente HTTP propio para todas las llamadas
+func (c *Config) WithHTTPClient(httpClient *http.Client) *Config {
+	c.HTTPClient = httpClient
+	return c
+}
+
+// WithProxy configura el proxy de salida
+func (c *Config) WithProxy(proxy string) *Config {
+	c.Proxy = proxy
+	return c
+}
+
+// WithTLSConfig configura los parámetros TLS de las conexiones a AFIP
+func (c *Config) WithTLSConfig(tlsConfig *tls.Config) *Config {
+	c.TLSConfig = tlsConfig
+	return c
+}
+
+// WithClientTLSCert configura el certificado de cliente para mTLS
+func (c *Config) WithClientTLSCert(cert *tls.Certificate) *Config {
+	c.ClientTLSCert = cert
+	return c
+}
+
+// WithRequestCapture configura el hook de captura de requests y responses
+func (c *Config) WithRequestCapture(capture RequestCapture) *Config {
+	c.RequestCapture = capture
+	return c
+}
+
+// WithInterceptor agrega interceptores al final de la cadena de llamadas SOAP
+func (c *Config) WithInterceptor(interceptors ...Interceptor) *Config {
+	c.Interceptors = append(c.Interceptors, interceptors...)
+	return c
+}
+
+// WithAuthCacheTTL configura el TTL del cache de autenticación
+func (c *Config) WithAuthCacheTTL(ttl time.Duration) *Config {
+	c.AuthCacheTTL = ttl
+	return c
+}
+
+// WithPESCurrencyRateMode configura cómo se envía la cotización en PES
+func (c *Config) WithPESCurrencyRateMode(mode CurrencyRateMode) *Config {
+	c.PESCurrencyRateMode = mode
+	return c
+}
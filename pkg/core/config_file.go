@@ -0,0 +1,165 @@
+package core
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dlarregola/arca_invoice_lib/pkg/models"
+)
+
+// configFileDurations son los campos de duración del archivo de
+// configuración, que aceptan "30s" o una cantidad de segundos
+var configFileDurations = []string{"timeout", "retry_delay", "auth_cache_ttl", "wsaa_request_ttl", "circuit_breaker_cooldown"}
+
+// LoadConfigFile lee una configuración JSON o YAML (según la extensión .json,
+// .yaml o .yml) con los mismos nombres de campo que Config (ej. "cuit",
+// "environment", "timeout"), partiendo de DefaultConfig, y la valida.
+// "certificate" y "private_key" pueden ser la ruta del archivo (relativa al
+// archivo de configuración) o su contenido en base64 o PEM. Las duraciones
+// aceptan "30s" o una cantidad de segundos (ej. 30).
+//
+// Del formato YAML se admiten los pares "clave: valor" de primer nivel, que
+// es todo lo que usa Config (ver parseYAMLConfig).
+//
+// Si hay campos inválidos (por ejemplo, el CUIT) se retornan todos los
+// problemas juntos como models.ValidationErrors.
+func LoadConfigFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file: %w", err)
+	}
+
+	var fields map[string]json.RawMessage
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		if fields, err = parseYAMLConfig(data); err != nil {
+			return nil, fmt.Errorf("config file %s: invalid YAML: %w", path, err)
+		}
+	default:
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil, fmt.Errorf("config file %s: invalid JSON: %w", path, err)
+		}
+	}
+
+	var errs models.ValidationErrors
+	dir := filepath.Dir(path)
+	certificate := readConfigFileCredential(fields, "certificate", dir, &errs)
+	privateKey := readConfigFileCredential(fields, "private_key", dir, &errs)
+
+	for _, field := range configFileDurations {
+		raw, ok := fields[field]
+		if !ok || string(raw) == "null" {
+			delete(fields, field)
+			continue
+		}
+		duration, err := parseConfigFileDuration(raw)
+		if err != nil {
+			errs.Add(field, fmt.Sprintf("%s debe ser una duración (ej. 30s) o una cantidad de segundos", field), string(raw))
+			delete(fields, field)
+			continue
+		}
+		fields[field], _ = json.Marshal(duration)
+	}
+
+	normalized, err := json.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	config := DefaultConfig()
+	decoder := json.NewDecoder(bytes.NewReader(normalized))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&config); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	config.Certificate = certificate
+	config.PrivateKey = privateKey
+
+	// Agregar los errores de validación de los campos que se pudieron leer
+	if err := config.Validate(); err != nil {
+		var validationErrs models.ValidationErrors
+		if !errors.As(err, &validationErrs) {
+			return &config, err
+		}
+		for _, validationErr := range validationErrs {
+			if !hasFieldError(errs, validationErr.Field) {
+				errs = append(errs, validationErr)
+			}
+		}
+	}
+
+	if errs.HasErrors() {
+		return &config, errs
+	}
+
+	return &config, nil
+}
+
+// parseConfigFileDuration interpreta una duración del archivo: un texto como
+// "30s" o "30", o un número, que se toma como segundos igual que en
+// ConfigFromEnv
+func parseConfigFileDuration(raw json.RawMessage) (time.Duration, error) {
+	var seconds float64
+	if err := json.Unmarshal(raw, &seconds); err == nil {
+		if seconds < 0 {
+			return 0, fmt.Errorf("negative duration")
+		}
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return 0, err
+	}
+	return parseDuration(value)
+}
+
+// readConfigFileCredential quita del archivo el certificado o la clave y
+// retorna su contenido: el archivo indicado si existe, el valor en base64 o
+// el PEM tal como está
+func readConfigFileCredential(fields map[string]json.RawMessage, field, dir string, errs *models.ValidationErrors) []byte {
+	raw, ok := fields[field]
+	if !ok {
+		return nil
+	}
+	delete(fields, field)
+
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		errs.Add(field, fmt.Sprintf("%s debe ser una ruta, base64 o PEM", field), nil)
+		return nil
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+
+	if strings.HasPrefix(value, "-----BEGIN") {
+		return []byte(value)
+	}
+
+	path := value
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	if data, err := os.ReadFile(path); err == nil {
+		return data
+	} else if !errors.Is(err, os.ErrNotExist) {
+		errs.Add(field, fmt.Sprintf("no se pudo leer %s: %v", path, err), value)
+		return nil
+	}
+
+	if data, err := base64.StdEncoding.DecodeString(value); err == nil {
+		return data
+	}
+
+	errs.Add(field, fmt.Sprintf("no existe el archivo %s ni es un valor base64 válido", path), value)
+	return nil
+}
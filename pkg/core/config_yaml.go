@@ -0,0 +1,166 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// configFieldKinds mapea el nombre de cada campo de Config en el archivo
+// (su tag json) a su tipo, para decidir cómo convertir los valores YAML
+var configFieldKinds = func() map[string]reflect.Kind {
+	kinds := make(map[string]reflect.Kind)
+	configType := reflect.TypeOf(Config{})
+	for i := 0; i < configType.NumField(); i++ {
+		field := configType.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		kinds[name] = field.Type.Kind()
+	}
+	return kinds
+}()
+
+// parseYAMLConfig lee un archivo YAML de configuración y retorna sus campos
+// como JSON, para procesarlos igual que un archivo JSON. Admite el
+// subconjunto de YAML que usa Config: pares "clave: valor" de primer nivel,
+// comentarios, valores entre comillas y bloques "|" o ">" (ej. un
+// certificado PEM). Los valores se convierten según el tipo del campo, de
+// modo que "cuit: 20123456789" se lee como texto.
+func parseYAMLConfig(data []byte) (map[string]json.RawMessage, error) {
+	fields := make(map[string]json.RawMessage)
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || trimmed == "---" {
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			return nil, fmt.Errorf("line %d: sólo se admiten campos de primer nivel", i+1)
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("line %d: se esperaba \"clave: valor\"", i+1)
+		}
+		key = strings.TrimSpace(key)
+		if _, exists := fields[key]; exists {
+			return nil, fmt.Errorf("line %d: campo %s repetido", i+1, key)
+		}
+		value = strings.TrimSpace(value)
+
+		var text string
+		quoted := false
+		switch {
+		case strings.HasPrefix(value, "|") || strings.HasPrefix(value, ">"):
+			var consumed int
+			text, consumed = yamlBlockScalar(lines[i+1:], value)
+			i += consumed
+			quoted = true
+		case strings.HasPrefix(value, `"`):
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: valor entre comillas inválido: %s", i+1, value)
+			}
+			text, quoted = unquoted, true
+		case strings.HasPrefix(value, "'"):
+			if len(value) < 2 || !strings.HasSuffix(value, "'") {
+				return nil, fmt.Errorf("line %d: valor entre comillas inválido: %s", i+1, value)
+			}
+			text, quoted = strings.ReplaceAll(value[1:len(value)-1], "''", "'"), true
+		case strings.HasPrefix(value, "[") || strings.HasPrefix(value, "{"):
+			return nil, fmt.Errorf("line %d: %s: no se admiten listas ni objetos", i+1, key)
+		default:
+			if comment := strings.Index(value, " #"); comment >= 0 {
+				value = strings.TrimSpace(value[:comment])
+			}
+			if value == "" && i+1 < len(lines) && strings.HasPrefix(lines[i+1], " ") {
+				return nil, fmt.Errorf("line %d: %s: no se admiten listas ni objetos", i+1, key)
+			}
+			text = value
+		}
+
+		raw, err := yamlValue(key, text, quoted)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", i+1, err)
+		}
+		fields[key] = raw
+	}
+
+	return fields, nil
+}
+
+// yamlBlockScalar lee las líneas indentadas de un bloque "|" (conserva los
+// saltos de línea) o ">" (los reemplaza por espacios) y retorna su texto y
+// la cantidad de líneas consumidas
+func yamlBlockScalar(lines []string, header string) (string, int) {
+	var block []string
+	indent := -1
+	consumed := 0
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			block = append(block, "")
+			consumed++
+			continue
+		}
+		lineIndent := len(line) - len(strings.TrimLeft(line, " "))
+		if lineIndent == 0 {
+			break
+		}
+		if indent < 0 {
+			indent = lineIndent
+		}
+		if lineIndent < indent {
+			break
+		}
+		block = append(block, line[indent:])
+		consumed++
+	}
+
+	// Las líneas vacías al final no forman parte del bloque
+	for len(block) > 0 && block[len(block)-1] == "" {
+		block = block[:len(block)-1]
+	}
+
+	separator := "\n"
+	if strings.HasPrefix(header, ">") {
+		separator = " "
+	}
+	text := strings.Join(block, separator)
+	if !strings.HasSuffix(header, "-") {
+		text += "\n"
+	}
+	return text, consumed
+}
+
+// yamlValue convierte el valor de un campo a JSON según su tipo en Config.
+// Los valores sin comillas de campos numéricos o booleanos se pasan tal
+// cual, para que LoadConfigFile informe los inválidos.
+func yamlValue(key, text string, quoted bool) (json.RawMessage, error) {
+	if !quoted && (text == "" || text == "~" || text == "null") {
+		return json.RawMessage("null"), nil
+	}
+
+	switch configFieldKinds[key] {
+	case reflect.Bool:
+		if quoted {
+			break
+		}
+		value, err := strconv.ParseBool(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s debe ser true o false", key)
+		}
+		return json.Marshal(value)
+	case reflect.Int, reflect.Int64:
+		if _, err := strconv.ParseFloat(text, 64); !quoted && err == nil {
+			return json.RawMessage(text), nil
+		}
+	}
+
+	return json.Marshal(text)
+}
@@ -0,0 +1,104 @@
+package core
+
+import (
+	"context"
+
+	"github.com/dlarregola/arca_invoice_lib/pkg/models"
+)
+
+// requestIDKey es la clave del ID de correlación en el contexto
+type requestIDKey struct{}
+
+// environmentKey es la clave del ambiente forzado en el contexto
+type environmentKey struct{}
+
+// WithRequestID retorna un contexto que lleva el ID de correlación del
+// request. Los servicios y el cliente SOAP lo incluyen en sus logs, de modo
+// que se pueden seguir los comprobantes de cada empresa aun cuando se
+// intercalan muchas llamadas.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext retorna el ID de correlación del contexto, o "" si no
+// tiene uno
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// WithEnvironmentOverride retorna un contexto que dirige las llamadas hechas
+// con él al ambiente env (WSAA, WSFE y WSFEX), en lugar del Environment de la
+// configuración. Permite usar un mismo cliente contra homologación y
+// producción sin reconstruirlo; el certificado debe estar habilitado en
+// ambos ambientes. Cada llamada con un ambiente distinto del configurado
+// escribe una advertencia en el log. Un env que no sea EnvironmentTesting ni
+// EnvironmentProduction se ignora (las llamadas usan el ambiente
+// configurado) y también se advierte en el log.
+func WithEnvironmentOverride(ctx context.Context, env models.Environment) context.Context {
+	return context.WithValue(ctx, environmentKey{}, env)
+}
+
+// EnvironmentOverrideFromContext retorna el ambiente forzado con
+// WithEnvironmentOverride y si el contexto tiene uno válido
+func EnvironmentOverrideFromContext(ctx context.Context) (models.Environment, bool) {
+	env, ok := environmentOverride(ctx)
+	return env, ok && isValidEnvironment(env)
+}
+
+// environmentOverride retorna el ambiente guardado en ctx, sea o no válido
+func environmentOverride(ctx context.Context) (models.Environment, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	env, ok := ctx.Value(environmentKey{}).(models.Environment)
+	return env, ok && env != ""
+}
+
+// isValidEnvironment indica si env es uno de los ambientes de AFIP
+func isValidEnvironment(env models.Environment) bool {
+	return env == models.EnvironmentTesting || env == models.EnvironmentProduction
+}
+
+// ForContext retorna la configuración a usar en las llamadas hechas con ctx:
+// la misma si el contexto no fuerza otro ambiente o una copia con el
+// ambiente de WithEnvironmentOverride. BaseURLOverride y WSAADestination
+// siguen teniendo prioridad sobre el ambiente.
+func (c *Config) ForContext(ctx context.Context) *Config {
+	env, ok := EnvironmentOverrideFromContext(ctx)
+	if !ok {
+		return c
+	}
+	return c.ForEnvironment(env)
+}
+
+// ForEnvironment retorna la configuración con el ambiente env: la misma si
+// ya es el configurado o una copia con Environment reemplazado
+func (c *Config) ForEnvironment(env models.Environment) *Config {
+	if env == c.Environment {
+		return c
+	}
+
+	config := *c
+	config.Environment = env
+	return &config
+}
+
+// LogEnvironmentOverride escribe una advertencia en logger si ctx dirige la
+// llamada a service a un ambiente distinto del configurado, para detectar
+// mezclas accidentales de homologación y producción. También advierte si el
+// ambiente forzado es inválido y por lo tanto se ignora.
+func LogEnvironmentOverride(ctx context.Context, logger interface{}, config *Config, service string) {
+	env, ok := environmentOverride(ctx)
+	if !ok || env == config.Environment {
+		return
+	}
+	if !isValidEnvironment(env) {
+		LogWarnf(ctx, logger, "%s: ignoring invalid environment override %q, calling configured %s", service, env, config.Environment)
+		return
+	}
+	LogWarnf(ctx, logger, "%s: environment override in effect, calling %s instead of configured %s", service, env, config.Environment)
+}
@@ -0,0 +1,158 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dlarregola/arca_invoice_lib/pkg/models"
+)
+
+// Variables de entorno leídas por ConfigFromEnv
+const (
+	EnvCUIT            = "ARCA_CUIT"
+	EnvRepresentedCUIT = "ARCA_REPRESENTED_CUIT"
+	EnvEnvironment     = "ARCA_ENVIRONMENT"
+	EnvCertPath        = "ARCA_CERT_PATH"
+	EnvKeyPath         = "ARCA_KEY_PATH"
+	EnvTimeout         = "ARCA_TIMEOUT"
+	EnvRetryAttempts   = "ARCA_RETRY_ATTEMPTS"
+	EnvRetryDelay      = "ARCA_RETRY_DELAY"
+	EnvProxy           = "ARCA_PROXY"
+	EnvLogLevel        = "ARCA_LOG_LEVEL"
+	EnvAuthCacheTTL    = "ARCA_AUTH_CACHE_TTL"
+	EnvWSAARequestTTL  = "ARCA_WSAA_REQUEST_TTL"
+	EnvBaseURL         = "ARCA_BASE_URL"
+	EnvUserAgent       = "ARCA_USER_AGENT"
+)
+
+// ConfigFromEnv arma una configuración a partir de variables de entorno,
+// partiendo de DefaultConfig. ARCA_CUIT, ARCA_CERT_PATH y ARCA_KEY_PATH son
+// obligatorias; el certificado y la clave se leen de los archivos indicados.
+// Las duraciones aceptan el formato de time.ParseDuration ("30s") o segundos.
+// Si falta alguna variable o la configuración resultante no es válida se
+// retornan todos los problemas juntos como models.ValidationErrors.
+func ConfigFromEnv() (Config, error) {
+	config := DefaultConfig()
+	var errs models.ValidationErrors
+
+	if env, ok := lookupEnv(EnvEnvironment); ok {
+		config.Environment = models.Environment(strings.ToLower(env))
+	}
+
+	if cuit, ok := lookupEnv(EnvCUIT); ok {
+		config.CUIT = cuit
+	} else {
+		errs.Add("cuit", fmt.Sprintf("%s no está definida", EnvCUIT), nil)
+	}
+	if cuit, ok := lookupEnv(EnvRepresentedCUIT); ok {
+		config.RepresentedCUIT = cuit
+	}
+
+	config.Certificate = readEnvFile(EnvCertPath, "certificate", &errs)
+	config.PrivateKey = readEnvFile(EnvKeyPath, "private_key", &errs)
+
+	parseEnvDuration(EnvTimeout, "timeout", &config.Timeout, &errs)
+	parseEnvDuration(EnvRetryDelay, "retry_delay", &config.RetryDelay, &errs)
+	parseEnvDuration(EnvAuthCacheTTL, "auth_cache_ttl", &config.AuthCacheTTL, &errs)
+	parseEnvDuration(EnvWSAARequestTTL, "wsaa_request_ttl", &config.WSAARequestTTL, &errs)
+
+	if value, ok := lookupEnv(EnvRetryAttempts); ok {
+		attempts, err := strconv.Atoi(value)
+		if err != nil {
+			errs.Add("retry_attempts", fmt.Sprintf("%s debe ser un número entero", EnvRetryAttempts), value)
+		} else {
+			config.RetryAttempts = attempts
+		}
+	}
+
+	if proxy, ok := lookupEnv(EnvProxy); ok {
+		config.Proxy = proxy
+	}
+	if level, ok := lookupEnv(EnvLogLevel); ok {
+		config.LogLevel = level
+	}
+	if baseURL, ok := lookupEnv(EnvBaseURL); ok {
+		config.BaseURLOverride = baseURL
+	}
+	if userAgent, ok := lookupEnv(EnvUserAgent); ok {
+		config.UserAgent = userAgent
+	}
+
+	// Agregar los errores de validación de los campos que se pudieron leer
+	if err := config.Validate(); err != nil {
+		var validationErrs models.ValidationErrors
+		if !errors.As(err, &validationErrs) {
+			return config, err
+		}
+		for _, validationErr := range validationErrs {
+			if !hasFieldError(errs, validationErr.Field) {
+				errs = append(errs, validationErr)
+			}
+		}
+	}
+
+	if errs.HasErrors() {
+		return config, errs
+	}
+
+	return config, nil
+}
+
+// lookupEnv retorna el valor de una variable de entorno definida y no vacía
+func lookupEnv(name string) (string, bool) {
+	value := strings.TrimSpace(os.Getenv(name))
+	return value, value != ""
+}
+
+// readEnvFile lee el archivo indicado por una variable de entorno obligatoria
+func readEnvFile(name, field string, errs *models.ValidationErrors) []byte {
+	path, ok := lookupEnv(name)
+	if !ok {
+		errs.Add(field, fmt.Sprintf("%s no está definida", name), nil)
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		errs.Add(field, fmt.Sprintf("no se pudo leer %s: %v", name, err), path)
+		return nil
+	}
+	return data
+}
+
+// parseEnvDuration lee una duración opcional como "30s" o como segundos
+func parseEnvDuration(name, field string, target *time.Duration, errs *models.ValidationErrors) {
+	value, ok := lookupEnv(name)
+	if !ok {
+		return
+	}
+
+	duration, err := parseDuration(value)
+	if err != nil {
+		errs.Add(field, fmt.Sprintf("%s debe ser una duración (ej. 30s) o una cantidad de segundos", name), value)
+		return
+	}
+	*target = duration
+}
+
+// parseDuration interpreta una duración como "30s" o como segundos
+func parseDuration(value string) (time.Duration, error) {
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// hasFieldError indica si ya hay un error registrado para el campo
+func hasFieldError(errs models.ValidationErrors, field string) bool {
+	for _, err := range errs {
+		if err.Field == field {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,136 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Niveles de logging aceptados por Config.LogLevel y StdLogger
+const (
+	LogLevelDebug = "debug"
+	LogLevelInfo  = "info"
+	LogLevelWarn  = "warn"
+	LogLevelError = "error"
+)
+
+// logLevelOrder ordena los niveles de menor a mayor severidad
+var logLevelOrder = map[string]int{
+	LogLevelDebug: 0,
+	LogLevelInfo:  1,
+	LogLevelWarn:  2,
+	LogLevelError: 3,
+}
+
+// StdLogger es un logger simple que escribe una línea por mensaje en un
+// io.Writer, descartando los mensajes por debajo del nivel configurado.
+// Implementa interfaces.Logger y es el logger por defecto de NewARCAClient.
+type StdLogger struct {
+	out   io.Writer
+	level int
+	mutex sync.Mutex
+}
+
+// NewStdLogger crea un logger que escribe en out (os.Stderr si es nil) los
+// mensajes de nivel level o superior. Un nivel vacío o desconocido equivale
+// a "info".
+func NewStdLogger(out io.Writer, level string) *StdLogger {
+	if out == nil {
+		out = os.Stderr
+	}
+
+	l := &StdLogger{out: out}
+	l.SetLevel(level)
+	return l
+}
+
+// SetLevel cambia el nivel mínimo de los mensajes que se escriben
+func (l *StdLogger) SetLevel(level string) {
+	order, ok := logLevelOrder[strings.ToLower(strings.TrimSpace(level))]
+	if !ok {
+		order = logLevelOrder[LogLevelInfo]
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.level = order
+}
+
+// GetLevel retorna el nivel configurado
+func (l *StdLogger) GetLevel() interface{} {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	for name, order := range logLevelOrder {
+		if order == l.level {
+			return name
+		}
+	}
+	return LogLevelInfo
+}
+
+func (l *StdLogger) Debug(args ...interface{}) { l.log(LogLevelDebug, fmt.Sprint(args...)) }
+func (l *StdLogger) Debugf(format string, args ...interface{}) {
+	l.log(LogLevelDebug, fmt.Sprintf(format, args...))
+}
+func (l *StdLogger) Info(args ...interface{}) { l.log(LogLevelInfo, fmt.Sprint(args...)) }
+func (l *StdLogger) Infof(format string, args ...interface{}) {
+	l.log(LogLevelInfo, fmt.Sprintf(format, args...))
+}
+func (l *StdLogger) Warn(args ...interface{}) { l.log(LogLevelWarn, fmt.Sprint(args...)) }
+func (l *StdLogger) Warnf(format string, args ...interface{}) {
+	l.log(LogLevelWarn, fmt.Sprintf(format, args...))
+}
+func (l *StdLogger) Error(args ...interface{}) { l.log(LogLevelError, fmt.Sprint(args...)) }
+func (l *StdLogger) Errorf(format string, args ...interface{}) {
+	l.log(LogLevelError, fmt.Sprintf(format, args...))
+}
+
+// log escribe el mensaje si su nivel alcanza el configurado
+func (l *StdLogger) log(level, message string) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if logLevelOrder[level] < l.level {
+		return
+	}
+	fmt.Fprintf(l.out, "%s [%s] %s\n", time.Now().Format(time.RFC3339), strings.ToUpper(level), message)
+}
+
+// LogInfof escribe un mensaje de nivel info en logger, si implementa Infof,
+// anteponiendo el ID de correlación del contexto cuando lo hay
+func LogInfof(ctx context.Context, logger interface{}, format string, args ...interface{}) {
+	l, ok := logger.(interface {
+		Infof(format string, args ...interface{})
+	})
+	if !ok {
+		return
+	}
+
+	if id := RequestIDFromContext(ctx); id != "" {
+		format = "[request_id=%s] " + format
+		args = append([]interface{}{id}, args...)
+	}
+	l.Infof(format, args...)
+}
+
+// LogWarnf escribe un mensaje de nivel warn en logger, si implementa Warnf,
+// anteponiendo el ID de correlación del contexto cuando lo hay
+func LogWarnf(ctx context.Context, logger interface{}, format string, args ...interface{}) {
+	l, ok := logger.(interface {
+		Warnf(format string, args ...interface{})
+	})
+	if !ok {
+		return
+	}
+
+	if id := RequestIDFromContext(ctx); id != "" {
+		format = "[request_id=%s] " + format
+		args = append([]interface{}{id}, args...)
+	}
+	l.Warnf(format, args...)
+}
@@ -0,0 +1,74 @@
+package core
+
+import (
+	"context"
+	"sync"
+)
+
+// TokenCache almacena los tickets de acceso de WSAA. La implementación por
+// defecto es en memoria; en despliegues con varias réplicas que comparten un
+// CUIT conviene una implementación compartida (Redis, Memcached) para que
+// cada réplica no pida su propio ticket y AFIP no responda "TA ya vigente".
+//
+// Las claves identifican ambiente, CUIT y servicio (ej. "production:20123456789:wsfe").
+// Get retorna nil sin error si la clave no existe.
+type TokenCache interface {
+	Get(ctx context.Context, key string) (*AccessTicket, error)
+	Set(ctx context.Context, key string, ticket *AccessTicket) error
+	Delete(ctx context.Context, key string) error
+}
+
+// MemoryTokenCache es el TokenCache en memoria usado por defecto
+type MemoryTokenCache struct {
+	tickets map[string]*AccessTicket
+	mutex   sync.RWMutex
+}
+
+// NewMemoryTokenCache crea un cache de tickets en memoria
+func NewMemoryTokenCache() *MemoryTokenCache {
+	return &MemoryTokenCache{
+		tickets: make(map[string]*AccessTicket),
+	}
+}
+
+// Get implementa TokenCache
+func (c *MemoryTokenCache) Get(ctx context.Context, key string) (*AccessTicket, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.tickets[key], nil
+}
+
+// Set implementa TokenCache
+func (c *MemoryTokenCache) Set(ctx context.Context, key string, ticket *AccessTicket) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.tickets[key] = ticket
+	return nil
+}
+
+// Delete implementa TokenCache
+func (c *MemoryTokenCache) Delete(ctx context.Context, key string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	delete(c.tickets, key)
+	return nil
+}
+
+// Clear elimina todos los tickets
+func (c *MemoryTokenCache) Clear() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.tickets = make(map[string]*AccessTicket)
+}
+
+// Len retorna la cantidad de tickets almacenados
+func (c *MemoryTokenCache) Len() int {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return len(c.tickets)
+}
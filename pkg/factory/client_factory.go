@@ -1,6 +1,7 @@
 package factory
 
 import (
+	"crypto/tls"
 	"time"
 
 	"github.com/dlarregola/arca_invoice_lib/internal/client"
@@ -17,9 +18,36 @@ type clientManagerFactory struct {
 	config client.ManagerConfig
 }
 
+// Option configura parámetros opcionales del manager
+type Option func(*client.ManagerConfig)
+
+// WithTLSConfig configura los parámetros TLS de las conexiones a AFIP
+// (versión mínima, CAs propias). Por defecto se exige TLS 1.2+.
+func WithTLSConfig(tlsConfig *tls.Config) Option {
+	return func(config *client.ManagerConfig) {
+		config.TLSConfig = tlsConfig
+	}
+}
+
+// WithBaseURLOverride redirige todas las llamadas a AFIP a la URL base dada,
+// por ejemplo un reverse proxy o un servidor de prueba
+func WithBaseURLOverride(url string) Option {
+	return func(config *client.ManagerConfig) {
+		config.BaseURLOverride = url
+	}
+}
+
+// WithCleanupInterval habilita la limpieza automática de clientes inactivos
+// cada el intervalo dado. El janitor se detiene con Close del manager.
+func WithCleanupInterval(interval time.Duration) Option {
+	return func(config *client.ManagerConfig) {
+		config.CleanupInterval = interval
+	}
+}
+
 // NewClientManagerFactory crea una nueva instancia del factor
 // Add config params to the factory to override the default values
-func NewClientManagerFactory(cacheSize int, idleTimeout time.Duration, httpTimeout time.Duration, maxRetryAttempts int, logger interfaces.Logger) ClientManagerFactory {
+func NewClientManagerFactory(cacheSize int, idleTimeout time.Duration, httpTimeout time.Duration, maxRetryAttempts int, logger interfaces.Logger, opts ...Option) ClientManagerFactory {
 	config := client.ManagerConfig{
 		ClientCacheSize:   cacheSize,
 		ClientIdleTimeout: idleTimeout,
@@ -27,6 +55,9 @@ func NewClientManagerFactory(cacheSize int, idleTimeout time.Duration, httpTimeo
 		MaxRetryAttempts:  maxRetryAttempts,
 		Logger:            logger,
 	}
+	for _, opt := range opts {
+		opt(&config)
+	}
 	return &clientManagerFactory{config: config}
 }
 
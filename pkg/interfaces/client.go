@@ -3,6 +3,8 @@ package interfaces
 import (
 	"context"
 	"time"
+
+	"github.com/dlarregola/arca_invoice_lib/pkg/models"
 )
 
 // Logger es la interfaz para logging
@@ -17,6 +19,24 @@ type Logger interface {
 	Errorf(format string, args ...interface{})
 }
 
+// FieldLogger es implementado opcionalmente por los loggers que soportan
+// campos estructurados (ej. un adapter de logrus o zap). Los servicios
+// registran sus datos con las claves LogField*; si el logger no implementa
+// FieldLogger, los campos se agregan al final del mensaje como clave=valor.
+type FieldLogger interface {
+	WithFields(fields map[string]interface{}) Logger
+}
+
+// Claves de los campos que registran los servicios
+const (
+	LogFieldCompany       = "company"
+	LogFieldService       = "service"
+	LogFieldAction        = "action"
+	LogFieldPointOfSale   = "point_of_sale"
+	LogFieldInvoiceType   = "invoice_type"
+	LogFieldInvoiceNumber = "invoice_number"
+)
+
 // ARCAClientManager es la interfaz principal para el manager multi-tenant
 type ARCAClientManager interface {
 	// GetClientForCompany obtiene un cliente específico para una empresa
@@ -32,8 +52,18 @@ type ARCAClientManager interface {
 	// InvalidateClient invalida el cache de un cliente específico
 	InvalidateClient(companyID string)
 
+	// RotateCompanyCredentials reemplaza de forma atómica el cliente de una
+	// empresa por uno creado con newConfig (ej. certificado renovado). Las
+	// llamadas siguientes usan las credenciales nuevas y piden un token nuevo;
+	// las que están en curso terminan con el cliente anterior.
+	RotateCompanyCredentials(companyID string, newConfig CompanyConfig) error
+
 	// GetCacheStats retorna estadísticas del cache
 	GetCacheStats() CacheStats
+
+	// Close cierra todos los clientes en cache y detiene las tareas internas.
+	// Luego de Close, GetClientForCompany retorna error.
+	Close() error
 }
 
 // ARCAClient es la interfaz para un cliente de una empresa específica
@@ -47,9 +77,14 @@ type ARCAClient interface {
 	// GetCompanyInfo retorna información de la empresa
 	GetCompanyInfo() CompanyInfo
 
-	// IsHealthy verifica el estado de la conexión
+	// IsHealthy verifica el estado de la conexión. Falla si el certificado
+	// está vencido o todavía no es válido.
 	IsHealthy(ctx context.Context) error
 
+	// CheckCertificate informa la vigencia del certificado de la empresa,
+	// con estado "warning" si vence en menos de 15 días
+	CheckCertificate(ctx context.Context) (*models.CertStatus, error)
+
 	// Close cierra el cliente y limpia recursos
 	Close() error
 }
@@ -10,6 +10,12 @@ type WSFEService interface {
 	// AuthorizeInvoice autoriza un comprobante
 	AuthorizeInvoice(ctx context.Context, invoice *models.Invoice) (*models.AuthorizationResponse, error)
 
+	// AuthorizeInvoicesConcurrent autoriza un lote de comprobantes con a lo
+	// sumo concurrency llamadas simultáneas. Los resultados respetan el orden
+	// del lote; los comprobantes que fallan quedan en nil y sus errores se
+	// informan juntos en un *models.BatchError.
+	AuthorizeInvoicesConcurrent(ctx context.Context, invoices []*models.Invoice, concurrency int) ([]*models.AuthorizationResponse, error)
+
 	// QueryInvoice consulta un comprobante
 	QueryInvoice(ctx context.Context, query *models.InvoiceQuery) (*models.Invoice, error)
 
@@ -28,6 +34,7 @@ type WSFEService interface {
 	// GetConceptTypes obtiene los tipos de concepto disponibles
 	GetConceptTypes(ctx context.Context) ([]models.ConceptType, error)
 
-	// GetInvoiceTypes obtiene los tipos de comprobante disponibles
-	GetInvoiceTypes(ctx context.Context) ([]models.InvoiceType, error)
+	// GetInvoiceTypes obtiene los tipos de comprobante disponibles, con su
+	// descripción y vigencia
+	GetInvoiceTypes(ctx context.Context) ([]models.InvoiceTypeInfo, error)
 }
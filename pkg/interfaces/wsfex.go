@@ -10,6 +10,12 @@ type WSFEXService interface {
 	// AuthorizeExportInvoice autoriza un comprobante de exportación
 	AuthorizeExportInvoice(ctx context.Context, invoice *models.ExportInvoice) (*models.ExportAuthResponse, error)
 
+	// AuthorizeExportInvoices autoriza un lote de comprobantes de exportación
+	// con a lo sumo concurrency llamadas simultáneas. Los resultados respetan
+	// el orden del lote; los comprobantes que fallan quedan en nil y sus
+	// errores se informan juntos en un *models.BatchError.
+	AuthorizeExportInvoices(ctx context.Context, invoices []*models.ExportInvoice, concurrency int) ([]*models.ExportAuthResponse, error)
+
 	// QueryExportInvoice consulta un comprobante de exportación
 	QueryExportInvoice(ctx context.Context, query *models.ExportInvoiceQuery) (*models.ExportInvoice, error)
 
@@ -0,0 +1,361 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// InvoiceBuilder arma un Invoice calculando Amount, NonTaxableAmount,
+// ExemptAmount, TaxAmount y TotalAmount a partir de los ítems y sus
+// impuestos. Los ítems con IVA al 0% suman al no gravado y los exentos al
+// exento, no al neto gravado:
+//
+//	invoice, err := models.NewInvoiceBuilder().
+//		WithType(models.InvoiceTypeB).
+//		WithPointOfSale(1).
+//		WithCustomer(models.DocumentTypeDNI, "12345678").
+//		AddItem("Producto", 2, 500).
+//		AddTax(models.TaxTypeIVA, models.TaxRate21).
+//		Build()
+type InvoiceBuilder struct {
+	invoice Invoice
+	errors  ValidationErrors
+
+	pricesIncludeTax bool
+	grossTotal       float64
+}
+
+// taxInclusiveTolerance es la diferencia máxima admitida, por ítem, entre el
+// precio final y neto más IVA al descomponer precios con IVA incluido
+const taxInclusiveTolerance = 0.01
+
+// NewInvoiceBuilder crea un builder con concepto productos, moneda PES y fecha actual
+func NewInvoiceBuilder() *InvoiceBuilder {
+	now := time.Now()
+	return &InvoiceBuilder{
+		invoice: Invoice{
+			InvoiceBase: InvoiceBase{
+				IssueDate:    now,
+				ConceptType:  ConceptTypeProducts,
+				CurrencyType: CurrencyTypePES,
+				CurrencyRate: 1,
+			},
+		},
+	}
+}
+
+// WithType configura el tipo de comprobante
+func (b *InvoiceBuilder) WithType(invoiceType InvoiceType) *InvoiceBuilder {
+	b.invoice.InvoiceType = invoiceType
+	return b
+}
+
+// WithPointOfSale configura el punto de venta
+func (b *InvoiceBuilder) WithPointOfSale(pointOfSale int) *InvoiceBuilder {
+	b.invoice.PointOfSale = pointOfSale
+	return b
+}
+
+// WithInvoiceNumber configura el número de comprobante
+func (b *InvoiceBuilder) WithInvoiceNumber(invoiceNumber int) *InvoiceBuilder {
+	b.invoice.InvoiceNumber = invoiceNumber
+	return b
+}
+
+// WithConcept configura el tipo de concepto
+func (b *InvoiceBuilder) WithConcept(conceptType ConceptType) *InvoiceBuilder {
+	b.invoice.ConceptType = conceptType
+	return b
+}
+
+// WithDate configura la fecha del comprobante
+func (b *InvoiceBuilder) WithDate(date time.Time) *InvoiceBuilder {
+	b.invoice.IssueDate = date
+	return b
+}
+
+// WithServicePeriod configura el período del servicio facturado, para los
+// conceptos servicios y mixto
+func (b *InvoiceBuilder) WithServicePeriod(from, to time.Time) *InvoiceBuilder {
+	b.invoice.ServiceFrom = from
+	b.invoice.ServiceTo = to
+	return b
+}
+
+// WithPaymentDueDate configura la fecha de vencimiento de pago, obligatoria
+// para los conceptos servicios y mixto
+func (b *InvoiceBuilder) WithPaymentDueDate(date time.Time) *InvoiceBuilder {
+	b.invoice.PaymentDueDate = date
+	return b
+}
+
+// WithCurrency configura la moneda y su cotización
+func (b *InvoiceBuilder) WithCurrency(currency CurrencyType, rate float64) *InvoiceBuilder {
+	b.invoice.CurrencyType = currency
+	b.invoice.CurrencyRate = rate
+	return b
+}
+
+// WithCustomer configura el documento del receptor
+func (b *InvoiceBuilder) WithCustomer(docType DocumentType, docNumber string) *InvoiceBuilder {
+	b.invoice.DocType = docType
+	b.invoice.DocNumber = docNumber
+	return b
+}
+
+// WithIssuer configura el documento y nombre del emisor
+func (b *InvoiceBuilder) WithIssuer(docType DocumentType, docNumber, name string) *InvoiceBuilder {
+	b.invoice.DocTypeFrom = docType
+	b.invoice.DocNumberFrom = docNumber
+	b.invoice.NameFrom = name
+	return b
+}
+
+// WithNotes configura las observaciones del comprobante
+func (b *InvoiceBuilder) WithNotes(notes string) *InvoiceBuilder {
+	b.invoice.Notes = notes
+	return b
+}
+
+// WithAssociatedInvoice agrega un comprobante asociado, obligatorio en las
+// notas de débito y crédito
+func (b *InvoiceBuilder) WithAssociatedInvoice(invoiceType InvoiceType, pointOfSale, invoiceNumber int) *InvoiceBuilder {
+	b.invoice.AssociatedInvoices = append(b.invoice.AssociatedInvoices, AssociatedInvoice{
+		InvoiceType:   invoiceType,
+		PointOfSale:   pointOfSale,
+		InvoiceNumber: invoiceNumber,
+	})
+	return b
+}
+
+// WithAssociatedPeriod configura el período que ajusta la nota de débito o
+// crédito, en lugar de un comprobante asociado
+func (b *InvoiceBuilder) WithAssociatedPeriod(from, to time.Time) *InvoiceBuilder {
+	b.invoice.AssociatedPeriod = &AssociatedPeriod{From: from, To: to}
+	return b
+}
+
+// PricesIncludeTax indica si los precios de AddItem incluyen IVA, como los
+// precios al público. En ese caso AddTax con IVA descompone el total del ítem
+// en neto gravado e IVA, de modo que ambos sumen exactamente el precio final.
+// Debe llamarse antes de agregar ítems.
+func (b *InvoiceBuilder) PricesIncludeTax(enabled bool) *InvoiceBuilder {
+	if len(b.invoice.Items) > 0 {
+		b.errors.Add("items", "PricesIncludeTax debe llamarse antes de agregar ítems", enabled)
+		return b
+	}
+	b.pricesIncludeTax = enabled
+	return b
+}
+
+// AddItem agrega un ítem; el total se calcula como cantidad * precio unitario
+func (b *InvoiceBuilder) AddItem(description string, quantity, unitPrice float64) *InvoiceBuilder {
+	if b.pricesIncludeTax {
+		b.grossTotal += round2(quantity * unitPrice)
+	}
+	b.invoice.Items = append(b.invoice.Items, Item{
+		Description: description,
+		Quantity:    quantity,
+		UnitPrice:   unitPrice,
+		TotalPrice:  round2(quantity * unitPrice),
+	})
+	return b
+}
+
+// AddTax agrega un impuesto calculado sobre el total del último ítem agregado.
+// El IVA queda en el ítem; los demás impuestos se informan como tributos.
+func (b *InvoiceBuilder) AddTax(taxType TaxType, rate TaxRate) *InvoiceBuilder {
+	if len(b.invoice.Items) == 0 {
+		b.errors.Add("taxes", "AddTax requiere haber agregado un ítem", rate)
+		return b
+	}
+
+	item := &b.invoice.Items[len(b.invoice.Items)-1]
+	if taxType == TaxTypeIVA && b.pricesIncludeTax {
+		b.addIncludedIVA(item, rate)
+		return b
+	}
+
+	tax := Tax{
+		Type:   taxType,
+		Rate:   rate,
+		Base:   item.TotalPrice,
+		Amount: round2(item.TotalPrice * rate.Percent() / 100),
+	}
+
+	if taxType == TaxTypeIVA {
+		item.Taxes = append(item.Taxes, tax)
+	} else {
+		b.invoice.Taxes = append(b.invoice.Taxes, tax)
+	}
+	return b
+}
+
+// addIncludedIVA descompone el precio final del ítem en neto gravado e IVA.
+// El IVA se calcula sobre el neto, como lo valida AFIP, y el neto se ajusta
+// en un centavo cuando hace falta para que neto más IVA reconcilie con el
+// precio final. Si ningún ajuste reconcilia, queda el neto más cercano y la
+// diferencia de un centavo se controla en Build.
+func (b *InvoiceBuilder) addIncludedIVA(item *Item, rate TaxRate) {
+	for _, tax := range item.Taxes {
+		if tax.Type == TaxTypeIVA {
+			b.errors.Add("taxes", "Con precios con IVA incluido cada ítem admite una sola alícuota de IVA", rate)
+			return
+		}
+	}
+
+	gross := item.TotalPrice
+	net := round2(gross / (1 + rate.Percent()/100))
+	for _, candidate := range []float64{net, round2(net - 0.01), round2(net + 0.01)} {
+		if round2(candidate+round2(candidate*rate.Percent()/100)) == gross {
+			net = candidate
+			break
+		}
+	}
+	item.TotalPrice = net
+	if item.Quantity > 0 {
+		item.UnitPrice = net / item.Quantity
+	}
+	item.Taxes = append(item.Taxes, Tax{
+		Type:   TaxTypeIVA,
+		Rate:   rate,
+		Base:   net,
+		Amount: round2(net * rate.Percent() / 100),
+	})
+}
+
+// AddTribute agrega un tributo (impuesto distinto de IVA) a nivel comprobante
+func (b *InvoiceBuilder) AddTribute(taxType TaxType, base, amount float64) *InvoiceBuilder {
+	if taxType == TaxTypeIVA {
+		b.errors.Add("taxes", "El IVA se informa por ítem con AddTax", taxType)
+		return b
+	}
+
+	b.invoice.Taxes = append(b.invoice.Taxes, Tax{
+		Type:   taxType,
+		Base:   base,
+		Amount: round2(amount),
+	})
+	return b
+}
+
+// Build calcula los importes y retorna el comprobante, o los errores de
+// validación encontrados al armarlo
+func (b *InvoiceBuilder) Build() (*Invoice, error) {
+	errors := append(ValidationErrors{}, b.errors...)
+	invoice := b.invoice
+
+	if invoice.InvoiceType <= 0 {
+		errors.Add("invoice_type", "Tipo de comprobante obligatorio", invoice.InvoiceType)
+	}
+	if invoice.PointOfSale <= 0 || invoice.PointOfSale > 9999 {
+		errors.Add("point_of_sale", "Punto de venta debe estar entre 1 y 9999", invoice.PointOfSale)
+	}
+	if len(invoice.Items) == 0 {
+		errors.Add("items", "La factura debe tener al menos un ítem", nil)
+	}
+	if invoice.ConceptType != ConceptTypeProducts {
+		switch {
+		case invoice.PaymentDueDate.IsZero():
+			errors.Add("payment_due_date", "Fecha de vencimiento de pago obligatoria para los conceptos servicios y mixto", nil)
+		case invoice.PaymentDueDate.Before(startOfDay(invoice.GetIssueDate())):
+			errors.Add("payment_due_date", "La fecha de vencimiento de pago no puede ser anterior a la fecha del comprobante", invoice.PaymentDueDate)
+		}
+		if !invoice.ServiceTo.IsZero() && invoice.ServiceTo.Before(invoice.ServiceFrom) {
+			errors.Add("service_to", "La fecha de fin de servicio no puede ser anterior a la de inicio", invoice.ServiceTo)
+		}
+	}
+	if invoice.InvoiceType.RequiresAssociatedInvoice() && len(invoice.AssociatedInvoices) == 0 && invoice.AssociatedPeriod == nil {
+		errors.Add("associated_invoices", fmt.Sprintf("%s requiere informar el comprobante o el período asociado", invoice.InvoiceType), nil)
+	}
+	validateAssociatedPeriod(invoice.AssociatedInvoices, invoice.AssociatedPeriod, &errors)
+
+	var amount, nonTaxable, exempt, taxAmount, tributes float64
+	for i, item := range invoice.Items {
+		if item.Description == "" {
+			errors.Add(fmt.Sprintf("items[%d].description", i), "Descripción del ítem no puede estar vacía", item.Description)
+		}
+		if item.Quantity <= 0 {
+			errors.Add(fmt.Sprintf("items[%d].quantity", i), "Cantidad debe ser mayor a 0", item.Quantity)
+		}
+		if item.UnitPrice < 0 {
+			errors.Add(fmt.Sprintf("items[%d].unit_price", i), "Precio unitario no puede ser negativo", item.UnitPrice)
+		}
+
+		switch rate, untaxed := untaxedRate(item); {
+		case !untaxed:
+			amount += item.TotalPrice
+		case rate == TaxRateExempt:
+			exempt += item.TotalPrice
+		default:
+			nonTaxable += item.TotalPrice
+		}
+		for _, tax := range item.Taxes {
+			taxAmount += tax.Amount
+		}
+	}
+	for _, tax := range invoice.Taxes {
+		tributes += tax.Amount
+	}
+
+	// Los comprobantes C no discriminan IVA
+	if invoice.InvoiceType.IsClassC() && taxAmount != 0 {
+		errors.Add("taxes", fmt.Sprintf("%s no discrimina IVA: no use AddTax con IVA", invoice.InvoiceType), taxAmount)
+	}
+
+	// Con precios con IVA incluido, neto, no gravado y exento más IVA deben
+	// reconstruir los precios finales, con a lo sumo un centavo de diferencia
+	// de redondeo por ítem
+	tolerance := taxInclusiveTolerance*float64(len(invoice.Items)) + 1e-9
+	if net := amount + nonTaxable + exempt; b.pricesIncludeTax && math.Abs(net+taxAmount-b.grossTotal) > tolerance {
+		errors.Add("total_amount", fmt.Sprintf("Neto más IVA (%.2f) no coincide con los precios finales (%.2f)", net+taxAmount, b.grossTotal), net+taxAmount)
+	}
+
+	if errors.HasErrors() {
+		return nil, errors
+	}
+
+	invoice.Amount = round2(amount)
+	invoice.NonTaxableAmount = round2(nonTaxable)
+	invoice.ExemptAmount = round2(exempt)
+	invoice.TaxAmount = round2(taxAmount)
+	invoice.TotalAmount = round2(amount + nonTaxable + exempt + taxAmount + tributes)
+
+	return &invoice, nil
+}
+
+// untaxedRate retorna la alícuota de IVA del ítem y true si es no gravada
+// (0%) o exenta
+func untaxedRate(item Item) (TaxRate, bool) {
+	for _, tax := range item.Taxes {
+		if tax.Type == TaxTypeIVA && (tax.Rate == TaxRate0 || tax.Rate == TaxRateExempt) {
+			return tax.Rate, true
+		}
+	}
+	return 0, false
+}
+
+// Percent retorna la alícuota como porcentaje (TaxRate105 -> 10.5)
+func (r TaxRate) Percent() float64 {
+	switch r {
+	case TaxRate105:
+		return 10.5
+	case TaxRate25:
+		return 2.5
+	case TaxRateExempt:
+		return 0
+	default:
+		return float64(r)
+	}
+}
+
+// startOfDay retorna el comienzo del día de t, en su misma zona horaria
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// round2 redondea un importe a dos decimales
+func round2(value float64) float64 {
+	return math.Round(value*100) / 100
+}
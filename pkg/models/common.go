@@ -1,6 +1,10 @@
 package models
 
 import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -26,6 +30,11 @@ const (
 	DocumentTypePAS  DocumentType = 17
 	DocumentTypeDE   DocumentType = 18
 	DocumentTypeDI   DocumentType = 19
+
+	// DocumentTypeConsumidorFinal identifica a un consumidor final anónimo
+	// (DocTipo 99). Se informa con número de documento "0" y sólo en
+	// comprobantes B y C.
+	DocumentTypeConsumidorFinal DocumentType = 99
 )
 
 // ConceptType representa los tipos de concepto
@@ -37,6 +46,29 @@ const (
 	ConceptTypeMixed    ConceptType = 3
 )
 
+// ExportType representa los tipos de exportación de WSFEX (Tipo_expo)
+type ExportType int
+
+const (
+	ExportTypeGoods    ExportType = 1
+	ExportTypeServices ExportType = 2
+	ExportTypeOther    ExportType = 4
+)
+
+// ExportTypeForConcept retorna el tipo de exportación que corresponde al
+// concepto: bienes para productos, servicios para servicios y otros para
+// mixto
+func ExportTypeForConcept(conceptType ConceptType) ExportType {
+	switch conceptType {
+	case ConceptTypeProducts:
+		return ExportTypeGoods
+	case ConceptTypeServices:
+		return ExportTypeServices
+	default:
+		return ExportTypeOther
+	}
+}
+
 // InvoiceType representa los tipos de comprobante
 type InvoiceType int
 
@@ -48,25 +80,252 @@ const (
 	InvoiceTypeM InvoiceType = 51
 	InvoiceTypeT InvoiceType = 60
 	InvoiceTypeR InvoiceType = 63
+
+	// Notas de débito y crédito
+	InvoiceTypeDebitNoteA  InvoiceType = 2
+	InvoiceTypeCreditNoteA InvoiceType = 3
+	InvoiceTypeDebitNoteB  InvoiceType = 7
+	InvoiceTypeCreditNoteB InvoiceType = 8
+	InvoiceTypeDebitNoteC  InvoiceType = 12
+	InvoiceTypeCreditNoteC InvoiceType = 13
+	InvoiceTypeDebitNoteE  InvoiceType = 20
+	InvoiceTypeCreditNoteE InvoiceType = 21
+	InvoiceTypeDebitNoteM  InvoiceType = 52
+	InvoiceTypeCreditNoteM InvoiceType = 53
+
+	// Factura de Crédito Electrónica MiPyME (FCE)
+	InvoiceTypeFCEA           InvoiceType = 201
+	InvoiceTypeFCEDebitNoteA  InvoiceType = 202
+	InvoiceTypeFCECreditNoteA InvoiceType = 203
+	InvoiceTypeFCEB           InvoiceType = 206
+	InvoiceTypeFCEDebitNoteB  InvoiceType = 207
+	InvoiceTypeFCECreditNoteB InvoiceType = 208
+	InvoiceTypeFCEC           InvoiceType = 211
+	InvoiceTypeFCEDebitNoteC  InvoiceType = 212
+	InvoiceTypeFCECreditNoteC InvoiceType = 213
 )
 
-// CurrencyType representa los tipos de moneda
+// invoiceTypeNames mapea los tipos de comprobante a su descripción
+var invoiceTypeNames = map[InvoiceType]string{
+	InvoiceTypeA: "Factura A",
+	InvoiceTypeB: "Factura B",
+	InvoiceTypeC: "Factura C",
+	InvoiceTypeE: "Factura E",
+	InvoiceTypeM: "Factura M",
+	InvoiceTypeT: "Factura T",
+	InvoiceTypeR: "Factura R",
+
+	InvoiceTypeDebitNoteA:  "Nota de Débito A",
+	InvoiceTypeCreditNoteA: "Nota de Crédito A",
+	InvoiceTypeDebitNoteB:  "Nota de Débito B",
+	InvoiceTypeCreditNoteB: "Nota de Crédito B",
+	InvoiceTypeDebitNoteC:  "Nota de Débito C",
+	InvoiceTypeCreditNoteC: "Nota de Crédito C",
+	InvoiceTypeDebitNoteE:  "Nota de Débito E",
+	InvoiceTypeCreditNoteE: "Nota de Crédito E",
+	InvoiceTypeDebitNoteM:  "Nota de Débito M",
+	InvoiceTypeCreditNoteM: "Nota de Crédito M",
+
+	InvoiceTypeFCEA:           "Factura de Crédito Electrónica MiPyME A",
+	InvoiceTypeFCEDebitNoteA:  "Nota de Débito Electrónica MiPyME A",
+	InvoiceTypeFCECreditNoteA: "Nota de Crédito Electrónica MiPyME A",
+	InvoiceTypeFCEB:           "Factura de Crédito Electrónica MiPyME B",
+	InvoiceTypeFCEDebitNoteB:  "Nota de Débito Electrónica MiPyME B",
+	InvoiceTypeFCECreditNoteB: "Nota de Crédito Electrónica MiPyME B",
+	InvoiceTypeFCEC:           "Factura de Crédito Electrónica MiPyME C",
+	InvoiceTypeFCEDebitNoteC:  "Nota de Débito Electrónica MiPyME C",
+	InvoiceTypeFCECreditNoteC: "Nota de Crédito Electrónica MiPyME C",
+}
+
+// String implementa fmt.Stringer
+func (t InvoiceType) String() string {
+	if name, ok := invoiceTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("InvoiceType(%d)", int(t))
+}
+
+// KnownInvoiceTypes retorna los tipos de comprobante conocidos por la
+// librería, ordenados por código. Es un catálogo local: la lista vigente en
+// AFIP se obtiene con wsfe.Service.GetInvoiceTypes.
+func KnownInvoiceTypes() []InvoiceTypeInfo {
+	types := make([]InvoiceTypeInfo, 0, len(invoiceTypeNames))
+	for id, name := range invoiceTypeNames {
+		types = append(types, InvoiceTypeInfo{ID: id, Description: name, Active: true})
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i].ID < types[j].ID })
+	return types
+}
+
+// IsFCE indica si el tipo de comprobante es de Factura de Crédito Electrónica MiPyME
+func (t InvoiceType) IsFCE() bool {
+	switch t {
+	case InvoiceTypeFCEA, InvoiceTypeFCEDebitNoteA, InvoiceTypeFCECreditNoteA,
+		InvoiceTypeFCEB, InvoiceTypeFCEDebitNoteB, InvoiceTypeFCECreditNoteB,
+		InvoiceTypeFCEC, InvoiceTypeFCEDebitNoteC, InvoiceTypeFCECreditNoteC:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsClassC indica si el comprobante es clase C, emitido por monotributistas y
+// exentos. Los comprobantes C no discriminan IVA: ImpIVA es 0 y no llevan
+// alícuotas.
+func (t InvoiceType) IsClassC() bool {
+	return t.LetterClass() == "C"
+}
+
+// IsCreditNote indica si el comprobante es una nota de crédito
+func (t InvoiceType) IsCreditNote() bool {
+	switch t {
+	case InvoiceTypeCreditNoteA, InvoiceTypeCreditNoteB, InvoiceTypeCreditNoteC,
+		InvoiceTypeCreditNoteE, InvoiceTypeCreditNoteM,
+		InvoiceTypeFCECreditNoteA, InvoiceTypeFCECreditNoteB, InvoiceTypeFCECreditNoteC:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsDebitNote indica si el comprobante es una nota de débito
+func (t InvoiceType) IsDebitNote() bool {
+	switch t {
+	case InvoiceTypeDebitNoteA, InvoiceTypeDebitNoteB, InvoiceTypeDebitNoteC,
+		InvoiceTypeDebitNoteE, InvoiceTypeDebitNoteM,
+		InvoiceTypeFCEDebitNoteA, InvoiceTypeFCEDebitNoteB, InvoiceTypeFCEDebitNoteC:
+		return true
+	default:
+		return false
+	}
+}
+
+// CreditNoteType retorna la nota de crédito de la misma letra (y FCE MiPyME
+// si corresponde) que anula el comprobante. Retorna false si el tipo ya es
+// una nota de crédito o no tiene nota de crédito asociada.
+func (t InvoiceType) CreditNoteType() (InvoiceType, bool) {
+	if t.IsCreditNote() {
+		return 0, false
+	}
+
+	if t.IsFCE() {
+		switch t.LetterClass() {
+		case "A":
+			return InvoiceTypeFCECreditNoteA, true
+		case "B":
+			return InvoiceTypeFCECreditNoteB, true
+		case "C":
+			return InvoiceTypeFCECreditNoteC, true
+		}
+		return 0, false
+	}
+
+	switch t.LetterClass() {
+	case "A":
+		return InvoiceTypeCreditNoteA, true
+	case "B":
+		return InvoiceTypeCreditNoteB, true
+	case "C":
+		return InvoiceTypeCreditNoteC, true
+	case "E":
+		return InvoiceTypeCreditNoteE, true
+	case "M":
+		return InvoiceTypeCreditNoteM, true
+	default:
+		return 0, false
+	}
+}
+
+// RequiresAssociatedInvoice indica si el comprobante debe informar los
+// comprobantes que ajusta (CbtesAsoc / Cmps_asoc): las notas de débito y
+// crédito, incluidas las FCE MiPyME
+func (t InvoiceType) RequiresAssociatedInvoice() bool {
+	return t.IsCreditNote() || t.IsDebitNote()
+}
+
+// LetterClass retorna la letra del comprobante (A, B, C, E o M), o una cadena
+// vacía para los tipos sin letra propia como T y R
+func (t InvoiceType) LetterClass() string {
+	switch t {
+	case InvoiceTypeA, InvoiceTypeDebitNoteA, InvoiceTypeCreditNoteA,
+		InvoiceTypeFCEA, InvoiceTypeFCEDebitNoteA, InvoiceTypeFCECreditNoteA:
+		return "A"
+	case InvoiceTypeB, InvoiceTypeDebitNoteB, InvoiceTypeCreditNoteB,
+		InvoiceTypeFCEB, InvoiceTypeFCEDebitNoteB, InvoiceTypeFCECreditNoteB:
+		return "B"
+	case InvoiceTypeC, InvoiceTypeDebitNoteC, InvoiceTypeCreditNoteC,
+		InvoiceTypeFCEC, InvoiceTypeFCEDebitNoteC, InvoiceTypeFCECreditNoteC:
+		return "C"
+	case InvoiceTypeE, InvoiceTypeDebitNoteE, InvoiceTypeCreditNoteE:
+		return "E"
+	case InvoiceTypeM, InvoiceTypeDebitNoteM, InvoiceTypeCreditNoteM:
+		return "M"
+	default:
+		return ""
+	}
+}
+
+// isFCEInvoice indica si el tipo es una factura FCE (no una nota de débito/crédito)
+func (t InvoiceType) isFCEInvoice() bool {
+	return t == InvoiceTypeFCEA || t == InvoiceTypeFCEB || t == InvoiceTypeFCEC
+}
+
+// FormatInvoiceNumber formatea un comprobante como PPPP-NNNNNNNN
+func FormatInvoiceNumber(pointOfSale, invoiceNumber int) string {
+	return fmt.Sprintf("%04d-%08d", pointOfSale, invoiceNumber)
+}
+
+// NormalizeCUIT retorna el CUIT en el formato de 11 dígitos que esperan los
+// web services de AFIP. Acepta el CUIT con o sin guiones.
+func NormalizeCUIT(cuit string) (string, error) {
+	digits := strings.NewReplacer("-", "", " ", "", ".", "").Replace(strings.TrimSpace(cuit))
+	if len(digits) != 11 {
+		return "", NewValidationError("cuit", "CUIT debe tener 11 dígitos", cuit)
+	}
+	for _, r := range digits {
+		if r < '0' || r > '9' {
+			return "", NewValidationError("cuit", "CUIT debe contener solo dígitos", cuit)
+		}
+	}
+	return digits, nil
+}
+
+// FormatCUIT formatea un CUIT de 11 dígitos como XX-XXXXXXXX-X. Si el valor
+// no tiene 11 dígitos se retorna sin cambios.
+func FormatCUIT(digits string) string {
+	normalized, err := NormalizeCUIT(digits)
+	if err != nil {
+		return digits
+	}
+	return normalized[:2] + "-" + normalized[2:10] + "-" + normalized[10:]
+}
+
+// CurrencyType es el código de moneda de AFIP (MonId), de 3 caracteres. Las
+// constantes cubren las monedas más usadas; el resto de los códigos se
+// obtiene con FEParamGetTiposMonedas (ver wsfe.Service.GetCurrencyTypes).
 type CurrencyType string
 
 const (
 	CurrencyTypePES CurrencyType = "PES" // Peso Argentino
-	CurrencyTypeUSD CurrencyType = "USD" // Dólar Estadounidense
-	CurrencyTypeEUR CurrencyType = "EUR" // Euro
-	CurrencyTypeBRL CurrencyType = "BRL" // Real Brasileño
+	CurrencyTypeUSD CurrencyType = "DOL" // Dólar Estadounidense
+	CurrencyTypeEUR CurrencyType = "060" // Euro
+	CurrencyTypeBRL CurrencyType = "012" // Real Brasileño
+	CurrencyTypeGBP CurrencyType = "021" // Libra Esterlina
+	CurrencyTypeJPY CurrencyType = "019" // Yen
 )
 
+// String implementa fmt.Stringer
+func (c CurrencyType) String() string {
+	return string(c)
+}
+
 // TaxType representa los tipos de impuesto
 type TaxType int
 
 const (
 	TaxTypeIVA TaxType = 1
-	TaxTypeII  TaxType = 2
-	TaxTypeIO  TaxType = 3
+	TaxTypeII  TaxType = 2 // Impuestos internos
+	TaxTypeIO  TaxType = 3 // Otros tributos
 )
 
 // TaxRate representa las alícuotas de IVA
@@ -130,12 +389,108 @@ type Item struct {
 	Taxes       []Tax   `json:"taxes,omitempty" xml:"taxes,omitempty"`
 }
 
+// Optional representa un dato opcional del comprobante (Opcionales)
+type Optional struct {
+	ID    string `json:"id" xml:"id"`
+	Value string `json:"value" xml:"value"`
+}
+
+// IDs de datos opcionales usados por FCE MiPyME
+const (
+	OptionalIDFCECBU          = "2101"
+	OptionalIDFCEAlias        = "2102"
+	OptionalIDFCECancellation = "22"
+	OptionalIDFCETransfer     = "27"
+)
+
+// Modalidades de transferencia de FCE MiPyME
+const (
+	FCETransferSCA = "SCA" // Sistema de Circulación Abierta
+	FCETransferADC = "ADC" // Agente de Depósito Colectivo
+)
+
+// FCEData representa los datos propios de una Factura de Crédito Electrónica MiPyME
+type FCEData struct {
+	CBU          string `json:"cbu,omitempty" xml:"cbu,omitempty"`
+	Alias        string `json:"alias,omitempty" xml:"alias,omitempty"`
+	TransferType string `json:"transfer_type,omitempty" xml:"transfer_type,omitempty"`
+	Cancelled    bool   `json:"cancelled,omitempty" xml:"cancelled,omitempty"`
+}
+
+// Optionals retorna los datos opcionales que AFIP exige para el tipo FCE dado.
+// Las facturas informan CBU, alias y modalidad de transferencia; las notas de
+// débito/crédito informan si anulan el comprobante asociado.
+func (f *FCEData) Optionals(invoiceType InvoiceType) []Optional {
+	if !invoiceType.isFCEInvoice() {
+		cancelled := "N"
+		if f.Cancelled {
+			cancelled = "S"
+		}
+		return []Optional{{ID: OptionalIDFCECancellation, Value: cancelled}}
+	}
+
+	optionals := []Optional{{ID: OptionalIDFCECBU, Value: f.CBU}}
+	if f.Alias != "" {
+		optionals = append(optionals, Optional{ID: OptionalIDFCEAlias, Value: f.Alias})
+	}
+	optionals = append(optionals, Optional{ID: OptionalIDFCETransfer, Value: f.TransferType})
+	return optionals
+}
+
+// IDs de datos opcionales del régimen de bienes usados (RG 3411)
+const (
+	OptionalIDUsedGoodsSellerName        = "91"
+	OptionalIDUsedGoodsSellerNationality = "92"
+	OptionalIDUsedGoodsSellerAddress     = "93"
+)
+
+// UsedGoodsSeller representa al vendedor de un bien usado (persona no
+// inscripta) que el régimen de bienes usados exige informar como opcionales
+type UsedGoodsSeller struct {
+	Name        string `json:"name" xml:"name"`
+	Nationality string `json:"nationality" xml:"nationality"`
+	Address     string `json:"address" xml:"address"`
+}
+
+// ExportPermit representa un permiso de embarque de una exportación de bienes
+type ExportPermit struct {
+	ID                 string `json:"id" xml:"id"`
+	DestinationCountry string `json:"destination_country" xml:"destination_country"`
+}
+
+// ExportBuyer representa a uno de los compradores entre los que se reparte
+// una exportación (Compradores), con su porcentaje de participación. Los
+// porcentajes de todos los compradores deben sumar 100.
+type ExportBuyer struct {
+	DocType    DocumentType `json:"doc_type" xml:"doc_type"`
+	DocNumber  string       `json:"doc_number" xml:"doc_number"`
+	Percentage float64      `json:"percentage" xml:"percentage"`
+}
+
+// AssociatedInvoice representa un comprobante asociado (ej. la factura que
+// corrige una nota de crédito o débito)
+type AssociatedInvoice struct {
+	InvoiceType   InvoiceType `json:"invoice_type" xml:"invoice_type"`
+	PointOfSale   int         `json:"point_of_sale" xml:"point_of_sale"`
+	InvoiceNumber int         `json:"invoice_number" xml:"invoice_number"`
+	CUIT          string      `json:"cuit,omitempty" xml:"cuit,omitempty"`
+}
+
+// AssociatedPeriod representa el período que ajusta una nota de débito o
+// crédito (PeriodoAsoc), en lugar de comprobantes asociados puntuales. Lo
+// usan, por ejemplo, los servicios de facturación periódica.
+type AssociatedPeriod struct {
+	From time.Time `json:"from" xml:"from"`
+	To   time.Time `json:"to" xml:"to"`
+}
+
 // InvoiceBase representa los campos base de una factura
 type InvoiceBase struct {
 	BaseEntity
 	InvoiceType   InvoiceType  `json:"invoice_type" xml:"invoice_type"`
 	PointOfSale   int          `json:"point_of_sale" xml:"point_of_sale"`
 	InvoiceNumber int          `json:"invoice_number,omitempty" xml:"invoice_number,omitempty"`
+	IssueDate     time.Time    `json:"issue_date,omitempty" xml:"issue_date,omitempty"`
 	DateFrom      time.Time    `json:"date_from" xml:"date_from"`
 	DateTo        time.Time    `json:"date_to" xml:"date_to"`
 	ConceptType   ConceptType  `json:"concept_type" xml:"concept_type"`
@@ -146,9 +501,54 @@ type InvoiceBase struct {
 	TotalAmount   float64      `json:"total_amount" xml:"total_amount"`
 	Items         []Item       `json:"items" xml:"items"`
 	Taxes         []Tax        `json:"taxes,omitempty" xml:"taxes,omitempty"`
+	Optionals     []Optional   `json:"optionals,omitempty" xml:"optionals,omitempty"`
 	Notes         string       `json:"notes,omitempty" xml:"notes,omitempty"`
 }
 
+// GetIssueDate retorna la fecha del comprobante (CbteFch en WSFE):
+// IssueDate o, si no se informó, DateFrom, que versiones anteriores usaban
+// como fecha del comprobante
+func (b *InvoiceBase) GetIssueDate() time.Time {
+	if b.IssueDate.IsZero() {
+		return b.DateFrom
+	}
+	return b.IssueDate
+}
+
+// AuthResult representa el resultado (Resultado) que AFIP informa para una
+// autorización
+type AuthResult string
+
+const (
+	AuthResultApproved AuthResult = "A" // Aprobado
+	AuthResultRejected AuthResult = "R" // Rechazado
+	AuthResultPartial  AuthResult = "P" // Parcial (algunos comprobantes del lote rechazados)
+)
+
+// ParseAuthResult convierte el código de AFIP ("A", "R" o "P") en un
+// AuthResult. Ante un código desconocido retorna error junto con el código
+// tal como vino, para no perder el dato.
+func ParseAuthResult(code string) (AuthResult, error) {
+	result := AuthResult(strings.ToUpper(strings.TrimSpace(code)))
+	switch result {
+	case AuthResultApproved, AuthResultRejected, AuthResultPartial:
+		return result, nil
+	default:
+		return result, fmt.Errorf("resultado de autorización desconocido: %q", code)
+	}
+}
+
+// IsApproved indica si AFIP aprobó la autorización. Un resultado parcial no
+// se considera aprobado.
+func (r AuthResult) IsApproved() bool {
+	return r == AuthResultApproved
+}
+
+// String implementa fmt.Stringer
+func (r AuthResult) String() string {
+	return string(r)
+}
+
 // AuthorizationResult representa el resultado de una autorización
 type AuthorizationResult struct {
 	CAE               string      `json:"cae" xml:"cae"`
@@ -157,10 +557,90 @@ type AuthorizationResult struct {
 	PointOfSale       int         `json:"point_of_sale" xml:"point_of_sale"`
 	InvoiceType       InvoiceType `json:"invoice_type" xml:"invoice_type"`
 	AuthorizationDate time.Time   `json:"authorization_date" xml:"authorization_date"`
-	Status            string      `json:"status" xml:"status"`
+	Status            AuthResult  `json:"status" xml:"status"`
 	Message           string      `json:"message,omitempty" xml:"message,omitempty"`
 }
 
+// IsEmpty indica que el resultado no corresponde a ningún comprobante: es lo
+// que retorna wsfe.Service.GetLastAuthorizedInvoice (número 0 y sin Status)
+// cuando el punto de venta todavía no autorizó comprobantes del tipo
+func (r *AuthorizationResult) IsEmpty() bool {
+	return r.InvoiceNumber == 0
+}
+
+// Summary retorna un resumen legible del resultado para CLI o logs
+func (r *AuthorizationResult) Summary() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s %s - Estado: %s", r.InvoiceType, FormatInvoiceNumber(r.PointOfSale, r.InvoiceNumber), r.Status)
+
+	if r.CAE != "" {
+		fmt.Fprintf(&b, "\nCAE: %s", r.CAE)
+		if !r.CAEExpirationDate.IsZero() {
+			fmt.Fprintf(&b, " (vence %s)", r.CAEExpirationDate.Format("2006-01-02"))
+		}
+	}
+
+	if r.Message != "" {
+		fmt.Fprintf(&b, "\nObservaciones: %s", r.Message)
+	}
+
+	return b.String()
+}
+
+// String implementa fmt.Stringer con un resumen de una línea para logs
+func (r AuthorizationResult) String() string {
+	s := fmt.Sprintf("%s %s %s", r.InvoiceType, FormatInvoiceNumber(r.PointOfSale, r.InvoiceNumber), r.Status)
+	if r.CAE != "" {
+		s += " CAE " + r.CAE
+		if !r.CAEExpirationDate.IsZero() {
+			s += " vence " + r.CAEExpirationDate.Format("2006-01-02")
+		}
+	}
+	return s
+}
+
+// IsValid indica si el resultado tiene un CAE aprobado y vigente a la fecha
+// actual
+func (r *AuthorizationResult) IsValid() bool {
+	return r.IsValidAt(time.Now())
+}
+
+// IsValidAt es como IsValid pero evalúa la vigencia del CAE en now. El CAE
+// vale hasta el final del día de CAEExpirationDate.
+func (r *AuthorizationResult) IsValidAt(now time.Time) bool {
+	if r.CAE == "" || !r.Status.IsApproved() || r.CAEExpirationDate.IsZero() {
+		return false
+	}
+
+	expiration := r.CAEExpirationDate
+	endOfDay := time.Date(expiration.Year(), expiration.Month(), expiration.Day()+1, 0, 0, 0, 0, expiration.Location())
+	return now.Before(endOfDay)
+}
+
+// MarshalJSON serializa las fechas en RFC3339 sin fracciones de segundo y
+// omite las que no están informadas
+func (r AuthorizationResult) MarshalJSON() ([]byte, error) {
+	type plain AuthorizationResult
+	return json.Marshal(struct {
+		plain
+		CAEExpirationDate string `json:"cae_expiration_date,omitempty"`
+		AuthorizationDate string `json:"authorization_date,omitempty"`
+	}{
+		plain:             plain(r),
+		CAEExpirationDate: formatRFC3339(r.CAEExpirationDate),
+		AuthorizationDate: formatRFC3339(r.AuthorizationDate),
+	})
+}
+
+// formatRFC3339 formatea t en RFC3339, o vacío si t es cero
+func formatRFC3339(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
 // Parameters representa los parámetros del sistema
 type Parameters struct {
 	DocumentTypes []DocumentTypeInfo `json:"document_types" xml:"document_types"`
@@ -168,9 +648,29 @@ type Parameters struct {
 	CurrencyTypes []CurrencyTypeInfo `json:"currency_types" xml:"currency_types"`
 	TaxRates      []TaxRateInfo      `json:"tax_rates" xml:"tax_rates"`
 	ConceptTypes  []ConceptTypeInfo  `json:"concept_types" xml:"concept_types"`
+	Countries     []Destination      `json:"countries,omitempty" xml:"countries,omitempty"`
+	Incoterms     []IncotermInfo     `json:"incoterms,omitempty" xml:"incoterms,omitempty"`
+	PointsOfSale  []PointOfSaleInfo  `json:"points_of_sale,omitempty" xml:"points_of_sale,omitempty"`
 	LastUpdate    time.Time          `json:"last_update" xml:"last_update"`
 }
 
+// ExportParameters representa los catálogos de WSFEX que necesita un
+// formulario de factura de exportación. Extiende Parameters con los tipos de
+// exportación y las unidades de medida; ConceptTypes queda vacío, ya que los
+// tipos de exportación (por ejemplo 4, Otros) no son conceptos de WSFE.
+type ExportParameters struct {
+	Parameters
+	ExportTypes  []ExportTypeInfo `json:"export_types" xml:"export_types"`
+	UnitMeasures []UnitType       `json:"unit_measures" xml:"unit_measures"`
+}
+
+// ExportTypeInfo representa información de un tipo de exportación
+type ExportTypeInfo struct {
+	ID          ExportType `json:"id" xml:"id"`
+	Description string     `json:"description" xml:"description"`
+	Active      bool       `json:"active" xml:"active"`
+}
+
 // DocumentTypeInfo representa información de un tipo de documento
 type DocumentTypeInfo struct {
 	ID          DocumentType `json:"id" xml:"id"`
@@ -205,3 +705,85 @@ type ConceptTypeInfo struct {
 	Description string      `json:"description" xml:"description"`
 	Active      bool        `json:"active" xml:"active"`
 }
+
+// PointOfSaleInfo representa un punto de venta habilitado para web services
+// (FEParamGetPtosVenta). EmissionType es el régimen de emisión informado por
+// AFIP (ej. "CAE - Ws" o "CAEA - Ws").
+type PointOfSaleInfo struct {
+	Number       int    `json:"number" xml:"number"`
+	EmissionType string `json:"emission_type" xml:"emission_type"`
+	Blocked      bool   `json:"blocked" xml:"blocked"`
+	Active       bool   `json:"active" xml:"active"`
+}
+
+// AllowsCAE indica si el punto de venta puede solicitar CAE
+// (FECAESolicitar). Los puntos de venta "CAEA" sólo informan comprobantes
+// emitidos con CAEA; un régimen vacío o desconocido no se restringe.
+func (p PointOfSaleInfo) AllowsCAE() bool {
+	return !strings.HasPrefix(strings.ToUpper(strings.TrimSpace(p.EmissionType)), "CAEA")
+}
+
+// OptionalTypeInfo representa información de un tipo de dato opcional
+type OptionalTypeInfo struct {
+	ID          string `json:"id" xml:"id"`
+	Description string `json:"description" xml:"description"`
+	Active      bool   `json:"active" xml:"active"`
+}
+
+// Condiciones frente al IVA del receptor (FEParamGetCondicionIvaReceptor)
+const (
+	ReceptorIVAConditionRegistered        = 1  // IVA Responsable Inscripto
+	ReceptorIVAConditionExempt            = 4  // IVA Sujeto Exento
+	ReceptorIVAConditionFinalConsumer     = 5  // Consumidor Final
+	ReceptorIVAConditionMonotributo       = 6  // Responsable Monotributo
+	ReceptorIVAConditionNotCategorized    = 7  // Sujeto No Categorizado
+	ReceptorIVAConditionForeignSupplier   = 8  // Proveedor del Exterior
+	ReceptorIVAConditionForeignCustomer   = 9  // Cliente del Exterior
+	ReceptorIVAConditionReleased          = 10 // IVA Liberado - Ley N° 19.640
+	ReceptorIVAConditionSocialMonotributo = 13 // Monotributista Social
+	ReceptorIVAConditionNotApplicable     = 15 // IVA No Alcanzado
+	ReceptorIVAConditionIndependentWorker = 16 // Monotributo Trabajador Independiente Promovido
+)
+
+// ReceptorIVAConditionInfo representa una condición frente al IVA del
+// receptor y la clase de comprobante (A, B, C, M) en que puede informarse
+type ReceptorIVAConditionInfo struct {
+	ID           int    `json:"id" xml:"id"`
+	Description  string `json:"description" xml:"description"`
+	InvoiceClass string `json:"invoice_class" xml:"invoice_class"`
+}
+
+// ActivityInfo representa una actividad del contribuyente (FEParamGetActividades)
+type ActivityInfo struct {
+	ID          int    `json:"id" xml:"id"`
+	Order       int    `json:"order" xml:"order"`
+	Description string `json:"description" xml:"description"`
+}
+
+// IncotermInfo representa información de un Incoterm de exportación
+type IncotermInfo struct {
+	ID          string `json:"id" xml:"id"`
+	Description string `json:"description" xml:"description"`
+	Active      bool   `json:"active" xml:"active"`
+}
+
+// Estados de vigencia de un certificado (ver CertStatus)
+const (
+	CertStatusOK          = "ok"
+	CertStatusWarning     = "warning"
+	CertStatusExpired     = "expired"
+	CertStatusNotYetValid = "not_yet_valid"
+)
+
+// CertStatus representa la vigencia del certificado configurado. Status es
+// "warning" cuando faltan menos de 15 días para el vencimiento, para rotarlo
+// antes de que WSAA empiece a rechazar la autenticación.
+type CertStatus struct {
+	Subject         string    `json:"subject" xml:"subject"`
+	NotBefore       time.Time `json:"not_before" xml:"not_before"`
+	NotAfter        time.Time `json:"not_after" xml:"not_after"`
+	DaysUntilExpiry int       `json:"days_until_expiry" xml:"days_until_expiry"`
+	Valid           bool      `json:"valid" xml:"valid"`
+	Status          string    `json:"status" xml:"status"`
+	Message         string    `json:"message,omitempty" xml:"message,omitempty"`
+}
@@ -0,0 +1,218 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// strictEnumJSON controla cómo se deserializan los enums desde JSON
+var strictEnumJSON atomic.Bool
+
+// SetStrictEnumJSON activa o desactiva el modo estricto de deserialización de
+// enums. En modo estricto sólo se aceptan códigos conocidos y nombres
+// canónicos exactos; en modo laxo (por defecto) se acepta cualquier código
+// numérico y los nombres se comparan sin distinguir mayúsculas.
+func SetStrictEnumJSON(strict bool) {
+	strictEnumJSON.Store(strict)
+}
+
+// documentTypeNames mapea los tipos de documento a su nombre canónico
+var documentTypeNames = map[DocumentType]string{
+	DocumentTypeDNI:  "DNI",
+	DocumentTypeCUIT: "CUIT",
+	DocumentTypeCUIL: "CUIL",
+	DocumentTypeCDI:  "CDI",
+	DocumentTypeLE:   "LE",
+	DocumentTypeLC:   "LC",
+	DocumentTypeCI:   "CI",
+	DocumentTypePAS:  "PAS",
+	DocumentTypeDE:   "DE",
+	DocumentTypeDI:   "DI",
+
+	DocumentTypeConsumidorFinal: "ConsumidorFinal",
+}
+
+// invoiceTypeCodes mapea los tipos de comprobante a su nombre canónico en JSON
+var invoiceTypeCodes = map[InvoiceType]string{
+	InvoiceTypeA: "FacturaA",
+	InvoiceTypeB: "FacturaB",
+	InvoiceTypeC: "FacturaC",
+	InvoiceTypeE: "FacturaE",
+	InvoiceTypeM: "FacturaM",
+	InvoiceTypeT: "FacturaT",
+	InvoiceTypeR: "FacturaR",
+
+	InvoiceTypeDebitNoteA:  "NotaDebitoA",
+	InvoiceTypeCreditNoteA: "NotaCreditoA",
+	InvoiceTypeDebitNoteB:  "NotaDebitoB",
+	InvoiceTypeCreditNoteB: "NotaCreditoB",
+	InvoiceTypeDebitNoteC:  "NotaDebitoC",
+	InvoiceTypeCreditNoteC: "NotaCreditoC",
+	InvoiceTypeDebitNoteE:  "NotaDebitoE",
+	InvoiceTypeCreditNoteE: "NotaCreditoE",
+	InvoiceTypeDebitNoteM:  "NotaDebitoM",
+	InvoiceTypeCreditNoteM: "NotaCreditoM",
+
+	InvoiceTypeFCEA:           "FCEFacturaA",
+	InvoiceTypeFCEDebitNoteA:  "FCENotaDebitoA",
+	InvoiceTypeFCECreditNoteA: "FCENotaCreditoA",
+	InvoiceTypeFCEB:           "FCEFacturaB",
+	InvoiceTypeFCEDebitNoteB:  "FCENotaDebitoB",
+	InvoiceTypeFCECreditNoteB: "FCENotaCreditoB",
+	InvoiceTypeFCEC:           "FCEFacturaC",
+	InvoiceTypeFCEDebitNoteC:  "FCENotaDebitoC",
+	InvoiceTypeFCECreditNoteC: "FCENotaCreditoC",
+}
+
+// conceptTypeNames mapea los tipos de concepto a su nombre canónico
+var conceptTypeNames = map[ConceptType]string{
+	ConceptTypeProducts: "Productos",
+	ConceptTypeServices: "Servicios",
+	ConceptTypeMixed:    "ProductosYServicios",
+}
+
+// exportTypeNames mapea los tipos de exportación a su nombre canónico
+var exportTypeNames = map[ExportType]string{
+	ExportTypeGoods:    "Bienes",
+	ExportTypeServices: "Servicios",
+	ExportTypeOther:    "Otros",
+}
+
+// taxRateNames mapea las alícuotas a su nombre canónico
+var taxRateNames = map[TaxRate]string{
+	TaxRate0:      "0%",
+	TaxRate105:    "10.5%",
+	TaxRate21:     "21%",
+	TaxRate27:     "27%",
+	TaxRate25:     "2.5%",
+	TaxRate5:      "5%",
+	TaxRateExempt: "Exento",
+}
+
+// String implementa fmt.Stringer
+func (t DocumentType) String() string {
+	if name, ok := documentTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("DocumentType(%d)", int(t))
+}
+
+// MarshalJSON serializa el tipo de documento con su nombre canónico
+func (t DocumentType) MarshalJSON() ([]byte, error) {
+	return marshalEnum(t, documentTypeNames)
+}
+
+// UnmarshalJSON acepta el código numérico o el nombre canónico ("CUIT")
+func (t *DocumentType) UnmarshalJSON(data []byte) error {
+	return unmarshalEnum(data, "DocumentType", documentTypeNames, t)
+}
+
+// MarshalJSON serializa el tipo de comprobante con su nombre canónico
+func (t InvoiceType) MarshalJSON() ([]byte, error) {
+	return marshalEnum(t, invoiceTypeCodes)
+}
+
+// UnmarshalJSON acepta el código numérico o el nombre canónico ("FacturaA")
+func (t *InvoiceType) UnmarshalJSON(data []byte) error {
+	return unmarshalEnum(data, "InvoiceType", invoiceTypeCodes, t)
+}
+
+// String implementa fmt.Stringer
+func (t ConceptType) String() string {
+	if name, ok := conceptTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("ConceptType(%d)", int(t))
+}
+
+// MarshalJSON serializa el tipo de concepto con su nombre canónico
+func (t ConceptType) MarshalJSON() ([]byte, error) {
+	return marshalEnum(t, conceptTypeNames)
+}
+
+// UnmarshalJSON acepta el código numérico o el nombre canónico ("Servicios")
+func (t *ConceptType) UnmarshalJSON(data []byte) error {
+	return unmarshalEnum(data, "ConceptType", conceptTypeNames, t)
+}
+
+// String implementa fmt.Stringer
+func (t ExportType) String() string {
+	if name, ok := exportTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("ExportType(%d)", int(t))
+}
+
+// MarshalJSON serializa el tipo de exportación con su nombre canónico
+func (t ExportType) MarshalJSON() ([]byte, error) {
+	return marshalEnum(t, exportTypeNames)
+}
+
+// UnmarshalJSON acepta el código numérico o el nombre canónico ("Bienes")
+func (t *ExportType) UnmarshalJSON(data []byte) error {
+	return unmarshalEnum(data, "ExportType", exportTypeNames, t)
+}
+
+// String implementa fmt.Stringer
+func (r TaxRate) String() string {
+	if name, ok := taxRateNames[r]; ok {
+		return name
+	}
+	return fmt.Sprintf("TaxRate(%d)", int(r))
+}
+
+// MarshalJSON serializa la alícuota con su nombre canónico
+func (r TaxRate) MarshalJSON() ([]byte, error) {
+	return marshalEnum(r, taxRateNames)
+}
+
+// UnmarshalJSON acepta el código numérico o el nombre canónico ("10.5%")
+func (r *TaxRate) UnmarshalJSON(data []byte) error {
+	return unmarshalEnum(data, "TaxRate", taxRateNames, r)
+}
+
+// marshalEnum serializa un valor conocido como string y uno desconocido como número
+func marshalEnum[T ~int](value T, names map[T]string) ([]byte, error) {
+	if name, ok := names[value]; ok {
+		return json.Marshal(name)
+	}
+	return json.Marshal(int(value))
+}
+
+// unmarshalEnum deserializa un enum desde su código numérico o su nombre
+func unmarshalEnum[T ~int](data []byte, typeName string, names map[T]string, target *T) error {
+	strict := strictEnumJSON.Load()
+
+	var code int
+	if err := json.Unmarshal(data, &code); err == nil {
+		if _, ok := names[T(code)]; strict && !ok {
+			return fmt.Errorf("unknown %s code %d", typeName, code)
+		}
+		*target = T(code)
+		return nil
+	}
+
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return fmt.Errorf("invalid %s: %s", typeName, string(data))
+	}
+
+	for value, candidate := range names {
+		if candidate == name || (!strict && strings.EqualFold(candidate, strings.TrimSpace(name))) {
+			*target = value
+			return nil
+		}
+	}
+
+	if !strict {
+		if code, err := strconv.Atoi(strings.TrimSpace(name)); err == nil {
+			*target = T(code)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown %s %q", typeName, name)
+}
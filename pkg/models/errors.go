@@ -1,8 +1,10 @@
 package models
 
 import (
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 )
 
 // ARCAError representa un error específico de ARCA
@@ -20,6 +22,26 @@ func (e *ARCAError) Error() string {
 	return fmt.Sprintf("ARCA Error %s: %s", e.Code, e.Message)
 }
 
+// ErrInvoiceNotFound indica que AFIP no tiene registrado el comprobante
+// consultado. Se compara con errors.Is; el *ARCAError con el código y el
+// mensaje de AFIP sigue disponible con errors.As.
+var ErrInvoiceNotFound = errors.New("comprobante inexistente")
+
+// ErrDuplicateInvoiceNumber indica que el número de comprobante ya tiene CAE
+// otorgado para otro comprobante (distinto importe, receptor o fecha), por
+// ejemplo porque otro proceso usó el mismo número
+var ErrDuplicateInvoiceNumber = errors.New("número de comprobante ya autorizado para otro comprobante")
+
+// ErrCircuitOpen indica que la llamada no se envió porque el circuit breaker
+// del servicio está abierto tras fallas consecutivas de AFIP. No es un error
+// reintentable: la llamada debe repetirse cuando venza el cooldown.
+var ErrCircuitOpen = errors.New("circuito abierto: servicio de AFIP no disponible")
+
+// Is permite comparar un ARCAError con los errores centinela del paquete
+func (e *ARCAError) Is(target error) bool {
+	return target == ErrInvoiceNotFound && e.Code == ErrorCodeInvoiceNotFound
+}
+
 // IsARCAError verifica si un error es un ARCAError
 func IsARCAError(err error) bool {
 	_, ok := err.(*ARCAError)
@@ -90,11 +112,15 @@ func (e *AuthenticationError) Error() string {
 	return fmt.Sprintf("Authentication Error: %s", e.Message)
 }
 
-// NetworkError representa un error de red
+// NetworkError representa un error de red. Body es el comienzo de la
+// respuesta cuando AFIP respondió un body vacío, cortado o que no es XML;
+// Retryable marca esos casos como transitorios aunque el status sea 200.
 type NetworkError struct {
-	Message string `json:"message" xml:"message"`
-	URL     string `json:"url,omitempty" xml:"url,omitempty"`
-	Status  int    `json:"status,omitempty" xml:"status,omitempty"`
+	Message   string `json:"message" xml:"message"`
+	URL       string `json:"url,omitempty" xml:"url,omitempty"`
+	Status    int    `json:"status,omitempty" xml:"status,omitempty"`
+	Body      string `json:"body,omitempty" xml:"body,omitempty"`
+	Retryable bool   `json:"retryable,omitempty" xml:"retryable,omitempty"`
 }
 
 // Error implementa la interfaz error
@@ -105,6 +131,103 @@ func (e *NetworkError) Error() string {
 	return fmt.Sprintf("Network Error: %s", e.Message)
 }
 
+// RateLimitError representa un rechazo de AFIP por exceso de requests
+// (HTTP 429 o código 30004). RetryAfter es la espera indicada por el servidor
+// en el header Retry-After, o cero si no la informó.
+type RateLimitError struct {
+	ARCAError
+	RetryAfter time.Duration `json:"retry_after,omitempty" xml:"retry_after,omitempty"`
+}
+
+// Error implementa la interfaz error
+func (e *RateLimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("%s (reintentar en %s)", e.ARCAError.Error(), e.RetryAfter)
+	}
+	return e.ARCAError.Error()
+}
+
+// Unwrap permite obtener el ARCAError subyacente con errors.As
+func (e *RateLimitError) Unwrap() error {
+	return &e.ARCAError
+}
+
+// IsRateLimitError verifica si un error es un RateLimitError
+func IsRateLimitError(err error) bool {
+	var rateLimitErr *RateLimitError
+	return errors.As(err, &rateLimitErr)
+}
+
+// GetRetryAfter retorna la espera sugerida por AFIP para un RateLimitError,
+// o cero para cualquier otro error
+func GetRetryAfter(err error) time.Duration {
+	var rateLimitErr *RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return rateLimitErr.RetryAfter
+	}
+	return 0
+}
+
+// BatchError agrupa los errores de un lote de comprobantes, indexados por
+// la posición del comprobante en el lote
+type BatchError struct {
+	Total  int           `json:"total" xml:"total"`
+	Errors map[int]error `json:"-" xml:"-"`
+}
+
+// NewBatchError crea un BatchError a partir de los errores de cada posición,
+// o retorna nil si ninguno falló
+func NewBatchError(errs []error) error {
+	batchErr := &BatchError{Total: len(errs), Errors: make(map[int]error)}
+	for i, err := range errs {
+		if err != nil {
+			batchErr.Errors[i] = err
+		}
+	}
+	if len(batchErr.Errors) == 0 {
+		return nil
+	}
+	return batchErr
+}
+
+// Error implementa la interfaz error
+func (e *BatchError) Error() string {
+	first := -1
+	for i := range e.Errors {
+		if first < 0 || i < first {
+			first = i
+		}
+	}
+	return fmt.Sprintf("%d de %d comprobantes fallaron (primero en posición %d: %v)", len(e.Errors), e.Total, first, e.Errors[first])
+}
+
+// Unwrap permite usar errors.Is/errors.As sobre los errores individuales
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for i := 0; i < e.Total; i++ {
+		if err, ok := e.Errors[i]; ok {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// IsRetryableError indica si un error es transitorio y la operación puede reintentarse
+func IsRetryableError(err error) bool {
+	var networkErr *NetworkError
+	if errors.As(err, &networkErr) {
+		return networkErr.Retryable || networkErr.Status == 0 || networkErr.Status >= 500
+	}
+
+	var arcaErr *ARCAError
+	if errors.As(err, &arcaErr) {
+		info, _ := LookupError(arcaErr.Code)
+		return info.Retryable
+	}
+
+	return false
+}
+
 // Códigos de error comunes de ARCA
 const (
 	// Errores de autenticación
@@ -127,6 +250,10 @@ const (
 	ErrorCodeInvalidDocumentType   = "20010"
 	ErrorCodeInvalidDocumentNumber = "20011"
 
+	// ErrorCodeInvoiceNotFound es el código que informa FECompConsultar
+	// cuando el comprobante no existe (ver ErrInvoiceNotFound)
+	ErrorCodeInvoiceNotFound = "602"
+
 	// Errores de sistema
 	ErrorCodeServiceUnavailable = "30001"
 	ErrorCodeTimeout            = "30002"
@@ -134,36 +261,87 @@ const (
 	ErrorCodeRateLimitExceeded  = "30004"
 )
 
-// ErrorMessages mapea códigos de error a mensajes descriptivos
-var ErrorMessages = map[string]string{
-	ErrorCodeCUITNotEnabled:     "CUIT no habilitado para facturación electrónica",
-	ErrorCodeInvalidCertificate: "Certificado inválido o no encontrado",
-	ErrorCodeExpiredCertificate: "Certificado expirado",
-	ErrorCodeInvalidToken:       "Token de acceso inválido",
-	ErrorCodeTokenExpired:       "Token de acceso expirado",
-
-	ErrorCodeInvalidInvoiceType:    "Tipo de comprobante inválido",
-	ErrorCodeInvalidPointOfSale:    "Punto de venta inválido",
-	ErrorCodeInvalidInvoiceNumber:  "Número de comprobante inválido",
-	ErrorCodeInvalidAmount:         "Monto inválido",
-	ErrorCodeInvalidTaxAmount:      "Monto de impuestos inválido",
-	ErrorCodeInvalidTotalAmount:    "Monto total inválido",
-	ErrorCodeInvalidDate:           "Fecha inválida",
-	ErrorCodeInvalidCurrency:       "Moneda inválida",
-	ErrorCodeInvalidConceptType:    "Tipo de concepto inválido",
-	ErrorCodeInvalidDocumentType:   "Tipo de documento inválido",
-	ErrorCodeInvalidDocumentNumber: "Número de documento inválido",
-
-	ErrorCodeServiceUnavailable: "Servicio no disponible",
-	ErrorCodeTimeout:            "Timeout en la comunicación",
-	ErrorCodeInvalidResponse:    "Respuesta inválida del servidor",
-	ErrorCodeRateLimitExceeded:  "Límite de requests excedido",
+// ErrorCategory agrupa los códigos de error según su origen
+type ErrorCategory string
+
+const (
+	ErrorCategoryAuth    ErrorCategory = "auth"    // Autenticación con WSAA y certificados
+	ErrorCategoryInvoice ErrorCategory = "invoice" // Datos del comprobante
+	ErrorCategorySystem  ErrorCategory = "system"  // Disponibilidad y comunicación con AFIP
+)
+
+// ErrorSeverity indica qué hacer ante un error
+type ErrorSeverity string
+
+const (
+	// ErrorSeverityCritical requiere intervención (certificado, habilitación del CUIT)
+	ErrorSeverityCritical ErrorSeverity = "critical"
+	// ErrorSeverityError indica datos inválidos: el comprobante debe corregirse
+	ErrorSeverityError ErrorSeverity = "error"
+	// ErrorSeverityWarning indica un problema transitorio
+	ErrorSeverityWarning ErrorSeverity = "warning"
+)
+
+// ErrorInfo describe un código de error de ARCA
+type ErrorInfo struct {
+	Code      string        `json:"code"`
+	Message   string        `json:"message"`
+	Category  ErrorCategory `json:"category"`
+	Severity  ErrorSeverity `json:"severity"`
+	Retryable bool          `json:"retryable"`
+}
+
+// errorCatalog contiene la descripción de cada código de error conocido
+var errorCatalog = map[string]ErrorInfo{
+	ErrorCodeCUITNotEnabled:     {Message: "CUIT no habilitado para facturación electrónica", Category: ErrorCategoryAuth, Severity: ErrorSeverityCritical},
+	ErrorCodeInvalidCertificate: {Message: "Certificado inválido o no encontrado", Category: ErrorCategoryAuth, Severity: ErrorSeverityCritical},
+	ErrorCodeExpiredCertificate: {Message: "Certificado expirado", Category: ErrorCategoryAuth, Severity: ErrorSeverityCritical},
+	ErrorCodeInvalidToken:       {Message: "Token de acceso inválido", Category: ErrorCategoryAuth, Severity: ErrorSeverityError},
+	ErrorCodeTokenExpired:       {Message: "Token de acceso expirado", Category: ErrorCategoryAuth, Severity: ErrorSeverityError},
+
+	ErrorCodeInvalidInvoiceType:    {Message: "Tipo de comprobante inválido", Category: ErrorCategoryInvoice, Severity: ErrorSeverityError},
+	ErrorCodeInvalidPointOfSale:    {Message: "Punto de venta inválido", Category: ErrorCategoryInvoice, Severity: ErrorSeverityError},
+	ErrorCodeInvalidInvoiceNumber:  {Message: "Número de comprobante inválido", Category: ErrorCategoryInvoice, Severity: ErrorSeverityError},
+	ErrorCodeInvalidAmount:         {Message: "Monto inválido", Category: ErrorCategoryInvoice, Severity: ErrorSeverityError},
+	ErrorCodeInvalidTaxAmount:      {Message: "Monto de impuestos inválido", Category: ErrorCategoryInvoice, Severity: ErrorSeverityError},
+	ErrorCodeInvalidTotalAmount:    {Message: "Monto total inválido", Category: ErrorCategoryInvoice, Severity: ErrorSeverityError},
+	ErrorCodeInvalidDate:           {Message: "Fecha inválida", Category: ErrorCategoryInvoice, Severity: ErrorSeverityError},
+	ErrorCodeInvalidCurrency:       {Message: "Moneda inválida", Category: ErrorCategoryInvoice, Severity: ErrorSeverityError},
+	ErrorCodeInvalidConceptType:    {Message: "Tipo de concepto inválido", Category: ErrorCategoryInvoice, Severity: ErrorSeverityError},
+	ErrorCodeInvalidDocumentType:   {Message: "Tipo de documento inválido", Category: ErrorCategoryInvoice, Severity: ErrorSeverityError},
+	ErrorCodeInvalidDocumentNumber: {Message: "Número de documento inválido", Category: ErrorCategoryInvoice, Severity: ErrorSeverityError},
+	ErrorCodeInvoiceNotFound:       {Message: "Comprobante inexistente", Category: ErrorCategoryInvoice, Severity: ErrorSeverityError},
+
+	ErrorCodeServiceUnavailable: {Message: "Servicio no disponible", Category: ErrorCategorySystem, Severity: ErrorSeverityWarning, Retryable: true},
+	ErrorCodeTimeout:            {Message: "Timeout en la comunicación", Category: ErrorCategorySystem, Severity: ErrorSeverityWarning, Retryable: true},
+	ErrorCodeInvalidResponse:    {Message: "Respuesta inválida del servidor", Category: ErrorCategorySystem, Severity: ErrorSeverityError},
+	ErrorCodeRateLimitExceeded:  {Message: "Límite de requests excedido", Category: ErrorCategorySystem, Severity: ErrorSeverityWarning, Retryable: true},
+}
+
+// ErrorMessages mapea códigos de error a mensajes descriptivos. Se arma a
+// partir del catálogo; para la categoría y si es reintentable use LookupError.
+var ErrorMessages = func() map[string]string {
+	messages := make(map[string]string, len(errorCatalog))
+	for code, info := range errorCatalog {
+		messages[code] = info.Message
+	}
+	return messages
+}()
+
+// LookupError retorna la descripción de un código de error y si es conocido
+func LookupError(code string) (ErrorInfo, bool) {
+	info, exists := errorCatalog[code]
+	if !exists {
+		return ErrorInfo{}, false
+	}
+	info.Code = code
+	return info, true
 }
 
 // GetErrorMessage obtiene el mensaje descriptivo para un código de error
 func GetErrorMessage(code string) string {
-	if message, exists := ErrorMessages[code]; exists {
-		return message
+	if info, exists := LookupError(code); exists {
+		return info.Message
 	}
 	return "Error desconocido"
 }
@@ -177,6 +355,23 @@ func NewARCAError(code, details string) *ARCAError {
 	}
 }
 
+// NewRateLimitError crea un nuevo error de límite de requests excedido
+func NewRateLimitError(details string, retryAfter time.Duration) *RateLimitError {
+	return &RateLimitError{
+		ARCAError:  *NewARCAError(ErrorCodeRateLimitExceeded, details),
+		RetryAfter: retryAfter,
+	}
+}
+
+// NewServiceError crea el error correspondiente a un error informado por AFIP
+// en la respuesta: RateLimitError para el código 30004, ARCAError para el resto
+func NewServiceError(code, details string) error {
+	if code == ErrorCodeRateLimitExceeded {
+		return NewRateLimitError(details, 0)
+	}
+	return NewARCAError(code, details)
+}
+
 // NewValidationError crea un nuevo error de validación
 func NewValidationError(field, message string, value interface{}) *ValidationError {
 	return &ValidationError{
@@ -12,16 +12,42 @@ type Invoice struct {
 	DocTypeFrom   DocumentType `json:"doc_type_from" xml:"doc_type_from"`
 	DocNumberFrom string       `json:"doc_number_from" xml:"doc_number_from"`
 	NameFrom      string       `json:"name_from" xml:"name_from"`
-	ServiceFrom   time.Time    `json:"service_from" xml:"service_from"`
+	// ServiceFrom y ServiceTo son el período del servicio facturado
+	// (FchServDesde y FchServHasta), obligatorio para los conceptos
+	// servicios y mixto
+	ServiceFrom time.Time `json:"service_from" xml:"service_from"`
+	ServiceTo   time.Time `json:"service_to,omitempty" xml:"service_to,omitempty"`
+	// PaymentDueDate es la fecha de vencimiento de pago (FchVtoPago),
+	// obligatoria para los conceptos servicios y mixto
+	PaymentDueDate time.Time `json:"payment_due_date,omitempty" xml:"payment_due_date,omitempty"`
+	FCE            *FCEData  `json:"fce,omitempty" xml:"fce,omitempty"`
+	// AssociatedInvoices son los comprobantes que ajusta una nota de débito
+	// o crédito (ver InvoiceType.RequiresAssociatedInvoice)
+	AssociatedInvoices []AssociatedInvoice `json:"associated_invoices,omitempty" xml:"associated_invoices,omitempty"`
+	// AssociatedPeriod reemplaza a AssociatedInvoices cuando la nota ajusta
+	// un período; se informa uno u otro, no ambos
+	AssociatedPeriod *AssociatedPeriod `json:"associated_period,omitempty" xml:"associated_period,omitempty"`
+	// NonTaxableAmount (ImpTotConc) y ExemptAmount (ImpOpEx) son los
+	// importes no gravados y exentos, que no forman parte de Amount (ImpNeto)
+	NonTaxableAmount float64 `json:"non_taxable_amount,omitempty" xml:"non_taxable_amount,omitempty"`
+	ExemptAmount     float64 `json:"exempt_amount,omitempty" xml:"exempt_amount,omitempty"`
 }
 
 // ExportInvoice representa una factura de exportación
 type ExportInvoice struct {
 	InvoiceBase
-	Destination     string    `json:"destination" xml:"destination"`
-	DestinationCode string    `json:"destination_code" xml:"destination_code"`
-	ExportDate      time.Time `json:"export_date" xml:"export_date"`
-	ExportType      string    `json:"export_type" xml:"export_type"`
+	Destination     string     `json:"destination" xml:"destination"`
+	DestinationCode string     `json:"destination_code" xml:"destination_code"`
+	ExportDate      time.Time  `json:"export_date" xml:"export_date"`
+	ExportType      ExportType `json:"export_type" xml:"export_type"`
+
+	Incoterm            string              `json:"incoterm,omitempty" xml:"incoterm,omitempty"`
+	IncotermDescription string              `json:"incoterm_description,omitempty" xml:"incoterm_description,omitempty"`
+	Permits             []ExportPermit      `json:"permits,omitempty" xml:"permits,omitempty"`
+	AssociatedInvoices  []AssociatedInvoice `json:"associated_invoices,omitempty" xml:"associated_invoices,omitempty"`
+	// Buyers reparte la exportación entre varios compradores (consorcios o
+	// exportaciones conjuntas); vacío si hay un único cliente
+	Buyers []ExportBuyer `json:"buyers,omitempty" xml:"buyers,omitempty"`
 }
 
 // InvoiceQuery representa una consulta de factura
@@ -50,14 +76,14 @@ type AuthorizationResponse struct {
 	PointOfSale       int         `json:"point_of_sale" xml:"point_of_sale"`
 	InvoiceType       InvoiceType `json:"invoice_type" xml:"invoice_type"`
 	AuthorizationDate time.Time   `json:"authorization_date" xml:"authorization_date"`
-	Status            string      `json:"status" xml:"status"`
+	Status            AuthResult  `json:"status" xml:"status"`
 	Message           string      `json:"message,omitempty" xml:"message,omitempty"`
 }
 
 // ExportAuthResponse representa la respuesta de autorización de exportación
 type ExportAuthResponse struct {
 	AuthorizationResponse
-	ExportType string `json:"export_type" xml:"export_type"`
+	ExportType ExportType `json:"export_type" xml:"export_type"`
 }
 
 // LastInvoiceResponse representa la respuesta del último comprobante
@@ -68,10 +94,22 @@ type LastInvoiceResponse struct {
 	Date          time.Time   `json:"date" xml:"date"`
 }
 
-// CAEAResponse representa la respuesta de consulta CAEA
+// IsEmpty indica que todavía no hay comprobantes autorizados para el punto
+// de venta y tipo (número 0)
+func (r *LastInvoiceResponse) IsEmpty() bool {
+	return r.InvoiceNumber == 0
+}
+
+// CAEAResponse representa la respuesta de consulta CAEA. La vigencia va de
+// ValidFrom a ExpirationDate y MaxAmount es el importe máximo por comprobante.
 type CAEAResponse struct {
 	CAEA           string    `json:"caea" xml:"caea"`
+	Period         int       `json:"period,omitempty" xml:"period,omitempty"`
+	Order          int       `json:"order,omitempty" xml:"order,omitempty"`
+	ValidFrom      time.Time `json:"valid_from,omitempty" xml:"valid_from,omitempty"`
 	ExpirationDate time.Time `json:"expiration_date" xml:"expiration_date"`
+	ReportDeadline time.Time `json:"report_deadline,omitempty" xml:"report_deadline,omitempty"`
+	MaxAmount      float64   `json:"max_amount,omitempty" xml:"max_amount,omitempty"`
 	Status         string    `json:"status" xml:"status"`
 	Message        string    `json:"message,omitempty" xml:"message,omitempty"`
 }
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"fmt"
+	"math"
+)
+
+// Validate valida los datos mínimos de la factura sin llamar a AFIP. Retorna
+// ValidationErrors con todos los problemas encontrados.
+func (i *Invoice) Validate() error {
+	var errors ValidationErrors
+
+	i.InvoiceBase.validate(&errors)
+
+	if i.ConceptType != ConceptTypeProducts && i.PaymentDueDate.IsZero() {
+		errors.Add("payment_due_date", "Fecha de vencimiento de pago obligatoria para los conceptos servicios y mixto", nil)
+	}
+
+	validateAssociatedPeriod(i.AssociatedInvoices, i.AssociatedPeriod, &errors)
+
+	if errors.HasErrors() {
+		return errors
+	}
+	return nil
+}
+
+// validateAssociatedPeriod verifica que el período asociado tenga fechas en
+// orden y que no se informe junto con comprobantes asociados
+func validateAssociatedPeriod(associated []AssociatedInvoice, period *AssociatedPeriod, errors *ValidationErrors) {
+	if period == nil {
+		return
+	}
+
+	switch {
+	case len(associated) > 0:
+		errors.Add("associated_period", "Informe comprobantes asociados o un período asociado, no ambos", period)
+	case period.From.IsZero() || period.To.IsZero():
+		errors.Add("associated_period", "Fechas desde y hasta del período asociado obligatorias", period)
+	case period.To.Before(period.From):
+		errors.Add("associated_period", "La fecha hasta del período asociado no puede ser anterior a la fecha desde", period)
+	}
+}
+
+// Validate valida los datos mínimos de la factura de exportación sin llamar
+// a AFIP. Retorna ValidationErrors con todos los problemas encontrados.
+func (i *ExportInvoice) Validate() error {
+	var errors ValidationErrors
+
+	i.InvoiceBase.validate(&errors)
+
+	if i.Destination == "" {
+		errors.Add("destination", "Destino no puede estar vacío", i.Destination)
+	}
+
+	switch i.ExportType {
+	case 0:
+		errors.Add("export_type", "Tipo de exportación no puede estar vacío", i.ExportType)
+	case ExportTypeGoods, ExportTypeServices, ExportTypeOther:
+	default:
+		errors.Add("export_type", "Tipo de exportación no válido (1 = bienes, 2 = servicios, 4 = otros)", i.ExportType)
+	}
+
+	if i.InvoiceType.RequiresAssociatedInvoice() && len(i.AssociatedInvoices) == 0 {
+		errors.Add("associated_invoices", fmt.Sprintf("%s requiere informar el comprobante asociado (Cmps_asoc)", i.InvoiceType), nil)
+	}
+
+	if len(i.Buyers) > 0 {
+		var total float64
+		for _, buyer := range i.Buyers {
+			total += buyer.Percentage
+		}
+		if math.Abs(total-100) > 0.01 {
+			errors.Add("buyers", fmt.Sprintf("Los porcentajes de los compradores deben sumar 100 (suman %.2f)", total), i.Buyers)
+		}
+	}
+
+	if errors.HasErrors() {
+		return errors
+	}
+	return nil
+}
+
+// validate agrega a errors los problemas de los campos comunes
+func (b *InvoiceBase) validate(errors *ValidationErrors) {
+	if b.InvoiceNumber <= 0 {
+		errors.Add("invoice_number", "Número de comprobante debe ser mayor a 0", b.InvoiceNumber)
+	}
+
+	if b.PointOfSale <= 0 {
+		errors.Add("point_of_sale", "Punto de venta debe ser mayor a 0", b.PointOfSale)
+	}
+
+	if b.Amount <= 0 {
+		errors.Add("amount", "Importe debe ser mayor a 0", b.Amount)
+	}
+
+	if len(b.Items) == 0 {
+		errors.Add("items", "La factura debe tener al menos un ítem", nil)
+	}
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+)
+
+// QRBaseURL es la URL del código QR que deben imprimir los comprobantes
+// electrónicos (RG 4892/2020)
+const QRBaseURL = "https://www.afip.gob.ar/fe/qr/"
+
+// Tipos de código de autorización del QR (tipoCodAut)
+const (
+	QRAuthTypeCAE  = "E"
+	QRAuthTypeCAEA = "A"
+)
+
+// QRData son los datos del comprobante que codifica el QR. DocType y
+// DocNumber se omiten si el receptor no está identificado.
+type QRData struct {
+	Version       int     `json:"ver"`
+	Date          string  `json:"fecha"` // AAAA-MM-DD
+	CUIT          int64   `json:"cuit"`
+	PointOfSale   int     `json:"ptoVta"`
+	InvoiceType   int     `json:"tipoCmp"`
+	InvoiceNumber int     `json:"nroCmp"`
+	Amount        float64 `json:"importe"`
+	Currency      string  `json:"moneda"`
+	CurrencyRate  float64 `json:"ctz"`
+	DocType       int     `json:"tipoDocRec,omitempty"`
+	DocNumber     int64   `json:"nroDocRec,omitempty"`
+	AuthType      string  `json:"tipoCodAut"`
+	AuthCode      int64   `json:"codAut"`
+}
+
+// URL retorna la URL del QR: QRBaseURL con los datos en JSON codificados en
+// base64 en el parámetro p
+func (q QRData) URL() (string, error) {
+	data, err := json.Marshal(q)
+	if err != nil {
+		return "", err
+	}
+	return QRBaseURL + "?p=" + base64.StdEncoding.EncodeToString(data), nil
+}
@@ -0,0 +1,112 @@
+package models
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"strconv"
+)
+
+// resultColumns son los encabezados del CSV de resultados, con la
+// terminología de AFIP
+var resultColumns = []string{"Posicion", "CbteTipo", "PtoVta", "CbteNro", "Resultado", "CodAutorizacion", "CAEFchVto", "FchProceso", "Observaciones", "Error"}
+
+// ResultRecord es una fila del resumen de un lote de autorizaciones
+type ResultRecord struct {
+	Position          int    `json:"Posicion"`
+	InvoiceType       int    `json:"CbteTipo,omitempty"`
+	PointOfSale       int    `json:"PtoVta,omitempty"`
+	InvoiceNumber     int    `json:"CbteNro,omitempty"`
+	Status            string `json:"Resultado,omitempty"`
+	CAE               string `json:"CodAutorizacion,omitempty"`
+	CAEExpirationDate string `json:"CAEFchVto,omitempty"`
+	AuthorizationDate string `json:"FchProceso,omitempty"`
+	Observations      string `json:"Observaciones,omitempty"`
+	Error             string `json:"Error,omitempty"`
+}
+
+// NewResultRecords arma una fila por comprobante del lote. batchErr es el
+// error retornado junto con los resultados; si es un *BatchError, cada
+// comprobante fallido lleva su error en la columna Error.
+func NewResultRecords(results []*AuthorizationResult, batchErr error) []ResultRecord {
+	var batch *BatchError
+	errors.As(batchErr, &batch)
+
+	records := make([]ResultRecord, 0, len(results))
+	for i, result := range results {
+		record := ResultRecord{Position: i}
+		if result != nil {
+			record.InvoiceType = int(result.InvoiceType)
+			record.PointOfSale = result.PointOfSale
+			record.InvoiceNumber = result.InvoiceNumber
+			record.Status = string(result.Status)
+			record.CAE = result.CAE
+			record.Observations = result.Message
+			if !result.CAEExpirationDate.IsZero() {
+				record.CAEExpirationDate = result.CAEExpirationDate.Format("20060102")
+			}
+			if !result.AuthorizationDate.IsZero() {
+				record.AuthorizationDate = result.AuthorizationDate.Format("20060102")
+			}
+		}
+		if batch != nil {
+			if err, ok := batch.Errors[i]; ok {
+				record.Error = err.Error()
+			}
+		}
+		records = append(records, record)
+	}
+
+	return records
+}
+
+// MarshalResultsCSV serializa el resultado de un lote como CSV, con una fila
+// por comprobante. Acepta directamente lo que retorna AuthorizeInvoicesConcurrent:
+//
+//	data, err := models.MarshalResultsCSV(service.AuthorizeInvoicesConcurrent(ctx, invoices, 4))
+func MarshalResultsCSV(results []*AuthorizationResult, batchErr error) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+
+	if err := writer.Write(resultColumns); err != nil {
+		return nil, err
+	}
+	for _, record := range NewResultRecords(results, batchErr) {
+		row := []string{
+			strconv.Itoa(record.Position),
+			formatOptionalInt(record.InvoiceType),
+			formatOptionalInt(record.PointOfSale),
+			formatOptionalInt(record.InvoiceNumber),
+			record.Status,
+			record.CAE,
+			record.CAEExpirationDate,
+			record.AuthorizationDate,
+			record.Observations,
+			record.Error,
+		}
+		if err := writer.Write(row); err != nil {
+			return nil, err
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// MarshalResultsJSON serializa el resultado de un lote como un arreglo JSON
+// de ResultRecord, con las mismas columnas que MarshalResultsCSV
+func MarshalResultsJSON(results []*AuthorizationResult, batchErr error) ([]byte, error) {
+	return json.Marshal(NewResultRecords(results, batchErr))
+}
+
+// formatOptionalInt formatea un entero, dejando vacío el cero
+func formatOptionalInt(value int) string {
+	if value == 0 {
+		return ""
+	}
+	return strconv.Itoa(value)
+}
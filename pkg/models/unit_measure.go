@@ -0,0 +1,125 @@
+package models
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// unitMeasureNames mapea los códigos de unidad de medida de WSFEX (Umed,
+// ver FEXGetPARAM_UMed) a su descripción
+var unitMeasureNames = map[string]string{
+	"1":  "Kilogramos",
+	"2":  "Metros",
+	"3":  "Metros cuadrados",
+	"4":  "Metros cúbicos",
+	"5":  "Litros",
+	"6":  "1000 kWh",
+	"7":  "Unidades",
+	"8":  "Pares",
+	"9":  "Docenas",
+	"10": "Quilates",
+	"11": "Millares",
+	"14": "Gramos",
+	"15": "Milímetros",
+	"16": "Milímetros cúbicos",
+	"17": "Kilómetros",
+	"18": "Hectolitros",
+	"20": "Centímetros",
+	"25": "Jgo. pqt. mazo naipes",
+	"27": "Centímetros cúbicos",
+	"29": "Toneladas",
+	"30": "Decámetros cúbicos",
+	"31": "Hectómetros cúbicos",
+	"32": "Kilómetros cúbicos",
+	"33": "Microgramos",
+	"34": "Nanogramos",
+	"35": "Picogramos",
+	"41": "Miligramos",
+	"47": "Mililitros",
+	"48": "Curie",
+	"49": "Milicurie",
+	"50": "Microcurie",
+	"51": "Unidades internacionales de actividad hormonal",
+	"52": "Millones de unidades internacionales de actividad hormonal",
+	"53": "Kilogramos base",
+	"54": "Gruesa",
+	"61": "Kilogramos bruto",
+	"62": "Unidades internacionales de actividad antibiótica",
+	"63": "Millones de unidades internacionales de actividad antibiótica",
+	"64": "Unidades internacionales de actividad inmunoglobulina",
+	"65": "Millones de unidades internacionales de actividad inmunoglobulina",
+	"66": "Kilogramos activo",
+	"67": "Gramos activo",
+	"68": "Gramos base",
+	"96": "Packs",
+	"97": "Señas/Anticipos",
+	"98": "Otras unidades",
+	"99": "Bonificación",
+}
+
+// unitMeasureAliases mapea abreviaturas de uso habitual al código de Umed
+var unitMeasureAliases = map[string]string{
+	"kg":  "1",
+	"m":   "2",
+	"m2":  "3",
+	"m3":  "4",
+	"l":   "5",
+	"lt":  "5",
+	"kwh": "6",
+	"u":   "7",
+	"un":  "7",
+	"ud":  "7",
+	"par": "8",
+	"doc": "9",
+	"g":   "14",
+	"gr":  "14",
+	"mm":  "15",
+	"km":  "17",
+	"hl":  "18",
+	"cm":  "20",
+	"cm3": "27",
+	"t":   "29",
+	"tn":  "29",
+	"mg":  "41",
+	"ml":  "47",
+}
+
+// KnownUnitMeasures retorna el catálogo de unidades de medida de WSFEX
+// conocido por la librería, ordenado por código. La lista vigente en AFIP se
+// obtiene con wsfex.Service.GetUnitMeasures.
+func KnownUnitMeasures() []UnitType {
+	units := make([]UnitType, 0, len(unitMeasureNames))
+	for id, name := range unitMeasureNames {
+		units = append(units, UnitType{ID: id, Description: name, Active: true})
+	}
+	sort.Slice(units, func(i, j int) bool {
+		a, _ := strconv.Atoi(units[i].ID)
+		b, _ := strconv.Atoi(units[j].ID)
+		return a < b
+	})
+	return units
+}
+
+// ParseUnitMeasure traduce una unidad de medida al código Umed de WSFEX.
+// Acepta el código numérico, la descripción del catálogo (ej. "Kilogramos")
+// o una abreviatura habitual (ej. "kg", "un"), sin distinguir mayúsculas.
+// Los códigos numéricos se aceptan aunque no estén en el catálogo local.
+func ParseUnitMeasure(value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	if code, err := strconv.Atoi(value); err == nil {
+		return strconv.Itoa(code), code > 0
+	}
+
+	key := strings.ToLower(value)
+	if code, ok := unitMeasureAliases[key]; ok {
+		return code, true
+	}
+	for code, name := range unitMeasureNames {
+		if strings.ToLower(name) == key {
+			return code, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,44 @@
+package testutil
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"time"
+)
+
+// GenerateCertificate genera un certificado autofirmado y su clave privada
+// en formato DER, como los espera client.Config. Sólo sirve para tests.
+func GenerateCertificate(cuit string) (cert []byte, key []byte, err error) {
+	return GenerateCertificateWithExpiry(cuit, time.Now().Add(24*time.Hour))
+}
+
+// GenerateCertificateWithExpiry es como GenerateCertificate pero con la fecha
+// de vencimiento dada, para simular certificados vencidos o por vencer
+func GenerateCertificateWithExpiry(cuit string, notAfter time.Time) (cert []byte, key []byte, err error) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject: pkix.Name{
+			CommonName:   "testutil",
+			Organization: []string{"ARCA testutil"},
+			SerialNumber: "CUIT " + cuit,
+		},
+		NotBefore: time.Now().Add(-time.Hour),
+		NotAfter:  notAfter,
+		KeyUsage:  x509.KeyUsageDigitalSignature,
+	}
+
+	cert, err = x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return cert, x509.MarshalPKCS1PrivateKey(privateKey), nil
+}
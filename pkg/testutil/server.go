@@ -0,0 +1,435 @@
+// Package testutil provee un servidor AFIP simulado para tests de integración.
+//
+// El servidor responde a WSAA (loginCms) y a los métodos principales de
+// WSFEv1 y WSFEXv1 con respuestas predefinidas que pueden reemplazarse por acción:
+//
+//	server := testutil.NewFakeAFIPServer()
+//	defer server.Close()
+//
+//	config, err := server.Config()
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	auth := client.NewWSAAAuth(&config, nil)
+//	service := wsfe.NewService(&config, auth, nil)
+//
+// Para apuntar una configuración existente al servidor basta con asignar
+// config.BaseURLOverride = server.URL.
+package testutil
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dlarregola/arca_invoice_lib/pkg/client"
+	"github.com/dlarregola/arca_invoice_lib/pkg/models"
+)
+
+// Acciones soportadas por el servidor simulado
+const (
+	ActionLoginCms                       = "loginCms"
+	ActionFECAESolicitar                 = "FECAESolicitar"
+	ActionFECAEARegInformativo           = "FECAEARegInformativo"
+	ActionFECompConsultar                = "FECompConsultar"
+	ActionFECompUltimoAutorizado         = "FECompUltimoAutorizado"
+	ActionFEDummy                        = "FEDummy"
+	ActionFEXAuthorize                   = "FEXAuthorize"
+	ActionFEParamGetActividades          = "FEParamGetActividades"
+	ActionFEParamGetCondicionIvaReceptor = "FEParamGetCondicionIvaReceptor"
+	ActionFEParamGetPtosVenta            = "FEParamGetPtosVenta"
+	ActionFEParamGetTiposCbte            = "FEParamGetTiposCbte"
+	ActionFEParamGetTiposConcepto        = "FEParamGetTiposConcepto"
+	ActionFEParamGetTiposDoc             = "FEParamGetTiposDoc"
+	ActionFEParamGetTiposIva             = "FEParamGetTiposIva"
+	ActionFEParamGetTiposMonedas         = "FEParamGetTiposMonedas"
+	ActionFEParamGetTiposOpcional        = "FEParamGetTiposOpcional"
+	ActionFEXCheckPermiso                = "FEXCheck_Permiso"
+	ActionFEXGetCMP                      = "FEXGetCMP"
+	ActionFEXGetLastCMP                  = "FEXGetLast_CMP"
+	ActionFEXGetPARAMCtz                 = "FEXGetPARAM_Ctz"
+	ActionFEXGetPARAMDSTPais             = "FEXGetPARAM_DST_pais"
+	ActionFEXGetPARAMIncoterms           = "FEXGetPARAM_Incoterms"
+	ActionFEXGetPARAMMON                 = "FEXGetPARAM_MON"
+	ActionFEXGetPARAMTipoCbte            = "FEXGetPARAM_Tipo_Cbte"
+	ActionFEXGetPARAMTipoExpo            = "FEXGetPARAM_Tipo_Expo"
+	ActionFEXGetPARAMUMed                = "FEXGetPARAM_UMed"
+)
+
+// Credenciales que entrega el WSAA simulado
+const (
+	FakeToken = "fake-token"
+	FakeSign  = "fake-sign"
+)
+
+// defaultResults contiene el contenido por defecto del elemento <Método>Result
+var defaultResults = map[string]string{
+	ActionFECAESolicitar: `<FeCabResp><Cuit>20123456786</Cuit><PtoVta>1</PtoVta><CbteTipo>1</CbteTipo>` +
+		`<FchProceso>20240115103000</FchProceso><CantReg>1</CantReg><Resultado>A</Resultado></FeCabResp>` +
+		`<FeDetResp><FECAEDetResponse><Concepto>1</Concepto><DocTipo>80</DocTipo><DocNro>20123456786</DocNro>` +
+		`<CbteDesde>1</CbteDesde><CbteHasta>1</CbteHasta><CbteFch>20240115</CbteFch><Resultado>A</Resultado>` +
+		`<CAE>74123456789012</CAE><CAEFchVto>20240125</CAEFchVto></FECAEDetResponse></FeDetResp>`,
+	ActionFECAEARegInformativo: `<FeCabResp><Cuit>20123456786</Cuit><PtoVta>1</PtoVta><CbteTipo>1</CbteTipo>` +
+		`<FchProceso>20240120103000</FchProceso><CantReg>1</CantReg><Resultado>A</Resultado></FeCabResp>` +
+		`<FeDetResp><FECAEADetResponse><Concepto>2</Concepto><DocTipo>80</DocTipo><DocNro>20123456786</DocNro>` +
+		`<CbteDesde>1</CbteDesde><CbteHasta>1</CbteHasta><CbteFch>20240115</CbteFch><Resultado>A</Resultado>` +
+		`<CAEA>21064126523746</CAEA></FECAEADetResponse></FeDetResp>`,
+	ActionFECompConsultar: `<ResultGet><Concepto>1</Concepto><DocTipo>80</DocTipo><DocNro>20123456786</DocNro>` +
+		`<CbteDesde>1</CbteDesde><CbteHasta>1</CbteHasta><CbteFch>20240115</CbteFch><ImpTotal>1210</ImpTotal>` +
+		`<MonId>PES</MonId><MonCotiz>1</MonCotiz><Resultado>A</Resultado><CodAutorizacion>74123456789012</CodAutorizacion>` +
+		`<EmisionTipo>CAE</EmisionTipo><FchVto>20240125</FchVto><PtoVta>1</PtoVta><CbteTipo>1</CbteTipo></ResultGet>`,
+	ActionFECompUltimoAutorizado: `<PtoVta>1</PtoVta><CbteTipo>1</CbteTipo><CbteNro>0</CbteNro>`,
+	ActionFEDummy:                `<AppServer>OK</AppServer><DbServer>OK</DbServer><AuthServer>OK</AuthServer>`,
+	ActionFEParamGetActividades: `<ResultGet>` +
+		`<ActividadesTipo><Id>620100</Id><Orden>1</Orden><Desc>Servicios de consultores en informática</Desc></ActividadesTipo>` +
+		`<ActividadesTipo><Id>474010</Id><Orden>2</Orden><Desc>Venta al por menor de equipos informáticos</Desc></ActividadesTipo>` +
+		`</ResultGet>`,
+	ActionFEParamGetCondicionIvaReceptor: `<ResultGet>` +
+		`<CondicionIvaReceptor><Id>1</Id><Desc>IVA Responsable Inscripto</Desc><Cmp_Clase>A/M/C</Cmp_Clase></CondicionIvaReceptor>` +
+		`<CondicionIvaReceptor><Id>5</Id><Desc>Consumidor Final</Desc><Cmp_Clase>B/C</Cmp_Clase></CondicionIvaReceptor>` +
+		`</ResultGet>`,
+	ActionFEParamGetPtosVenta: `<ResultGet>` +
+		`<PtoVenta><Nro>1</Nro><EmisionTipo>CAE - Ws</EmisionTipo><Bloqueado>N</Bloqueado><FchBaja>NULL</FchBaja></PtoVenta>` +
+		`<PtoVenta><Nro>2</Nro><EmisionTipo>CAEA - Ws</EmisionTipo><Bloqueado>S</Bloqueado><FchBaja>NULL</FchBaja></PtoVenta>` +
+		`</ResultGet>`,
+	ActionFEParamGetTiposCbte: `<ResultGet>` +
+		`<CbteTipo><Id>1</Id><Desc>Factura A</Desc><FchDesde>20100917</FchDesde><FchHasta>NULL</FchHasta></CbteTipo>` +
+		`<CbteTipo><Id>3</Id><Desc>Nota de Crédito A</Desc><FchDesde>20100917</FchDesde><FchHasta>NULL</FchHasta></CbteTipo>` +
+		`<CbteTipo><Id>6</Id><Desc>Factura B</Desc><FchDesde>20100917</FchDesde><FchHasta>NULL</FchHasta></CbteTipo>` +
+		`<CbteTipo><Id>51</Id><Desc>Factura M</Desc><FchDesde>20150522</FchDesde><FchHasta>NULL</FchHasta></CbteTipo>` +
+		`<CbteTipo><Id>49</Id><Desc>Comprobante de Compra de Bienes Usados a Consumidor Final</Desc><FchDesde>20130401</FchDesde><FchHasta>20200101</FchHasta></CbteTipo>` +
+		`</ResultGet>`,
+	ActionFEParamGetTiposConcepto: `<ResultGet>` +
+		`<ConceptoTipo><Id>1</Id><Desc>Producto</Desc><FchDesde>20100917</FchDesde><FchHasta>NULL</FchHasta></ConceptoTipo>` +
+		`<ConceptoTipo><Id>2</Id><Desc>Servicios</Desc><FchDesde>20100917</FchDesde><FchHasta>NULL</FchHasta></ConceptoTipo>` +
+		`<ConceptoTipo><Id>3</Id><Desc>Productos y Servicios</Desc><FchDesde>20100917</FchDesde><FchHasta>NULL</FchHasta></ConceptoTipo>` +
+		`</ResultGet>`,
+	ActionFEParamGetTiposDoc: `<ResultGet>` +
+		`<DocTipo><Id>80</Id><Desc>CUIT</Desc><FchDesde>20080725</FchDesde><FchHasta>NULL</FchHasta></DocTipo>` +
+		`<DocTipo><Id>86</Id><Desc>CUIL</Desc><FchDesde>20080725</FchDesde><FchHasta>NULL</FchHasta></DocTipo>` +
+		`<DocTipo><Id>96</Id><Desc>DNI</Desc><FchDesde>20080725</FchDesde><FchHasta>NULL</FchHasta></DocTipo>` +
+		`<DocTipo><Id>99</Id><Desc>Doc. (Otro)</Desc><FchDesde>20080725</FchDesde><FchHasta>NULL</FchHasta></DocTipo>` +
+		`</ResultGet>`,
+	ActionFEParamGetTiposIva: `<ResultGet>` +
+		`<IvaTipo><Id>3</Id><Desc>0%</Desc><FchDesde>20090220</FchDesde><FchHasta>NULL</FchHasta></IvaTipo>` +
+		`<IvaTipo><Id>4</Id><Desc>10.5%</Desc><FchDesde>20090220</FchDesde><FchHasta>NULL</FchHasta></IvaTipo>` +
+		`<IvaTipo><Id>5</Id><Desc>21%</Desc><FchDesde>20090220</FchDesde><FchHasta>NULL</FchHasta></IvaTipo>` +
+		`<IvaTipo><Id>6</Id><Desc>27%</Desc><FchDesde>20090220</FchDesde><FchHasta>NULL</FchHasta></IvaTipo>` +
+		`<IvaTipo><Id>8</Id><Desc>5%</Desc><FchDesde>20141020</FchDesde><FchHasta>NULL</FchHasta></IvaTipo>` +
+		`<IvaTipo><Id>9</Id><Desc>2.5%</Desc><FchDesde>20141020</FchDesde><FchHasta>NULL</FchHasta></IvaTipo>` +
+		`</ResultGet>`,
+	ActionFEParamGetTiposMonedas: `<ResultGet>` +
+		`<Moneda><Id>PES</Id><Desc>Pesos Argentinos</Desc><FchDesde>20090403</FchDesde><FchHasta>NULL</FchHasta></Moneda>` +
+		`<Moneda><Id>DOL</Id><Desc>Dólar Estadounidense</Desc><FchDesde>20090403</FchDesde><FchHasta>NULL</FchHasta></Moneda>` +
+		`<Moneda><Id>060</Id><Desc>Euro</Desc><FchDesde>20090403</FchDesde><FchHasta>NULL</FchHasta></Moneda>` +
+		`<Moneda><Id>012</Id><Desc>Real</Desc><FchDesde>20090403</FchDesde><FchHasta>NULL</FchHasta></Moneda>` +
+		`<Moneda><Id>021</Id><Desc>Libra Esterlina</Desc><FchDesde>20090403</FchDesde><FchHasta>NULL</FchHasta></Moneda>` +
+		`</ResultGet>`,
+	ActionFEParamGetTiposOpcional: `<ResultGet>` +
+		`<OpcionalTipo><Id>2</Id><Desc>Excepción Vigencia RG 3668</Desc><FchDesde>20140501</FchDesde><FchHasta>20150101</FchHasta></OpcionalTipo>` +
+		`<OpcionalTipo><Id>22</Id><Desc>Anulación</Desc><FchDesde>20190401</FchDesde><FchHasta>NULL</FchHasta></OpcionalTipo>` +
+		`<OpcionalTipo><Id>27</Id><Desc>Transferencia</Desc><FchDesde>20190401</FchDesde><FchHasta>NULL</FchHasta></OpcionalTipo>` +
+		`<OpcionalTipo><Id>2101</Id><Desc>CBU del emisor</Desc><FchDesde>20190401</FchDesde><FchHasta>NULL</FchHasta></OpcionalTipo>` +
+		`<OpcionalTipo><Id>2102</Id><Desc>Alias del emisor</Desc><FchDesde>20190401</FchDesde><FchHasta>NULL</FchHasta></OpcionalTipo>` +
+		`</ResultGet>`,
+	ActionFEXAuthorize: `<FEXResultAuth><Id>1</Id><Cuit>20123456786</Cuit><Cae>74123456789012</Cae>` +
+		`<Fch_venc_Cae>20240125</Fch_venc_Cae><Fch_cbte>20240115</Fch_cbte><Resultado>A</Resultado><Reproceso>N</Reproceso>` +
+		`<Motivos_Obs></Motivos_Obs><Punto_vta>1</Punto_vta><Cbte_nro>1</Cbte_nro><Cbte_tipo>19</Cbte_tipo></FEXResultAuth>`,
+	ActionFEXCheckPermiso: `<FEXResultGet><Status>OK</Status></FEXResultGet>`,
+	ActionFEXGetLastCMP:   `<FEXResult_LastCMP><Cbte_nro>0</Cbte_nro><Cbte_fecha></Cbte_fecha></FEXResult_LastCMP>`,
+	ActionFEXGetPARAMCtz:  `<FEXResultGet><Mon_id>DOL</Mon_id><Mon_ctz>1050.5</Mon_ctz><Fch_cotiz>20240115</Fch_cotiz></FEXResultGet>`,
+	ActionFEXGetPARAMDSTPais: `<FEXResultGet>` +
+		`<ClsFEXResponse_DST_pais><DST_Codigo>203</DST_Codigo><DST_Ds>BRASIL</DST_Ds></ClsFEXResponse_DST_pais>` +
+		`<ClsFEXResponse_DST_pais><DST_Codigo>208</DST_Codigo><DST_Ds>CHILE</DST_Ds></ClsFEXResponse_DST_pais>` +
+		`<ClsFEXResponse_DST_pais><DST_Codigo>212</DST_Codigo><DST_Ds>ESTADOS UNIDOS</DST_Ds></ClsFEXResponse_DST_pais>` +
+		`</FEXResultGet>`,
+	ActionFEXGetPARAMIncoterms: `<FEXResultGet>` +
+		`<ClsFEXResponse_Inc><Inc_Id>EXW</Inc_Id><Inc_Ds>EXW</Inc_Ds><Inc_vig_desde>20100101</Inc_vig_desde><Inc_vig_hasta>NULL</Inc_vig_hasta></ClsFEXResponse_Inc>` +
+		`<ClsFEXResponse_Inc><Inc_Id>FOB</Inc_Id><Inc_Ds>FOB</Inc_Ds><Inc_vig_desde>20100101</Inc_vig_desde><Inc_vig_hasta>NULL</Inc_vig_hasta></ClsFEXResponse_Inc>` +
+		`<ClsFEXResponse_Inc><Inc_Id>CIF</Inc_Id><Inc_Ds>CIF</Inc_Ds><Inc_vig_desde>20100101</Inc_vig_desde><Inc_vig_hasta>NULL</Inc_vig_hasta></ClsFEXResponse_Inc>` +
+		`</FEXResultGet>`,
+	ActionFEXGetPARAMMON: `<FEXResultGet>` +
+		`<ClsFEXResponse_Mon><Mon_Id>DOL</Mon_Id><Mon_Ds>Dólar Estadounidense</Mon_Ds><Mon_vig_desde>20100101</Mon_vig_desde><Mon_vig_hasta>NULL</Mon_vig_hasta></ClsFEXResponse_Mon>` +
+		`<ClsFEXResponse_Mon><Mon_Id>060</Mon_Id><Mon_Ds>Euro</Mon_Ds><Mon_vig_desde>20100101</Mon_vig_desde><Mon_vig_hasta>NULL</Mon_vig_hasta></ClsFEXResponse_Mon>` +
+		`</FEXResultGet>`,
+	ActionFEXGetPARAMTipoCbte: `<FEXResultGet>` +
+		`<ClsFEXResponse_Cbte_Tipo><Cbte_Id>19</Cbte_Id><Cbte_Ds>Factura E</Cbte_Ds><Cbte_vig_desde>20100101</Cbte_vig_desde><Cbte_vig_hasta>NULL</Cbte_vig_hasta></ClsFEXResponse_Cbte_Tipo>` +
+		`<ClsFEXResponse_Cbte_Tipo><Cbte_Id>20</Cbte_Id><Cbte_Ds>Nota de Débito E</Cbte_Ds><Cbte_vig_desde>20100101</Cbte_vig_desde><Cbte_vig_hasta>NULL</Cbte_vig_hasta></ClsFEXResponse_Cbte_Tipo>` +
+		`<ClsFEXResponse_Cbte_Tipo><Cbte_Id>21</Cbte_Id><Cbte_Ds>Nota de Crédito E</Cbte_Ds><Cbte_vig_desde>20100101</Cbte_vig_desde><Cbte_vig_hasta>NULL</Cbte_vig_hasta></ClsFEXResponse_Cbte_Tipo>` +
+		`</FEXResultGet>`,
+	ActionFEXGetPARAMTipoExpo: `<FEXResultGet>` +
+		`<ClsFEXResponse_Tex><Tex_Id>1</Tex_Id><Tex_Ds>Exportación definitiva de Bienes</Tex_Ds><Tex_vig_desde>20100101</Tex_vig_desde><Tex_vig_hasta>NULL</Tex_vig_hasta></ClsFEXResponse_Tex>` +
+		`<ClsFEXResponse_Tex><Tex_Id>2</Tex_Id><Tex_Ds>Servicios</Tex_Ds><Tex_vig_desde>20100101</Tex_vig_desde><Tex_vig_hasta>NULL</Tex_vig_hasta></ClsFEXResponse_Tex>` +
+		`<ClsFEXResponse_Tex><Tex_Id>4</Tex_Id><Tex_Ds>Otros</Tex_Ds><Tex_vig_desde>20100101</Tex_vig_desde><Tex_vig_hasta>NULL</Tex_vig_hasta></ClsFEXResponse_Tex>` +
+		`</FEXResultGet>`,
+	ActionFEXGetPARAMUMed: `<FEXResultGet>` +
+		`<ClsFEXResponse_UMed><Umed_Id>1</Umed_Id><Umed_Ds>kilogramos</Umed_Ds><Umed_vig_desde>20100101</Umed_vig_desde><Umed_vig_hasta>NULL</Umed_vig_hasta></ClsFEXResponse_UMed>` +
+		`<ClsFEXResponse_UMed><Umed_Id>7</Umed_Id><Umed_Ds>unidades</Umed_Ds><Umed_vig_desde>20100101</Umed_vig_desde><Umed_vig_hasta>NULL</Umed_vig_hasta></ClsFEXResponse_UMed>` +
+		`<ClsFEXResponse_UMed><Umed_Id>10</Umed_Id><Umed_Ds>quilates</Umed_Ds><Umed_vig_desde>20100101</Umed_vig_desde><Umed_vig_hasta>20200101</Umed_vig_hasta></ClsFEXResponse_UMed>` +
+		`</FEXResultGet>`,
+}
+
+// fault representa un SOAP Fault configurado para una acción
+type fault struct {
+	code    string
+	message string
+}
+
+// FakeAFIPServer es un servidor httptest que simula WSAA, WSFEv1 y WSFEXv1
+type FakeAFIPServer struct {
+	*httptest.Server
+
+	mutex    sync.Mutex
+	results  map[string]string
+	faults   map[string]fault
+	statuses map[string]int
+	headers  map[string]http.Header
+	calls    map[string]int
+	bodies   map[string][]byte
+}
+
+// NewFakeAFIPServer crea e inicia un servidor AFIP simulado
+func NewFakeAFIPServer() *FakeAFIPServer {
+	s := newFakeAFIPServer()
+	s.Start()
+	return s
+}
+
+// NewFakeAFIPTLSServer crea e inicia un servidor AFIP simulado sobre HTTPS.
+// Su certificado no es de confianza por defecto: usar server.Certificate()
+// para armar el pool de CAs de Config.TLSConfig.
+func NewFakeAFIPTLSServer() *FakeAFIPServer {
+	s := newFakeAFIPServer()
+	s.StartTLS()
+	return s
+}
+
+// newFakeAFIPServer crea el servidor simulado sin iniciarlo
+func newFakeAFIPServer() *FakeAFIPServer {
+	s := &FakeAFIPServer{
+		results:  make(map[string]string),
+		faults:   make(map[string]fault),
+		statuses: make(map[string]int),
+		headers:  make(map[string]http.Header),
+		calls:    make(map[string]int),
+		bodies:   make(map[string][]byte),
+	}
+	for action, result := range defaultResults {
+		s.results[action] = result
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws/services/LoginCms", s.handleWSAA)
+	mux.HandleFunc("/wsfev1/service.asmx", s.handleService)
+	mux.HandleFunc("/wsfexv1/service.asmx", s.handleService)
+	s.Server = httptest.NewUnstartedServer(mux)
+
+	return s
+}
+
+// Config retorna una configuración de testing apuntada al servidor, con un
+// certificado autofirmado generado para el CUIT 20-12345678-6
+func (s *FakeAFIPServer) Config() (client.Config, error) {
+	cert, key, err := GenerateCertificate("20123456786")
+	if err != nil {
+		return client.Config{}, err
+	}
+
+	config := client.DefaultConfig()
+	config.Environment = models.EnvironmentTesting
+	config.CUIT = "20-12345678-6"
+	config.Certificate = cert
+	config.PrivateKey = key
+	config.RetryDelay = 10 * time.Millisecond
+	config.BaseURLOverride = s.URL
+
+	return config, nil
+}
+
+// SetResult reemplaza el contenido del elemento <Método>Result para una acción.
+// Los errores de negocio de AFIP se simulan incluyendo <Errors><Err>...</Err></Errors>.
+func (s *FakeAFIPServer) SetResult(action, resultXML string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.results[action] = resultXML
+}
+
+// SetFault hace que una acción responda con un SOAP Fault
+func (s *FakeAFIPServer) SetFault(action, code, message string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.faults[action] = fault{code: code, message: message}
+}
+
+// SetHTTPStatus hace que una acción responda con el status HTTP dado y sin cuerpo
+func (s *FakeAFIPServer) SetHTTPStatus(action string, status int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.statuses[action] = status
+}
+
+// SetHeader agrega un header HTTP a las respuestas de una acción (por ejemplo
+// Retry-After junto con un status 429)
+func (s *FakeAFIPServer) SetHeader(action, name, value string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.headers[action] == nil {
+		s.headers[action] = make(http.Header)
+	}
+	s.headers[action].Set(name, value)
+}
+
+// Reset restaura las respuestas por defecto y limpia los contadores
+func (s *FakeAFIPServer) Reset() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.results = make(map[string]string)
+	for action, result := range defaultResults {
+		s.results[action] = result
+	}
+	s.faults = make(map[string]fault)
+	s.statuses = make(map[string]int)
+	s.headers = make(map[string]http.Header)
+	s.calls = make(map[string]int)
+	s.bodies = make(map[string][]byte)
+}
+
+// Calls retorna la cantidad de llamadas recibidas para una acción
+func (s *FakeAFIPServer) Calls(action string) int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.calls[action]
+}
+
+// LastRequest retorna el último envelope SOAP recibido para una acción
+func (s *FakeAFIPServer) LastRequest(action string) []byte {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.bodies[action]
+}
+
+// handleWSAA responde a loginCms con un loginTicketResponse válido por 12 horas
+func (s *FakeAFIPServer) handleWSAA(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	if s.handleConfigured(w, ActionLoginCms, body) {
+		return
+	}
+
+	now := time.Now()
+	ticket := fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<loginTicketResponse version="1.0"><header><source>CN=wsaahomo, O=AFIP, C=AR, SERIALNUMBER=CUIT 33693450239</source>`+
+		`<destination>SERIALNUMBER=CUIT 20123456786</destination><uniqueId>1</uniqueId>`+
+		`<generationTime>%s</generationTime><expirationTime>%s</expirationTime></header>`+
+		`<credentials><token>%s</token><sign>%s</sign></credentials></loginTicketResponse>`,
+		now.Format(time.RFC3339), now.Add(12*time.Hour).Format(time.RFC3339), FakeToken, FakeSign)
+
+	var escaped bytes.Buffer
+	_ = xml.EscapeText(&escaped, []byte(ticket))
+
+	writeEnvelope(w, http.StatusOK, fmt.Sprintf(
+		`<loginCmsResponse xmlns="http://wsaa.view.sua.dvadac.desein.afip.gov"><loginCmsReturn>%s</loginCmsReturn></loginCmsResponse>`,
+		escaped.String()))
+}
+
+// handleService responde a los métodos de WSFEv1/WSFEXv1
+func (s *FakeAFIPServer) handleService(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	action := requestAction(r, body)
+	if s.handleConfigured(w, action, body) {
+		return
+	}
+
+	s.mutex.Lock()
+	result, ok := s.results[action]
+	s.mutex.Unlock()
+
+	if !ok {
+		writeFault(w, "soap:Client", fmt.Sprintf("Acción no soportada por el servidor simulado: %s", action))
+		return
+	}
+
+	writeEnvelope(w, http.StatusOK, fmt.Sprintf(
+		`<%[1]sResponse xmlns="http://ar.gov.afip.dif.FEV1/"><%[1]sResult>%[2]s</%[1]sResult></%[1]sResponse>`,
+		action, result))
+}
+
+// handleConfigured registra la llamada y responde con el fault o status
+// configurado, si lo hay. Retorna true si ya se escribió la respuesta.
+func (s *FakeAFIPServer) handleConfigured(w http.ResponseWriter, action string, body []byte) bool {
+	s.mutex.Lock()
+	s.calls[action]++
+	s.bodies[action] = body
+	f, hasFault := s.faults[action]
+	status, hasStatus := s.statuses[action]
+	for name, values := range s.headers[action] {
+		w.Header()[name] = values
+	}
+	s.mutex.Unlock()
+
+	switch {
+	case hasStatus:
+		w.WriteHeader(status)
+		return true
+	case hasFault:
+		writeFault(w, f.code, f.message)
+		return true
+	}
+
+	return false
+}
+
+// requestAction determina la acción a partir del header SOAPAction o, si no
+// está presente, del primer elemento dentro del Body
+func requestAction(r *http.Request, body []byte) string {
+	if action := strings.Trim(r.Header.Get("SOAPAction"), `"`); action != "" {
+		if i := strings.LastIndex(action, "/"); i >= 0 {
+			action = action[i+1:]
+		}
+		return action
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	inBody := false
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return ""
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			if inBody {
+				return start.Name.Local
+			}
+			inBody = start.Name.Local == "Body"
+		}
+	}
+}
+
+// writeEnvelope escribe un envelope SOAP con el contenido dado en el Body
+func writeEnvelope(w http.ResponseWriter, status int, content string) {
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="utf-8"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body>%s</soap:Body></soap:Envelope>`, content)
+}
+
+// writeFault escribe un SOAP Fault con HTTP 500, como lo hacen los servicios .asmx
+func writeFault(w http.ResponseWriter, code, message string) {
+	var escaped bytes.Buffer
+	_ = xml.EscapeText(&escaped, []byte(message))
+
+	writeEnvelope(w, http.StatusInternalServerError, fmt.Sprintf(
+		`<soap:Fault><faultcode>%s</faultcode><faultstring>%s</faultstring></soap:Fault>`, code, escaped.String()))
+}
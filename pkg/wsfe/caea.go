@@ -0,0 +1,207 @@
+package wsfe
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dlarregola/arca_invoice_lib/pkg/models"
+)
+
+// CAEAService define las operaciones de CAEA que usa CAEASession.
+// Service la implementa; los tests pueden reemplazarla por un mock.
+type CAEAService interface {
+	GetCAEA(ctx context.Context, period, order, fiscalYear int) (*CAEAResponse, error)
+	RegisterCAEAInvoices(ctx context.Context, caea string, invoices []*Invoice) ([]*models.AuthorizationResult, error)
+	ReportCAEANoMovement(ctx context.Context, caea string, pointOfSale int) error
+}
+
+// CAEASessionState representa el estado de una sesión CAEA
+type CAEASessionState string
+
+const (
+	CAEASessionNew    CAEASessionState = "new"
+	CAEASessionActive CAEASessionState = "active"
+	CAEASessionClosed CAEASessionState = "closed"
+)
+
+// CAEASession coordina el ciclo de vida de un CAEA: solicitarlo, emitir
+// comprobantes offline, informarlos y declarar los puntos de venta sin movimiento
+type CAEASession struct {
+	service CAEAService
+
+	Period     int
+	Order      int
+	FiscalYear int
+
+	CAEA           string
+	ValidFrom      time.Time
+	DueDate        time.Time
+	ReportDeadline time.Time
+	MaxAmount      float64
+
+	state      CAEASessionState
+	pending    map[caeaGroup][]*Invoice
+	registered map[int]int
+	mutex      sync.Mutex
+}
+
+// caeaGroup agrupa comprobantes que se informan en una misma llamada
+type caeaGroup struct {
+	pointOfSale int
+	invoiceType models.InvoiceType
+}
+
+// NewCAEASession crea una nueva sesión CAEA para el período y orden dados
+func NewCAEASession(service CAEAService, period, order, fiscalYear int) *CAEASession {
+	return &CAEASession{
+		service:    service,
+		Period:     period,
+		Order:      order,
+		FiscalYear: fiscalYear,
+		state:      CAEASessionNew,
+		pending:    make(map[caeaGroup][]*Invoice),
+		registered: make(map[int]int),
+	}
+}
+
+// Start solicita el CAEA a AFIP y activa la sesión
+func (s *CAEASession) Start(ctx context.Context) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.state != CAEASessionNew {
+		return fmt.Errorf("CAEA session already started")
+	}
+
+	response, err := s.service.GetCAEA(ctx, s.Period, s.Order, s.FiscalYear)
+	if err != nil {
+		return fmt.Errorf("error requesting CAEA: %w", err)
+	}
+
+	s.CAEA = response.Result.CAEA
+	s.ValidFrom = response.Result.ValidFrom.Time
+	s.DueDate = response.Result.DueDate.Time
+	s.ReportDeadline = response.Result.ReportDeadline.Time
+	s.MaxAmount = response.Result.MaxAmount
+	s.state = CAEASessionActive
+
+	return nil
+}
+
+// State retorna el estado actual de la sesión
+func (s *CAEASession) State() CAEASessionState {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.state
+}
+
+// IssueInvoice emite un comprobante offline con el CAEA de la sesión.
+// El comprobante queda pendiente hasta que se llame a Register.
+func (s *CAEASession) IssueInvoice(invoice *Invoice) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.state != CAEASessionActive {
+		return fmt.Errorf("CAEA session is not active")
+	}
+
+	if !s.DueDate.IsZero() && time.Now().After(s.DueDate) {
+		return models.NewARCAError(models.ErrorCodeInvalidDate, fmt.Sprintf("CAEA %s vencido el %s", s.CAEA, s.DueDate.Format("2006-01-02")))
+	}
+
+	if s.MaxAmount > 0 && invoice.TotalAmount > s.MaxAmount {
+		return models.NewValidationError("total_amount", fmt.Sprintf("El monto excede el máximo por comprobante del CAEA (%.2f)", s.MaxAmount), invoice.TotalAmount)
+	}
+
+	invoice.CAE = s.CAEA
+	invoice.CAEDueDate = s.DueDate
+
+	group := caeaGroup{pointOfSale: invoice.PointOfSale, invoiceType: invoice.InvoiceType}
+	s.pending[group] = append(s.pending[group], invoice)
+
+	return nil
+}
+
+// PendingCount retorna la cantidad de comprobantes emitidos aún no informados
+func (s *CAEASession) PendingCount() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	count := 0
+	for _, invoices := range s.pending {
+		count += len(invoices)
+	}
+	return count
+}
+
+// Register informa a AFIP los comprobantes pendientes, agrupados por punto de venta y tipo
+func (s *CAEASession) Register(ctx context.Context) ([]*models.AuthorizationResult, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.state != CAEASessionActive {
+		return nil, fmt.Errorf("CAEA session is not active")
+	}
+
+	return s.registerPending(ctx)
+}
+
+// registerPending informa los comprobantes pendientes; requiere tener el lock
+func (s *CAEASession) registerPending(ctx context.Context) ([]*models.AuthorizationResult, error) {
+	groups := make([]caeaGroup, 0, len(s.pending))
+	for group := range s.pending {
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].pointOfSale != groups[j].pointOfSale {
+			return groups[i].pointOfSale < groups[j].pointOfSale
+		}
+		return groups[i].invoiceType < groups[j].invoiceType
+	})
+
+	var results []*models.AuthorizationResult
+	for _, group := range groups {
+		invoices := s.pending[group]
+		groupResults, err := s.service.RegisterCAEAInvoices(ctx, s.CAEA, invoices)
+		if err != nil {
+			return results, fmt.Errorf("error registering CAEA invoices for point of sale %d: %w", group.pointOfSale, err)
+		}
+		results = append(results, groupResults...)
+		s.registered[group.pointOfSale] += len(invoices)
+		delete(s.pending, group)
+	}
+
+	return results, nil
+}
+
+// Close informa los comprobantes pendientes, declara sin movimiento los
+// puntos de venta dados que no emitieron comprobantes y cierra la sesión
+func (s *CAEASession) Close(ctx context.Context, pointsOfSale ...int) ([]*models.AuthorizationResult, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.state != CAEASessionActive {
+		return nil, fmt.Errorf("CAEA session is not active")
+	}
+
+	results, err := s.registerPending(ctx)
+	if err != nil {
+		return results, err
+	}
+
+	for _, pointOfSale := range pointsOfSale {
+		if s.registered[pointOfSale] > 0 {
+			continue
+		}
+		if err := s.service.ReportCAEANoMovement(ctx, s.CAEA, pointOfSale); err != nil {
+			return results, fmt.Errorf("error reporting no movement for point of sale %d: %w", pointOfSale, err)
+		}
+	}
+
+	s.state = CAEASessionClosed
+	return results, nil
+}
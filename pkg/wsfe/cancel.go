@@ -0,0 +1,86 @@
+package wsfe
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dlarregola/arca_invoice_lib/pkg/models"
+)
+
+// CancelInvoice anula un comprobante autorizado emitiendo la nota de crédito
+// de la misma letra (ver models.InvoiceType.CreditNoteType) por el total del
+// original y la autoriza. Los datos del comprobante (receptor, concepto,
+// moneda, importes, alícuotas de IVA e importes no gravados y exentos) se
+// consultan con FECompConsultar; la
+// nota se numera con NextInvoiceNumber, lleva la fecha del día y asocia el
+// original en CbtesAsoc. Si el vencimiento de pago del original ya pasó, la
+// nota vence en el día. Las notas de crédito FCE MiPyME se informan como
+// anulación del comprobante asociado.
+//
+// Los comprobantes con tributos no se pueden anular automáticamente:
+// FECompConsultar no informa su detalle.
+func (s *Service) CancelInvoice(ctx context.Context, original *models.AuthorizationResult) (*models.AuthorizationResult, error) {
+	// Validar parámetros
+	if original == nil {
+		return nil, models.NewValidationError("original", "Comprobante a anular obligatorio", nil)
+	}
+	if original.Status != models.AuthResultApproved || original.CAE == "" {
+		return nil, models.NewValidationError("original", "Sólo se pueden anular comprobantes aprobados con CAE", original.Status)
+	}
+	creditNoteType, ok := original.InvoiceType.CreditNoteType()
+	if !ok {
+		return nil, models.NewValidationError("invoice_type", fmt.Sprintf("%s no se puede anular con una nota de crédito", original.InvoiceType), original.InvoiceType)
+	}
+
+	// Consultar comprobante original
+	response, err := s.queryInvoice(ctx, original.PointOfSale, int(original.InvoiceType), original.InvoiceNumber)
+	if err != nil {
+		return nil, fmt.Errorf("error querying original invoice: %w", err)
+	}
+	if response.Result.TributeAmount != 0 {
+		return nil, models.NewValidationError("original", "No se pueden anular automáticamente comprobantes con tributos", original.InvoiceType)
+	}
+	invoice := invoiceFromQuery(response)
+	if invoice.CAE != original.CAE {
+		return nil, models.NewValidationError("cae", "El CAE no coincide con el que AFIP informa para el comprobante", original.CAE)
+	}
+
+	number, err := s.NextInvoiceNumber(ctx, original.PointOfSale, int(creditNoteType))
+	if err != nil {
+		return nil, err
+	}
+
+	// Crear nota de crédito
+	now := s.config.Now()
+	creditNote := &Invoice{
+		InvoiceBase:          invoice.InvoiceBase,
+		DocType:              invoice.DocType,
+		DocNumber:            invoice.DocNumber,
+		DocTypeFrom:          models.DocumentTypeCUIT,
+		DocNumberFrom:        models.FormatCUIT(s.config.GetAuthCUIT()),
+		ServiceFrom:          invoice.ServiceFrom,
+		ServiceTo:            invoice.ServiceTo,
+		PaymentDueDate:       invoice.PaymentDueDate,
+		ReceptorIVACondition: invoice.ReceptorIVACondition,
+		AssociatedInvoices: []models.AssociatedInvoice{{
+			InvoiceType:   original.InvoiceType,
+			PointOfSale:   original.PointOfSale,
+			InvoiceNumber: original.InvoiceNumber,
+			CUIT:          s.config.GetAuthCUIT(),
+		}},
+	}
+	creditNote.InvoiceType = creditNoteType
+	creditNote.InvoiceNumber = number
+	creditNote.IssueDate = now
+	if creditNote.DocType == models.DocumentTypeCUIT || creditNote.DocType == models.DocumentTypeCUIL {
+		creditNote.DocNumber = models.FormatCUIT(creditNote.DocNumber)
+	}
+	if !creditNote.PaymentDueDate.IsZero() && creditNote.PaymentDueDate.Before(now) {
+		creditNote.PaymentDueDate = now
+	}
+	if creditNoteType.IsFCE() {
+		creditNote.FCE = &models.FCEData{Cancelled: true}
+	}
+
+	return s.AuthorizeInvoice(ctx, creditNote)
+}
@@ -0,0 +1,116 @@
+package wsfe
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dlarregola/arca_invoice_lib/pkg/models"
+)
+
+// IssuedInvoice es el resultado de IssueInvoice: el comprobante autorizado
+// con su número, su CAE y la URL del QR a imprimir
+type IssuedInvoice struct {
+	Invoice           *Invoice                    `json:"invoice"`
+	Result            *models.AuthorizationResult `json:"result"`
+	InvoiceNumber     int                         `json:"invoice_number"`
+	CAE               string                      `json:"cae"`
+	CAEExpirationDate time.Time                   `json:"cae_expiration_date"`
+	QRURL             string                      `json:"qr_url"`
+}
+
+// IssueInvoice emite una factura en una sola llamada: si InvoiceNumber es 0 lo
+// completa con NextInvoiceNumber, autoriza la factura con AuthorizeInvoice y
+// arma la URL del QR. La factura queda con el número, el CAE y su
+// vencimiento. Si AFIP rechaza el comprobante retorna error con sus
+// observaciones.
+//
+// Para lotes, reintentos o CAEA use los métodos individuales. Como
+// NextInvoiceNumber, no reserva el número: si varios procesos emiten en el
+// mismo punto de venta deben coordinarse con un lock externo.
+func (s *Service) IssueInvoice(ctx context.Context, invoice *Invoice) (*IssuedInvoice, error) {
+	// Numerar el comprobante
+	if invoice.InvoiceNumber == 0 {
+		next, err := s.NextInvoiceNumber(ctx, invoice.PointOfSale, int(invoice.InvoiceType))
+		if err != nil {
+			return nil, fmt.Errorf("error getting next invoice number: %w", err)
+		}
+		invoice.InvoiceNumber = next
+	}
+
+	// Autorizar
+	result, err := s.AuthorizeInvoice(ctx, invoice)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Status.IsApproved() || result.CAE == "" {
+		return nil, fmt.Errorf("invoice %s %s rejected: %s", invoice.InvoiceType, models.FormatInvoiceNumber(invoice.PointOfSale, invoice.InvoiceNumber), result.Message)
+	}
+	invoice.CAE = result.CAE
+	invoice.CAEDueDate = result.CAEExpirationDate
+
+	// Armar QR
+	qrURL, err := s.QRURL(invoice)
+	if err != nil {
+		return nil, err
+	}
+
+	return &IssuedInvoice{
+		Invoice:           invoice,
+		Result:            result,
+		InvoiceNumber:     invoice.InvoiceNumber,
+		CAE:               result.CAE,
+		CAEExpirationDate: result.CAEExpirationDate,
+		QRURL:             qrURL,
+	}, nil
+}
+
+// QRURL retorna la URL del código QR (RG 4892/2020) de una factura ya
+// autorizada, con el CUIT emisor de la configuración y el CAE de la factura
+func (s *Service) QRURL(invoice *Invoice) (string, error) {
+	cuit, err := strconv.ParseInt(s.config.GetAuthCUIT(), 10, 64)
+	if err != nil {
+		return "", models.NewValidationError("cuit", "CUIT del emisor no válido para el QR", s.config.GetAuthCUIT())
+	}
+	authCode, err := strconv.ParseInt(invoice.CAE, 10, 64)
+	if err != nil {
+		return "", models.NewValidationError("cae", "La factura no tiene un CAE válido para el QR", invoice.CAE)
+	}
+
+	currencyRate := invoice.CurrencyRate
+	if currencyRate == 0 {
+		currencyRate = 1
+	}
+
+	qr := models.QRData{
+		Version:       1,
+		Date:          invoice.GetIssueDate().Format("2006-01-02"),
+		CUIT:          cuit,
+		PointOfSale:   invoice.PointOfSale,
+		InvoiceType:   int(invoice.InvoiceType),
+		InvoiceNumber: invoice.InvoiceNumber,
+		Amount:        invoice.TotalAmount,
+		Currency:      string(invoice.CurrencyType),
+		CurrencyRate:  currencyRate,
+		AuthType:      models.QRAuthTypeCAE,
+		AuthCode:      authCode,
+	}
+	if docNumber, err := strconv.ParseInt(digits(invoice.DocNumber), 10, 64); err == nil && docNumber != 0 {
+		qr.DocType = int(invoice.DocType)
+		qr.DocNumber = docNumber
+	}
+
+	return qr.URL()
+}
+
+// digits retorna sólo los dígitos de value (ej. un CUIT con guiones)
+func digits(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, value)
+}
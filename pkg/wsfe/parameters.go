@@ -0,0 +1,301 @@
+package wsfe
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dlarregola/arca_invoice_lib/internal/batch"
+	"github.com/dlarregola/arca_invoice_lib/internal/utils"
+	"github.com/dlarregola/arca_invoice_lib/pkg/models"
+)
+
+// DefaultParametersTTL es el tiempo que GetParameters reutiliza los
+// catálogos consultados. AFIP los modifica muy de vez en cuando.
+const DefaultParametersTTL = 24 * time.Hour
+
+// SetParametersTTL configura cuánto tiempo GetParameters reutiliza los
+// catálogos consultados (DefaultParametersTTL si es 0)
+func (s *Service) SetParametersTTL(ttl time.Duration) {
+	s.parametersMutex.Lock()
+	defer s.parametersMutex.Unlock()
+
+	s.parametersTTL = ttl
+}
+
+// GetParameters retorna los catálogos de AFIP: tipos de documento, de
+// comprobante y de concepto, monedas, alícuotas y puntos de venta. Los
+// catálogos se consultan con WarmupParameters y se reutilizan, por ambiente,
+// mientras no venza el TTL (ver SetParametersTTL).
+func (s *Service) GetParameters(ctx context.Context) (*models.Parameters, error) {
+	s.parametersMutex.Lock()
+	params, ttl := s.parameters[s.environment(ctx)], s.parametersTTL
+	s.parametersMutex.Unlock()
+
+	if ttl <= 0 {
+		ttl = DefaultParametersTTL
+	}
+	if params != nil && s.config.Now().Sub(params.LastUpdate) < ttl {
+		return params, nil
+	}
+
+	return s.WarmupParameters(ctx)
+}
+
+// WarmupParameters consulta todos los catálogos de AFIP en paralelo y los
+// deja en cache, reemplazando los anteriores del mismo ambiente (incluidas
+// las alícuotas, monedas y tipos de opcional que usan las validaciones en
+// vivo). Conviene llamarlo al iniciar la aplicación para que el primer
+// formulario no espere a AFIP.
+// LastUpdate es el momento de la consulta.
+func (s *Service) WarmupParameters(ctx context.Context) (*models.Parameters, error) {
+	// Obtener el ticket antes de las consultas en paralelo, para pedirlo a
+	// WSAA una sola vez
+	if _, err := s.auth.GetAccessTicket(ctx, "wsfe"); err != nil {
+		return nil, fmt.Errorf("error getting access ticket: %w", err)
+	}
+
+	env := s.environment(ctx)
+
+	s.taxRatesMutex.Lock()
+	delete(s.taxRates, env)
+	s.taxRatesMutex.Unlock()
+
+	s.currencyTypesMutex.Lock()
+	delete(s.currencyTypes, env)
+	s.currencyTypesMutex.Unlock()
+
+	s.optionalTypesMutex.Lock()
+	delete(s.optionalTypes, env)
+	s.optionalTypesMutex.Unlock()
+
+	params := &models.Parameters{LastUpdate: s.config.Now()}
+	fetchers := []func(ctx context.Context) error{
+		func(ctx context.Context) (err error) {
+			params.DocumentTypes, err = s.GetDocumentTypes(ctx)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			params.InvoiceTypes, err = s.GetInvoiceTypes(ctx)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			params.CurrencyTypes, err = s.GetCurrencyTypes(ctx)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			params.TaxRates, err = s.GetTaxRates(ctx)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			params.ConceptTypes, err = s.GetConceptTypes(ctx)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			params.PointsOfSale, err = s.GetPointsOfSale(ctx)
+			return err
+		},
+	}
+
+	_, errs := batch.Run(ctx, fetchers, len(fetchers), func(ctx context.Context, fetch func(context.Context) error) (struct{}, error) {
+		return struct{}{}, fetch(ctx)
+	})
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	s.parametersMutex.Lock()
+	if s.parameters == nil {
+		s.parameters = make(map[models.Environment]*models.Parameters)
+	}
+	s.parameters[env] = params
+	s.parametersMutex.Unlock()
+
+	return params, nil
+}
+
+// GetDocumentTypes obtiene los tipos de documento de AFIP (FEParamGetTiposDoc)
+func (s *Service) GetDocumentTypes(ctx context.Context) ([]models.DocumentTypeInfo, error) {
+	// Obtener ticket de acceso
+	ticket, err := s.auth.GetAccessTicket(ctx, "wsfe")
+	if err != nil {
+		return nil, fmt.Errorf("error getting access ticket: %w", err)
+	}
+
+	// Crear request
+	request := &ParametersRequest{}
+	request.Auth = NewAuth(s.config, ticket)
+
+	// Realizar llamada SOAP
+	var response DocumentTypesResponse
+	if err := s.callSOAP(ctx, "FEParamGetTiposDoc", request, &response); err != nil {
+		return nil, err
+	}
+
+	// Verificar errores
+	if len(response.Errors) > 0 {
+		error := response.Errors[0]
+		return nil, models.NewServiceError(error.Code, error.Message)
+	}
+
+	documentTypes := make([]models.DocumentTypeInfo, 0, len(response.DocumentTypes))
+	for _, dt := range response.DocumentTypes {
+		documentTypes = append(documentTypes, models.DocumentTypeInfo{
+			ID:          models.DocumentType(dt.ID),
+			Description: dt.Description,
+			Active:      utils.IsActiveParameter(dt.DateTo),
+		})
+	}
+
+	return documentTypes, nil
+}
+
+// GetConceptTypes obtiene los tipos de concepto de AFIP (FEParamGetTiposConcepto)
+func (s *Service) GetConceptTypes(ctx context.Context) ([]models.ConceptTypeInfo, error) {
+	// Obtener ticket de acceso
+	ticket, err := s.auth.GetAccessTicket(ctx, "wsfe")
+	if err != nil {
+		return nil, fmt.Errorf("error getting access ticket: %w", err)
+	}
+
+	// Crear request
+	request := &ParametersRequest{}
+	request.Auth = NewAuth(s.config, ticket)
+
+	// Realizar llamada SOAP
+	var response ConceptTypesResponse
+	if err := s.callSOAP(ctx, "FEParamGetTiposConcepto", request, &response); err != nil {
+		return nil, err
+	}
+
+	// Verificar errores
+	if len(response.Errors) > 0 {
+		error := response.Errors[0]
+		return nil, models.NewServiceError(error.Code, error.Message)
+	}
+
+	conceptTypes := make([]models.ConceptTypeInfo, 0, len(response.ConceptTypes))
+	for _, ct := range response.ConceptTypes {
+		conceptTypes = append(conceptTypes, models.ConceptTypeInfo{
+			ID:          models.ConceptType(ct.ID),
+			Description: ct.Description,
+			Active:      utils.IsActiveParameter(ct.DateTo),
+		})
+	}
+
+	return conceptTypes, nil
+}
+
+// GetPointsOfSale obtiene los puntos de venta habilitados para web services
+// (FEParamGetPtosVenta). Si el contribuyente no tiene ninguno AFIP informa
+// el error 602 (sin resultados) y se retorna una lista vacía.
+func (s *Service) GetPointsOfSale(ctx context.Context) ([]models.PointOfSaleInfo, error) {
+	// Obtener ticket de acceso
+	ticket, err := s.auth.GetAccessTicket(ctx, "wsfe")
+	if err != nil {
+		return nil, fmt.Errorf("error getting access ticket: %w", err)
+	}
+
+	// Crear request
+	request := &ParametersRequest{}
+	request.Auth = NewAuth(s.config, ticket)
+
+	// Realizar llamada SOAP
+	var response PointsOfSaleResponse
+	if err := s.callSOAP(ctx, "FEParamGetPtosVenta", request, &response); err != nil {
+		return nil, err
+	}
+
+	// Verificar errores
+	if len(response.Errors) > 0 {
+		error := response.Errors[0]
+		if error.Code == models.ErrorCodeInvoiceNotFound {
+			return []models.PointOfSaleInfo{}, nil
+		}
+		return nil, models.NewServiceError(error.Code, error.Message)
+	}
+
+	pointsOfSale := make([]models.PointOfSaleInfo, 0, len(response.PointsOfSale))
+	for _, pos := range response.PointsOfSale {
+		pointsOfSale = append(pointsOfSale, models.PointOfSaleInfo{
+			Number:       pos.Number,
+			EmissionType: pos.EmissionType,
+			Blocked:      pos.Blocked == "S",
+			Active:       utils.IsActiveParameter(pos.DateTo),
+		})
+	}
+
+	return pointsOfSale, nil
+}
+
+// GetAllowedInvoiceTypes retorna los tipos de comprobante que puede emitir
+// el punto de venta, combinando los puntos de venta habilitados para web
+// services (FEParamGetPtosVenta) con los tipos de comprobante vigentes
+// (FEParamGetTiposCbte). Usa los catálogos de GetParameters, por lo que
+// respeta su cache. Si el punto de venta no está habilitado para web
+// services, está bloqueado, fue dado de baja o sólo emite con CAEA (no
+// puede usar FECAESolicitar) retorna un ValidationError.
+func (s *Service) GetAllowedInvoiceTypes(ctx context.Context, pointOfSale int) ([]models.InvoiceType, error) {
+	// Validar parámetros
+	if err := utils.ValidatePointOfSale(pointOfSale); err != nil {
+		return nil, err
+	}
+
+	params, err := s.GetParameters(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var pos *models.PointOfSaleInfo
+	for i := range params.PointsOfSale {
+		if params.PointsOfSale[i].Number == pointOfSale {
+			pos = &params.PointsOfSale[i]
+			break
+		}
+	}
+	switch {
+	case pos == nil:
+		return nil, models.NewValidationError("point_of_sale", fmt.Sprintf("El punto de venta %d no está habilitado para web services en AFIP", pointOfSale), pointOfSale)
+	case pos.Blocked:
+		return nil, models.NewValidationError("point_of_sale", fmt.Sprintf("El punto de venta %d está bloqueado", pointOfSale), pointOfSale)
+	case !pos.Active:
+		return nil, models.NewValidationError("point_of_sale", fmt.Sprintf("El punto de venta %d fue dado de baja", pointOfSale), pointOfSale)
+	case !pos.AllowsCAE():
+		return nil, models.NewValidationError("point_of_sale", fmt.Sprintf("El punto de venta %d sólo emite comprobantes con CAEA (%s)", pointOfSale, pos.EmissionType), pointOfSale)
+	}
+
+	allowed := make([]models.InvoiceType, 0, len(params.InvoiceTypes))
+	for _, invoiceType := range params.InvoiceTypes {
+		if invoiceType.Active {
+			allowed = append(allowed, invoiceType.ID)
+		}
+	}
+
+	return allowed, nil
+}
+
+// SetLivePointOfSaleValidation habilita la verificación, antes de autorizar,
+// de que el punto de venta de la factura pueda emitir su tipo de comprobante
+// (ver GetAllowedInvoiceTypes)
+func (s *Service) SetLivePointOfSaleValidation(enabled bool) {
+	s.livePointsOfSale = enabled
+}
+
+// validatePointOfSaleInvoiceType valida contra AFIP que el punto de venta
+// pueda emitir el tipo de comprobante de la factura
+func (s *Service) validatePointOfSaleInvoiceType(ctx context.Context, invoice *Invoice) error {
+	allowed, err := s.GetAllowedInvoiceTypes(ctx, invoice.PointOfSale)
+	if err != nil {
+		return err
+	}
+
+	for _, invoiceType := range allowed {
+		if invoiceType == invoice.InvoiceType {
+			return nil
+		}
+	}
+
+	return models.NewValidationError("invoice_type", fmt.Sprintf("El punto de venta %d no puede emitir comprobantes tipo %d", invoice.PointOfSale, invoice.InvoiceType), invoice.InvoiceType)
+}
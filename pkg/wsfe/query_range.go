@@ -0,0 +1,80 @@
+package wsfe
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/dlarregola/arca_invoice_lib/internal/batch"
+	"github.com/dlarregola/arca_invoice_lib/internal/utils"
+	"github.com/dlarregola/arca_invoice_lib/pkg/models"
+)
+
+// MaxQueryRange es la cantidad máxima de comprobantes que consulta
+// QueryInvoiceRange en una llamada
+const MaxQueryRange = 1000
+
+// QueryInvoiceRange consulta con FECompConsultar los comprobantes from a to
+// (inclusive) del punto de venta y tipo, con a lo sumo
+// batch.DefaultConcurrency llamadas simultáneas. Ante un límite de requests
+// de AFIP cada consulta se reintenta hasta RetryAttempts veces, respetando
+// la espera indicada.
+//
+// El resultado tiene una posición por número (la del comprobante n es
+// n - from). Los números que fallan, incluidos los inexistentes en AFIP,
+// quedan en nil y sus errores se informan juntos en un *models.BatchError
+// indexado por posición; errors.Is(err, models.ErrInvoiceNotFound) indica que
+// hay huecos en la numeración.
+func (s *Service) QueryInvoiceRange(ctx context.Context, pointOfSale, invoiceType, from, to int) ([]*Invoice, error) {
+	// Validar parámetros
+	if err := utils.ValidatePointOfSale(pointOfSale); err != nil {
+		return nil, err
+	}
+	if err := utils.ValidateInvoiceType(models.InvoiceType(invoiceType)); err != nil {
+		return nil, err
+	}
+	if err := utils.ValidateInvoiceNumber(from); err != nil {
+		return nil, err
+	}
+	if err := utils.ValidateInvoiceNumber(to); err != nil {
+		return nil, err
+	}
+	if to < from {
+		return nil, models.NewValidationError("to", "El número final no puede ser menor al inicial", to)
+	}
+	if to-from+1 > MaxQueryRange {
+		return nil, models.NewValidationError("to", fmt.Sprintf("No se pueden consultar más de %d comprobantes por llamada", MaxQueryRange), to)
+	}
+
+	numbers := make([]int, 0, to-from+1)
+	for number := from; number <= to; number++ {
+		numbers = append(numbers, number)
+	}
+
+	invoices, errs := batch.Run(ctx, numbers, batch.DefaultConcurrency, func(ctx context.Context, number int) (*Invoice, error) {
+		return s.getInvoiceWithRateLimit(ctx, pointOfSale, invoiceType, number)
+	})
+	return invoices, models.NewBatchError(errs)
+}
+
+// getInvoiceWithRateLimit consulta un comprobante reintentando ante un
+// límite de requests de AFIP
+func (s *Service) getInvoiceWithRateLimit(ctx context.Context, pointOfSale, invoiceType, invoiceNumber int) (*Invoice, error) {
+	for attempt := 0; ; attempt++ {
+		invoice, err := s.GetInvoice(ctx, pointOfSale, invoiceType, invoiceNumber)
+		if !models.IsRateLimitError(err) || attempt >= s.config.RetryAttempts {
+			return invoice, err
+		}
+
+		delay := s.config.RetryDelay
+		if retryAfter := models.GetRetryAfter(err); retryAfter > delay {
+			delay = retryAfter
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package wsfe
+
+import (
+	"strings"
+
+	"github.com/dlarregola/arca_invoice_lib/pkg/models"
+)
+
+// RegimeContext representa un régimen especial de facturación (bienes usados,
+// turismo, etc.) que agrega validaciones y datos opcionales propios. El
+// servicio no infiere el régimen a partir de la factura: se activa
+// explícitamente con Service.SetRegimeContext.
+//
+// Para agregar un régimen nuevo alcanza con implementar esta interfaz:
+// Validate agrega a errors lo que el régimen exige de la factura y Optionals
+// retorna los datos opcionales que se suman a los de invoice.Optionals.
+type RegimeContext interface {
+	// Name retorna el nombre del régimen, usado en los mensajes de error
+	Name() string
+
+	// Validate agrega a errors las validaciones propias del régimen
+	Validate(invoice *Invoice, errors *models.ValidationErrors)
+
+	// Optionals retorna los datos opcionales que el régimen inyecta en el
+	// request. Puede llamarse con facturas sin validar (NewAuthorizationRequest).
+	Optionals(invoice *Invoice) []models.Optional
+}
+
+// SetRegimeContext activa un régimen especial para todas las facturas que
+// autorice el servicio. Con nil (el valor por defecto) se factura en el
+// régimen general.
+func (s *Service) SetRegimeContext(regime RegimeContext) {
+	s.regime = regime
+}
+
+// UsedGoodsRegime es el régimen de bienes usados (RG 3411). La operación no
+// discrimina IVA ni tributos y el comprobante debe informar nombre,
+// nacionalidad y domicilio del vendedor del bien (opcionales 91, 92 y 93),
+// tomados de invoice.UsedGoodsSeller.
+type UsedGoodsRegime struct{}
+
+// Name implementa RegimeContext
+func (UsedGoodsRegime) Name() string {
+	return "bienes usados"
+}
+
+// Validate implementa RegimeContext
+func (UsedGoodsRegime) Validate(invoice *Invoice, errors *models.ValidationErrors) {
+	seller := invoice.UsedGoodsSeller
+	if seller == nil {
+		errors.Add("used_goods_seller", "El régimen de bienes usados requiere los datos del vendedor", nil)
+	} else {
+		if strings.TrimSpace(seller.Name) == "" {
+			errors.Add("used_goods_seller.name", "Nombre del vendedor es requerido", seller.Name)
+		}
+		if strings.TrimSpace(seller.Nationality) == "" {
+			errors.Add("used_goods_seller.nationality", "Nacionalidad del vendedor es requerida", seller.Nationality)
+		}
+		if strings.TrimSpace(seller.Address) == "" {
+			errors.Add("used_goods_seller.address", "Domicilio del vendedor es requerido", seller.Address)
+		}
+	}
+
+	if invoice.TaxAmount != 0 {
+		errors.Add("tax_amount", "El régimen de bienes usados no discrimina IVA", invoice.TaxAmount)
+	}
+	if len(invoice.Taxes) > 0 {
+		errors.Add("taxes", "El régimen de bienes usados no admite tributos", invoice.Taxes)
+	}
+}
+
+// Optionals implementa RegimeContext
+func (UsedGoodsRegime) Optionals(invoice *Invoice) []models.Optional {
+	seller := invoice.UsedGoodsSeller
+	if seller == nil {
+		return nil
+	}
+	return []models.Optional{
+		{ID: models.OptionalIDUsedGoodsSellerName, Value: seller.Name},
+		{ID: models.OptionalIDUsedGoodsSellerNationality, Value: seller.Nationality},
+		{ID: models.OptionalIDUsedGoodsSellerAddress, Value: seller.Address},
+	}
+}
@@ -1,22 +1,58 @@
 package wsfe
 
 import (
-	"github.com/dlarregola/arca_invoice_lib/internal/utils"
-	"github.com/dlarregola/arca_invoice_lib/pkg/client"
-	"github.com/dlarregola/arca_invoice_lib/pkg/models"
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dlarregola/arca_invoice_lib/internal/batch"
+	"github.com/dlarregola/arca_invoice_lib/internal/soap"
+	"github.com/dlarregola/arca_invoice_lib/internal/utils"
+	"github.com/dlarregola/arca_invoice_lib/pkg/core"
+	"github.com/dlarregola/arca_invoice_lib/pkg/models"
 )
 
 // Service representa el servicio WSFEv1
 type Service struct {
-	config *client.Config
-	auth   *client.WSAAAuth
-	logger interface{}
+	config           *core.Config
+	auth             *core.WSAAAuth
+	logger           interface{}
+	idempotencyGuard bool
+
+	requireActivities        bool
+	regime                   RegimeContext
+	consumidorFinalThreshold float64
+
+	// Los catálogos en cache se separan por ambiente, ya que una llamada
+	// puede forzar otro con core.WithEnvironmentOverride
+	liveTaxRates  bool
+	taxRates      map[models.Environment][]models.TaxRateInfo
+	taxRatesMutex sync.Mutex
+
+	liveCurrencies     bool
+	currencyTypes      map[models.Environment][]models.CurrencyTypeInfo
+	currencyTypesMutex sync.Mutex
+
+	liveOptionals      bool
+	optionalTypes      map[models.Environment][]models.OptionalTypeInfo
+	optionalTypesMutex sync.Mutex
+
+	livePointsOfSale bool
+
+	parametersTTL   time.Duration
+	parameters      map[models.Environment]*models.Parameters
+	parametersMutex sync.Mutex
+
+	soapClient *soap.Client
+	soapOnce   sync.Once
 }
 
 // NewService crea un nuevo servicio WSFEv1
-func NewService(config *client.Config, auth *client.WSAAAuth, logger interface{}) *Service {
+func NewService(config *core.Config, auth *core.WSAAAuth, logger interface{}) *Service {
 	return &Service{
 		config: config,
 		auth:   auth,
@@ -24,6 +60,25 @@ func NewService(config *client.Config, auth *client.WSAAAuth, logger interface{}
 	}
 }
 
+// NewAuth arma el bloque Auth a partir del ticket de acceso. El CUIT
+// informado es el representado, si está configurado.
+func NewAuth(config *core.Config, ticket *core.AccessTicket) Auth {
+	return Auth{
+		Token: ticket.Token,
+		Sign:  ticket.Sign,
+		CUIT:  config.GetAuthCUIT(),
+	}
+}
+
+// SetIdempotencyGuard habilita la verificación de comprobantes ya autorizados
+// antes de reintentar una autorización fallida. Si AFIP autorizó el comprobante
+// pero la respuesta se perdió (por ejemplo, por timeout), reintentar a ciegas
+// produce un error de número duplicado; con la guarda se consulta primero
+// FECompConsultar y, si el CAE ya fue otorgado, se retorna ese resultado.
+func (s *Service) SetIdempotencyGuard(enabled bool) {
+	s.idempotencyGuard = enabled
+}
+
 // AuthorizeInvoice autoriza una factura
 func (s *Service) AuthorizeInvoice(ctx context.Context, invoice *Invoice) (*models.AuthorizationResult, error) {
 	// Validar factura
@@ -31,6 +86,137 @@ func (s *Service) AuthorizeInvoice(ctx context.Context, invoice *Invoice) (*mode
 		return nil, err
 	}
 
+	if s.liveTaxRates {
+		if err := s.validateTaxRates(ctx, invoice); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.liveCurrencies {
+		if err := s.validateCurrencyType(ctx, invoice); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.liveOptionals {
+		if err := s.validateOptionals(ctx, invoice); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.livePointsOfSale {
+		if err := s.validatePointOfSaleInvoiceType(ctx, invoice); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.idempotencyGuard {
+		return s.authorizeInvoiceWithGuard(ctx, invoice)
+	}
+
+	return s.authorizeInvoice(ctx, invoice)
+}
+
+// AuthorizeInvoicesConcurrent autoriza un lote de facturas con a lo sumo
+// concurrency llamadas simultáneas a AFIP (4 si es 0). Los
+// resultados respetan el orden del lote; las facturas que fallan quedan en
+// nil y sus errores se informan juntos en un *models.BatchError. Las facturas
+// de un mismo punto de venta y tipo deben numerarse de antemano, ya que AFIP
+// exige que se autoricen en orden correlativo.
+func (s *Service) AuthorizeInvoicesConcurrent(ctx context.Context, invoices []*Invoice, concurrency int) ([]*models.AuthorizationResult, error) {
+	results, errs := batch.Run(ctx, invoices, concurrency, s.AuthorizeInvoice)
+	return results, models.NewBatchError(errs)
+}
+
+// authorizeInvoiceWithGuard reintenta la autorización ante errores transitorios,
+// verificando antes de cada reintento si el comprobante ya fue autorizado
+func (s *Service) authorizeInvoiceWithGuard(ctx context.Context, invoice *Invoice) (*models.AuthorizationResult, error) {
+	for attempt := 0; ; attempt++ {
+		result, err := s.authorizeInvoice(ctx, invoice)
+		if err == nil || !models.IsRetryableError(err) {
+			return result, err
+		}
+
+		// El error pudo ocurrir después de que AFIP otorgara el CAE
+		existing, queryErr := s.findAuthorizedInvoice(ctx, invoice)
+		if errors.Is(queryErr, models.ErrDuplicateInvoiceNumber) {
+			return nil, queryErr
+		}
+		if queryErr == nil && existing != nil {
+			return existing, nil
+		}
+
+		if attempt >= s.config.RetryAttempts {
+			return nil, err
+		}
+
+		// Ante un límite de requests se respeta la espera indicada por AFIP
+		delay := s.config.RetryDelay
+		if retryAfter := models.GetRetryAfter(err); retryAfter > delay {
+			delay = retryAfter
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// findAuthorizedInvoice consulta si el comprobante ya tiene CAE otorgado.
+// Si el número tiene CAE pero el comprobante autorizado no coincide con
+// invoice en importe total, receptor o fecha, retorna un error que envuelve
+// models.ErrDuplicateInvoiceNumber: el CAE pertenece a otro comprobante.
+func (s *Service) findAuthorizedInvoice(ctx context.Context, invoice *Invoice) (*models.AuthorizationResult, error) {
+	existing, err := s.GetInvoice(ctx, invoice.PointOfSale, int(invoice.InvoiceType), invoice.InvoiceNumber)
+	if errors.Is(err, models.ErrInvoiceNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if existing.CAE == "" {
+		return nil, nil
+	}
+	if !sameAuthorizedInvoice(invoice, existing) {
+		return nil, fmt.Errorf("%s %s: %w", invoice.InvoiceType, models.FormatInvoiceNumber(invoice.PointOfSale, invoice.InvoiceNumber), models.ErrDuplicateInvoiceNumber)
+	}
+
+	return &models.AuthorizationResult{
+		CAE:               existing.CAE,
+		CAEExpirationDate: existing.CAEDueDate,
+		InvoiceNumber:     existing.InvoiceNumber,
+		PointOfSale:       existing.PointOfSale,
+		InvoiceType:       existing.InvoiceType,
+		AuthorizationDate: existing.IssueDate,
+		Status:            models.AuthResultApproved,
+		Message:           "Comprobante ya autorizado",
+	}, nil
+}
+
+// sameAuthorizedInvoice indica si el comprobante consultado en AFIP
+// corresponde a invoice: mismo importe total, receptor y fecha
+func sameAuthorizedInvoice(invoice, existing *Invoice) bool {
+	return utils.RoundAmount(invoice.TotalAmount) == utils.RoundAmount(existing.TotalAmount) &&
+		invoice.DocType == existing.DocType &&
+		digitsOnly(invoice.DocNumber) == digitsOnly(existing.DocNumber) &&
+		invoice.GetIssueDate().Format(utils.AFIPDateFormat) == existing.GetIssueDate().Format(utils.AFIPDateFormat)
+}
+
+// digitsOnly retorna los dígitos de un número de documento, sin guiones ni
+// espacios
+func digitsOnly(value string) string {
+	return strings.Map(func(r rune) rune {
+		if r < '0' || r > '9' {
+			return -1
+		}
+		return r
+	}, value)
+}
+
+// authorizeInvoice realiza la llamada FECAESolicitar para una factura ya validada
+func (s *Service) authorizeInvoice(ctx context.Context, invoice *Invoice) (*models.AuthorizationResult, error) {
 	// Obtener ticket de acceso
 	ticket, err := s.auth.GetAccessTicket(ctx, "wsfe")
 	if err != nil {
@@ -38,50 +224,9 @@ func (s *Service) AuthorizeInvoice(ctx context.Context, invoice *Invoice) (*mode
 	}
 
 	// Crear request
-	request := &AuthorizationRequest{}
-	request.Auth.Token = ticket.Token
-	request.Auth.Sign = ticket.Sign
-	request.Auth.CUIT = s.config.CUIT
-
-	// Configurar datos de la factura
-	request.Request.InvoiceType = int(invoice.InvoiceType)
-	request.Request.PointOfSale = invoice.PointOfSale
-	request.Request.InvoiceNumber = invoice.InvoiceNumber
-	request.Request.DateFrom = invoice.DateFrom
-	request.Request.DateTo = invoice.DateTo
-	request.Request.ServiceFrom = invoice.ServiceFrom
-	request.Request.Amount = invoice.Amount
-	request.Request.TaxAmount = invoice.TaxAmount
-	request.Request.TotalAmount = invoice.TotalAmount
-	request.Request.CurrencyType = string(invoice.CurrencyType)
-	request.Request.CurrencyRate = invoice.CurrencyRate
-	request.Request.ConceptType = int(invoice.ConceptType)
-	request.Request.DocType = int(invoice.DocType)
-	request.Request.DocNumber = invoice.DocNumber
-	request.Request.DocTypeFrom = int(invoice.DocTypeFrom)
-	request.Request.DocNumberFrom = invoice.DocNumberFrom
-	request.Request.NameFrom = invoice.NameFrom
-
-	// Configurar ítems
-	for _, item := range invoice.Items {
-		requestItem := struct {
-			Description string  `xml:"Concepto"`
-			Quantity    float64 `xml:"Cantidad"`
-			UnitPrice   float64 `xml:"PrecioUnit"`
-			TotalPrice  float64 `xml:"Importe"`
-			ProductCode string  `xml:"CodProd"`
-			UnitMeasure string  `xml:"UnidadMedida"`
-			Discount    float64 `xml:"Descuento"`
-		}{
-			Description: item.Description,
-			Quantity:    item.Quantity,
-			UnitPrice:   item.UnitPrice,
-			TotalPrice:  item.TotalPrice,
-			ProductCode: item.ProductCode,
-			UnitMeasure: item.UnitMeasure,
-			Discount:    item.Discount,
-		}
-		request.Request.Items = append(request.Request.Items, requestItem)
+	request, err := s.NewAuthorizationRequest(invoice, NewAuth(s.config, ticket))
+	if err != nil {
+		return nil, err
 	}
 
 	// Realizar llamada SOAP
@@ -93,26 +238,227 @@ func (s *Service) AuthorizeInvoice(ctx context.Context, invoice *Invoice) (*mode
 	// Verificar errores
 	if len(response.Errors) > 0 {
 		error := response.Errors[0]
-		return nil, models.NewARCAError(error.Code, error.Message)
+		return nil, models.NewServiceError(error.Code, error.Message)
 	}
 
-	// Crear resultado
+	// Crear resultado (un Resultado desconocido se conserva tal como vino)
+	status, _ := models.ParseAuthResult(response.Header.Status)
 	result := &models.AuthorizationResult{
-		CAE:               response.Result.CAE,
-		CAEExpirationDate: response.Result.CAEDueDate,
-		InvoiceNumber:     response.Result.InvoiceNumber,
-		PointOfSale:       response.Result.PointOfSale,
-		InvoiceType:       models.InvoiceType(response.Result.InvoiceType),
-		AuthorizationDate: response.Result.AuthorizationDate,
-		Status:            response.Result.Status,
-		Message:           response.Result.Message,
+		PointOfSale:       response.Header.PointOfSale,
+		InvoiceType:       models.InvoiceType(response.Header.InvoiceType),
+		AuthorizationDate: response.Header.AuthorizationDate.Time,
+		Status:            status,
 	}
+	if len(response.Details) > 0 {
+		detail := response.Details[0]
+		result.CAE = detail.CAE
+		result.CAEExpirationDate = detail.CAEDueDate.Time
+		result.InvoiceNumber = detail.InvoiceNumber
+		result.Status, _ = models.ParseAuthResult(detail.Status)
+
+		var observations []string
+		for _, observation := range detail.Observations {
+			observations = append(observations, fmt.Sprintf("%s: %s", observation.Code, observation.Message))
+		}
+		result.Message = strings.Join(observations, "; ")
+	}
+	core.LogInfof(ctx, s.logger, "Invoice %s %s authorized: status %s, CAE %s", result.InvoiceType, models.FormatInvoiceNumber(result.PointOfSale, result.InvoiceNumber), result.Status, result.CAE)
 
 	return result, nil
 }
 
-// GetInvoice consulta una factura específica
+// BuildAuthorizeRequestXML valida la factura y retorna el envelope SOAP de
+// FECAESolicitar que enviaría AuthorizeInvoice, sin llamar a WSAA ni a WSFE.
+// Como no se pide un ticket de acceso, Token y Sign quedan vacíos.
+func (s *Service) BuildAuthorizeRequestXML(invoice *Invoice) ([]byte, error) {
+	// Validar factura
+	if err := s.validateInvoice(invoice); err != nil {
+		return nil, err
+	}
+
+	// Crear request
+	request, err := s.NewAuthorizationRequest(invoice, NewAuth(s.config, &core.AccessTicket{}))
+	if err != nil {
+		return nil, err
+	}
+
+	return soap.BuildEnvelope("FECAESolicitar", request)
+}
+
+// DryRunResult es el resultado de ValidateAndBuild: los errores de
+// validación o, si la factura es válida, el request y el envelope SOAP que
+// enviaría AuthorizeInvoice
+type DryRunResult struct {
+	Request          *AuthorizationRequest
+	Envelope         []byte
+	ValidationErrors models.ValidationErrors
+}
+
+// Valid indica si la factura pasó la validación
+func (r *DryRunResult) Valid() bool {
+	return !r.ValidationErrors.HasErrors()
+}
+
+// ValidateAndBuild valida la factura y arma el request de FECAESolicitar sin
+// llamar a WSAA ni a WSFE, por lo que no consume numeración de AFIP. Sirve
+// para verificar los datos de punta a punta en CI o preproducción. Los
+// problemas de la factura se informan en ValidationErrors del resultado; el
+// error se reserva para fallas al armar el request. Las validaciones en vivo
+// (alícuotas, monedas, opcionales) no se aplican porque consultan a AFIP.
+func (s *Service) ValidateAndBuild(invoice *Invoice) (*DryRunResult, error) {
+	// Validar factura
+	result := &DryRunResult{}
+	if err := s.validateInvoice(invoice); err != nil {
+		if !errors.As(err, &result.ValidationErrors) {
+			return nil, err
+		}
+		return result, nil
+	}
+
+	// Crear request
+	request, err := s.NewAuthorizationRequest(invoice, NewAuth(s.config, &core.AccessTicket{}))
+	if err != nil {
+		return nil, err
+	}
+	envelope, err := soap.BuildEnvelope("FECAESolicitar", request)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Request = request
+	result.Envelope = envelope
+	return result, nil
+}
+
+// NewAuthorizationRequest arma el request de FECAESolicitar para una factura
+func (s *Service) NewAuthorizationRequest(invoice *Invoice, auth Auth) (*AuthorizationRequest, error) {
+	detail, err := s.newAuthorizationDetail(invoice)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &AuthorizationRequest{}
+	request.Auth = auth
+
+	// Configurar cabecera (un comprobante por request)
+	request.Request.Header.Count = 1
+	request.Request.Header.PointOfSale = invoice.PointOfSale
+	request.Request.Header.InvoiceType = int(invoice.InvoiceType)
+	request.Request.Details = []AuthorizationDetail{detail}
+
+	return request, nil
+}
+
+// newAuthorizationDetail arma el detalle de un comprobante, común a
+// FECAESolicitar y FECAEARegInformativo
+func (s *Service) newAuthorizationDetail(invoice *Invoice) (AuthorizationDetail, error) {
+	currencyRate, err := s.currencyRate(invoice)
+	if err != nil {
+		return AuthorizationDetail{}, err
+	}
+
+	// Configurar datos de la factura
+	nonTaxable, exempt := invoice.UntaxedTotals()
+	detail := AuthorizationDetail{
+		ConceptType:          int(invoice.ConceptType),
+		DocType:              int(invoice.DocType),
+		DocNumber:            invoice.DocNumber,
+		InvoiceFrom:          invoice.InvoiceNumber,
+		InvoiceTo:            invoice.InvoiceNumber,
+		InvoiceDate:          AFIPDate{Time: invoice.GetIssueDate()},
+		TotalAmount:          Amount(invoice.TotalAmount),
+		UntaxedAmount:        Amount(nonTaxable),
+		Amount:               Amount(invoice.Amount),
+		ExemptAmount:         Amount(exempt),
+		TaxAmount:            Amount(invoice.TaxAmount),
+		CurrencyType:         string(invoice.CurrencyType),
+		CurrencyRate:         currencyRate,
+		ReceptorIVACondition: invoice.ReceptorIVACondition,
+	}
+	// Configurar tributos: ImpTrib debe coincidir con la suma de Tributos
+	var tributes float64
+	for _, tax := range invoice.Taxes {
+		tributes += tax.Amount
+	}
+	detail.TributeAmount = Amount(utils.RoundAmount(tributes))
+	detail.Tributes = tributeBreakdown(invoice.Taxes)
+	if invoice.ConceptType != models.ConceptTypeProducts {
+		detail.ServiceFrom = soap.OptionalDate(invoice.ServiceFrom)
+		detail.ServiceTo = soap.OptionalDate(invoice.ServiceTo)
+		detail.PaymentDueDate = soap.OptionalDate(invoice.PaymentDueDate)
+	}
+
+	// Configurar alícuotas de IVA (los comprobantes C no discriminan IVA)
+	if !invoice.InvoiceType.IsClassC() {
+		detail.IVA = ivaBreakdown(invoice.Items)
+	}
+
+	// Configurar datos opcionales
+	for _, optional := range s.invoiceOptionals(invoice) {
+		detail.Optionals = append(detail.Optionals, Optional{
+			ID:    optional.ID,
+			Value: optional.Value,
+		})
+	}
+
+	// Configurar comprobantes o período asociados
+	for _, associated := range invoice.AssociatedInvoices {
+		detail.AssociatedInvoices = append(detail.AssociatedInvoices, AssociatedInvoice{
+			InvoiceType:   int(associated.InvoiceType),
+			PointOfSale:   associated.PointOfSale,
+			InvoiceNumber: associated.InvoiceNumber,
+			CUIT:          associated.CUIT,
+		})
+	}
+	if period := invoice.AssociatedPeriod; period != nil {
+		detail.AssociatedPeriod = &AssociatedPeriod{
+			From: AFIPDate{Time: period.From},
+			To:   AFIPDate{Time: period.To},
+		}
+	}
+
+	// Configurar actividades del emisor
+	for _, code := range invoice.ActivityCodes {
+		detail.Activities = append(detail.Activities, Activity{ID: code})
+	}
+
+	return detail, nil
+}
+
+// currencyRate resuelve la cotización a enviar según el modo configurado para PES
+func (s *Service) currencyRate(invoice *Invoice) (float64, error) {
+	if invoice.CurrencyType != models.CurrencyTypePES {
+		return invoice.CurrencyRate, nil
+	}
+
+	switch s.config.PESCurrencyRateMode {
+	case core.CurrencyRateModePassthrough:
+		return invoice.CurrencyRate, nil
+	case core.CurrencyRateModeValidate:
+		if invoice.CurrencyRate != 1 {
+			return 0, models.NewValidationError("currency_rate", "La cotización para PES debe ser 1", invoice.CurrencyRate)
+		}
+		return 1, nil
+	default:
+		return 1, nil
+	}
+}
+
+// GetInvoice consulta una factura específica. Si AFIP no tiene registrado el
+// comprobante, el error cumple errors.Is(err, models.ErrInvoiceNotFound), lo
+// que permite distinguirlo de una falla de comunicación. AFIP no informa el
+// detalle de ítems: se arma un ítem por alícuota de IVA con su base imponible.
 func (s *Service) GetInvoice(ctx context.Context, pointOfSale, invoiceType, invoiceNumber int) (*Invoice, error) {
+	response, err := s.queryInvoice(ctx, pointOfSale, invoiceType, invoiceNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	return invoiceFromQuery(response), nil
+}
+
+// queryInvoice realiza la llamada FECompConsultar
+func (s *Service) queryInvoice(ctx context.Context, pointOfSale, invoiceType, invoiceNumber int) (*QueryResponse, error) {
 	// Validar parámetros
 	if err := utils.ValidatePointOfSale(pointOfSale); err != nil {
 		return nil, err
@@ -132,9 +478,7 @@ func (s *Service) GetInvoice(ctx context.Context, pointOfSale, invoiceType, invo
 
 	// Crear request
 	request := &QueryRequest{}
-	request.Auth.Token = ticket.Token
-	request.Auth.Sign = ticket.Sign
-	request.Auth.CUIT = s.config.CUIT
+	request.Auth = NewAuth(s.config, ticket)
 	request.Request.InvoiceType = invoiceType
 	request.Request.PointOfSale = pointOfSale
 	request.Request.InvoiceNumber = invoiceNumber
@@ -148,26 +492,66 @@ func (s *Service) GetInvoice(ctx context.Context, pointOfSale, invoiceType, invo
 	// Verificar errores
 	if len(response.Errors) > 0 {
 		error := response.Errors[0]
-		return nil, models.NewARCAError(error.Code, error.Message)
+		return nil, models.NewServiceError(error.Code, error.Message)
 	}
 
+	return &response, nil
+}
+
+// invoiceFromQuery arma la factura de una respuesta de FECompConsultar
+func invoiceFromQuery(response *QueryResponse) *Invoice {
 	// Crear factura
+	result := response.Result
 	invoice := &Invoice{
 		InvoiceBase: models.InvoiceBase{
-			InvoiceType:   models.InvoiceType(response.Result.InvoiceType),
-			PointOfSale:   response.Result.PointOfSale,
-			InvoiceNumber: response.Result.InvoiceNumber,
-			DateFrom:      response.Result.DateFrom,
-			Amount:        response.Result.Amount,
-			CurrencyType:  models.CurrencyType(response.Result.CurrencyType),
-			CurrencyRate:  response.Result.CurrencyRate,
+			InvoiceType:   models.InvoiceType(result.InvoiceType),
+			PointOfSale:   result.PointOfSale,
+			InvoiceNumber: result.InvoiceNumber,
+			IssueDate:     result.DateFrom.Time,
+			DateFrom:      result.DateFrom.Time,
+			DateTo:        result.DateFrom.Time,
+			ConceptType:   models.ConceptType(result.ConceptType),
+			CurrencyType:  models.CurrencyType(result.CurrencyType),
+			CurrencyRate:  result.CurrencyRate,
+			Amount:        result.Amount,
+			TaxAmount:     result.TaxAmount,
+			TotalAmount:   result.TotalAmount,
+			Items:         queryItems(response),
 		},
+		DocType:              models.DocumentType(result.DocType),
+		DocNumber:            result.DocNumber,
+		ServiceFrom:          result.ServiceFrom.Time,
+		ServiceTo:            result.ServiceTo.Time,
+		PaymentDueDate:       result.PaymentDueDate.Time,
+		ReceptorIVACondition: result.ReceptorIVACondition,
+		CAE:                  result.CAE,
+		CAEDueDate:           result.CAEDueDate.Time,
 	}
 
-	return invoice, nil
+	return invoice
 }
 
-// GetLastAuthorizedInvoice obtiene el último comprobante autorizado
+// NextInvoiceNumber retorna el número a usar en el próximo comprobante del
+// punto de venta y tipo: el último autorizado (FECompUltimoAutorizado) más
+// uno.
+//
+// El número no se reserva: si varios procesos emiten en el mismo punto de
+// venta pueden obtener el mismo número y AFIP rechazará al segundo. En ese
+// caso serialice la numeración y la autorización con un lock externo (por
+// ejemplo, en la base de datos) o use un punto de venta por proceso.
+func (s *Service) NextInvoiceNumber(ctx context.Context, pointOfSale, invoiceType int) (int, error) {
+	last, err := s.GetLastAuthorizedInvoice(ctx, pointOfSale, invoiceType)
+	if err != nil {
+		return 0, err
+	}
+
+	return last.InvoiceNumber + 1, nil
+}
+
+// GetLastAuthorizedInvoice obtiene el último comprobante autorizado del punto
+// de venta y tipo. Si todavía no se autorizó ninguno AFIP informa el número
+// 0: el resultado no es un error sino uno vacío (IsEmpty) sin Status, y el
+// próximo número es 1 (ver NextInvoiceNumber).
 func (s *Service) GetLastAuthorizedInvoice(ctx context.Context, pointOfSale, invoiceType int) (*models.AuthorizationResult, error) {
 	// Validar parámetros
 	if err := utils.ValidatePointOfSale(pointOfSale); err != nil {
@@ -185,11 +569,9 @@ func (s *Service) GetLastAuthorizedInvoice(ctx context.Context, pointOfSale, inv
 
 	// Crear request
 	request := &LastAuthorizedRequest{}
-	request.Auth.Token = ticket.Token
-	request.Auth.Sign = ticket.Sign
-	request.Auth.CUIT = s.config.CUIT
-	request.Request.InvoiceType = invoiceType
-	request.Request.PointOfSale = pointOfSale
+	request.Auth = NewAuth(s.config, ticket)
+	request.InvoiceType = invoiceType
+	request.PointOfSale = pointOfSale
 
 	// Realizar llamada SOAP
 	var response LastAuthorizedResponse
@@ -200,23 +582,170 @@ func (s *Service) GetLastAuthorizedInvoice(ctx context.Context, pointOfSale, inv
 	// Verificar errores
 	if len(response.Errors) > 0 {
 		error := response.Errors[0]
-		return nil, models.NewARCAError(error.Code, error.Message)
+		return nil, models.NewServiceError(error.Code, error.Message)
 	}
 
-	// Crear resultado
+	// Crear resultado (sin Status si todavía no hay comprobantes)
 	result := &models.AuthorizationResult{
-		InvoiceNumber:     response.Result.InvoiceNumber,
-		PointOfSale:       response.Result.PointOfSale,
-		InvoiceType:       models.InvoiceType(response.Result.InvoiceType),
-		AuthorizationDate: response.Result.DateFrom,
-		Status:            "A",
+		InvoiceNumber: response.InvoiceNumber,
+		PointOfSale:   pointOfSale,
+		InvoiceType:   models.InvoiceType(invoiceType),
+	}
+	if !result.IsEmpty() {
+		result.Status = models.AuthResultApproved
 	}
 
 	return result, nil
 }
 
-// GetParameters obtiene los parámetros del sistema
-func (s *Service) GetParameters(ctx context.Context) (*models.Parameters, error) {
+// GetCAEA obtiene un CAEA para el período (AAAAMM) y la quincena (1 o 2).
+// fiscalYear no se envía a AFIP: el período ya incluye el año.
+func (s *Service) GetCAEA(ctx context.Context, period, order, fiscalYear int) (*CAEAResponse, error) {
+	// Obtener ticket de acceso
+	ticket, err := s.auth.GetAccessTicket(ctx, "wsfe")
+	if err != nil {
+		return nil, fmt.Errorf("error getting access ticket: %w", err)
+	}
+
+	// Crear request
+	request := &CAEARequest{}
+	request.Auth = NewAuth(s.config, ticket)
+	request.Period = period
+	request.Order = order
+
+	// Realizar llamada SOAP
+	var response CAEAResponse
+	if err := s.callSOAP(ctx, "FECAEASolicitar", request, &response); err != nil {
+		return nil, err
+	}
+
+	// Verificar errores
+	if len(response.Errors) > 0 {
+		error := response.Errors[0]
+		return nil, models.NewServiceError(error.Code, error.Message)
+	}
+
+	return &response, nil
+}
+
+// RegisterCAEAInvoices informa a AFIP los comprobantes emitidos con un CAEA.
+// Todos los comprobantes deben compartir punto de venta y tipo.
+func (s *Service) RegisterCAEAInvoices(ctx context.Context, caea string, invoices []*Invoice) ([]*models.AuthorizationResult, error) {
+	if len(invoices) == 0 {
+		return nil, models.NewValidationError("invoices", "Debe informar al menos un comprobante", invoices)
+	}
+	pointOfSale, invoiceType := invoices[0].PointOfSale, invoices[0].InvoiceType
+	for _, invoice := range invoices {
+		if invoice.PointOfSale != pointOfSale || invoice.InvoiceType != invoiceType {
+			return nil, models.NewValidationError("invoices", "Los comprobantes deben compartir punto de venta y tipo", invoice.InvoiceNumber)
+		}
+		if err := s.validateCAEAInvoice(invoice); err != nil {
+			return nil, err
+		}
+	}
+
+	// Obtener ticket de acceso
+	ticket, err := s.auth.GetAccessTicket(ctx, "wsfe")
+	if err != nil {
+		return nil, fmt.Errorf("error getting access ticket: %w", err)
+	}
+
+	// Crear request
+	request := &CAEARegisterRequest{}
+	request.Auth = NewAuth(s.config, ticket)
+	request.Request.Header.Count = len(invoices)
+	request.Request.Header.PointOfSale = pointOfSale
+	request.Request.Header.InvoiceType = int(invoiceType)
+	for _, invoice := range invoices {
+		detail, err := s.newAuthorizationDetail(invoice)
+		if err != nil {
+			return nil, err
+		}
+		request.Request.Details = append(request.Request.Details, CAEARegisterDetail{
+			AuthorizationDetail: detail,
+			CAEA:                caea,
+		})
+	}
+
+	// Realizar llamada SOAP
+	var response CAEARegisterResponse
+	if err := s.callSOAP(ctx, "FECAEARegInformativo", request, &response); err != nil {
+		return nil, err
+	}
+
+	// Verificar errores
+	if len(response.Errors) > 0 {
+		error := response.Errors[0]
+		return nil, models.NewServiceError(error.Code, error.Message)
+	}
+
+	var results []*models.AuthorizationResult
+	for _, detail := range response.Details {
+		status, _ := models.ParseAuthResult(detail.Status)
+		result := &models.AuthorizationResult{
+			CAE:           detail.CAEA,
+			InvoiceNumber: detail.InvoiceNumber,
+			PointOfSale:   response.Header.PointOfSale,
+			InvoiceType:   models.InvoiceType(response.Header.InvoiceType),
+			Status:        status,
+		}
+		var messages []string
+		for _, obs := range detail.Observations {
+			messages = append(messages, fmt.Sprintf("%s: %s", obs.Code, obs.Message))
+		}
+		result.Message = strings.Join(messages, "; ")
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// ReportCAEANoMovement informa que un punto de venta no emitió comprobantes con el CAEA
+func (s *Service) ReportCAEANoMovement(ctx context.Context, caea string, pointOfSale int) error {
+	if err := utils.ValidatePointOfSale(pointOfSale); err != nil {
+		return err
+	}
+
+	// Obtener ticket de acceso
+	ticket, err := s.auth.GetAccessTicket(ctx, "wsfe")
+	if err != nil {
+		return fmt.Errorf("error getting access ticket: %w", err)
+	}
+
+	// Crear request
+	request := &CAEANoMovementRequest{
+		Auth:        NewAuth(s.config, ticket),
+		PointOfSale: pointOfSale,
+		CAEA:        caea,
+	}
+
+	// Realizar llamada SOAP
+	var response CAEANoMovementResponse
+	if err := s.callSOAP(ctx, "FECAEASinMovimientoInformar", request, &response); err != nil {
+		return err
+	}
+
+	// Verificar errores
+	if len(response.Errors) > 0 {
+		error := response.Errors[0]
+		return models.NewServiceError(error.Code, error.Message)
+	}
+
+	return nil
+}
+
+// GetOptionalTypes obtiene los tipos de datos opcionales (Opcionales) válidos
+// (FEParamGetTiposOpcional). La lista se consulta una sola vez por ambiente
+// y queda en cache mientras viva el servicio.
+func (s *Service) GetOptionalTypes(ctx context.Context) ([]models.OptionalTypeInfo, error) {
+	s.optionalTypesMutex.Lock()
+	defer s.optionalTypesMutex.Unlock()
+
+	env := s.environment(ctx)
+	if optionalTypes, ok := s.optionalTypes[env]; ok {
+		return optionalTypes, nil
+	}
+
 	// Obtener ticket de acceso
 	ticket, err := s.auth.GetAccessTicket(ctx, "wsfe")
 	if err != nil {
@@ -225,77 +754,292 @@ func (s *Service) GetParameters(ctx context.Context) (*models.Parameters, error)
 
 	// Crear request
 	request := &ParametersRequest{}
-	request.Auth.Token = ticket.Token
-	request.Auth.Sign = ticket.Sign
-	request.Auth.CUIT = s.config.CUIT
+	request.Auth = NewAuth(s.config, ticket)
 
 	// Realizar llamada SOAP
-	var response ParametersResponse
-	if err := s.callSOAP(ctx, "FEParamGetTiposConcepto", request, &response); err != nil {
+	var response OptionalTypesResponse
+	if err := s.callSOAP(ctx, "FEParamGetTiposOpcional", request, &response); err != nil {
 		return nil, err
 	}
 
 	// Verificar errores
 	if len(response.Errors) > 0 {
 		error := response.Errors[0]
-		return nil, models.NewARCAError(error.Code, error.Message)
+		return nil, models.NewServiceError(error.Code, error.Message)
 	}
 
-	// Crear parámetros
-	params := &models.Parameters{
-		LastUpdate: response.LastUpdate,
+	optionalTypes := make([]models.OptionalTypeInfo, 0, len(response.OptionalTypes))
+	for _, ot := range response.OptionalTypes {
+		optionalTypes = append(optionalTypes, models.OptionalTypeInfo{
+			ID:          ot.ID,
+			Description: ot.Description,
+			Active:      utils.IsActiveParameter(ot.DateTo),
+		})
+	}
+
+	if s.optionalTypes == nil {
+		s.optionalTypes = make(map[models.Environment][]models.OptionalTypeInfo)
+	}
+	s.optionalTypes[env] = optionalTypes
+	return optionalTypes, nil
+}
+
+// SetLiveOptionalValidation habilita la validación de los datos opcionales
+// (incluidos los que agregan FCE y el régimen especial) contra la lista de
+// FEParamGetTiposOpcional antes de autorizar
+func (s *Service) SetLiveOptionalValidation(enabled bool) {
+	s.liveOptionals = enabled
+}
+
+// validateOptionals valida los datos opcionales que se enviarán a AFIP
+// contra la lista de tipos habilitados
+func (s *Service) validateOptionals(ctx context.Context, invoice *Invoice) error {
+	optionals := s.invoiceOptionals(invoice)
+	if len(optionals) == 0 {
+		return nil
+	}
+
+	optionalTypes, err := s.GetOptionalTypes(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting optional types: %w", err)
+	}
+
+	if errs := utils.ValidateOptionals(optionals, optionalTypes...); errs.HasErrors() {
+		return errs
 	}
+	return nil
+}
 
-	// Convertir tipos de documento
-	for _, dt := range response.DocumentTypes {
-		params.DocumentTypes = append(params.DocumentTypes, models.DocumentTypeInfo{
-			ID:          models.DocumentType(dt.ID),
-			Description: dt.Description,
-			Active:      dt.Active,
+// invoiceOptionals retorna los datos opcionales a enviar: los de la factura
+// más los que agregan FCE MiPyME y el régimen especial
+func (s *Service) invoiceOptionals(invoice *Invoice) []models.Optional {
+	optionals := invoice.Optionals
+	if invoice.FCE != nil && invoice.InvoiceType.IsFCE() {
+		optionals = append(append([]models.Optional{}, optionals...), invoice.FCE.Optionals(invoice.InvoiceType)...)
+	}
+	if s.regime != nil {
+		optionals = append(append([]models.Optional{}, optionals...), s.regime.Optionals(invoice)...)
+	}
+	return optionals
+}
+
+// GetActivities obtiene las actividades registradas del contribuyente
+// (FEParamGetActividades), para informarlas en Invoice.ActivityCodes
+func (s *Service) GetActivities(ctx context.Context) ([]models.ActivityInfo, error) {
+	// Obtener ticket de acceso
+	ticket, err := s.auth.GetAccessTicket(ctx, "wsfe")
+	if err != nil {
+		return nil, fmt.Errorf("error getting access ticket: %w", err)
+	}
+
+	// Crear request
+	request := &ParametersRequest{}
+	request.Auth = NewAuth(s.config, ticket)
+
+	// Realizar llamada SOAP
+	var response ActivitiesResponse
+	if err := s.callSOAP(ctx, "FEParamGetActividades", request, &response); err != nil {
+		return nil, err
+	}
+
+	// Verificar errores
+	if len(response.Errors) > 0 {
+		error := response.Errors[0]
+		return nil, models.NewServiceError(error.Code, error.Message)
+	}
+
+	activities := make([]models.ActivityInfo, 0, len(response.Activities))
+	for _, activity := range response.Activities {
+		activities = append(activities, models.ActivityInfo{
+			ID:          activity.ID,
+			Order:       activity.Order,
+			Description: activity.Description,
+		})
+	}
+
+	return activities, nil
+}
+
+// GetReceptorIVAConditions obtiene las condiciones frente al IVA del
+// receptor (FEParamGetCondicionIvaReceptor), para informarlas en
+// Invoice.ReceptorIVACondition
+func (s *Service) GetReceptorIVAConditions(ctx context.Context) ([]models.ReceptorIVAConditionInfo, error) {
+	// Obtener ticket de acceso
+	ticket, err := s.auth.GetAccessTicket(ctx, "wsfe")
+	if err != nil {
+		return nil, fmt.Errorf("error getting access ticket: %w", err)
+	}
+
+	// Crear request
+	request := &ReceptorIVAConditionsRequest{}
+	request.Auth = NewAuth(s.config, ticket)
+
+	// Realizar llamada SOAP
+	var response ReceptorIVAConditionsResponse
+	if err := s.callSOAP(ctx, "FEParamGetCondicionIvaReceptor", request, &response); err != nil {
+		return nil, err
+	}
+
+	// Verificar errores
+	if len(response.Errors) > 0 {
+		error := response.Errors[0]
+		return nil, models.NewServiceError(error.Code, error.Message)
+	}
+
+	conditions := make([]models.ReceptorIVAConditionInfo, 0, len(response.Conditions))
+	for _, condition := range response.Conditions {
+		conditions = append(conditions, models.ReceptorIVAConditionInfo{
+			ID:           condition.ID,
+			Description:  condition.Description,
+			InvoiceClass: condition.InvoiceClass,
 		})
 	}
 
-	// Convertir tipos de factura
+	return conditions, nil
+}
+
+// SetRequireActivities exige que cada factura informe al menos un código de
+// actividad, para contribuyentes alcanzados por RG 5616 y similares
+func (s *Service) SetRequireActivities(enabled bool) {
+	s.requireActivities = enabled
+}
+
+// SetConsumidorFinalThreshold configura el importe en pesos a partir del cual
+// se exige identificar al consumidor final (DocTipo 99). Con 0 se usa el
+// valor vigente de AFIP, utils.ConsumidorFinalThreshold.
+func (s *Service) SetConsumidorFinalThreshold(amount float64) {
+	s.consumidorFinalThreshold = amount
+}
+
+// GetInvoiceTypes obtiene los tipos de comprobante de AFIP
+// (FEParamGetTiposCbte), incluidas las notas de débito/crédito y las
+// clases M, T y R. Los tipos dados de baja se informan con Active en false.
+func (s *Service) GetInvoiceTypes(ctx context.Context) ([]models.InvoiceTypeInfo, error) {
+	// Obtener ticket de acceso
+	ticket, err := s.auth.GetAccessTicket(ctx, "wsfe")
+	if err != nil {
+		return nil, fmt.Errorf("error getting access ticket: %w", err)
+	}
+
+	// Crear request
+	request := &ParametersRequest{}
+	request.Auth = NewAuth(s.config, ticket)
+
+	// Realizar llamada SOAP
+	var response InvoiceTypesResponse
+	if err := s.callSOAP(ctx, "FEParamGetTiposCbte", request, &response); err != nil {
+		return nil, err
+	}
+
+	// Verificar errores
+	if len(response.Errors) > 0 {
+		error := response.Errors[0]
+		return nil, models.NewServiceError(error.Code, error.Message)
+	}
+
+	invoiceTypes := make([]models.InvoiceTypeInfo, 0, len(response.InvoiceTypes))
 	for _, it := range response.InvoiceTypes {
-		params.InvoiceTypes = append(params.InvoiceTypes, models.InvoiceTypeInfo{
+		invoiceTypes = append(invoiceTypes, models.InvoiceTypeInfo{
 			ID:          models.InvoiceType(it.ID),
 			Description: it.Description,
-			Active:      it.Active,
+			Active:      utils.IsActiveParameter(it.DateTo),
 		})
 	}
 
-	// Convertir tipos de moneda
-	for _, ct := range response.CurrencyTypes {
-		params.CurrencyTypes = append(params.CurrencyTypes, models.CurrencyTypeInfo{
-			ID:          models.CurrencyType(ct.ID),
-			Description: ct.Description,
-			Active:      ct.Active,
-		})
+	return invoiceTypes, nil
+}
+
+// GetTaxRates obtiene las alícuotas de IVA vigentes (FEParamGetTiposIva).
+// La lista se consulta una sola vez por ambiente y queda en cache mientras
+// viva el servicio.
+func (s *Service) GetTaxRates(ctx context.Context) ([]models.TaxRateInfo, error) {
+	s.taxRatesMutex.Lock()
+	defer s.taxRatesMutex.Unlock()
+
+	env := s.environment(ctx)
+	if taxRates, ok := s.taxRates[env]; ok {
+		return taxRates, nil
 	}
 
-	// Convertir alícuotas
+	// Obtener ticket de acceso
+	ticket, err := s.auth.GetAccessTicket(ctx, "wsfe")
+	if err != nil {
+		return nil, fmt.Errorf("error getting access ticket: %w", err)
+	}
+
+	// Crear request
+	request := &ParametersRequest{}
+	request.Auth = NewAuth(s.config, ticket)
+
+	// Realizar llamada SOAP
+	var response TaxRatesResponse
+	if err := s.callSOAP(ctx, "FEParamGetTiposIva", request, &response); err != nil {
+		return nil, err
+	}
+
+	// Verificar errores
+	if len(response.Errors) > 0 {
+		error := response.Errors[0]
+		return nil, models.NewServiceError(error.Code, error.Message)
+	}
+
+	taxRates := make([]models.TaxRateInfo, 0, len(response.TaxRates))
 	for _, tr := range response.TaxRates {
-		params.TaxRates = append(params.TaxRates, models.TaxRateInfo{
-			ID:          models.TaxRate(tr.ID),
+		taxRates = append(taxRates, models.TaxRateInfo{
+			ID:          taxRateFromAFIP(tr.ID, tr.Description),
 			Description: tr.Description,
-			Active:      tr.Active,
+			Active:      utils.IsActiveParameter(tr.DateTo),
 		})
 	}
 
-	// Convertir tipos de concepto
-	for _, ct := range response.ConceptTypes {
-		params.ConceptTypes = append(params.ConceptTypes, models.ConceptTypeInfo{
-			ID:          models.ConceptType(ct.ID),
-			Description: ct.Description,
-			Active:      ct.Active,
-		})
+	if s.taxRates == nil {
+		s.taxRates = make(map[models.Environment][]models.TaxRateInfo)
 	}
+	s.taxRates[env] = taxRates
+	return taxRates, nil
+}
 
-	return params, nil
+// SetLiveTaxRateValidation habilita la validación de alícuotas contra la
+// lista vigente de AFIP en lugar de las constantes de models.TaxRate
+func (s *Service) SetLiveTaxRateValidation(enabled bool) {
+	s.liveTaxRates = enabled
 }
 
-// GetCAEA obtiene un CAEA
-func (s *Service) GetCAEA(ctx context.Context, period, order, fiscalYear int) (*CAEAResponse, error) {
+// validateTaxRates valida las alícuotas de IVA de los ítems contra la lista de AFIP.
+// Las alícuotas no gravada y exenta no se validan: no viajan en AlicIva y
+// FEParamGetTiposIva no lista el exento.
+func (s *Service) validateTaxRates(ctx context.Context, invoice *Invoice) error {
+	taxRates, err := s.GetTaxRates(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting tax rates: %w", err)
+	}
+
+	for _, item := range invoice.Items {
+		for _, tax := range item.Taxes {
+			if tax.Type != models.TaxTypeIVA || isUntaxedRate(tax.Rate) {
+				continue
+			}
+			if err := utils.ValidateTaxRate(tax.Rate, taxRates...); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// GetCurrencyTypes obtiene las monedas habilitadas por AFIP (FEParamGetTiposMonedas).
+// La lista se consulta una sola vez por ambiente y queda en cache mientras
+// viva el servicio.
+func (s *Service) GetCurrencyTypes(ctx context.Context) ([]models.CurrencyTypeInfo, error) {
+	s.currencyTypesMutex.Lock()
+	defer s.currencyTypesMutex.Unlock()
+
+	env := s.environment(ctx)
+	if currencyTypes, ok := s.currencyTypes[env]; ok {
+		return currencyTypes, nil
+	}
+
 	// Obtener ticket de acceso
 	ticket, err := s.auth.GetAccessTicket(ctx, "wsfe")
 	if err != nil {
@@ -303,107 +1047,305 @@ func (s *Service) GetCAEA(ctx context.Context, period, order, fiscalYear int) (*
 	}
 
 	// Crear request
-	request := &CAEARequest{}
-	request.Auth.Token = ticket.Token
-	request.Auth.Sign = ticket.Sign
-	request.Auth.CUIT = s.config.CUIT
-	request.Request.Period = period
-	request.Request.Order = order
-	request.Request.FiscalYear = fiscalYear
+	request := &ParametersRequest{}
+	request.Auth = NewAuth(s.config, ticket)
 
 	// Realizar llamada SOAP
-	var response CAEAResponse
-	if err := s.callSOAP(ctx, "FECAEASolicitar", request, &response); err != nil {
+	var response CurrencyTypesResponse
+	if err := s.callSOAP(ctx, "FEParamGetTiposMonedas", request, &response); err != nil {
 		return nil, err
 	}
 
 	// Verificar errores
 	if len(response.Errors) > 0 {
 		error := response.Errors[0]
-		return nil, models.NewARCAError(error.Code, error.Message)
+		return nil, models.NewServiceError(error.Code, error.Message)
 	}
 
-	return &response, nil
+	currencyTypes := make([]models.CurrencyTypeInfo, 0, len(response.CurrencyTypes))
+	for _, ct := range response.CurrencyTypes {
+		currencyTypes = append(currencyTypes, models.CurrencyTypeInfo{
+			ID:          models.CurrencyType(ct.ID),
+			Description: ct.Description,
+			Active:      utils.IsActiveParameter(ct.DateTo),
+		})
+	}
+
+	if s.currencyTypes == nil {
+		s.currencyTypes = make(map[models.Environment][]models.CurrencyTypeInfo)
+	}
+	s.currencyTypes[env] = currencyTypes
+	return currencyTypes, nil
 }
 
-// validateInvoice valida una factura
-func (s *Service) validateInvoice(invoice *Invoice) error {
-	var errors models.ValidationErrors
+// SetLiveCurrencyValidation habilita la validación de la moneda contra la
+// lista de AFIP en lugar de sólo el formato del código
+func (s *Service) SetLiveCurrencyValidation(enabled bool) {
+	s.liveCurrencies = enabled
+}
 
-	// Validar campos básicos
-	if err := utils.ValidateInvoiceType(invoice.InvoiceType); err != nil {
-		errors.Add("invoice_type", err.Error(), invoice.InvoiceType)
+// validateCurrencyType valida la moneda de la factura contra la lista de AFIP
+func (s *Service) validateCurrencyType(ctx context.Context, invoice *Invoice) error {
+	currencyTypes, err := s.GetCurrencyTypes(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting currency types: %w", err)
 	}
 
-	if err := utils.ValidatePointOfSale(invoice.PointOfSale); err != nil {
-		errors.Add("point_of_sale", err.Error(), invoice.PointOfSale)
-	}
+	return utils.ValidateCurrencyType(invoice.CurrencyType, currencyTypes...)
+}
 
-	if err := utils.ValidateInvoiceNumber(invoice.InvoiceNumber); err != nil {
-		errors.Add("invoice_number", err.Error(), invoice.InvoiceNumber)
-	}
+// afipTaxRates asocia los códigos de alícuota de AFIP con models.TaxRate
+var afipTaxRates = map[int]models.TaxRate{
+	3: models.TaxRate0,
+	4: models.TaxRate105,
+	5: models.TaxRate21,
+	6: models.TaxRate27,
+	8: models.TaxRate5,
+	9: models.TaxRate25,
+}
 
-	if err := utils.ValidateDate(invoice.DateFrom, "date_from"); err != nil {
-		errors.Add("date_from", err.Error(), invoice.DateFrom)
+// taxRateToAFIP convierte una alícuota al código de AFIP (2 = Exento)
+func taxRateToAFIP(rate models.TaxRate) int {
+	if rate == models.TaxRateExempt {
+		return 2
 	}
-
-	if err := utils.ValidateDate(invoice.DateTo, "date_to"); err != nil {
-		errors.Add("date_to", err.Error(), invoice.DateTo)
+	for id, afipRate := range afipTaxRates {
+		if afipRate == rate {
+			return id
+		}
 	}
+	return int(rate)
+}
 
-	if err := utils.ValidateConceptType(invoice.ConceptType); err != nil {
-		errors.Add("concept_type", err.Error(), invoice.ConceptType)
+// isUntaxedRate indica si la alícuota corresponde a un importe no gravado
+// (0%) o exento, que se informa en ImpTotConc o ImpOpEx y no en AlicIva
+func isUntaxedRate(rate models.TaxRate) bool {
+	return rate == models.TaxRate0 || rate == models.TaxRateExempt
+}
+
+// UntaxedTotals retorna los importes no gravado (ImpTotConc) y exento
+// (ImpOpEx) del comprobante: NonTaxableAmount y ExemptAmount si se
+// informaron o, si no, la suma de las bases de los ítems con IVA al 0% y
+// exento
+func (i *Invoice) UntaxedTotals() (nonTaxable, exempt float64) {
+	for _, item := range i.Items {
+		for _, tax := range item.Taxes {
+			switch {
+			case tax.Type != models.TaxTypeIVA:
+			case tax.Rate == models.TaxRate0:
+				nonTaxable += tax.Base
+			case tax.Rate == models.TaxRateExempt:
+				exempt += tax.Base
+			}
+		}
 	}
 
-	if err := utils.ValidateCurrencyType(invoice.CurrencyType); err != nil {
-		errors.Add("currency_type", err.Error(), invoice.CurrencyType)
+	if i.NonTaxableAmount != 0 {
+		nonTaxable = i.NonTaxableAmount
+	}
+	if i.ExemptAmount != 0 {
+		exempt = i.ExemptAmount
 	}
+	return utils.RoundAmount(nonTaxable), utils.RoundAmount(exempt)
+}
+
+// afipTributes mapea los tipos de impuesto distintos de IVA al código y la
+// descripción de FEParamGetTiposTributos
+var afipTributes = map[models.TaxType]struct {
+	id          int
+	description string
+}{
+	models.TaxTypeII: {4, "Impuestos Internos"},
+	models.TaxTypeIO: {99, "Otros"},
+}
 
-	if err := utils.ValidateAmount(invoice.Amount, "amount"); err != nil {
-		errors.Add("amount", err.Error(), invoice.Amount)
+// tributeBreakdown arma el bloque Tributos del request con los impuestos del
+// comprobante. La alícuota se informa como importe sobre base imponible.
+func tributeBreakdown(taxes []models.Tax) []Tribute {
+	var breakdown []Tribute
+	for _, tax := range taxes {
+		tribute, ok := afipTributes[tax.Type]
+		if !ok {
+			tribute = afipTributes[models.TaxTypeIO]
+		}
+
+		var rate float64
+		if tax.Base != 0 {
+			rate = utils.RoundAmount(tax.Amount / tax.Base * 100)
+		}
+		breakdown = append(breakdown, Tribute{
+			ID:          tribute.id,
+			Description: tribute.description,
+			Base:        Amount(utils.RoundAmount(tax.Base)),
+			Rate:        Amount(rate),
+			Amount:      Amount(utils.RoundAmount(tax.Amount)),
+		})
 	}
+	return breakdown
+}
 
-	if err := utils.ValidateAmount(invoice.TaxAmount, "tax_amount"); err != nil {
-		errors.Add("tax_amount", err.Error(), invoice.TaxAmount)
+// ivaBreakdown agrupa el IVA de los ítems por alícuota, en el orden en que
+// aparece cada una, para armar el bloque Iva del request. Los ítems no
+// gravados y exentos se informan aparte (ver UntaxedTotals).
+func ivaBreakdown(items []models.Item) []AlicIva {
+	var breakdown []AlicIva
+	index := make(map[int]int)
+	for _, item := range items {
+		for _, tax := range item.Taxes {
+			if tax.Type != models.TaxTypeIVA || isUntaxedRate(tax.Rate) {
+				continue
+			}
+
+			id := taxRateToAFIP(tax.Rate)
+			i, ok := index[id]
+			if !ok {
+				i = len(breakdown)
+				index[id] = i
+				breakdown = append(breakdown, AlicIva{ID: id})
+			}
+			breakdown[i].Base = Amount(utils.RoundAmount(float64(breakdown[i].Base) + tax.Base))
+			breakdown[i].Amount = Amount(utils.RoundAmount(float64(breakdown[i].Amount) + tax.Amount))
+		}
 	}
+	return breakdown
+}
 
-	if err := utils.ValidateAmount(invoice.TotalAmount, "total_amount"); err != nil {
-		errors.Add("total_amount", err.Error(), invoice.TotalAmount)
+// queryItems arma los ítems de un comprobante consultado. FECompConsultar no
+// informa el detalle de ítems, así que se arma uno por alícuota de IVA con su
+// base imponible, o uno solo por el neto si no hay alícuotas (comprobantes C),
+// más uno por el importe no gravado y otro por el exento, si los hay.
+func queryItems(response *QueryResponse) []models.Item {
+	result := response.Result
+	var items []models.Item
+	if len(result.IVA) == 0 {
+		if result.Amount != 0 || (result.UntaxedAmount == 0 && result.ExemptAmount == 0) {
+			items = append(items, models.Item{
+				Description: "Neto gravado",
+				Quantity:    1,
+				UnitPrice:   result.Amount,
+				TotalPrice:  result.Amount,
+			})
+		}
 	}
 
-	// Validar documento
-	if err := utils.ValidateDocumentType(invoice.DocType); err != nil {
-		errors.Add("doc_type", err.Error(), invoice.DocType)
+	for _, iva := range result.IVA {
+		rate := taxRateFromAFIP(iva.ID, "")
+		if iva.ID == 2 {
+			rate = models.TaxRateExempt
+		}
+		items = append(items, models.Item{
+			Description: fmt.Sprintf("Neto gravado %s", rate),
+			Quantity:    1,
+			UnitPrice:   iva.Base,
+			TotalPrice:  iva.Base,
+			Taxes: []models.Tax{
+				{Type: models.TaxTypeIVA, Rate: rate, Base: iva.Base, Amount: iva.Amount},
+			},
+		})
 	}
 
-	if err := utils.ValidateDocumentNumber(invoice.DocType, invoice.DocNumber); err != nil {
-		errors.Add("doc_number", err.Error(), invoice.DocNumber)
+	untaxed := []struct {
+		description string
+		rate        models.TaxRate
+		amount      float64
+	}{
+		{"No gravado", models.TaxRate0, result.UntaxedAmount},
+		{"Exento", models.TaxRateExempt, result.ExemptAmount},
+	}
+	for _, u := range untaxed {
+		if u.amount == 0 {
+			continue
+		}
+		items = append(items, models.Item{
+			Description: u.description,
+			Quantity:    1,
+			UnitPrice:   u.amount,
+			TotalPrice:  u.amount,
+			Taxes: []models.Tax{
+				{Type: models.TaxTypeIVA, Rate: u.rate, Base: u.amount},
+			},
+		})
 	}
+	return items
+}
 
-	// Validar documento del cliente
-	if err := utils.ValidateDocumentType(invoice.DocTypeFrom); err != nil {
-		errors.Add("doc_type_from", err.Error(), invoice.DocTypeFrom)
+// taxRateFromAFIP convierte un código de alícuota de AFIP a models.TaxRate.
+// Para códigos desconocidos se deriva de la descripción ("10.5%" -> 105).
+func taxRateFromAFIP(id int, description string) models.TaxRate {
+	if rate, ok := afipTaxRates[id]; ok {
+		return rate
 	}
 
-	if err := utils.ValidateDocumentNumber(invoice.DocTypeFrom, invoice.DocNumberFrom); err != nil {
-		errors.Add("doc_number_from", err.Error(), invoice.DocNumberFrom)
+	digits := strings.NewReplacer("%", "", ".", "", ",", "", " ", "").Replace(description)
+	if value, err := strconv.Atoi(digits); err == nil {
+		return models.TaxRate(value)
 	}
 
-	// Validar ítems
-	if err := utils.ValidateItems(invoice.Items); err != nil {
-		errors.Add("items", err.Error(), invoice.Items)
+	return models.TaxRate(id)
+}
+
+// validateInvoice valida una factura con la configuración del servicio
+func (s *Service) validateInvoice(invoice *Invoice) error {
+	return invoice.validate(validationOptions{
+		now:                      s.config.Now(),
+		liveCurrencies:           s.liveCurrencies,
+		requireActivities:        s.requireActivities,
+		regime:                   s.regime,
+		consumidorFinalThreshold: s.consumidorFinalThreshold,
+	})
+}
+
+// validateCAEAInvoice valida un comprobante emitido con CAEA antes de
+// informarlo. Se informa después de emitido, por lo que la ventana de la
+// fecha del comprobante se controla contra la fecha de emisión y no contra
+// la fecha actual.
+func (s *Service) validateCAEAInvoice(invoice *Invoice) error {
+	now := invoice.GetIssueDate()
+	if now.IsZero() {
+		now = s.config.Now()
 	}
+	return invoice.validate(validationOptions{
+		now:                      now,
+		liveCurrencies:           s.liveCurrencies,
+		requireActivities:        s.requireActivities,
+		regime:                   s.regime,
+		consumidorFinalThreshold: s.consumidorFinalThreshold,
+	})
+}
 
-	if errors.HasErrors() {
-		return errors
+// Dummy consulta el estado de los servidores de WSFEv1 (FEDummy)
+func (s *Service) Dummy(ctx context.Context) (*DummyResponse, error) {
+	var response DummyResponse
+	if err := s.callSOAP(ctx, "FEDummy", &DummyRequest{}, &response); err != nil {
+		return nil, err
 	}
 
-	return nil
+	return &response, nil
+}
+
+// environment retorna el ambiente de las llamadas hechas con ctx: el
+// forzado con core.WithEnvironmentOverride o el configurado
+func (s *Service) environment(ctx context.Context) models.Environment {
+	return s.config.ForContext(ctx).Environment
 }
 
 // callSOAP realiza una llamada SOAP
 func (s *Service) callSOAP(ctx context.Context, action string, request interface{}, response interface{}) error {
-	// Esta es una implementación simplificada
-	// En una implementación real, usarías el cliente SOAP interno
-	return fmt.Errorf("SOAP call not implemented yet")
+	s.soapOnce.Do(func() {
+		s.soapClient = soap.NewClient(s.config.GetWSFEURL(), s.config.Timeout, soap.AsLogrus(s.logger))
+		s.soapClient.SetHTTPClient(s.config.GetHTTPClient())
+		s.soapClient.SetUserAgent(s.config.GetUserAgent())
+		s.soapClient.SetCapture(s.config.RequestCapture, s.config.LogRequests, s.config.LogResponses)
+		s.soapClient.SetValidateRequests(s.config.ValidateRequests)
+		s.soapClient.SetInterceptors(s.config.Interceptors)
+		s.soapClient.SetCircuitBreaker(s.config.GetCircuitBreaker("wsfe"))
+		for _, env := range []models.Environment{models.EnvironmentTesting, models.EnvironmentProduction} {
+			config := s.config.ForEnvironment(env)
+			s.soapClient.SetEnvironmentEndpoint(env, config.GetWSFEURL(), config.GetCircuitBreaker("wsfe"))
+		}
+	})
+
+	core.LogEnvironmentOverride(ctx, s.logger, s.config, "wsfe")
+
+	return s.soapClient.Call(ctx, action, request, response)
 }
@@ -1,8 +1,11 @@
 package wsfe
 
 import (
-	"github.com/dlarregola/arca_invoice_lib/pkg/models"
+	"encoding/xml"
 	"time"
+
+	"github.com/dlarregola/arca_invoice_lib/internal/soap"
+	"github.com/dlarregola/arca_invoice_lib/pkg/models"
 )
 
 // Invoice representa una factura nacional
@@ -14,9 +17,37 @@ type Invoice struct {
 	DocNumberFrom string              `json:"doc_number_from" xml:"doc_number_from"`
 	NameFrom      string              `json:"name_from,omitempty" xml:"name_from,omitempty"`
 	AddressFrom   *models.Address     `json:"address_from,omitempty" xml:"address_from,omitempty"`
-	ServiceFrom   string              `json:"service_from,omitempty" xml:"service_from,omitempty"`
-	CAE           string              `json:"cae,omitempty" xml:"cae,omitempty"`
-	CAEDueDate    time.Time           `json:"cae_due_date,omitempty" xml:"cae_due_date,omitempty"`
+	// ServiceFrom y ServiceTo (período del servicio) y PaymentDueDate son
+	// obligatorios para los conceptos servicios y mixto. PaymentDueDate no
+	// puede ser anterior a la fecha del comprobante (ver
+	// utils.ValidatePaymentDueDate).
+	ServiceFrom    time.Time       `json:"service_from,omitempty" xml:"service_from,omitempty"`
+	ServiceTo      time.Time       `json:"service_to,omitempty" xml:"service_to,omitempty"`
+	PaymentDueDate time.Time       `json:"payment_due_date,omitempty" xml:"payment_due_date,omitempty"`
+	CAE            string          `json:"cae,omitempty" xml:"cae,omitempty"`
+	CAEDueDate     time.Time       `json:"cae_due_date,omitempty" xml:"cae_due_date,omitempty"`
+	FCE            *models.FCEData `json:"fce,omitempty" xml:"fce,omitempty"`
+	// ActivityCodes son los códigos de actividad del emisor (Actividades),
+	// según los informa FEParamGetActividades
+	ActivityCodes []int `json:"activity_codes,omitempty" xml:"activity_codes,omitempty"`
+	// ReceptorIVACondition es la condición frente al IVA del receptor
+	// (CondicionIVAReceptorId), obligatoria desde RG 5616. Ver
+	// GetReceptorIVAConditions y las constantes models.ReceptorIVACondition*
+	ReceptorIVACondition int `json:"receptor_iva_condition" xml:"receptor_iva_condition"`
+	// UsedGoodsSeller es el vendedor del bien usado; sólo lo usa el régimen
+	// de bienes usados (ver UsedGoodsRegime)
+	UsedGoodsSeller *models.UsedGoodsSeller `json:"used_goods_seller,omitempty" xml:"used_goods_seller,omitempty"`
+	// AssociatedInvoices (CbtesAsoc) son los comprobantes que ajusta una nota
+	// de débito o crédito. AssociatedPeriod (PeriodoAsoc) los reemplaza
+	// cuando la nota ajusta un período; se informa uno u otro, no ambos.
+	AssociatedInvoices []models.AssociatedInvoice `json:"associated_invoices,omitempty" xml:"associated_invoices,omitempty"`
+	AssociatedPeriod   *models.AssociatedPeriod   `json:"associated_period,omitempty" xml:"associated_period,omitempty"`
+	// NonTaxableAmount (ImpTotConc) y ExemptAmount (ImpOpEx) son los
+	// importes no gravados y exentos, que no forman parte de Amount (ImpNeto).
+	// Si son 0 se calculan con las bases de los ítems con IVA al 0% (no
+	// gravado) y exento (ver UntaxedTotals).
+	NonTaxableAmount float64 `json:"non_taxable_amount,omitempty" xml:"non_taxable_amount,omitempty"`
+	ExemptAmount     float64 `json:"exempt_amount,omitempty" xml:"exempt_amount,omitempty"`
 }
 
 // InvoiceItem representa un ítem de factura nacional
@@ -27,195 +58,488 @@ type InvoiceItem struct {
 	Discount    float64 `json:"discount,omitempty" xml:"discount,omitempty"`
 }
 
-// AuthorizationRequest representa el request de autorización
+// AFIPDate es una fecha del request o de la respuesta, en formato AAAAMMDD
+// (ver soap.AFIPDate)
+type AFIPDate = soap.AFIPDate
+
+// Amount es un importe del request, con dos decimales (ver soap.Amount)
+type Amount = soap.Amount
+
+// Auth representa el bloque de autenticación de cada request
+type Auth struct {
+	Token string `xml:"Token"`
+	Sign  string `xml:"Sign"`
+	CUIT  string `xml:"Cuit"`
+}
+
+// Optional representa un dato opcional dentro del request
+type Optional struct {
+	ID    string `xml:"Id"`
+	Value string `xml:"Valor"`
+}
+
+// AlicIva representa el subtotal de IVA de una alícuota dentro del request
+type AlicIva struct {
+	ID     int    `xml:"Id"`
+	Base   Amount `xml:"BaseImp"`
+	Amount Amount `xml:"Importe"`
+}
+
+// Tribute representa un tributo (impuesto distinto de IVA) dentro del request
+type Tribute struct {
+	ID          int    `xml:"Id"`
+	Description string `xml:"Desc"`
+	Base        Amount `xml:"BaseImp"`
+	Rate        Amount `xml:"Alic"`
+	Amount      Amount `xml:"Importe"`
+}
+
+// AssociatedInvoice representa un comprobante asociado dentro del request
+type AssociatedInvoice struct {
+	InvoiceType   int    `xml:"Tipo"`
+	PointOfSale   int    `xml:"PtoVta"`
+	InvoiceNumber int    `xml:"Nro"`
+	CUIT          string `xml:"Cuit,omitempty"`
+}
+
+// AssociatedPeriod representa el período asociado dentro del request
+type AssociatedPeriod struct {
+	From AFIPDate `xml:"FchDesde"`
+	To   AFIPDate `xml:"FchHasta"`
+}
+
+// Activity representa una actividad del emisor dentro del request
+type Activity struct {
+	ID int `xml:"Id"`
+}
+
+// AlicIvaList es la lista Iva>AlicIva del request; vacía no se envía
+type AlicIvaList []AlicIva
+
+// MarshalXML implementa xml.Marshaler
+func (l AlicIvaList) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return soap.EncodeList(e, start, "AlicIva", l)
+}
+
+// TributeList es la lista Tributos>Tributo del request; vacía no se envía
+type TributeList []Tribute
+
+// MarshalXML implementa xml.Marshaler
+func (l TributeList) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return soap.EncodeList(e, start, "Tributo", l)
+}
+
+// OptionalList es la lista Opcionales>Opcional del request; vacía no se envía
+type OptionalList []Optional
+
+// MarshalXML implementa xml.Marshaler
+func (l OptionalList) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return soap.EncodeList(e, start, "Opcional", l)
+}
+
+// AssociatedInvoiceList es la lista CbtesAsoc>CbteAsoc del request; vacía no
+// se envía
+type AssociatedInvoiceList []AssociatedInvoice
+
+// MarshalXML implementa xml.Marshaler
+func (l AssociatedInvoiceList) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return soap.EncodeList(e, start, "CbteAsoc", l)
+}
+
+// ActivityList es la lista Actividades>Actividad del request; vacía no se envía
+type ActivityList []Activity
+
+// MarshalXML implementa xml.Marshaler
+func (l ActivityList) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return soap.EncodeList(e, start, "Actividad", l)
+}
+
+// AuthorizationDetail representa un comprobante dentro de FECAESolicitar
+// (FECAEDetRequest). El orden de los campos sigue el WSDL de WSFEv1.
+type AuthorizationDetail struct {
+	ConceptType    int       `xml:"Concepto"`
+	DocType        int       `xml:"DocTipo"`
+	DocNumber      string    `xml:"DocNro"`
+	InvoiceFrom    int       `xml:"CbteDesde"`
+	InvoiceTo      int       `xml:"CbteHasta"`
+	InvoiceDate    AFIPDate  `xml:"CbteFch"`
+	TotalAmount    Amount    `xml:"ImpTotal"`
+	UntaxedAmount  Amount    `xml:"ImpTotConc"`
+	Amount         Amount    `xml:"ImpNeto"`
+	ExemptAmount   Amount    `xml:"ImpOpEx"`
+	TributeAmount  Amount    `xml:"ImpTrib"`
+	TaxAmount      Amount    `xml:"ImpIVA"`
+	ServiceFrom    *AFIPDate `xml:"FchServDesde,omitempty"`
+	ServiceTo      *AFIPDate `xml:"FchServHasta,omitempty"`
+	PaymentDueDate *AFIPDate `xml:"FchVtoPago,omitempty"`
+	CurrencyType   string    `xml:"MonId"`
+	CurrencyRate   float64   `xml:"MonCotiz"`
+	// CondicionIVAReceptorId, obligatorio desde RG 5616
+	ReceptorIVACondition int                   `xml:"CondicionIVAReceptorId,omitempty"`
+	AssociatedInvoices   AssociatedInvoiceList `xml:"CbtesAsoc,omitempty"`
+	Tributes             TributeList           `xml:"Tributos,omitempty"`
+	IVA                  AlicIvaList           `xml:"Iva,omitempty"`
+	Optionals            OptionalList          `xml:"Opcionales,omitempty"`
+	AssociatedPeriod     *AssociatedPeriod     `xml:"PeriodoAsoc,omitempty"`
+	Activities           ActivityList          `xml:"Actividades,omitempty"`
+}
+
+// AuthorizationRequest representa el request de FECAESolicitar
 type AuthorizationRequest struct {
-	Auth struct {
-		Token string `xml:"token"`
-		Sign  string `xml:"sign"`
-		CUIT  string `xml:"cuit"`
-	} `xml:"Auth"`
+	Auth    Auth `xml:"Auth"`
 	Request struct {
-		InvoiceType   int       `xml:"FeCabReq"`
-		PointOfSale   int       `xml:"FeCabReq"`
-		InvoiceNumber int       `xml:"FeCabReq"`
-		DateFrom      time.Time `xml:"FeCabReq"`
-		DateTo        time.Time `xml:"FeCabReq"`
-		ServiceFrom   string    `xml:"FeCabReq"`
-		Amount        float64   `xml:"FeCabReq"`
-		TaxAmount     float64   `xml:"FeCabReq"`
-		TotalAmount   float64   `xml:"FeCabReq"`
-		CurrencyType  string    `xml:"FeCabReq"`
-		CurrencyRate  float64   `xml:"FeCabReq"`
-		ConceptType   int       `xml:"FeCabReq"`
-		DocType       int       `xml:"FeDetReq"`
-		DocNumber     string    `xml:"FeDetReq"`
-		DocTypeFrom   int       `xml:"FeDetReq"`
-		DocNumberFrom string    `xml:"FeDetReq"`
-		NameFrom      string    `xml:"FeDetReq"`
-		Items         []struct {
-			Description string  `xml:"Concepto"`
-			Quantity    float64 `xml:"Cantidad"`
-			UnitPrice   float64 `xml:"PrecioUnit"`
-			TotalPrice  float64 `xml:"Importe"`
-			ProductCode string  `xml:"CodProd"`
-			UnitMeasure string  `xml:"UnidadMedida"`
-			Discount    float64 `xml:"Descuento"`
-		} `xml:"FeDetReq"`
+		Header struct {
+			Count       int `xml:"CantReg"`
+			PointOfSale int `xml:"PtoVta"`
+			InvoiceType int `xml:"CbteTipo"`
+		} `xml:"FeCabReq"`
+		Details []AuthorizationDetail `xml:"FeDetReq>FECAEDetRequest"`
 	} `xml:"FeCAEReq"`
 }
 
-// AuthorizationResponse representa la respuesta de autorización
+// AuthorizationResponse representa la respuesta de autorización. La
+// cabecera (FeCabResp) informa el lote y cada detalle (FECAEDetResponse) el
+// resultado y el CAE de un comprobante.
 type AuthorizationResponse struct {
-	Result struct {
-		CAE               string    `xml:"CAE"`
-		CAEDueDate        time.Time `xml:"CAEFchVto"`
-		InvoiceNumber     int       `xml:"CbteDesde"`
-		PointOfSale       int       `xml:"PuntoVta"`
-		InvoiceType       int       `xml:"CbteTipo"`
-		AuthorizationDate time.Time `xml:"FchProceso"`
-		Status            string    `xml:"Resultado"`
-		Message           string    `xml:"Observaciones"`
+	Header struct {
+		PointOfSale       int      `xml:"PtoVta"`
+		InvoiceType       int      `xml:"CbteTipo"`
+		AuthorizationDate AFIPDate `xml:"FchProceso"`
+		Status            string   `xml:"Resultado"`
 	} `xml:"FeCabResp"`
+	Details []struct {
+		InvoiceNumber int      `xml:"CbteDesde"`
+		CAE           string   `xml:"CAE"`
+		CAEDueDate    AFIPDate `xml:"CAEFchVto"`
+		Status        string   `xml:"Resultado"`
+		Observations  []struct {
+			Code    string `xml:"Code"`
+			Message string `xml:"Msg"`
+		} `xml:"Observaciones>Obs"`
+	} `xml:"FeDetResp>FECAEDetResponse"`
 	Errors []struct {
 		Code    string `xml:"Code"`
 		Message string `xml:"Msg"`
-	} `xml:"Errors"`
+	} `xml:"Errors>Err"`
 }
 
-// QueryRequest representa el request de consulta
+// QueryRequest representa el request de FECompConsultar
 type QueryRequest struct {
-	Auth struct {
-		Token string `xml:"token"`
-		Sign  string `xml:"sign"`
-		CUIT  string `xml:"cuit"`
-	} `xml:"Auth"`
+	Auth    Auth `xml:"Auth"`
 	Request struct {
-		InvoiceType   int `xml:"FeCompConsReq"`
-		PointOfSale   int `xml:"FeCompConsReq"`
-		InvoiceNumber int `xml:"FeCompConsReq"`
+		InvoiceType   int `xml:"CbteTipo"`
+		InvoiceNumber int `xml:"CbteNro"`
+		PointOfSale   int `xml:"PtoVta"`
 	} `xml:"FeCompConsReq"`
 }
 
-// QueryResponse representa la respuesta de consulta
+// QueryResponse representa la respuesta de FECompConsultar
 type QueryResponse struct {
 	Result struct {
-		InvoiceType   int       `xml:"CbteTipo"`
-		PointOfSale   int       `xml:"PuntoVta"`
-		InvoiceNumber int       `xml:"CbteNro"`
-		DateFrom      time.Time `xml:"CbteFch"`
-		Amount        float64   `xml:"ImpTotal"`
-		CurrencyType  string    `xml:"MonId"`
-		CurrencyRate  float64   `xml:"MonCotIz"`
-		Status        string    `xml:"Resultado"`
-		Message       string    `xml:"Observaciones"`
-	} `xml:"FeCompConsResult"`
+		ConceptType          int      `xml:"Concepto"`
+		DocType              int      `xml:"DocTipo"`
+		DocNumber            string   `xml:"DocNro"`
+		InvoiceType          int      `xml:"CbteTipo"`
+		PointOfSale          int      `xml:"PtoVta"`
+		InvoiceNumber        int      `xml:"CbteDesde"`
+		DateFrom             AFIPDate `xml:"CbteFch"`
+		TotalAmount          float64  `xml:"ImpTotal"`
+		UntaxedAmount        float64  `xml:"ImpTotConc"`
+		Amount               float64  `xml:"ImpNeto"`
+		ExemptAmount         float64  `xml:"ImpOpEx"`
+		TributeAmount        float64  `xml:"ImpTrib"`
+		TaxAmount            float64  `xml:"ImpIVA"`
+		ServiceFrom          AFIPDate `xml:"FchServDesde"`
+		ServiceTo            AFIPDate `xml:"FchServHasta"`
+		PaymentDueDate       AFIPDate `xml:"FchVtoPago"`
+		CurrencyType         string   `xml:"MonId"`
+		CurrencyRate         float64  `xml:"MonCotiz"`
+		ReceptorIVACondition int      `xml:"CondicionIVAReceptorId"`
+		IVA                  []struct {
+			ID     int     `xml:"Id"`
+			Base   float64 `xml:"BaseImp"`
+			Amount float64 `xml:"Importe"`
+		} `xml:"Iva>AlicIva"`
+		CAE        string   `xml:"CodAutorizacion"`
+		CAEDueDate AFIPDate `xml:"FchVto"`
+		Status     string   `xml:"Resultado"`
+	} `xml:"ResultGet"`
 	Errors []struct {
 		Code    string `xml:"Code"`
 		Message string `xml:"Msg"`
-	} `xml:"Errors"`
+	} `xml:"Errors>Err"`
 }
 
-// LastAuthorizedRequest representa el request para obtener el último autorizado
+// LastAuthorizedRequest representa el request de FECompUltimoAutorizado
 type LastAuthorizedRequest struct {
-	Auth struct {
-		Token string `xml:"token"`
-		Sign  string `xml:"sign"`
-		CUIT  string `xml:"cuit"`
-	} `xml:"Auth"`
-	Request struct {
-		InvoiceType int `xml:"FeCompUltimoAutorizadoReq"`
-		PointOfSale int `xml:"FeCompUltimoAutorizadoReq"`
-	} `xml:"FeCompUltimoAutorizadoReq"`
+	Auth        Auth `xml:"Auth"`
+	PointOfSale int  `xml:"PtoVta"`
+	InvoiceType int  `xml:"CbteTipo"`
 }
 
-// LastAuthorizedResponse representa la respuesta del último autorizado
+// LastAuthorizedResponse representa la respuesta del último autorizado.
+// FECompUltimoAutorizadoResult sólo informa punto de venta, tipo y número;
+// el número es 0 si todavía no hay comprobantes autorizados.
 type LastAuthorizedResponse struct {
-	Result struct {
-		InvoiceType   int       `xml:"CbteTipo"`
-		PointOfSale   int       `xml:"PuntoVta"`
-		InvoiceNumber int       `xml:"CbteNro"`
-		DateFrom      time.Time `xml:"CbteFch"`
-		Amount        float64   `xml:"ImpTotal"`
-		CurrencyType  string    `xml:"MonId"`
-		CurrencyRate  float64   `xml:"MonCotIz"`
-	} `xml:"FeCompUltimoAutorizadoResult"`
-	Errors []struct {
+	PointOfSale   int `xml:"PtoVta"`
+	InvoiceType   int `xml:"CbteTipo"`
+	InvoiceNumber int `xml:"CbteNro"`
+	Errors        []struct {
 		Code    string `xml:"Code"`
 		Message string `xml:"Msg"`
-	} `xml:"Errors"`
+	} `xml:"Errors>Err"`
 }
 
 // ParametersRequest representa el request de parámetros
 type ParametersRequest struct {
-	Auth struct {
-		Token string `xml:"token"`
-		Sign  string `xml:"sign"`
-		CUIT  string `xml:"cuit"`
-	} `xml:"Auth"`
+	Auth Auth `xml:"Auth"`
 }
 
-// ParametersResponse representa la respuesta de parámetros
-type ParametersResponse struct {
+// DocumentTypesResponse representa la respuesta de FEParamGetTiposDoc
+type DocumentTypesResponse struct {
 	DocumentTypes []struct {
 		ID          int    `xml:"Id"`
 		Description string `xml:"Desc"`
-		Active      bool   `xml:"FchDesde"`
-	} `xml:"DocTipo"`
-	InvoiceTypes []struct {
+		DateFrom    string `xml:"FchDesde"`
+		DateTo      string `xml:"FchHasta"`
+	} `xml:"ResultGet>DocTipo"`
+	Errors []struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Msg"`
+	} `xml:"Errors>Err"`
+}
+
+// ConceptTypesResponse representa la respuesta de FEParamGetTiposConcepto
+type ConceptTypesResponse struct {
+	ConceptTypes []struct {
 		ID          int    `xml:"Id"`
 		Description string `xml:"Desc"`
-		Active      bool   `xml:"FchDesde"`
-	} `xml:"CbteTipo"`
-	CurrencyTypes []struct {
+		DateFrom    string `xml:"FchDesde"`
+		DateTo      string `xml:"FchHasta"`
+	} `xml:"ResultGet>ConceptoTipo"`
+	Errors []struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Msg"`
+	} `xml:"Errors>Err"`
+}
+
+// PointsOfSaleResponse representa la respuesta de FEParamGetPtosVenta.
+// Bloqueado es "S" o "N" y FchBaja es "NULL" si el punto de venta sigue vigente.
+type PointsOfSaleResponse struct {
+	PointsOfSale []struct {
+		Number       int    `xml:"Nro"`
+		EmissionType string `xml:"EmisionTipo"`
+		Blocked      string `xml:"Bloqueado"`
+		DateTo       string `xml:"FchBaja"`
+	} `xml:"ResultGet>PtoVenta"`
+	Errors []struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Msg"`
+	} `xml:"Errors>Err"`
+}
+
+// OptionalTypesResponse representa la respuesta de FEParamGetTiposOpcional
+type OptionalTypesResponse struct {
+	OptionalTypes []struct {
 		ID          string `xml:"Id"`
 		Description string `xml:"Desc"`
-		Active      bool   `xml:"FchDesde"`
-	} `xml:"MonId"`
+		DateFrom    string `xml:"FchDesde"`
+		DateTo      string `xml:"FchHasta"`
+	} `xml:"ResultGet>OpcionalTipo"`
+	Errors []struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Msg"`
+	} `xml:"Errors>Err"`
+}
+
+// TaxRatesResponse representa la respuesta de FEParamGetTiposIva
+type TaxRatesResponse struct {
 	TaxRates []struct {
 		ID          int    `xml:"Id"`
 		Description string `xml:"Desc"`
-		Active      bool   `xml:"FchDesde"`
-	} `xml:"IvaTipo"`
-	ConceptTypes []struct {
+		DateFrom    string `xml:"FchDesde"`
+		DateTo      string `xml:"FchHasta"`
+	} `xml:"ResultGet>IvaTipo"`
+	Errors []struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Msg"`
+	} `xml:"Errors>Err"`
+}
+
+// InvoiceTypesResponse representa la respuesta de FEParamGetTiposCbte
+type InvoiceTypesResponse struct {
+	InvoiceTypes []struct {
+		ID          int    `xml:"Id"`
+		Description string `xml:"Desc"`
+		DateFrom    string `xml:"FchDesde"`
+		DateTo      string `xml:"FchHasta"`
+	} `xml:"ResultGet>CbteTipo"`
+	Errors []struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Msg"`
+	} `xml:"Errors>Err"`
+}
+
+// CurrencyTypesResponse representa la respuesta de FEParamGetTiposMonedas
+type CurrencyTypesResponse struct {
+	CurrencyTypes []struct {
+		ID          string `xml:"Id"`
+		Description string `xml:"Desc"`
+		DateFrom    string `xml:"FchDesde"`
+		DateTo      string `xml:"FchHasta"`
+	} `xml:"ResultGet>Moneda"`
+	Errors []struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Msg"`
+	} `xml:"Errors>Err"`
+}
+
+// ActivitiesResponse representa la respuesta de FEParamGetActividades
+type ActivitiesResponse struct {
+	Activities []struct {
 		ID          int    `xml:"Id"`
+		Order       int    `xml:"Orden"`
 		Description string `xml:"Desc"`
-		Active      bool   `xml:"FchDesde"`
-	} `xml:"ConceptoTipo"`
-	LastUpdate time.Time `xml:"FchServDesde"`
-	Errors     []struct {
+	} `xml:"ResultGet>ActividadesTipo"`
+	Errors []struct {
 		Code    string `xml:"Code"`
 		Message string `xml:"Msg"`
-	} `xml:"Errors"`
+	} `xml:"Errors>Err"`
 }
 
-// CAEARequest representa el request de CAEA
+// ReceptorIVAConditionsRequest representa el request de
+// FEParamGetCondicionIvaReceptor. ClaseCmp filtra por clase de comprobante
+// (A, B, C, M); vacío retorna todas las condiciones.
+type ReceptorIVAConditionsRequest struct {
+	Auth         Auth   `xml:"Auth"`
+	InvoiceClass string `xml:"ClaseCmp,omitempty"`
+}
+
+// ReceptorIVAConditionsResponse representa la respuesta de FEParamGetCondicionIvaReceptor
+type ReceptorIVAConditionsResponse struct {
+	Conditions []struct {
+		ID           int    `xml:"Id"`
+		Description  string `xml:"Desc"`
+		InvoiceClass string `xml:"Cmp_Clase"`
+	} `xml:"ResultGet>CondicionIvaReceptor"`
+	Errors []struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Msg"`
+	} `xml:"Errors>Err"`
+}
+
+// CAEARequest representa el request de FECAEASolicitar. El período
+// (AAAAMM) ya incluye el año.
 type CAEARequest struct {
-	Auth struct {
-		Token string `xml:"token"`
-		Sign  string `xml:"sign"`
-		CUIT  string `xml:"cuit"`
-	} `xml:"Auth"`
-	Request struct {
-		Period     int `xml:"CAEAReq"`
-		Order      int `xml:"CAEAReq"`
-		FiscalYear int `xml:"CAEAReq"`
-	} `xml:"CAEAReq"`
+	Auth   Auth `xml:"Auth"`
+	Period int  `xml:"Periodo"`
+	Order  int  `xml:"Orden"`
 }
 
 // CAEAResponse representa la respuesta de CAEA
 type CAEAResponse struct {
 	Result struct {
-		CAEA       string    `xml:"CAEA"`
-		Period     int       `xml:"Periodo"`
-		Order      int       `xml:"Orden"`
-		FiscalYear int       `xml:"FchVigDesde"`
-		DueDate    time.Time `xml:"FchVigHasta"`
-		MaxAmount  float64   `xml:"MaximoImporte"`
-		Status     string    `xml:"Resultado"`
-		Message    string    `xml:"Observaciones"`
+		CAEA           string   `xml:"CAEA"`
+		Period         int      `xml:"Periodo"`
+		Order          int      `xml:"Orden"`
+		ValidFrom      AFIPDate `xml:"FchVigDesde"`
+		DueDate        AFIPDate `xml:"FchVigHasta"`
+		ReportDeadline AFIPDate `xml:"FchTopeInf"`
+		MaxAmount      float64  `xml:"MaximoImporte"`
+		Status         string   `xml:"Resultado"`
+		Message        string   `xml:"Observaciones"`
 	} `xml:"CAEAResult"`
 	Errors []struct {
 		Code    string `xml:"Code"`
 		Message string `xml:"Msg"`
-	} `xml:"Errors"`
+	} `xml:"Errors>Err"`
+}
+
+// ToCAEAResponse convierte la respuesta a models.CAEAResponse
+func (r *CAEAResponse) ToCAEAResponse() *models.CAEAResponse {
+	return &models.CAEAResponse{
+		CAEA:           r.Result.CAEA,
+		Period:         r.Result.Period,
+		Order:          r.Result.Order,
+		ValidFrom:      r.Result.ValidFrom.Time,
+		ExpirationDate: r.Result.DueDate.Time,
+		ReportDeadline: r.Result.ReportDeadline.Time,
+		MaxAmount:      r.Result.MaxAmount,
+		Status:         r.Result.Status,
+		Message:        r.Result.Message,
+	}
+}
+
+// CAEARegisterDetail representa un comprobante emitido con CAEA a informar
+// (FECAEADetRequest): los datos de FECAEDetRequest seguidos del CAEA
+type CAEARegisterDetail struct {
+	AuthorizationDetail
+	CAEA string `xml:"CAEA"`
+}
+
+// CAEARegisterRequest representa el request de FECAEARegInformativo
+type CAEARegisterRequest struct {
+	Auth    Auth `xml:"Auth"`
+	Request struct {
+		Header struct {
+			Count       int `xml:"CantReg"`
+			PointOfSale int `xml:"PtoVta"`
+			InvoiceType int `xml:"CbteTipo"`
+		} `xml:"FeCabReq"`
+		Details []CAEARegisterDetail `xml:"FeDetReq>FECAEADetRequest"`
+	} `xml:"FeCAEARegInfReq"`
+}
+
+// CAEARegisterResponse representa la respuesta de FECAEARegInformativo
+type CAEARegisterResponse struct {
+	Header struct {
+		PointOfSale int    `xml:"PtoVta"`
+		InvoiceType int    `xml:"CbteTipo"`
+		Status      string `xml:"Resultado"`
+	} `xml:"FeCabResp"`
+	Details []struct {
+		InvoiceNumber int    `xml:"CbteDesde"`
+		CAEA          string `xml:"CAEA"`
+		Status        string `xml:"Resultado"`
+		Observations  []struct {
+			Code    string `xml:"Code"`
+			Message string `xml:"Msg"`
+		} `xml:"Observaciones>Obs"`
+	} `xml:"FeDetResp>FECAEADetResponse"`
+	Errors []struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Msg"`
+	} `xml:"Errors>Err"`
+}
+
+// CAEANoMovementRequest representa el request de FECAEASinMovimientoInformar
+type CAEANoMovementRequest struct {
+	Auth        Auth   `xml:"Auth"`
+	PointOfSale int    `xml:"PtoVta"`
+	CAEA        string `xml:"CAEA"`
+}
+
+// CAEANoMovementResponse representa la respuesta de FECAEASinMovimientoInformar
+type CAEANoMovementResponse struct {
+	Result struct {
+		CAEA        string `xml:"CAEA"`
+		PointOfSale int    `xml:"PtoVta"`
+		Status      string `xml:"Resultado"`
+	} `xml:"FECAEASinMovimientoInformarResult"`
+	Errors []struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Msg"`
+	} `xml:"Errors>Err"`
+}
+
+// DummyRequest representa el request de FEDummy (no requiere autenticación)
+type DummyRequest struct {
+	XMLName xml.Name `xml:"http://ar.gov.afip.dif.FEV1/ FEDummy"`
+}
+
+// DummyResponse representa el estado de los servidores de WSFEv1
+type DummyResponse struct {
+	AppServer  string `xml:"AppServer"`
+	DbServer   string `xml:"DbServer"`
+	AuthServer string `xml:"AuthServer"`
 }
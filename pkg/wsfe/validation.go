@@ -0,0 +1,191 @@
+package wsfe
+
+import (
+	"time"
+
+	"github.com/dlarregola/arca_invoice_lib/internal/utils"
+	"github.com/dlarregola/arca_invoice_lib/pkg/models"
+)
+
+// validationOptions son los parámetros del Service que afectan la validación
+// de una factura
+type validationOptions struct {
+	now               time.Time
+	liveCurrencies    bool
+	requireActivities bool
+	regime            RegimeContext
+	// consumidorFinalThreshold es el umbral de identificación del
+	// consumidor final; 0 usa utils.ConsumidorFinalThreshold
+	consumidorFinalThreshold float64
+}
+
+// Validate valida la factura sin crear un Service ni llamar a AFIP, por
+// ejemplo para validar un formulario antes de enviarlo. Retorna
+// models.ValidationErrors con todos los problemas encontrados. Usa la hora
+// del sistema y no aplica las opciones del Service (monedas en vivo,
+// actividades obligatorias, régimen especial), que sí aplica AuthorizeInvoice.
+func (i *Invoice) Validate() error {
+	return i.validate(validationOptions{now: time.Now()})
+}
+
+// amountInPesos retorna el total del comprobante convertido a pesos
+func (i *Invoice) amountInPesos() float64 {
+	if i.CurrencyType != "" && i.CurrencyType != models.CurrencyTypePES && i.CurrencyRate > 0 {
+		return i.TotalAmount * i.CurrencyRate
+	}
+	return i.TotalAmount
+}
+
+// validate valida la factura según opts
+func (i *Invoice) validate(opts validationOptions) error {
+	var errors models.ValidationErrors
+
+	// Validar campos básicos
+	if err := utils.ValidateInvoiceType(i.InvoiceType); err != nil {
+		errors.Add("invoice_type", err.Error(), i.InvoiceType)
+	}
+
+	if err := utils.ValidatePointOfSale(i.PointOfSale); err != nil {
+		errors.Add("point_of_sale", err.Error(), i.PointOfSale)
+	}
+
+	if err := utils.ValidateInvoiceNumber(i.InvoiceNumber); err != nil {
+		errors.Add("invoice_number", err.Error(), i.InvoiceNumber)
+	}
+
+	// Sin IssueDate la fecha del comprobante se toma de DateFrom
+	if i.IssueDate.IsZero() {
+		if err := utils.ValidateDateAt(i.DateFrom, "date_from", opts.now); err != nil {
+			errors.Add("date_from", err.Error(), i.DateFrom)
+		}
+
+		if err := utils.ValidateDateAt(i.DateTo, "date_to", opts.now); err != nil {
+			errors.Add("date_to", err.Error(), i.DateTo)
+		}
+	}
+
+	if issueDate := i.GetIssueDate(); !issueDate.IsZero() {
+		if err := utils.ValidateIssueDate(i.ConceptType, issueDate, opts.now); err != nil {
+			errors.Add("issue_date", err.Error(), issueDate)
+		}
+	}
+
+	if err := utils.ValidateConceptType(i.ConceptType); err != nil {
+		errors.Add("concept_type", err.Error(), i.ConceptType)
+	}
+
+	if err := utils.ValidateConceptDates(i.ConceptType, i.ServiceFrom, i.ServiceTo, i.PaymentDueDate, true); err != nil {
+		errors.Add("service_dates", err.Error(), i.ConceptType)
+	}
+
+	if err := utils.ValidatePaymentDueDate(i.ConceptType, i.PaymentDueDate, i.GetIssueDate()); err != nil {
+		errors.Add("payment_due_date", err.Error(), i.PaymentDueDate)
+	}
+
+	// Con validación en vivo la moneda se valida contra la lista de AFIP
+	if !opts.liveCurrencies {
+		if err := utils.ValidateCurrencyType(i.CurrencyType); err != nil {
+			errors.Add("currency_type", err.Error(), i.CurrencyType)
+		}
+	}
+
+	if err := utils.ValidateAmount(i.Amount, "amount"); err != nil {
+		errors.Add("amount", err.Error(), i.Amount)
+	}
+
+	if err := utils.ValidateAmount(i.TaxAmount, "tax_amount"); err != nil {
+		errors.Add("tax_amount", err.Error(), i.TaxAmount)
+	}
+
+	if err := utils.ValidateAmount(i.TotalAmount, "total_amount"); err != nil {
+		errors.Add("total_amount", err.Error(), i.TotalAmount)
+	}
+
+	if err := utils.ValidateAmount(i.NonTaxableAmount, "non_taxable_amount"); err != nil {
+		errors.Add("non_taxable_amount", err.Error(), i.NonTaxableAmount)
+	}
+
+	if err := utils.ValidateAmount(i.ExemptAmount, "exempt_amount"); err != nil {
+		errors.Add("exempt_amount", err.Error(), i.ExemptAmount)
+	}
+
+	nonTaxable, exempt := i.UntaxedTotals()
+	if err := utils.ValidateClassCUntaxedAmounts(i.InvoiceType, nonTaxable, exempt); err != nil {
+		errors.Add("non_taxable_amount", err.Error(), nonTaxable)
+	}
+
+	if err := utils.ValidateInvoiceTotal(i.Amount, nonTaxable, exempt, i.TaxAmount, i.TotalAmount, i.Taxes); err != nil {
+		errors.Add("total_amount", err.Error(), i.TotalAmount)
+	}
+
+	// Validar documento
+	if err := utils.ValidateDocumentType(i.DocType); err != nil {
+		errors.Add("doc_type", err.Error(), i.DocType)
+	}
+
+	if err := utils.ValidateDocumentNumber(i.DocType, i.DocNumber); err != nil {
+		errors.Add("doc_number", err.Error(), i.DocNumber)
+	}
+
+	if err := utils.ValidateConsumidorFinal(i.InvoiceType, i.DocType); err != nil {
+		errors.Add("doc_type", err.Error(), i.DocType)
+	} else if err := utils.ValidateConsumidorFinalAmount(i.DocType, i.amountInPesos(), opts.consumidorFinalThreshold); err != nil {
+		errors.Add("doc_type", err.Error(), i.TotalAmount)
+	}
+
+	// Validar documento del cliente
+	if err := utils.ValidateDocumentType(i.DocTypeFrom); err != nil {
+		errors.Add("doc_type_from", err.Error(), i.DocTypeFrom)
+	}
+
+	if err := utils.ValidateDocumentNumber(i.DocTypeFrom, i.DocNumberFrom); err != nil {
+		errors.Add("doc_number_from", err.Error(), i.DocNumberFrom)
+	}
+
+	// Validar ítems
+	if err := utils.ValidateItems(i.Items); err != nil {
+		errors.Add("items", err.Error(), i.Items)
+	}
+
+	if err := utils.ValidateClassCTaxes(i.InvoiceType, i.TaxAmount, i.Items); err != nil {
+		errors.Add("tax_amount", err.Error(), i.TaxAmount)
+	} else if err := utils.ValidateItemsTaxes(i.TaxAmount, i.Items); err != nil {
+		errors.Add("tax_amount", err.Error(), i.TaxAmount)
+	} else if err := utils.ValidateTaxBreakdown(i.TaxAmount, i.Items); err != nil {
+		errors.Add("tax_amount", err.Error(), i.TaxAmount)
+	}
+
+	// Validar comprobantes o período asociados
+	if err := utils.ValidateAssociatedReferences(i.InvoiceType, i.AssociatedInvoices, i.AssociatedPeriod); err != nil {
+		errors.Add("associated_invoices", err.Error(), i.AssociatedInvoices)
+	}
+
+	// Validar datos opcionales
+	errors = append(errors, utils.ValidateOptionals(i.Optionals)...)
+
+	// Validar datos FCE MiPyME
+	if err := utils.ValidateFCE(i.InvoiceType, i.FCE); err != nil {
+		errors.Add("fce", err.Error(), i.FCE)
+	}
+
+	// Validar condición frente al IVA del receptor
+	if err := utils.ValidateReceptorIVACondition(i.ReceptorIVACondition); err != nil {
+		errors.Add("receptor_iva_condition", err.Error(), i.ReceptorIVACondition)
+	}
+
+	// Validar actividades del emisor
+	if err := utils.ValidateActivityCodes(i.ActivityCodes, opts.requireActivities); err != nil {
+		errors.Add("activity_codes", err.Error(), i.ActivityCodes)
+	}
+
+	// Validar régimen especial
+	if opts.regime != nil {
+		opts.regime.Validate(i, &errors)
+	}
+
+	if errors.HasErrors() {
+		return errors
+	}
+
+	return nil
+}
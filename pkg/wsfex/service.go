@@ -2,22 +2,34 @@ package wsfex
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
 
+	"github.com/dlarregola/arca_invoice_lib/internal/batch"
+	"github.com/dlarregola/arca_invoice_lib/internal/soap"
 	"github.com/dlarregola/arca_invoice_lib/internal/utils"
-	"github.com/dlarregola/arca_invoice_lib/pkg/client"
+	"github.com/dlarregola/arca_invoice_lib/pkg/core"
 	"github.com/dlarregola/arca_invoice_lib/pkg/models"
 )
 
 // Service representa el servicio WSFEXv1
 type Service struct {
-	config *client.Config
-	auth   *client.WSAAAuth
+	config *core.Config
+	auth   *core.WSAAAuth
 	logger interface{}
+
+	liveUnitMeasures  bool
+	unitMeasures      map[models.Environment][]models.UnitType
+	unitMeasuresMutex sync.Mutex
+
+	soapClient *soap.Client
+	soapOnce   sync.Once
 }
 
 // NewService crea un nuevo servicio WSFEXv1
-func NewService(config *client.Config, auth *client.WSAAAuth, logger interface{}) *Service {
+func NewService(config *core.Config, auth *core.WSAAAuth, logger interface{}) *Service {
 	return &Service{
 		config: config,
 		auth:   auth,
@@ -25,6 +37,16 @@ func NewService(config *client.Config, auth *client.WSAAAuth, logger interface{}
 	}
 }
 
+// NewAuth arma el bloque Auth a partir del ticket de acceso. El CUIT
+// informado es el representado, si está configurado.
+func NewAuth(config *core.Config, ticket *core.AccessTicket) Auth {
+	return Auth{
+		Token: ticket.Token,
+		Sign:  ticket.Sign,
+		CUIT:  config.GetAuthCUIT(),
+	}
+}
+
 // AuthorizeExportInvoice autoriza una factura de exportación
 func (s *Service) AuthorizeExportInvoice(ctx context.Context, invoice *ExportInvoice) (*models.AuthorizationResult, error) {
 	// Validar factura
@@ -32,6 +54,12 @@ func (s *Service) AuthorizeExportInvoice(ctx context.Context, invoice *ExportInv
 		return nil, err
 	}
 
+	if s.liveUnitMeasures {
+		if err := s.validateUnitMeasures(ctx, invoice); err != nil {
+			return nil, err
+		}
+	}
+
 	// Obtener ticket de acceso
 	ticket, err := s.auth.GetAccessTicket(ctx, "wsfex")
 	if err != nil {
@@ -39,80 +67,209 @@ func (s *Service) AuthorizeExportInvoice(ctx context.Context, invoice *ExportInv
 	}
 
 	// Crear request
+	request := s.NewAuthorizationRequest(invoice, NewAuth(s.config, ticket))
+
+	// Realizar llamada SOAP
+	var response ExportAuthorizationResponse
+	if err := s.callSOAP(ctx, "FEXAuthorize", request, &response); err != nil {
+		return nil, err
+	}
+
+	// Verificar errores
+	if len(response.Errors) > 0 {
+		error := response.Errors[0]
+		return nil, models.NewServiceError(error.Code, error.Message)
+	}
+
+	// Crear resultado (un Resultado desconocido se conserva tal como vino)
+	status, _ := models.ParseAuthResult(response.Result.Status)
+	result := &models.AuthorizationResult{
+		CAE:               response.Result.CAE,
+		CAEExpirationDate: response.Result.CAEDueDate.Time,
+		InvoiceNumber:     response.Result.InvoiceNumber,
+		PointOfSale:       response.Result.PointOfSale,
+		InvoiceType:       models.InvoiceType(response.Result.InvoiceType),
+		AuthorizationDate: response.Result.InvoiceDate.Time,
+		Status:            status,
+		Message:           response.Result.Message,
+	}
+	core.LogInfof(ctx, s.logger, "Export invoice %s %s authorized: status %s, CAE %s", result.InvoiceType, models.FormatInvoiceNumber(result.PointOfSale, result.InvoiceNumber), result.Status, result.CAE)
+
+	return result, nil
+}
+
+// AuthorizeExportInvoices autoriza un lote de facturas de exportación con a
+// lo sumo concurrency llamadas simultáneas a AFIP (4 si es 0). FEXAuthorize
+// acepta un único comprobante por llamada, por lo que cada factura se envía
+// por separado. Los resultados respetan el orden del lote; las facturas que
+// fallan quedan en nil y sus errores se informan juntos en un
+// *models.BatchError. Como en WSFE, las facturas de un mismo punto de venta y
+// tipo deben numerarse de antemano.
+func (s *Service) AuthorizeExportInvoices(ctx context.Context, invoices []*ExportInvoice, concurrency int) ([]*models.AuthorizationResult, error) {
+	results, errs := batch.Run(ctx, invoices, concurrency, s.AuthorizeExportInvoice)
+	return results, models.NewBatchError(errs)
+}
+
+// BuildAuthorizeRequestXML valida la factura y retorna el envelope SOAP de
+// FEXAuthorize que enviaría AuthorizeExportInvoice, sin llamar a WSAA ni a
+// WSFEX. Como no se pide un ticket de acceso, Token y Sign quedan vacíos.
+func (s *Service) BuildAuthorizeRequestXML(invoice *ExportInvoice) ([]byte, error) {
+	// Validar factura
+	if err := s.validateExportInvoice(invoice); err != nil {
+		return nil, err
+	}
+
+	// Crear request
+	request := s.NewAuthorizationRequest(invoice, NewAuth(s.config, &core.AccessTicket{}))
+
+	return soap.BuildEnvelope("FEXAuthorize", request)
+}
+
+// DryRunResult es el resultado de ValidateAndBuild: los errores de
+// validación o, si la factura es válida, el request y el envelope SOAP que
+// enviaría AuthorizeExportInvoice
+type DryRunResult struct {
+	Request          *ExportAuthorizationRequest
+	Envelope         []byte
+	ValidationErrors models.ValidationErrors
+}
+
+// Valid indica si la factura pasó la validación
+func (r *DryRunResult) Valid() bool {
+	return !r.ValidationErrors.HasErrors()
+}
+
+// ValidateAndBuild valida la factura de exportación y arma el request de
+// FEXAuthorize sin llamar a WSAA ni a WSFEX, por lo que no consume
+// numeración de AFIP. Los problemas de la factura se informan en
+// ValidationErrors del resultado; el error se reserva para fallas al armar
+// el request. La validación en vivo de unidades de medida no se aplica
+// porque consulta a AFIP.
+func (s *Service) ValidateAndBuild(invoice *ExportInvoice) (*DryRunResult, error) {
+	// Validar factura
+	result := &DryRunResult{}
+	if err := s.validateExportInvoice(invoice); err != nil {
+		if !errors.As(err, &result.ValidationErrors) {
+			return nil, err
+		}
+		return result, nil
+	}
+
+	// Crear request
+	request := s.NewAuthorizationRequest(invoice, NewAuth(s.config, &core.AccessTicket{}))
+	envelope, err := soap.BuildEnvelope("FEXAuthorize", request)
+	if err != nil {
+		return nil, err
+	}
+
+	result.Request = request
+	result.Envelope = envelope
+	return result, nil
+}
+
+// NewAuthorizationRequest arma el request de FEXAuthorize para una factura de exportación
+func (s *Service) NewAuthorizationRequest(invoice *ExportInvoice, auth Auth) *ExportAuthorizationRequest {
 	request := &ExportAuthorizationRequest{}
-	request.Auth.Token = ticket.Token
-	request.Auth.Sign = ticket.Sign
-	request.Auth.CUIT = s.config.CUIT
+	request.Auth = auth
 
 	// Configurar datos de la factura
+	request.Request.ID = invoice.RequestID
+	if request.Request.ID == 0 {
+		request.Request.ID = int64(invoice.InvoiceNumber)
+	}
+	request.Request.InvoiceDate = AFIPDate{Time: invoice.DateFrom}
 	request.Request.InvoiceType = int(invoice.InvoiceType)
 	request.Request.PointOfSale = invoice.PointOfSale
 	request.Request.InvoiceNumber = invoice.InvoiceNumber
-	request.Request.DateFrom = invoice.DateFrom
-	request.Request.DateTo = invoice.DateTo
-	request.Request.ServiceFrom = invoice.ServiceFrom
-	request.Request.Amount = invoice.Amount
-	request.Request.TaxAmount = invoice.TaxAmount
-	request.Request.TotalAmount = invoice.TotalAmount
+	request.Request.ExportType = int(invoice.GetExportType())
 	request.Request.CurrencyType = string(invoice.CurrencyType)
 	request.Request.CurrencyRate = invoice.CurrencyRate
-	request.Request.ConceptType = int(invoice.ConceptType)
-	request.Request.DocType = int(invoice.DocType)
-	request.Request.DocNumber = invoice.DocNumber
-	request.Request.DocTypeFrom = int(invoice.DocTypeFrom)
-	request.Request.DocNumberFrom = invoice.DocNumberFrom
-	request.Request.NameFrom = invoice.NameFrom
-	request.Request.CountryFrom = invoice.CountryFrom
+	request.Request.TotalAmount = Amount(invoice.TotalAmount)
+	request.Request.Notes = invoice.Notes
+	request.Request.Language = LanguageSpanish
+
+	// Configurar cliente del exterior
+	request.Request.DestinationCountry = invoice.CountryFrom
+	request.Request.CustomerName = invoice.CustomerName
+	request.Request.CustomerAddress = invoice.CustomerAddress
+	request.Request.CustomerCountryCUIT = invoice.CustomerCountryCUIT
+	request.Request.CustomerTaxID = invoice.DocNumber
+	for _, buyer := range invoice.Buyers {
+		request.Request.Buyers = append(request.Request.Buyers, Buyer{
+			DocType:    int(buyer.DocType),
+			DocNumber:  buyer.DocNumber,
+			Percentage: Amount(buyer.Percentage),
+		})
+	}
 
 	// Configurar ítems
 	for _, item := range invoice.Items {
-		requestItem := struct {
-			Description string  `xml:"Concepto"`
-			Quantity    float64 `xml:"Cantidad"`
-			UnitPrice   float64 `xml:"PrecioUnit"`
-			TotalPrice  float64 `xml:"Importe"`
-			ProductCode string  `xml:"CodProd"`
-			UnitMeasure string  `xml:"UnidadMedida"`
-			Discount    float64 `xml:"Descuento"`
-			Country     string  `xml:"PaisDestino"`
-		}{
+		unitMeasure, ok := models.ParseUnitMeasure(item.UnitMeasure)
+		if !ok {
+			unitMeasure = item.UnitMeasure
+		}
+		request.Request.Items = append(request.Request.Items, ExportItem{
+			ProductCode: item.ProductCode,
 			Description: item.Description,
 			Quantity:    item.Quantity,
+			UnitMeasure: unitMeasure,
 			UnitPrice:   item.UnitPrice,
-			TotalPrice:  item.TotalPrice,
-			ProductCode: item.ProductCode,
-			UnitMeasure: item.UnitMeasure,
-			Discount:    item.Discount,
-			Country:     item.Country,
-		}
-		request.Request.Items = append(request.Request.Items, requestItem)
+			Discount:    Amount(item.Discount),
+			TotalPrice:  Amount(item.TotalPrice),
+		})
 	}
 
-	// Realizar llamada SOAP
-	var response ExportAuthorizationResponse
-	if err := s.callSOAP(ctx, "FEXAuthorize", request, &response); err != nil {
-		return nil, err
+	// Configurar datos de exportación
+	request.Request.Incoterm = invoice.Incoterm
+	if invoice.ConceptType != models.ConceptTypeProducts {
+		request.Request.PaymentDate = soap.OptionalDate(invoice.PaymentDueDate)
+	}
+	request.Request.IncotermDescription = invoice.IncotermDescription
+	if invoice.ConceptType == models.ConceptTypeProducts {
+		request.Request.PermitExists = "N"
+		if len(invoice.Permits) > 0 {
+			request.Request.PermitExists = "S"
+		}
+	}
+	for _, permit := range invoice.Permits {
+		request.Request.Permits = append(request.Request.Permits, Permit{
+			ID:                 permit.ID,
+			DestinationCountry: permit.DestinationCountry,
+		})
 	}
 
-	// Verificar errores
-	if len(response.Errors) > 0 {
-		error := response.Errors[0]
-		return nil, models.NewARCAError(error.Code, error.Message)
+	// Configurar comprobantes asociados
+	for _, associated := range invoice.AssociatedInvoices {
+		request.Request.AssociatedInvoices = append(request.Request.AssociatedInvoices, AssociatedInvoice{
+			InvoiceType:   int(associated.InvoiceType),
+			PointOfSale:   associated.PointOfSale,
+			InvoiceNumber: associated.InvoiceNumber,
+			CUIT:          associated.CUIT,
+		})
 	}
 
-	// Crear resultado
-	result := &models.AuthorizationResult{
-		CAE:               response.Result.CAE,
-		CAEExpirationDate: response.Result.CAEDueDate,
-		InvoiceNumber:     response.Result.InvoiceNumber,
-		PointOfSale:       response.Result.PointOfSale,
-		InvoiceType:       models.InvoiceType(response.Result.InvoiceType),
-		AuthorizationDate: response.Result.AuthorizationDate,
-		Status:            response.Result.Status,
-		Message:           response.Result.Message,
+	return request
+}
+
+// GetExportType retorna el tipo de exportación (Tipo_expo): ExportType o,
+// si no se informó, el que corresponde al concepto
+func (i *ExportInvoice) GetExportType() models.ExportType {
+	if i.ExportType != 0 {
+		return i.ExportType
 	}
+	return models.ExportTypeForConcept(i.ConceptType)
+}
 
-	return result, nil
+// conceptType traduce el tipo de exportación (Tipo_expo) al concepto
+func conceptType(exportType models.ExportType) models.ConceptType {
+	switch exportType {
+	case ExportTypeGoods:
+		return models.ConceptTypeProducts
+	case ExportTypeServices:
+		return models.ConceptTypeServices
+	default:
+		return models.ConceptTypeMixed
+	}
 }
 
 // GetExportInvoice consulta una factura de exportación específica
@@ -136,9 +293,7 @@ func (s *Service) GetExportInvoice(ctx context.Context, pointOfSale, invoiceType
 
 	// Crear request
 	request := &ExportQueryRequest{}
-	request.Auth.Token = ticket.Token
-	request.Auth.Sign = ticket.Sign
-	request.Auth.CUIT = s.config.CUIT
+	request.Auth = NewAuth(s.config, ticket)
 	request.Request.InvoiceType = invoiceType
 	request.Request.PointOfSale = pointOfSale
 	request.Request.InvoiceNumber = invoiceNumber
@@ -152,27 +307,104 @@ func (s *Service) GetExportInvoice(ctx context.Context, pointOfSale, invoiceType
 	// Verificar errores
 	if len(response.Errors) > 0 {
 		error := response.Errors[0]
-		return nil, models.NewARCAError(error.Code, error.Message)
+		return nil, models.NewServiceError(error.Code, error.Message)
+	}
+
+	return newExportInvoiceFromQuery(&response)
+}
+
+// newExportInvoiceFromQuery arma la factura de exportación completa a partir
+// de la respuesta de FEXGetCMP
+func newExportInvoiceFromQuery(response *ExportQueryResponse) (*ExportInvoice, error) {
+	result := response.Result
+
+	invoiceDate, err := utils.ParseAFIPDate(result.InvoiceDate)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing Fecha_cbte: %w", err)
+	}
+	caeDueDate, err := utils.ParseAFIPDate(result.CAEDueDate)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing Fch_venc_Cae: %w", err)
 	}
 
 	// Crear factura
 	invoice := &ExportInvoice{
 		InvoiceBase: models.InvoiceBase{
-			InvoiceType:   models.InvoiceType(response.Result.InvoiceType),
-			PointOfSale:   response.Result.PointOfSale,
-			InvoiceNumber: response.Result.InvoiceNumber,
-			DateFrom:      response.Result.DateFrom,
-			Amount:        response.Result.Amount,
-			CurrencyType:  models.CurrencyType(response.Result.CurrencyType),
-			CurrencyRate:  response.Result.CurrencyRate,
+			InvoiceType:   models.InvoiceType(result.InvoiceType),
+			PointOfSale:   result.PointOfSale,
+			InvoiceNumber: result.InvoiceNumber,
+			DateFrom:      invoiceDate,
+			DateTo:        invoiceDate,
+			ConceptType:   conceptType(models.ExportType(result.ExportType)),
+			CurrencyType:  models.CurrencyType(result.CurrencyType),
+			CurrencyRate:  result.CurrencyRate,
+			Amount:        result.TotalAmount,
+			TotalAmount:   result.TotalAmount,
+			Notes:         result.Notes,
 		},
+		DocNumber:           result.CustomerTaxID,
+		CountryFrom:         result.DestinationCountry,
+		ExportType:          models.ExportType(result.ExportType),
+		CAE:                 result.CAE,
+		CAEDueDate:          caeDueDate,
+		CustomerName:        result.CustomerName,
+		CustomerAddress:     result.CustomerAddress,
+		CustomerCountryCUIT: result.CustomerCountryCUIT,
+		RequestID:           result.ID,
+		Incoterm:            result.Incoterm,
+		IncotermDescription: result.IncotermDescription,
+	}
+
+	// Mapear ítems
+	for _, item := range result.Items {
+		invoice.Items = append(invoice.Items, models.Item{
+			Description: item.Description,
+			Quantity:    item.Quantity,
+			UnitPrice:   item.UnitPrice,
+			TotalPrice:  float64(item.TotalPrice),
+			ProductCode: item.ProductCode,
+			UnitMeasure: item.UnitMeasure,
+			Discount:    float64(item.Discount),
+		})
+	}
+
+	// Mapear permisos de embarque y comprobantes asociados
+	for _, permit := range result.Permits {
+		invoice.Permits = append(invoice.Permits, models.ExportPermit{
+			ID:                 permit.ID,
+			DestinationCountry: permit.DestinationCountry,
+		})
+	}
+	for _, associated := range result.AssociatedInvoices {
+		invoice.AssociatedInvoices = append(invoice.AssociatedInvoices, models.AssociatedInvoice{
+			InvoiceType:   models.InvoiceType(associated.InvoiceType),
+			PointOfSale:   associated.PointOfSale,
+			InvoiceNumber: associated.InvoiceNumber,
+			CUIT:          associated.CUIT,
+		})
 	}
 
 	return invoice, nil
 }
 
-// GetLastAuthorizedExportInvoice obtiene el último comprobante de exportación autorizado
-func (s *Service) GetLastAuthorizedExportInvoice(ctx context.Context, pointOfSale, invoiceType int) (*models.AuthorizationResult, error) {
+// NextInvoiceNumber retorna el número a usar en el próximo comprobante de
+// exportación del punto de venta y tipo: el último autorizado
+// (FEXGetLast_CMP) más uno. Como en WSFE, el número no se reserva: si varios
+// procesos emiten en el mismo punto de venta deben coordinarse con un lock
+// externo.
+func (s *Service) NextInvoiceNumber(ctx context.Context, pointOfSale, invoiceType int) (int, error) {
+	last, err := s.GetLastAuthorizedExportInvoice(ctx, pointOfSale, invoiceType)
+	if err != nil {
+		return 0, err
+	}
+
+	return last.InvoiceNumber + 1, nil
+}
+
+// GetLastAuthorizedExportInvoice obtiene el último comprobante de exportación
+// autorizado para el punto de venta y tipo dados. Si todavía no se autorizó
+// ninguno, InvoiceNumber es 0 y Date queda en cero.
+func (s *Service) GetLastAuthorizedExportInvoice(ctx context.Context, pointOfSale, invoiceType int) (*models.LastInvoiceResponse, error) {
 	// Validar parámetros
 	if err := utils.ValidatePointOfSale(pointOfSale); err != nil {
 		return nil, err
@@ -189,11 +421,9 @@ func (s *Service) GetLastAuthorizedExportInvoice(ctx context.Context, pointOfSal
 
 	// Crear request
 	request := &ExportLastAuthorizedRequest{}
-	request.Auth.Token = ticket.Token
-	request.Auth.Sign = ticket.Sign
-	request.Auth.CUIT = s.config.CUIT
-	request.Request.InvoiceType = invoiceType
-	request.Request.PointOfSale = pointOfSale
+	request.Auth.Auth = NewAuth(s.config, ticket)
+	request.Auth.PointOfSale = pointOfSale
+	request.Auth.InvoiceType = invoiceType
 
 	// Realizar llamada SOAP
 	var response ExportLastAuthorizedResponse
@@ -204,23 +434,94 @@ func (s *Service) GetLastAuthorizedExportInvoice(ctx context.Context, pointOfSal
 	// Verificar errores
 	if len(response.Errors) > 0 {
 		error := response.Errors[0]
-		return nil, models.NewARCAError(error.Code, error.Message)
+		return nil, models.NewServiceError(error.Code, error.Message)
 	}
 
-	// Crear resultado
-	result := &models.AuthorizationResult{
-		InvoiceNumber:     response.Result.InvoiceNumber,
-		PointOfSale:       response.Result.PointOfSale,
-		InvoiceType:       models.InvoiceType(response.Result.InvoiceType),
-		AuthorizationDate: response.Result.DateFrom,
-		Status:            "A",
+	date, err := utils.ParseAFIPDate(response.Result.Date)
+	if err != nil {
+		return nil, models.NewARCAError(models.ErrorCodeInvalidResponse, fmt.Sprintf("invalid Cbte_fecha %q: %v", response.Result.Date, err))
+	}
+
+	// Crear resultado (AFIP no repite el punto de venta ni el tipo)
+	result := &models.LastInvoiceResponse{
+		InvoiceType:   models.InvoiceType(invoiceType),
+		PointOfSale:   pointOfSale,
+		InvoiceNumber: response.Result.InvoiceNumber,
+		Date:          date,
 	}
 
 	return result, nil
 }
 
-// GetExportParameters obtiene los parámetros del sistema de exportación
-func (s *Service) GetExportParameters(ctx context.Context) (*models.Parameters, error) {
+// GetExportParameters consulta en paralelo todos los catálogos de
+// exportación: tipos de comprobante (FEXGetPARAM_Tipo_Cbte), monedas
+// (FEXGetPARAM_MON), tipos de exportación (FEXGetPARAM_Tipo_Expo), países de
+// destino (FEXGetPARAM_DST_pais), Incoterms (FEXGetPARAM_Incoterms) y
+// unidades de medida (FEXGetPARAM_UMed). WSFEX no tiene catálogo de tipos de
+// documento ni de alícuotas, por lo que DocumentTypes y TaxRates quedan
+// vacíos; ConceptTypes también, porque los tipos de exportación no son
+// conceptos de WSFE y se informan en ExportTypes. LastUpdate es el momento de
+// la consulta.
+func (s *Service) GetExportParameters(ctx context.Context) (*models.ExportParameters, error) {
+	// Obtener el ticket antes de las consultas en paralelo, para pedirlo a
+	// WSAA una sola vez
+	if _, err := s.auth.GetAccessTicket(ctx, "wsfex"); err != nil {
+		return nil, fmt.Errorf("error getting access ticket: %w", err)
+	}
+
+	params := &models.ExportParameters{Parameters: models.Parameters{LastUpdate: s.config.Now()}}
+	fetchers := []func(ctx context.Context) error{
+		func(ctx context.Context) (err error) {
+			params.InvoiceTypes, err = s.GetInvoiceTypes(ctx)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			params.CurrencyTypes, err = s.GetCurrencyTypes(ctx)
+			return err
+		},
+		func(ctx context.Context) error {
+			exportTypes, err := s.GetConceptTypes(ctx)
+			if err != nil {
+				return err
+			}
+			params.ExportTypes = make([]models.ExportTypeInfo, 0, len(exportTypes))
+			for _, et := range exportTypes {
+				params.ExportTypes = append(params.ExportTypes, models.ExportTypeInfo{
+					ID:          models.ExportType(et.ID),
+					Description: et.Description,
+					Active:      et.Active,
+				})
+			}
+			return nil
+		},
+		func(ctx context.Context) (err error) {
+			params.Countries, err = s.GetCountries(ctx)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			params.Incoterms, err = s.GetIncoterms(ctx)
+			return err
+		},
+		func(ctx context.Context) (err error) {
+			params.UnitMeasures, err = s.GetUnitMeasures(ctx)
+			return err
+		},
+	}
+
+	_, errs := batch.Run(ctx, fetchers, len(fetchers), func(ctx context.Context, fetch func(context.Context) error) (struct{}, error) {
+		return struct{}{}, fetch(ctx)
+	})
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return params, nil
+}
+
+// GetInvoiceTypes obtiene los tipos de comprobante de exportación (FEXGetPARAM_Tipo_Cbte)
+func (s *Service) GetInvoiceTypes(ctx context.Context) ([]models.InvoiceTypeInfo, error) {
 	// Obtener ticket de acceso
 	ticket, err := s.auth.GetAccessTicket(ctx, "wsfex")
 	if err != nil {
@@ -229,68 +530,140 @@ func (s *Service) GetExportParameters(ctx context.Context) (*models.Parameters,
 
 	// Crear request
 	request := &ExportParametersRequest{}
-	request.Auth.Token = ticket.Token
-	request.Auth.Sign = ticket.Sign
-	request.Auth.CUIT = s.config.CUIT
+	request.Auth = NewAuth(s.config, ticket)
 
 	// Realizar llamada SOAP
-	var response ExportParametersResponse
-	if err := s.callSOAP(ctx, "FEXGetParamTiposConcepto", request, &response); err != nil {
+	var response InvoiceTypesResponse
+	if err := s.callSOAP(ctx, "FEXGetPARAM_Tipo_Cbte", request, &response); err != nil {
 		return nil, err
 	}
 
 	// Verificar errores
 	if len(response.Errors) > 0 {
 		error := response.Errors[0]
-		return nil, models.NewARCAError(error.Code, error.Message)
-	}
-
-	// Crear parámetros
-	params := &models.Parameters{
-		LastUpdate: response.LastUpdate,
-	}
-
-	// Convertir tipos de documento
-	for _, dt := range response.DocumentTypes {
-		params.DocumentTypes = append(params.DocumentTypes, models.DocumentTypeInfo{
-			ID:          models.DocumentType(dt.ID),
-			Description: dt.Description,
-			Active:      dt.Active,
-		})
+		return nil, models.NewServiceError(error.Code, error.Message)
 	}
 
-	// Convertir tipos de factura
+	invoiceTypes := make([]models.InvoiceTypeInfo, 0, len(response.InvoiceTypes))
 	for _, it := range response.InvoiceTypes {
-		params.InvoiceTypes = append(params.InvoiceTypes, models.InvoiceTypeInfo{
+		invoiceTypes = append(invoiceTypes, models.InvoiceTypeInfo{
 			ID:          models.InvoiceType(it.ID),
 			Description: it.Description,
-			Active:      it.Active,
+			Active:      utils.IsActiveParameter(it.DateTo),
 		})
 	}
 
-	// Convertir tipos de moneda
+	return invoiceTypes, nil
+}
+
+// GetCurrencyTypes obtiene las monedas habilitadas para exportación (FEXGetPARAM_MON)
+func (s *Service) GetCurrencyTypes(ctx context.Context) ([]models.CurrencyTypeInfo, error) {
+	// Obtener ticket de acceso
+	ticket, err := s.auth.GetAccessTicket(ctx, "wsfex")
+	if err != nil {
+		return nil, fmt.Errorf("error getting access ticket: %w", err)
+	}
+
+	// Crear request
+	request := &ExportParametersRequest{}
+	request.Auth = NewAuth(s.config, ticket)
+
+	// Realizar llamada SOAP
+	var response CurrencyTypesResponse
+	if err := s.callSOAP(ctx, "FEXGetPARAM_MON", request, &response); err != nil {
+		return nil, err
+	}
+
+	// Verificar errores
+	if len(response.Errors) > 0 {
+		error := response.Errors[0]
+		return nil, models.NewServiceError(error.Code, error.Message)
+	}
+
+	currencyTypes := make([]models.CurrencyTypeInfo, 0, len(response.CurrencyTypes))
 	for _, ct := range response.CurrencyTypes {
-		params.CurrencyTypes = append(params.CurrencyTypes, models.CurrencyTypeInfo{
+		currencyTypes = append(currencyTypes, models.CurrencyTypeInfo{
 			ID:          models.CurrencyType(ct.ID),
 			Description: ct.Description,
-			Active:      ct.Active,
+			Active:      utils.IsActiveParameter(ct.DateTo),
 		})
 	}
 
-	// Convertir tipos de concepto
+	return currencyTypes, nil
+}
+
+// GetConceptTypes obtiene los tipos de exportación: bienes, servicios u otros (FEXGetPARAM_Tipo_Expo)
+func (s *Service) GetConceptTypes(ctx context.Context) ([]models.ConceptTypeInfo, error) {
+	// Obtener ticket de acceso
+	ticket, err := s.auth.GetAccessTicket(ctx, "wsfex")
+	if err != nil {
+		return nil, fmt.Errorf("error getting access ticket: %w", err)
+	}
+
+	// Crear request
+	request := &ExportParametersRequest{}
+	request.Auth = NewAuth(s.config, ticket)
+
+	// Realizar llamada SOAP
+	var response ConceptTypesResponse
+	if err := s.callSOAP(ctx, "FEXGetPARAM_Tipo_Expo", request, &response); err != nil {
+		return nil, err
+	}
+
+	// Verificar errores
+	if len(response.Errors) > 0 {
+		error := response.Errors[0]
+		return nil, models.NewServiceError(error.Code, error.Message)
+	}
+
+	conceptTypes := make([]models.ConceptTypeInfo, 0, len(response.ConceptTypes))
 	for _, ct := range response.ConceptTypes {
-		params.ConceptTypes = append(params.ConceptTypes, models.ConceptTypeInfo{
+		conceptTypes = append(conceptTypes, models.ConceptTypeInfo{
 			ID:          models.ConceptType(ct.ID),
 			Description: ct.Description,
-			Active:      ct.Active,
+			Active:      utils.IsActiveParameter(ct.DateTo),
 		})
 	}
 
-	return params, nil
+	return conceptTypes, nil
 }
 
-// GetExportCAEA obtiene un CAEA para exportación
-func (s *Service) GetExportCAEA(ctx context.Context, period, order, fiscalYear int) (*ExportCAEAResponse, error) {
+// GetCurrencyRate obtiene la cotización oficial de una moneda (FEXGetPARAM_Ctz).
+// Las facturas de exportación deben informar en CurrencyRate esta cotización.
+func (s *Service) GetCurrencyRate(ctx context.Context, currency string) (float64, error) {
+	if currency == "" {
+		return 0, models.NewValidationError("currency_type", "Moneda no puede estar vacía", currency)
+	}
+
+	// Obtener ticket de acceso
+	ticket, err := s.auth.GetAccessTicket(ctx, "wsfex")
+	if err != nil {
+		return 0, fmt.Errorf("error getting access ticket: %w", err)
+	}
+
+	// Crear request
+	request := &CurrencyRateRequest{
+		Auth:     NewAuth(s.config, ticket),
+		Currency: currency,
+	}
+
+	// Realizar llamada SOAP
+	var response CurrencyRateResponse
+	if err := s.callSOAP(ctx, "FEXGetPARAM_Ctz", request, &response); err != nil {
+		return 0, err
+	}
+
+	// Verificar errores
+	if len(response.Errors) > 0 {
+		error := response.Errors[0]
+		return 0, models.NewServiceError(error.Code, error.Message)
+	}
+
+	return response.Result.Rate, nil
+}
+
+// GetCountries obtiene los países de destino habilitados (FEXGetPARAM_DST_pais)
+func (s *Service) GetCountries(ctx context.Context) ([]models.Destination, error) {
 	// Obtener ticket de acceso
 	ticket, err := s.auth.GetAccessTicket(ctx, "wsfex")
 	if err != nil {
@@ -298,112 +671,247 @@ func (s *Service) GetExportCAEA(ctx context.Context, period, order, fiscalYear i
 	}
 
 	// Crear request
-	request := &ExportCAEARequest{}
-	request.Auth.Token = ticket.Token
-	request.Auth.Sign = ticket.Sign
-	request.Auth.CUIT = s.config.CUIT
-	request.Request.Period = period
-	request.Request.Order = order
-	request.Request.FiscalYear = fiscalYear
+	request := &ExportParametersRequest{}
+	request.Auth = NewAuth(s.config, ticket)
 
 	// Realizar llamada SOAP
-	var response ExportCAEAResponse
-	if err := s.callSOAP(ctx, "FEXGetCAEA", request, &response); err != nil {
+	var response CountriesResponse
+	if err := s.callSOAP(ctx, "FEXGetPARAM_DST_pais", request, &response); err != nil {
 		return nil, err
 	}
 
 	// Verificar errores
 	if len(response.Errors) > 0 {
 		error := response.Errors[0]
-		return nil, models.NewARCAError(error.Code, error.Message)
+		return nil, models.NewServiceError(error.Code, error.Message)
 	}
 
-	return &response, nil
+	countries := make([]models.Destination, 0, len(response.Countries))
+	for _, country := range response.Countries {
+		countries = append(countries, models.Destination{
+			ID:          country.ID,
+			Description: country.Description,
+			Active:      true,
+		})
+	}
+
+	return countries, nil
 }
 
-// validateExportInvoice valida una factura de exportación
-func (s *Service) validateExportInvoice(invoice *ExportInvoice) error {
-	var errors models.ValidationErrors
+// GetIncoterms obtiene los Incoterms habilitados (FEXGetPARAM_Incoterms)
+func (s *Service) GetIncoterms(ctx context.Context) ([]models.IncotermInfo, error) {
+	// Obtener ticket de acceso
+	ticket, err := s.auth.GetAccessTicket(ctx, "wsfex")
+	if err != nil {
+		return nil, fmt.Errorf("error getting access ticket: %w", err)
+	}
+
+	// Crear request
+	request := &ExportParametersRequest{}
+	request.Auth = NewAuth(s.config, ticket)
 
-	// Validar campos básicos
-	if err := utils.ValidateInvoiceType(invoice.InvoiceType); err != nil {
-		errors.Add("invoice_type", err.Error(), invoice.InvoiceType)
+	// Realizar llamada SOAP
+	var response IncotermsResponse
+	if err := s.callSOAP(ctx, "FEXGetPARAM_Incoterms", request, &response); err != nil {
+		return nil, err
 	}
 
-	if err := utils.ValidatePointOfSale(invoice.PointOfSale); err != nil {
-		errors.Add("point_of_sale", err.Error(), invoice.PointOfSale)
+	// Verificar errores
+	if len(response.Errors) > 0 {
+		error := response.Errors[0]
+		return nil, models.NewServiceError(error.Code, error.Message)
 	}
 
-	if err := utils.ValidateInvoiceNumber(invoice.InvoiceNumber); err != nil {
-		errors.Add("invoice_number", err.Error(), invoice.InvoiceNumber)
+	incoterms := make([]models.IncotermInfo, 0, len(response.Incoterms))
+	for _, incoterm := range response.Incoterms {
+		incoterms = append(incoterms, models.IncotermInfo{
+			ID:          incoterm.ID,
+			Description: incoterm.Description,
+			Active:      utils.IsActiveParameter(incoterm.DateTo),
+		})
 	}
 
-	if err := utils.ValidateDate(invoice.DateFrom, "date_from"); err != nil {
-		errors.Add("date_from", err.Error(), invoice.DateFrom)
+	return incoterms, nil
+}
+
+// CheckExportPermit verifica ante AFIP (FEXCheck_Permiso) que el permiso de
+// embarque exista para el país de destino, antes de informarlo en la factura.
+// Un permiso inexistente o de otro destino retorna false sin error.
+func (s *Service) CheckExportPermit(ctx context.Context, permitID, destinationCountry string) (bool, error) {
+	// Validar parámetros
+	if permitID == "" {
+		return false, models.NewValidationError("permit_id", "Identificador del permiso no puede estar vacío", permitID)
+	}
+	if err := utils.ValidateCountryCode(destinationCountry, "destination_country"); err != nil {
+		return false, err
 	}
 
-	if err := utils.ValidateDate(invoice.DateTo, "date_to"); err != nil {
-		errors.Add("date_to", err.Error(), invoice.DateTo)
+	// Obtener ticket de acceso
+	ticket, err := s.auth.GetAccessTicket(ctx, "wsfex")
+	if err != nil {
+		return false, fmt.Errorf("error getting access ticket: %w", err)
 	}
 
-	if err := utils.ValidateConceptType(invoice.ConceptType); err != nil {
-		errors.Add("concept_type", err.Error(), invoice.ConceptType)
+	// Crear request
+	request := &PermitCheckRequest{
+		Auth:               NewAuth(s.config, ticket),
+		PermitID:           permitID,
+		DestinationCountry: destinationCountry,
 	}
 
-	if err := utils.ValidateCurrencyType(invoice.CurrencyType); err != nil {
-		errors.Add("currency_type", err.Error(), invoice.CurrencyType)
+	// Realizar llamada SOAP
+	var response PermitCheckResponse
+	if err := s.callSOAP(ctx, "FEXCheck_Permiso", request, &response); err != nil {
+		return false, err
 	}
 
-	if err := utils.ValidateAmount(invoice.Amount, "amount"); err != nil {
-		errors.Add("amount", err.Error(), invoice.Amount)
+	// Verificar errores
+	if len(response.Errors) > 0 {
+		error := response.Errors[0]
+		return false, models.NewServiceError(error.Code, error.Message)
 	}
 
-	if err := utils.ValidateAmount(invoice.TaxAmount, "tax_amount"); err != nil {
-		errors.Add("tax_amount", err.Error(), invoice.TaxAmount)
+	switch strings.ToUpper(strings.TrimSpace(response.Result.Status)) {
+	case "OK":
+		return true, nil
+	case "NO":
+		return false, nil
+	default:
+		return false, models.NewARCAError(models.ErrorCodeInvalidResponse, fmt.Sprintf("invalid FEXCheck_Permiso status %q", response.Result.Status))
 	}
+}
+
+// GetUnitMeasures obtiene las unidades de medida habilitadas
+// (FEXGetPARAM_UMed). La lista se consulta una vez por ambiente y queda en
+// memoria.
+func (s *Service) GetUnitMeasures(ctx context.Context) ([]models.UnitType, error) {
+	s.unitMeasuresMutex.Lock()
+	defer s.unitMeasuresMutex.Unlock()
 
-	if err := utils.ValidateAmount(invoice.TotalAmount, "total_amount"); err != nil {
-		errors.Add("total_amount", err.Error(), invoice.TotalAmount)
+	env := s.environment(ctx)
+	if unitMeasures, ok := s.unitMeasures[env]; ok {
+		return unitMeasures, nil
 	}
 
-	// Validar documento
-	if err := utils.ValidateDocumentType(invoice.DocType); err != nil {
-		errors.Add("doc_type", err.Error(), invoice.DocType)
+	// Obtener ticket de acceso
+	ticket, err := s.auth.GetAccessTicket(ctx, "wsfex")
+	if err != nil {
+		return nil, fmt.Errorf("error getting access ticket: %w", err)
 	}
 
-	if err := utils.ValidateDocumentNumber(invoice.DocType, invoice.DocNumber); err != nil {
-		errors.Add("doc_number", err.Error(), invoice.DocNumber)
+	// Crear request
+	request := &ExportParametersRequest{}
+	request.Auth = NewAuth(s.config, ticket)
+
+	// Realizar llamada SOAP
+	var response UnitMeasuresResponse
+	if err := s.callSOAP(ctx, "FEXGetPARAM_UMed", request, &response); err != nil {
+		return nil, err
 	}
 
-	// Validar documento del cliente
-	if err := utils.ValidateDocumentType(invoice.DocTypeFrom); err != nil {
-		errors.Add("doc_type_from", err.Error(), invoice.DocTypeFrom)
+	// Verificar errores
+	if len(response.Errors) > 0 {
+		error := response.Errors[0]
+		return nil, models.NewServiceError(error.Code, error.Message)
 	}
 
-	if err := utils.ValidateDocumentNumber(invoice.DocTypeFrom, invoice.DocNumberFrom); err != nil {
-		errors.Add("doc_number_from", err.Error(), invoice.DocNumberFrom)
+	unitMeasures := make([]models.UnitType, 0, len(response.UnitMeasures))
+	for _, unit := range response.UnitMeasures {
+		unitMeasures = append(unitMeasures, models.UnitType{
+			ID:          unit.ID,
+			Description: unit.Description,
+			Active:      utils.IsActiveParameter(unit.DateTo),
+		})
+	}
+
+	if s.unitMeasures == nil {
+		s.unitMeasures = make(map[models.Environment][]models.UnitType)
+	}
+	s.unitMeasures[env] = unitMeasures
+	return unitMeasures, nil
+}
+
+// SetLiveUnitMeasureValidation habilita la validación de las unidades de
+// medida de los ítems contra la lista de AFIP. Sin ella sólo se verifica que
+// cada unidad se pueda traducir a un código Umed (ver
+// models.ParseUnitMeasure).
+func (s *Service) SetLiveUnitMeasureValidation(enabled bool) {
+	s.liveUnitMeasures = enabled
+}
+
+// validateUnitMeasures valida las unidades de medida de la factura contra la
+// lista de AFIP
+func (s *Service) validateUnitMeasures(ctx context.Context, invoice *ExportInvoice) error {
+	unitMeasures, err := s.GetUnitMeasures(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting unit measures: %w", err)
 	}
 
-	// Validar país de origen
-	if invoice.CountryFrom == "" {
-		errors.Add("country_from", "País de origen no puede estar vacío", invoice.CountryFrom)
+	return utils.ValidateUnitMeasures(invoice.Items, unitMeasures...)
+}
+
+// GetExportCAEA obtiene un CAEA para exportación. fiscalYear no se envía a
+// AFIP: el período (AAAAMM) ya incluye el año.
+func (s *Service) GetExportCAEA(ctx context.Context, period, order, fiscalYear int) (*ExportCAEAResponse, error) {
+	// Obtener ticket de acceso
+	ticket, err := s.auth.GetAccessTicket(ctx, "wsfex")
+	if err != nil {
+		return nil, fmt.Errorf("error getting access ticket: %w", err)
 	}
 
-	// Validar ítems
-	if err := utils.ValidateItems(invoice.Items); err != nil {
-		errors.Add("items", err.Error(), invoice.Items)
+	// Crear request
+	request := &ExportCAEARequest{}
+	request.Auth = NewAuth(s.config, ticket)
+	request.Period = period
+	request.Order = order
+
+	// Realizar llamada SOAP
+	var response ExportCAEAResponse
+	if err := s.callSOAP(ctx, "FEXGetCAEA", request, &response); err != nil {
+		return nil, err
 	}
 
-	if errors.HasErrors() {
-		return errors
+	// Verificar errores
+	if len(response.Errors) > 0 {
+		error := response.Errors[0]
+		return nil, models.NewServiceError(error.Code, error.Message)
 	}
 
-	return nil
+	return &response, nil
+}
+
+// validateExportInvoice valida una factura de exportación con la hora del
+// reloj configurado
+func (s *Service) validateExportInvoice(invoice *ExportInvoice) error {
+	return invoice.validate(validationOptions{
+		now:              s.config.Now(),
+		liveUnitMeasures: s.liveUnitMeasures,
+	})
+}
+
+// environment retorna el ambiente de las llamadas hechas con ctx: el
+// forzado con core.WithEnvironmentOverride o el configurado
+func (s *Service) environment(ctx context.Context) models.Environment {
+	return s.config.ForContext(ctx).Environment
 }
 
 // callSOAP realiza una llamada SOAP
 func (s *Service) callSOAP(ctx context.Context, action string, request interface{}, response interface{}) error {
-	// Esta es una implementación simplificada
-	// En una implementación real, usarías el cliente SOAP interno
-	return fmt.Errorf("SOAP call not implemented yet")
+	s.soapOnce.Do(func() {
+		s.soapClient = soap.NewClient(s.config.GetWSFEXURL(), s.config.Timeout, soap.AsLogrus(s.logger))
+		s.soapClient.SetHTTPClient(s.config.GetHTTPClient())
+		s.soapClient.SetUserAgent(s.config.GetUserAgent())
+		s.soapClient.SetCapture(s.config.RequestCapture, s.config.LogRequests, s.config.LogResponses)
+		s.soapClient.SetValidateRequests(s.config.ValidateRequests)
+		s.soapClient.SetInterceptors(s.config.Interceptors)
+		s.soapClient.SetCircuitBreaker(s.config.GetCircuitBreaker("wsfex"))
+		for _, env := range []models.Environment{models.EnvironmentTesting, models.EnvironmentProduction} {
+			config := s.config.ForEnvironment(env)
+			s.soapClient.SetEnvironmentEndpoint(env, config.GetWSFEXURL(), config.GetCircuitBreaker("wsfex"))
+		}
+	})
+
+	core.LogEnvironmentOverride(ctx, s.logger, s.config, "wsfex")
+
+	return s.soapClient.Call(ctx, action, request, response)
 }
@@ -1,8 +1,11 @@
 package wsfex
 
 import (
-	"github.com/dlarregola/arca_invoice_lib/pkg/models"
+	"encoding/xml"
 	"time"
+
+	"github.com/dlarregola/arca_invoice_lib/internal/soap"
+	"github.com/dlarregola/arca_invoice_lib/pkg/models"
 )
 
 // ExportInvoice representa una factura de exportación
@@ -15,9 +18,33 @@ type ExportInvoice struct {
 	NameFrom      string              `json:"name_from,omitempty" xml:"name_from,omitempty"`
 	AddressFrom   *models.Address     `json:"address_from,omitempty" xml:"address_from,omitempty"`
 	CountryFrom   string              `json:"country_from,omitempty" xml:"country_from,omitempty"`
-	ServiceFrom   string              `json:"service_from,omitempty" xml:"service_from,omitempty"`
-	CAE           string              `json:"cae,omitempty" xml:"cae,omitempty"`
-	CAEDueDate    time.Time           `json:"cae_due_date,omitempty" xml:"cae_due_date,omitempty"`
+	// ExportType es el tipo de exportación (Tipo_expo). Si es 0 se deriva
+	// del concepto (ver models.ExportTypeForConcept).
+	ExportType models.ExportType `json:"export_type,omitempty" xml:"export_type,omitempty"`
+	// PaymentDueDate (Fecha_pago) es obligatoria para exportaciones de
+	// servicios y mixtas. ServiceFrom y ServiceTo (período del servicio) son
+	// opcionales: FEXAuthorize no los recibe, sólo quedan en el comprobante.
+	ServiceFrom    time.Time `json:"service_from,omitempty" xml:"service_from,omitempty"`
+	ServiceTo      time.Time `json:"service_to,omitempty" xml:"service_to,omitempty"`
+	PaymentDueDate time.Time `json:"payment_due_date,omitempty" xml:"payment_due_date,omitempty"`
+	CAE            string    `json:"cae,omitempty" xml:"cae,omitempty"`
+	CAEDueDate     time.Time `json:"cae_due_date,omitempty" xml:"cae_due_date,omitempty"`
+	// Datos del cliente del exterior (Cliente, Domicilio_cliente y
+	// Cuit_pais_cliente en FEXAuthorize)
+	CustomerName        string `json:"customer_name,omitempty" xml:"customer_name,omitempty"`
+	CustomerAddress     string `json:"customer_address,omitempty" xml:"customer_address,omitempty"`
+	CustomerCountryCUIT string `json:"customer_country_cuit,omitempty" xml:"customer_country_cuit,omitempty"`
+	// RequestID identifica el request ante AFIP (Id, ver FEXGetLast_ID). Si
+	// es 0 se usa el número de comprobante.
+	RequestID int64 `json:"request_id,omitempty" xml:"request_id,omitempty"`
+	// Incoterm (ej. "FOB") es obligatorio para exportaciones de bienes
+	Incoterm            string                     `json:"incoterm,omitempty" xml:"incoterm,omitempty"`
+	IncotermDescription string                     `json:"incoterm_description,omitempty" xml:"incoterm_description,omitempty"`
+	Permits             []models.ExportPermit      `json:"permits,omitempty" xml:"permits,omitempty"`
+	AssociatedInvoices  []models.AssociatedInvoice `json:"associated_invoices,omitempty" xml:"associated_invoices,omitempty"`
+	// Buyers reparte la exportación entre varios compradores (Compradores);
+	// los porcentajes deben sumar 100. Vacío si hay un único cliente.
+	Buyers []models.ExportBuyer `json:"buyers,omitempty" xml:"buyers,omitempty"`
 }
 
 // ExportInvoiceItem representa un ítem de factura de exportación
@@ -29,56 +56,133 @@ type ExportInvoiceItem struct {
 	Country     string  `json:"country,omitempty" xml:"country,omitempty"`
 }
 
-// ExportAuthorizationRequest representa el request de autorización de exportación
+// Auth representa el bloque de autenticación de cada request
+type Auth struct {
+	Token string `xml:"Token"`
+	Sign  string `xml:"Sign"`
+	CUIT  string `xml:"Cuit"`
+}
+
+// Permit representa un permiso de embarque dentro del request
+type Permit struct {
+	ID                 string `xml:"Id_permiso"`
+	DestinationCountry string `xml:"Dst_merc"`
+}
+
+// AssociatedInvoice representa un comprobante asociado dentro del request
+type AssociatedInvoice struct {
+	InvoiceType   int    `xml:"Cbte_tipo"`
+	PointOfSale   int    `xml:"Cbte_punto_vta"`
+	InvoiceNumber int    `xml:"Cbte_nro"`
+	CUIT          string `xml:"Cbte_cuit,omitempty"`
+}
+
+// Buyer representa un comprador dentro del request
+type Buyer struct {
+	DocType    int    `xml:"Tipo_doc"`
+	DocNumber  string `xml:"Nro_doc"`
+	Percentage Amount `xml:"Porcentaje"`
+}
+
+// PermitList es la lista Permisos>Permiso del request; vacía no se envía
+type PermitList []Permit
+
+// MarshalXML implementa xml.Marshaler
+func (l PermitList) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return soap.EncodeList(e, start, "Permiso", l)
+}
+
+// AssociatedInvoiceList es la lista Cmps_asoc>Cmp_asoc del request; vacía no se envía
+type AssociatedInvoiceList []AssociatedInvoice
+
+// MarshalXML implementa xml.Marshaler
+func (l AssociatedInvoiceList) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return soap.EncodeList(e, start, "Cmp_asoc", l)
+}
+
+// BuyerList es la lista Compradores>Comprador del request; vacía no se envía
+type BuyerList []Buyer
+
+// MarshalXML implementa xml.Marshaler
+func (l BuyerList) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return soap.EncodeList(e, start, "Comprador", l)
+}
+
+// AFIPDate es una fecha del request o de la respuesta, en formato AAAAMMDD
+// (ver soap.AFIPDate)
+type AFIPDate = soap.AFIPDate
+
+// Amount es un importe del request, con dos decimales (ver soap.Amount)
+type Amount = soap.Amount
+
+// ExportItem representa un ítem dentro del request de autorización
+type ExportItem struct {
+	ProductCode string  `xml:"Pro_codigo"`
+	Description string  `xml:"Pro_ds"`
+	Quantity    float64 `xml:"Pro_qty"`
+	UnitMeasure string  `xml:"Pro_umed"`
+	UnitPrice   float64 `xml:"Pro_precio_uni"`
+	Discount    Amount  `xml:"Pro_bonificacion"`
+	TotalPrice  Amount  `xml:"Pro_total_item"`
+}
+
+// Tipos de exportación (Tipo_expo)
+const (
+	ExportTypeGoods    = models.ExportTypeGoods
+	ExportTypeServices = models.ExportTypeServices
+	ExportTypeOther    = models.ExportTypeOther
+)
+
+// LanguageSpanish es el idioma del comprobante (Idioma_cbte) por defecto
+const LanguageSpanish = 1
+
+// ExportAuthorizationRequest representa el request de FEXAuthorize. El
+// orden de los campos sigue el WSDL de WSFEXv1.
 type ExportAuthorizationRequest struct {
-	Auth struct {
-		Token string `xml:"token"`
-		Sign  string `xml:"sign"`
-		CUIT  string `xml:"cuit"`
-	} `xml:"Auth"`
+	Auth    Auth `xml:"Auth"`
 	Request struct {
-		InvoiceType   int       `xml:"FeCabReq"`
-		PointOfSale   int       `xml:"FeCabReq"`
-		InvoiceNumber int       `xml:"FeCabReq"`
-		DateFrom      time.Time `xml:"FeCabReq"`
-		DateTo        time.Time `xml:"FeCabReq"`
-		ServiceFrom   string    `xml:"FeCabReq"`
-		Amount        float64   `xml:"FeCabReq"`
-		TaxAmount     float64   `xml:"FeCabReq"`
-		TotalAmount   float64   `xml:"FeCabReq"`
-		CurrencyType  string    `xml:"FeCabReq"`
-		CurrencyRate  float64   `xml:"FeCabReq"`
-		ConceptType   int       `xml:"FeCabReq"`
-		DocType       int       `xml:"FeDetReq"`
-		DocNumber     string    `xml:"FeDetReq"`
-		DocTypeFrom   int       `xml:"FeDetReq"`
-		DocNumberFrom string    `xml:"FeDetReq"`
-		NameFrom      string    `xml:"FeDetReq"`
-		CountryFrom   string    `xml:"FeDetReq"`
-		Items         []struct {
-			Description string  `xml:"Concepto"`
-			Quantity    float64 `xml:"Cantidad"`
-			UnitPrice   float64 `xml:"PrecioUnit"`
-			TotalPrice  float64 `xml:"Importe"`
-			ProductCode string  `xml:"CodProd"`
-			UnitMeasure string  `xml:"UnidadMedida"`
-			Discount    float64 `xml:"Descuento"`
-			Country     string  `xml:"PaisDestino"`
-		} `xml:"FeDetReq"`
-	} `xml:"FEXAuthorize"`
-}
-
-// ExportAuthorizationResponse representa la respuesta de autorización de exportación
+		ID                  int64                 `xml:"Id"`
+		InvoiceDate         AFIPDate              `xml:"Fecha_cbte"`
+		InvoiceType         int                   `xml:"Cbte_Tipo"`
+		PointOfSale         int                   `xml:"Punto_vta"`
+		InvoiceNumber       int                   `xml:"Cbte_nro"`
+		ExportType          int                   `xml:"Tipo_expo"`
+		PermitExists        string                `xml:"Permiso_existente"`
+		Permits             PermitList            `xml:"Permisos,omitempty"`
+		DestinationCountry  string                `xml:"Dst_cmp"`
+		CustomerName        string                `xml:"Cliente"`
+		CustomerCountryCUIT string                `xml:"Cuit_pais_cliente,omitempty"`
+		CustomerAddress     string                `xml:"Domicilio_cliente"`
+		CustomerTaxID       string                `xml:"Id_impositivo,omitempty"`
+		Buyers              BuyerList             `xml:"Compradores,omitempty"`
+		CurrencyType        string                `xml:"Moneda_Id"`
+		CurrencyRate        float64               `xml:"Moneda_ctz"`
+		TotalAmount         Amount                `xml:"Imp_total"`
+		Notes               string                `xml:"Obs,omitempty"`
+		AssociatedInvoices  AssociatedInvoiceList `xml:"Cmps_asoc,omitempty"`
+		Incoterm            string                `xml:"Incoterms,omitempty"`
+		IncotermDescription string                `xml:"Incoterms_Ds,omitempty"`
+		Language            int                   `xml:"Idioma_cbte"`
+		Items               []ExportItem          `xml:"Items>Item"`
+		PaymentDate         *AFIPDate             `xml:"Fecha_pago,omitempty"`
+	} `xml:"Cmp"`
+}
+
+// ExportAuthorizationResponse representa la respuesta de FEXAuthorize
+// (FEXResultAuth). Las fechas llegan en formato AAAAMMDD.
 type ExportAuthorizationResponse struct {
 	Result struct {
-		CAE               string    `xml:"CAE"`
-		CAEDueDate        time.Time `xml:"CAEFchVto"`
-		InvoiceNumber     int       `xml:"CbteDesde"`
-		PointOfSale       int       `xml:"PuntoVta"`
-		InvoiceType       int       `xml:"CbteTipo"`
-		AuthorizationDate time.Time `xml:"FchProceso"`
-		Status            string    `xml:"Resultado"`
-		Message           string    `xml:"Observaciones"`
+		ID            int64    `xml:"Id"`
+		CUIT          string   `xml:"Cuit"`
+		CAE           string   `xml:"Cae"`
+		CAEDueDate    AFIPDate `xml:"Fch_venc_Cae"`
+		InvoiceDate   AFIPDate `xml:"Fch_cbte"`
+		Status        string   `xml:"Resultado"`
+		Reprocess     string   `xml:"Reproceso"`
+		Message       string   `xml:"Motivos_Obs"`
+		PointOfSale   int      `xml:"Punto_vta"`
+		InvoiceNumber int      `xml:"Cbte_nro"`
+		InvoiceType   int      `xml:"Cbte_tipo"`
 	} `xml:"FEXResultAuth"`
 	Errors []struct {
 		Code    string `xml:"Code"`
@@ -86,32 +190,45 @@ type ExportAuthorizationResponse struct {
 	} `xml:"Errors"`
 }
 
-// ExportQueryRequest representa el request de consulta de exportación
+// ExportQueryRequest representa el request de FEXGetCMP
 type ExportQueryRequest struct {
-	Auth struct {
-		Token string `xml:"token"`
-		Sign  string `xml:"sign"`
-		CUIT  string `xml:"cuit"`
-	} `xml:"Auth"`
+	Auth    Auth `xml:"Auth"`
 	Request struct {
-		InvoiceType   int `xml:"FEXGetCMP"`
-		PointOfSale   int `xml:"FEXGetCMP"`
-		InvoiceNumber int `xml:"FEXGetCMP"`
-	} `xml:"FEXGetCMP"`
+		InvoiceType   int `xml:"Cbte_tipo"`
+		PointOfSale   int `xml:"Punto_vta"`
+		InvoiceNumber int `xml:"Cbte_nro"`
+	} `xml:"Cmp"`
 }
 
-// ExportQueryResponse representa la respuesta de consulta de exportación
+// ExportQueryResponse representa la respuesta de FEXGetCMP. Las fechas
+// llegan en formato AAAAMMDD.
 type ExportQueryResponse struct {
 	Result struct {
-		InvoiceType   int       `xml:"CbteTipo"`
-		PointOfSale   int       `xml:"PuntoVta"`
-		InvoiceNumber int       `xml:"CbteNro"`
-		DateFrom      time.Time `xml:"CbteFch"`
-		Amount        float64   `xml:"ImpTotal"`
-		CurrencyType  string    `xml:"MonId"`
-		CurrencyRate  float64   `xml:"MonCotIz"`
-		Status        string    `xml:"Resultado"`
-		Message       string    `xml:"Observaciones"`
+		ID                  int64               `xml:"Id"`
+		InvoiceDate         string              `xml:"Fecha_cbte"`
+		InvoiceType         int                 `xml:"Cbte_tipo"`
+		PointOfSale         int                 `xml:"Punto_vta"`
+		InvoiceNumber       int                 `xml:"Cbte_nro"`
+		ExportType          int                 `xml:"Tipo_expo"`
+		PermitExists        string              `xml:"Permiso_existente"`
+		Permits             []Permit            `xml:"Permisos>Permiso"`
+		DestinationCountry  string              `xml:"Dst_cmp"`
+		CustomerName        string              `xml:"Cliente"`
+		CustomerCountryCUIT string              `xml:"Cuit_pais_cliente"`
+		CustomerAddress     string              `xml:"Domicilio_cliente"`
+		CustomerTaxID       string              `xml:"Id_impositivo"`
+		CurrencyType        string              `xml:"Moneda_Id"`
+		CurrencyRate        float64             `xml:"Moneda_ctz"`
+		TotalAmount         float64             `xml:"Imp_total"`
+		Notes               string              `xml:"Obs"`
+		AssociatedInvoices  []AssociatedInvoice `xml:"Cmps_asoc>Cmp_asoc"`
+		Incoterm            string              `xml:"Incoterms"`
+		IncotermDescription string              `xml:"Incoterms_Ds"`
+		Items               []ExportItem        `xml:"Items>Item"`
+		CAE                 string              `xml:"Cae"`
+		CAEDueDate          string              `xml:"Fch_venc_Cae"`
+		Status              string              `xml:"Resultado"`
+		Message             string              `xml:"Motivos_Obs"`
 	} `xml:"FEXResultGet"`
 	Errors []struct {
 		Code    string `xml:"Code"`
@@ -119,30 +236,23 @@ type ExportQueryResponse struct {
 	} `xml:"Errors"`
 }
 
-// ExportLastAuthorizedRequest representa el request para obtener el último autorizado de exportación
+// ExportLastAuthorizedRequest representa el request de FEXGetLast_CMP. El
+// punto de venta y el tipo de comprobante viajan dentro del bloque Auth.
 type ExportLastAuthorizedRequest struct {
 	Auth struct {
-		Token string `xml:"token"`
-		Sign  string `xml:"sign"`
-		CUIT  string `xml:"cuit"`
+		Auth
+		PointOfSale int `xml:"Pto_venta"`
+		InvoiceType int `xml:"Cbte_Tipo"`
 	} `xml:"Auth"`
-	Request struct {
-		InvoiceType int `xml:"FEXGetLast_CMP"`
-		PointOfSale int `xml:"FEXGetLast_CMP"`
-	} `xml:"FEXGetLast_CMP"`
 }
 
-// ExportLastAuthorizedResponse representa la respuesta del último autorizado de exportación
+// ExportLastAuthorizedResponse representa la respuesta de FEXGetLast_CMP.
+// AFIP sólo informa el número y la fecha (AAAAMMDD) del último comprobante.
 type ExportLastAuthorizedResponse struct {
 	Result struct {
-		InvoiceType   int       `xml:"CbteTipo"`
-		PointOfSale   int       `xml:"PuntoVta"`
-		InvoiceNumber int       `xml:"CbteNro"`
-		DateFrom      time.Time `xml:"CbteFch"`
-		Amount        float64   `xml:"ImpTotal"`
-		CurrencyType  string    `xml:"MonId"`
-		CurrencyRate  float64   `xml:"MonCotIz"`
-	} `xml:"FEXResultLast_CMP"`
+		InvoiceNumber int    `xml:"Cbte_nro"`
+		Date          string `xml:"Cbte_fecha"`
+	} `xml:"FEXResult_LastCMP"`
 	Errors []struct {
 		Code    string `xml:"Code"`
 		Message string `xml:"Msg"`
@@ -151,75 +261,166 @@ type ExportLastAuthorizedResponse struct {
 
 // ExportParametersRequest representa el request de parámetros de exportación
 type ExportParametersRequest struct {
-	Auth struct {
-		Token string `xml:"token"`
-		Sign  string `xml:"sign"`
-		CUIT  string `xml:"cuit"`
-	} `xml:"Auth"`
+	Auth Auth `xml:"Auth"`
 }
 
-// ExportParametersResponse representa la respuesta de parámetros de exportación
-type ExportParametersResponse struct {
-	DocumentTypes []struct {
-		ID          int    `xml:"Id"`
-		Description string `xml:"Desc"`
-		Active      bool   `xml:"FchDesde"`
-	} `xml:"DocTipo"`
+// InvoiceTypesResponse representa la respuesta de FEXGetPARAM_Tipo_Cbte
+type InvoiceTypesResponse struct {
 	InvoiceTypes []struct {
-		ID          int    `xml:"Id"`
-		Description string `xml:"Desc"`
-		Active      bool   `xml:"FchDesde"`
-	} `xml:"CbteTipo"`
+		ID          int    `xml:"Cbte_Id"`
+		Description string `xml:"Cbte_Ds"`
+		DateFrom    string `xml:"Cbte_vig_desde"`
+		DateTo      string `xml:"Cbte_vig_hasta"`
+	} `xml:"FEXResultGet>ClsFEXResponse_Cbte_Tipo"`
+	Errors []struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Msg"`
+	} `xml:"Errors"`
+}
+
+// CurrencyTypesResponse representa la respuesta de FEXGetPARAM_MON
+type CurrencyTypesResponse struct {
 	CurrencyTypes []struct {
-		ID          string `xml:"Id"`
-		Description string `xml:"Desc"`
-		Active      bool   `xml:"FchDesde"`
-	} `xml:"MonId"`
-	Countries []struct {
-		ID          string `xml:"Id"`
-		Description string `xml:"Desc"`
-		Active      bool   `xml:"FchDesde"`
-	} `xml:"Pais"`
+		ID          string `xml:"Mon_Id"`
+		Description string `xml:"Mon_Ds"`
+		DateFrom    string `xml:"Mon_vig_desde"`
+		DateTo      string `xml:"Mon_vig_hasta"`
+	} `xml:"FEXResultGet>ClsFEXResponse_Mon"`
+	Errors []struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Msg"`
+	} `xml:"Errors"`
+}
+
+// ConceptTypesResponse representa la respuesta de FEXGetPARAM_Tipo_Expo
+// (tipos de exportación: bienes, servicios u otros)
+type ConceptTypesResponse struct {
 	ConceptTypes []struct {
-		ID          int    `xml:"Id"`
-		Description string `xml:"Desc"`
-		Active      bool   `xml:"FchDesde"`
-	} `xml:"ConceptoTipo"`
-	LastUpdate time.Time `xml:"FchServDesde"`
-	Errors     []struct {
+		ID          int    `xml:"Tex_Id"`
+		Description string `xml:"Tex_Ds"`
+		DateFrom    string `xml:"Tex_vig_desde"`
+		DateTo      string `xml:"Tex_vig_hasta"`
+	} `xml:"FEXResultGet>ClsFEXResponse_Tex"`
+	Errors []struct {
 		Code    string `xml:"Code"`
 		Message string `xml:"Msg"`
 	} `xml:"Errors"`
 }
 
-// ExportCAEARequest representa el request de CAEA para exportación
+// ExportCAEARequest representa el request de CAEA para exportación. El
+// período (AAAAMM) ya incluye el año.
 type ExportCAEARequest struct {
-	Auth struct {
-		Token string `xml:"token"`
-		Sign  string `xml:"sign"`
-		CUIT  string `xml:"cuit"`
-	} `xml:"Auth"`
-	Request struct {
-		Period     int `xml:"FEXGetCAEA"`
-		Order      int `xml:"FEXGetCAEA"`
-		FiscalYear int `xml:"FEXGetCAEA"`
-	} `xml:"FEXGetCAEA"`
+	Auth   Auth `xml:"Auth"`
+	Period int  `xml:"Periodo"`
+	Order  int  `xml:"Orden"`
 }
 
 // ExportCAEAResponse representa la respuesta de CAEA para exportación
 type ExportCAEAResponse struct {
 	Result struct {
-		CAEA       string    `xml:"CAEA"`
-		Period     int       `xml:"Periodo"`
-		Order      int       `xml:"Orden"`
-		FiscalYear int       `xml:"FchVigDesde"`
-		DueDate    time.Time `xml:"FchVigHasta"`
-		MaxAmount  float64   `xml:"MaximoImporte"`
-		Status     string    `xml:"Resultado"`
-		Message    string    `xml:"Observaciones"`
+		CAEA      string   `xml:"CAEA"`
+		Period    int      `xml:"Periodo"`
+		Order     int      `xml:"Orden"`
+		ValidFrom AFIPDate `xml:"FchVigDesde"`
+		DueDate   AFIPDate `xml:"FchVigHasta"`
+		MaxAmount float64  `xml:"MaximoImporte"`
+		Status    string   `xml:"Resultado"`
+		Message   string   `xml:"Observaciones"`
 	} `xml:"FEXResultGetCAEA"`
 	Errors []struct {
 		Code    string `xml:"Code"`
 		Message string `xml:"Msg"`
 	} `xml:"Errors"`
 }
+
+// ToCAEAResponse convierte la respuesta a models.CAEAResponse
+func (r *ExportCAEAResponse) ToCAEAResponse() *models.CAEAResponse {
+	return &models.CAEAResponse{
+		CAEA:           r.Result.CAEA,
+		Period:         r.Result.Period,
+		Order:          r.Result.Order,
+		ValidFrom:      r.Result.ValidFrom.Time,
+		ExpirationDate: r.Result.DueDate.Time,
+		MaxAmount:      r.Result.MaxAmount,
+		Status:         r.Result.Status,
+		Message:        r.Result.Message,
+	}
+}
+
+// CurrencyRateRequest representa el request de FEXGetPARAM_Ctz
+type CurrencyRateRequest struct {
+	Auth     Auth   `xml:"Auth"`
+	Currency string `xml:"Mon_id"`
+}
+
+// CurrencyRateResponse representa la respuesta de FEXGetPARAM_Ctz
+type CurrencyRateResponse struct {
+	Result struct {
+		Currency string  `xml:"Mon_id"`
+		Rate     float64 `xml:"Mon_ctz"`
+		Date     string  `xml:"Fch_cotiz"`
+	} `xml:"FEXResultGet"`
+	Errors []struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Msg"`
+	} `xml:"Errors"`
+}
+
+// CountriesResponse representa la respuesta de FEXGetPARAM_DST_pais
+type CountriesResponse struct {
+	Countries []struct {
+		ID          string `xml:"DST_Codigo"`
+		Description string `xml:"DST_Ds"`
+	} `xml:"FEXResultGet>ClsFEXResponse_DST_pais"`
+	Errors []struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Msg"`
+	} `xml:"Errors"`
+}
+
+// PermitCheckRequest representa el request de FEXCheck_Permiso
+type PermitCheckRequest struct {
+	Auth               Auth   `xml:"Auth"`
+	PermitID           string `xml:"ID_Permiso"`
+	DestinationCountry string `xml:"Dst_merc"`
+}
+
+// PermitCheckResponse representa la respuesta de FEXCheck_Permiso. Status es
+// "OK" si el permiso existe para el destino y "NO" si no.
+type PermitCheckResponse struct {
+	Result struct {
+		Status string `xml:"Status"`
+	} `xml:"FEXResultGet"`
+	Errors []struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Msg"`
+	} `xml:"Errors"`
+}
+
+// UnitMeasuresResponse representa la respuesta de FEXGetPARAM_UMed
+type UnitMeasuresResponse struct {
+	UnitMeasures []struct {
+		ID          string `xml:"Umed_Id"`
+		Description string `xml:"Umed_Ds"`
+		DateFrom    string `xml:"Umed_vig_desde"`
+		DateTo      string `xml:"Umed_vig_hasta"`
+	} `xml:"FEXResultGet>ClsFEXResponse_UMed"`
+	Errors []struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Msg"`
+	} `xml:"Errors"`
+}
+
+// IncotermsResponse representa la respuesta de FEXGetPARAM_Incoterms
+type IncotermsResponse struct {
+	Incoterms []struct {
+		ID          string `xml:"Inc_Id"`
+		Description string `xml:"Inc_Ds"`
+		DateFrom    string `xml:"Inc_vig_desde"`
+		DateTo      string `xml:"Inc_vig_hasta"`
+	} `xml:"FEXResultGet>ClsFEXResponse_Inc"`
+	Errors []struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Msg"`
+	} `xml:"Errors"`
+}
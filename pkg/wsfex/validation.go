@@ -0,0 +1,145 @@
+package wsfex
+
+import (
+	"time"
+
+	"github.com/dlarregola/arca_invoice_lib/internal/utils"
+	"github.com/dlarregola/arca_invoice_lib/pkg/models"
+)
+
+// validationOptions son los parámetros del Service que afectan la validación
+// de una factura de exportación
+type validationOptions struct {
+	now              time.Time
+	liveUnitMeasures bool
+}
+
+// Validate valida la factura de exportación sin crear un Service ni llamar a
+// AFIP. Retorna models.ValidationErrors con todos los problemas encontrados.
+func (i *ExportInvoice) Validate() error {
+	return i.validate(validationOptions{now: time.Now()})
+}
+
+// validate valida la factura de exportación según opts
+func (i *ExportInvoice) validate(opts validationOptions) error {
+	var errors models.ValidationErrors
+
+	// Validar campos básicos
+	if err := utils.ValidateInvoiceType(i.InvoiceType); err != nil {
+		errors.Add("invoice_type", err.Error(), i.InvoiceType)
+	}
+
+	if err := utils.ValidatePointOfSale(i.PointOfSale); err != nil {
+		errors.Add("point_of_sale", err.Error(), i.PointOfSale)
+	}
+
+	if err := utils.ValidateInvoiceNumber(i.InvoiceNumber); err != nil {
+		errors.Add("invoice_number", err.Error(), i.InvoiceNumber)
+	}
+
+	if err := utils.ValidateDateAt(i.DateFrom, "date_from", opts.now); err != nil {
+		errors.Add("date_from", err.Error(), i.DateFrom)
+	}
+
+	if err := utils.ValidateDateAt(i.DateTo, "date_to", opts.now); err != nil {
+		errors.Add("date_to", err.Error(), i.DateTo)
+	}
+
+	if err := utils.ValidateConceptType(i.ConceptType); err != nil {
+		errors.Add("concept_type", err.Error(), i.ConceptType)
+	}
+
+	if err := utils.ValidateExportType(i.ExportType, i.ConceptType); err != nil {
+		errors.Add("export_type", err.Error(), i.ExportType)
+	}
+
+	if err := utils.ValidateCurrencyType(i.CurrencyType); err != nil {
+		errors.Add("currency_type", err.Error(), i.CurrencyType)
+	}
+
+	if err := utils.ValidateAmount(i.Amount, "amount"); err != nil {
+		errors.Add("amount", err.Error(), i.Amount)
+	}
+
+	if err := utils.ValidateAmount(i.TaxAmount, "tax_amount"); err != nil {
+		errors.Add("tax_amount", err.Error(), i.TaxAmount)
+	}
+
+	if err := utils.ValidateAmount(i.TotalAmount, "total_amount"); err != nil {
+		errors.Add("total_amount", err.Error(), i.TotalAmount)
+	}
+
+	// Validar documento
+	if err := utils.ValidateDocumentType(i.DocType); err != nil {
+		errors.Add("doc_type", err.Error(), i.DocType)
+	}
+
+	if err := utils.ValidateDocumentNumber(i.DocType, i.DocNumber); err != nil {
+		errors.Add("doc_number", err.Error(), i.DocNumber)
+	}
+
+	// Validar documento del cliente
+	if err := utils.ValidateDocumentType(i.DocTypeFrom); err != nil {
+		errors.Add("doc_type_from", err.Error(), i.DocTypeFrom)
+	}
+
+	if err := utils.ValidateDocumentNumber(i.DocTypeFrom, i.DocNumberFrom); err != nil {
+		errors.Add("doc_number_from", err.Error(), i.DocNumberFrom)
+	}
+
+	// Validar país de destino (se envía como Dst_cmp)
+	if err := utils.ValidateCountryCode(i.CountryFrom, "country_from"); err != nil {
+		errors.Add("country_from", err.Error(), i.CountryFrom)
+	}
+
+	// Validar ítems
+	if err := utils.ValidateItems(i.Items); err != nil {
+		errors.Add("items", err.Error(), i.Items)
+	}
+
+	// Con validación en vivo las unidades se validan contra la lista de AFIP
+	if !opts.liveUnitMeasures {
+		if err := utils.ValidateUnitMeasures(i.Items); err != nil {
+			errors.Add("unit_measure", err.Error(), i.Items)
+		}
+	}
+
+	// Validar datos de exportación
+	if err := utils.ValidateExportTaxes(i.InvoiceType, i.TaxAmount); err != nil {
+		errors.Add("tax_amount", err.Error(), i.TaxAmount)
+	}
+
+	if err := utils.ValidateExportCurrency(i.CurrencyType, i.CurrencyRate); err != nil {
+		errors.Add("currency_type", err.Error(), i.CurrencyType)
+	}
+
+	if err := utils.ValidateIncoterm(i.ConceptType, i.Incoterm); err != nil {
+		errors.Add("incoterm", err.Error(), i.Incoterm)
+	}
+
+	if err := utils.ValidateExportPermits(i.ConceptType, i.Permits); err != nil {
+		errors.Add("permits", err.Error(), i.Permits)
+	}
+
+	if err := utils.ValidateConceptDates(i.ConceptType, i.ServiceFrom, i.ServiceTo, i.PaymentDueDate, false); err != nil {
+		errors.Add("service_dates", err.Error(), i.ConceptType)
+	}
+
+	if err := utils.ValidatePaymentDueDate(i.ConceptType, i.PaymentDueDate, i.DateFrom); err != nil {
+		errors.Add("payment_due_date", err.Error(), i.PaymentDueDate)
+	}
+
+	if err := utils.ValidateExportBuyers(i.Buyers); err != nil {
+		errors.Add("buyers", err.Error(), i.Buyers)
+	}
+
+	if err := utils.ValidateExportAssociatedInvoices(i.InvoiceType, i.AssociatedInvoices); err != nil {
+		errors.Add("associated_invoices", err.Error(), i.AssociatedInvoices)
+	}
+
+	if errors.HasErrors() {
+		return errors
+	}
+
+	return nil
+}
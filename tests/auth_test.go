@@ -0,0 +1,358 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlarregola/arca_invoice_lib/pkg/client"
+	"github.com/dlarregola/arca_invoice_lib/pkg/models"
+	"github.com/dlarregola/arca_invoice_lib/pkg/testutil"
+	"github.com/dlarregola/arca_invoice_lib/pkg/wsfe"
+	"github.com/dlarregola/arca_invoice_lib/pkg/wsfex"
+)
+
+func TestRepresentedCUIT(t *testing.T) {
+	config := client.Config{
+		Environment:     models.EnvironmentTesting,
+		CUIT:            "20-12345678-9",
+		RepresentedCUIT: "30-98765432-1",
+		Certificate:     []byte("test certificate"),
+		PrivateKey:      []byte("test private key"),
+		Timeout:         30 * time.Second,
+		RetryAttempts:   3,
+		AuthCacheTTL:    23 * time.Hour,
+	}
+
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Config.Validate() should accept a represented CUIT: %v", err)
+	}
+
+	// El login lo firma el CUIT del certificado
+	auth := client.NewWSAAAuth(&config, nil)
+	loginRequest, err := auth.NewLoginTicketRequest("wsfe")
+	if err != nil {
+		t.Fatalf("NewLoginTicketRequest() error = %v", err)
+	}
+	if loginRequest.Header.Source != "20123456789" {
+		t.Errorf("Login source should be the certificate CUIT without dashes, got %s", loginRequest.Header.Source)
+	}
+
+	// El bloque Auth de WSFE/WSFEX informa el CUIT representado
+	ticket := &client.AccessTicket{Token: "token", Sign: "sign"}
+
+	feAuth := wsfe.NewAuth(&config, ticket)
+	if feAuth.CUIT != "30987654321" {
+		t.Errorf("WSFE Auth CUIT should be the represented CUIT without dashes, got %s", feAuth.CUIT)
+	}
+	if feAuth.Token != "token" || feAuth.Sign != "sign" {
+		t.Errorf("WSFE Auth should carry the ticket credentials, got %+v", feAuth)
+	}
+
+	fexAuth := wsfex.NewAuth(&config, ticket)
+	if fexAuth.CUIT != "30987654321" {
+		t.Errorf("WSFEX Auth CUIT should be the represented CUIT without dashes, got %s", fexAuth.CUIT)
+	}
+}
+
+func TestWSAADestination(t *testing.T) {
+	tests := []struct {
+		environment models.Environment
+		override    string
+		want        string
+	}{
+		{models.EnvironmentTesting, "", "cn=wsaahomo,o=afip,c=ar,serialNumber=CUIT 33693450239"},
+		{models.EnvironmentProduction, "", "cn=wsaa,o=afip,c=ar,serialNumber=CUIT 33693450239"},
+		{models.EnvironmentProduction, "cn=custom,o=afip,c=ar", "cn=custom,o=afip,c=ar"},
+	}
+
+	for _, tt := range tests {
+		config := client.Config{Environment: tt.environment, CUIT: "20-12345678-9", WSAADestination: tt.override}
+		loginRequest, err := client.NewWSAAAuth(&config, nil).NewLoginTicketRequest("wsfe")
+		if err != nil {
+			t.Fatalf("NewLoginTicketRequest() error = %v", err)
+		}
+		if loginRequest.Header.Destination != tt.want {
+			t.Errorf("Login destination for %s should be %q, got %q", tt.environment, tt.want, loginRequest.Header.Destination)
+		}
+	}
+}
+
+func TestWSAARequestTTL(t *testing.T) {
+	tests := []struct {
+		ttl  time.Duration
+		want time.Duration
+	}{
+		{0, client.DefaultWSAARequestTTL},
+		{5 * time.Minute, 5 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		config := client.Config{CUIT: "20-12345678-9", WSAARequestTTL: tt.ttl}
+		loginRequest, err := client.NewWSAAAuth(&config, nil).NewLoginTicketRequest("wsfe")
+		if err != nil {
+			t.Fatalf("NewLoginTicketRequest() error = %v", err)
+		}
+		const layout = "2006-01-02T15:04:05.000-07:00"
+		generation, _ := time.Parse(layout, loginRequest.Header.GenerationTime)
+		expiration, _ := time.Parse(layout, loginRequest.Header.ExpirationTime)
+		if got := expiration.Sub(generation); got != tt.want {
+			t.Errorf("Login request with TTL %v should be valid for %v, got %v", tt.ttl, tt.want, got)
+		}
+	}
+}
+
+func TestAuthCUITWithoutRepresented(t *testing.T) {
+	config := client.Config{CUIT: "20-12345678-9"}
+
+	ticket := &client.AccessTicket{Token: "token", Sign: "sign"}
+	if got := wsfe.NewAuth(&config, ticket).CUIT; got != "20123456789" {
+		t.Errorf("WSFE Auth CUIT should default to the certificate CUIT without dashes, got %s", got)
+	}
+}
+
+func TestNormalizeCUIT(t *testing.T) {
+	for _, cuit := range []string{"20-12345678-9", "20123456789", " 20.12345678.9 "} {
+		got, err := models.NormalizeCUIT(cuit)
+		if err != nil || got != "20123456789" {
+			t.Errorf("NormalizeCUIT(%q) = %q, %v; want 20123456789", cuit, got, err)
+		}
+	}
+
+	for _, cuit := range []string{"", "2012345678", "20-1234567A-9"} {
+		if _, err := models.NormalizeCUIT(cuit); err == nil {
+			t.Errorf("NormalizeCUIT(%q) should fail", cuit)
+		}
+	}
+
+	if got := models.FormatCUIT("20123456789"); got != "20-12345678-9" {
+		t.Errorf("FormatCUIT() = %q, want 20-12345678-9", got)
+	}
+	if got := models.FormatCUIT("123"); got != "123" {
+		t.Errorf("FormatCUIT() should return invalid values unchanged, got %q", got)
+	}
+}
+
+func TestInvalidRepresentedCUIT(t *testing.T) {
+	config := client.DefaultConfig()
+	config.CUIT = "20-12345678-9"
+	config.RepresentedCUIT = "30987654321"
+	config.Certificate = []byte("test certificate")
+	config.PrivateKey = []byte("test private key")
+
+	if err := config.Validate(); err == nil {
+		t.Error("Config.Validate() should reject a malformed represented CUIT")
+	}
+}
+
+// countingTokenCache simula un cache compartido entre réplicas
+type countingTokenCache struct {
+	*client.MemoryTokenCache
+	sets int
+}
+
+func (c *countingTokenCache) Set(ctx context.Context, key string, ticket *client.AccessTicket) error {
+	c.sets++
+	return c.MemoryTokenCache.Set(ctx, key, ticket)
+}
+
+func TestSharedTokenCache(t *testing.T) {
+	server := testutil.NewFakeAFIPServer()
+	defer server.Close()
+
+	config, err := server.Config()
+	if err != nil {
+		t.Fatalf("server.Config() error = %v", err)
+	}
+	cache := &countingTokenCache{MemoryTokenCache: client.NewMemoryTokenCache()}
+	config.TokenCache = cache
+
+	// Dos réplicas con el mismo CUIT comparten el ticket
+	first := client.NewWSAAAuth(&config, nil)
+	second := client.NewWSAAAuth(&config, nil)
+
+	ticket, err := first.GetAccessTicket(context.Background(), "wsfe")
+	if err != nil {
+		t.Fatalf("GetAccessTicket() error = %v", err)
+	}
+	shared, err := second.GetAccessTicket(context.Background(), "wsfe")
+	if err != nil {
+		t.Fatalf("GetAccessTicket() error = %v", err)
+	}
+
+	if shared.Token != ticket.Token {
+		t.Errorf("Second replica should reuse the cached ticket, got %q", shared.Token)
+	}
+	if calls := server.Calls(testutil.ActionLoginCms); calls != 1 {
+		t.Errorf("loginCms should be called once, got %d", calls)
+	}
+	if cache.sets != 1 {
+		t.Errorf("TokenCache.Set() should be called once, got %d", cache.sets)
+	}
+
+	key := "testing:20123456786:wsfe"
+	if cached, _ := cache.Get(context.Background(), key); cached == nil {
+		t.Errorf("Ticket should be stored under %q", key)
+	}
+	if first.GetCacheSize() != 1 {
+		t.Errorf("GetCacheSize() should use the cache Len(), got %d", first.GetCacheSize())
+	}
+}
+
+func TestDisableAuthCache(t *testing.T) {
+	server := testutil.NewFakeAFIPServer()
+	defer server.Close()
+
+	config, err := server.Config()
+	if err != nil {
+		t.Fatalf("server.Config() error = %v", err)
+	}
+	config.DisableAuthCache = true
+
+	auth := client.NewWSAAAuth(&config, nil)
+	for i := 0; i < 2; i++ {
+		if _, err := auth.GetAccessTicket(context.Background(), "wsfe"); err != nil {
+			t.Fatalf("GetAccessTicket() error = %v", err)
+		}
+	}
+
+	if calls := server.Calls(testutil.ActionLoginCms); calls != 2 {
+		t.Errorf("loginCms should be called on every request with the cache disabled, got %d", calls)
+	}
+}
+
+func TestAuthCacheExpiryWithFakeClock(t *testing.T) {
+	server := testutil.NewFakeAFIPServer()
+	defer server.Close()
+
+	config, err := server.Config()
+	if err != nil {
+		t.Fatalf("server.Config() error = %v", err)
+	}
+	clock := testutil.NewFakeClock(time.Now())
+	config.Clock = clock
+
+	auth := client.NewWSAAAuth(&config, nil)
+	ticket, err := auth.GetAccessTicket(context.Background(), "wsfe")
+	if err != nil {
+		t.Fatalf("GetAccessTicket() error = %v", err)
+	}
+	if !ticket.GenerationTime.Equal(clock.Now()) {
+		t.Errorf("Ticket should be generated at the clock time, got %v", ticket.GenerationTime)
+	}
+
+	clock.Advance(24*time.Hour - 6*time.Minute)
+	if _, err := auth.GetAccessTicket(context.Background(), "wsfe"); err != nil {
+		t.Fatalf("GetAccessTicket() error = %v", err)
+	}
+	if calls := server.Calls(testutil.ActionLoginCms); calls != 1 {
+		t.Errorf("Ticket should be reused before the expiry margin, got %d loginCms calls", calls)
+	}
+
+	clock.Advance(2 * time.Minute)
+	if _, err := auth.GetAccessTicket(context.Background(), "wsfe"); err != nil {
+		t.Fatalf("GetAccessTicket() error = %v", err)
+	}
+	if calls := server.Calls(testutil.ActionLoginCms); calls != 2 {
+		t.Errorf("Ticket within 5 minutes of expiry should be renewed, got %d loginCms calls", calls)
+	}
+}
+
+func TestGetTokenInfo(t *testing.T) {
+	server := testutil.NewFakeAFIPServer()
+	defer server.Close()
+
+	config, err := server.Config()
+	if err != nil {
+		t.Fatalf("server.Config() error = %v", err)
+	}
+	clock := testutil.NewFakeClock(time.Now())
+	config.Clock = clock
+	auth := client.NewWSAAAuth(&config, nil)
+
+	info, err := auth.GetTokenInfo("wsfe")
+	if err != nil {
+		t.Fatalf("GetTokenInfo() error = %v", err)
+	}
+	if info.Cached || info.Service != "wsfe" {
+		t.Errorf("GetTokenInfo() without a ticket should report it as not cached, got %+v", info)
+	}
+	if calls := server.Calls(testutil.ActionLoginCms); calls != 0 {
+		t.Errorf("GetTokenInfo() should not request a ticket, got %d loginCms calls", calls)
+	}
+
+	ticket, err := auth.GetAccessTicket(context.Background(), "wsfe")
+	if err != nil {
+		t.Fatalf("GetAccessTicket() error = %v", err)
+	}
+	clock.Advance(time.Hour)
+	info, err = auth.GetTokenInfo("wsfe")
+	if err != nil {
+		t.Fatalf("GetTokenInfo() error = %v", err)
+	}
+	if !info.Cached || !info.ExpirationTime.Equal(ticket.ExpirationTime) || !info.GenerationTime.Equal(ticket.GenerationTime) {
+		t.Errorf("GetTokenInfo() should describe the cached ticket, got %+v", info)
+	}
+	if want := ticket.ExpirationTime.Sub(clock.Now()); info.Remaining != want || info.Expired() {
+		t.Errorf("Remaining should be %v, got %v", want, info.Remaining)
+	}
+
+	clock.Advance(24 * time.Hour)
+	if info, _ = auth.GetTokenInfo("wsfe"); !info.Expired() || info.Remaining != 0 {
+		t.Errorf("Expired ticket should report no remaining validity, got %+v", info)
+	}
+}
+
+func TestExpiredCertificate(t *testing.T) {
+	server := testutil.NewFakeAFIPServer()
+	defer server.Close()
+
+	config, err := server.Config()
+	if err != nil {
+		t.Fatalf("server.Config() error = %v", err)
+	}
+	clock := testutil.NewFakeClock(time.Now().Add(48 * time.Hour))
+	config.Clock = clock
+
+	auth := client.NewWSAAAuth(&config, nil)
+	_, err = auth.GetAccessTicket(context.Background(), "wsfe")
+	var arcaErr *models.ARCAError
+	if !errors.As(err, &arcaErr) || arcaErr.Code != models.ErrorCodeExpiredCertificate || !strings.Contains(arcaErr.Details, "venció") {
+		t.Errorf("GetAccessTicket() should fail with an expired certificate error, got %v", err)
+	}
+
+	clock.Set(time.Now().Add(-48 * time.Hour))
+	_, err = auth.GetAccessTicket(context.Background(), "wsfe")
+	if !errors.As(err, &arcaErr) || arcaErr.Code != models.ErrorCodeExpiredCertificate || !strings.Contains(arcaErr.Details, "todavía no es válido") {
+		t.Errorf("GetAccessTicket() should fail with a not yet valid certificate error, got %v", err)
+	}
+
+	if calls := server.Calls(testutil.ActionLoginCms); calls != 0 {
+		t.Errorf("WSAA should not be called with an out of date certificate, got %d calls", calls)
+	}
+}
+
+func TestWSAAContextCancellation(t *testing.T) {
+	server := testutil.NewFakeAFIPServer()
+	defer server.Close()
+
+	config, err := server.Config()
+	if err != nil {
+		t.Fatalf("server.Config() error = %v", err)
+	}
+	auth := client.NewWSAAAuth(&config, nil)
+
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	if _, err := auth.GetAccessTicket(ctx, "wsfe"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("GetAccessTicket() should report the expired deadline, got %v", err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if _, err := auth.GetAccessTicket(ctx, "wsfe"); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetAccessTicket() should report the cancellation, got %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dlarregola/arca_invoice_lib/internal/batch"
+	"github.com/dlarregola/arca_invoice_lib/pkg/models"
+)
+
+func TestBatchRunOrderAndConcurrency(t *testing.T) {
+	items := make([]int, 20)
+	for i := range items {
+		items[i] = i
+	}
+
+	var inFlight, maxInFlight int32
+	results, errs := batch.Run(context.Background(), items, 3, func(ctx context.Context, item int) (string, error) {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			peak := atomic.LoadInt32(&maxInFlight)
+			if current <= peak || atomic.CompareAndSwapInt32(&maxInFlight, peak, current) {
+				break
+			}
+		}
+
+		time.Sleep(time.Millisecond * time.Duration(20-item))
+		if item%5 == 0 {
+			return "", fmt.Errorf("item %d failed", item)
+		}
+		return fmt.Sprintf("item-%d", item), nil
+	})
+
+	if maxInFlight > 3 {
+		t.Errorf("Run() should use at most 3 workers, got %d in flight", maxInFlight)
+	}
+	for i := range items {
+		if i%5 == 0 {
+			if errs[i] == nil || results[i] != "" {
+				t.Errorf("Item %d should fail, got %q %v", i, results[i], errs[i])
+			}
+			continue
+		}
+		if errs[i] != nil || results[i] != fmt.Sprintf("item-%d", i) {
+			t.Errorf("Item %d should keep its position, got %q %v", i, results[i], errs[i])
+		}
+	}
+
+	batchErr := models.NewBatchError(errs)
+	var typed *models.BatchError
+	if !errors.As(batchErr, &typed) || len(typed.Errors) != 4 || typed.Total != 20 {
+		t.Fatalf("NewBatchError() should index the 4 failures, got %v", batchErr)
+	}
+	if typed.Errors[5] == nil || typed.Errors[1] != nil {
+		t.Errorf("BatchError should be indexed by position, got %v", typed.Errors)
+	}
+}
+
+func TestBatchRunCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, errs := batch.Run(ctx, []int{1, 2, 3}, 2, func(ctx context.Context, item int) (int, error) {
+		return item, nil
+	})
+	for i, err := range errs {
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Item %d should not run after cancellation, got %v", i, err)
+		}
+	}
+
+	if err := models.NewBatchError([]error{nil, nil}); err != nil {
+		t.Errorf("NewBatchError() without failures should be nil, got %v", err)
+	}
+	if !errors.Is(models.NewBatchError(errs), context.Canceled) {
+		t.Error("BatchError should unwrap to the individual errors")
+	}
+}
@@ -0,0 +1,329 @@
+package tests
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dlarregola/arca_invoice_lib/internal/utils"
+	"github.com/dlarregola/arca_invoice_lib/pkg/client"
+	"github.com/dlarregola/arca_invoice_lib/pkg/models"
+	"github.com/dlarregola/arca_invoice_lib/pkg/wsfe"
+)
+
+func TestInvoiceBuilder(t *testing.T) {
+	invoice, err := models.NewInvoiceBuilder().
+		WithType(models.InvoiceTypeA).
+		WithPointOfSale(1).
+		WithCustomer(models.DocumentTypeCUIT, "20-12345678-6").
+		AddItem("Producto", 2, 500).
+		AddTax(models.TaxTypeIVA, models.TaxRate21).
+		AddItem("Libro", 1, 200).
+		AddTax(models.TaxTypeIVA, models.TaxRate105).
+		AddTribute(models.TaxTypeII, 1200, 36).
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	if invoice.Amount != 1200 {
+		t.Errorf("Amount should be 1200, got %v", invoice.Amount)
+	}
+	if invoice.TaxAmount != 231 {
+		t.Errorf("TaxAmount should be 210 + 21 = 231, got %v", invoice.TaxAmount)
+	}
+	if invoice.TotalAmount != 1467 {
+		t.Errorf("TotalAmount should be 1200 + 231 + 36 = 1467, got %v", invoice.TotalAmount)
+	}
+	if err := utils.ValidateTotalAmount(invoice.Amount, invoice.TaxAmount, invoice.TotalAmount, invoice.Taxes); err != nil {
+		t.Errorf("Built invoice should pass total validation: %v", err)
+	}
+	if err := utils.ValidateItemsTaxes(invoice.TaxAmount, invoice.Items); err != nil {
+		t.Errorf("Built invoice should carry item taxes: %v", err)
+	}
+}
+
+func TestInvoiceBuilderUntaxedAndExempt(t *testing.T) {
+	built, err := models.NewInvoiceBuilder().
+		WithType(models.InvoiceTypeA).
+		WithPointOfSale(1).
+		WithInvoiceNumber(1).
+		WithCustomer(models.DocumentTypeCUIT, "20-12345678-6").
+		AddItem("Producto", 1, 1000).
+		AddTax(models.TaxTypeIVA, models.TaxRate21).
+		AddItem("No gravado", 1, 200).
+		AddTax(models.TaxTypeIVA, models.TaxRate0).
+		AddItem("Exento", 1, 500).
+		AddTax(models.TaxTypeIVA, models.TaxRateExempt).
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	if built.Amount != 1000 || built.NonTaxableAmount != 200 || built.ExemptAmount != 500 {
+		t.Errorf("Amount, NonTaxableAmount and ExemptAmount should be 1000, 200 and 500, got %v %v %v", built.Amount, built.NonTaxableAmount, built.ExemptAmount)
+	}
+	if built.TaxAmount != 210 || built.TotalAmount != 1910 {
+		t.Errorf("TaxAmount and TotalAmount should be 210 and 1910, got %v %v", built.TaxAmount, built.TotalAmount)
+	}
+
+	invoice := &wsfe.Invoice{
+		InvoiceBase:          built.InvoiceBase,
+		DocType:              built.DocType,
+		DocNumber:            built.DocNumber,
+		DocTypeFrom:          models.DocumentTypeCUIT,
+		DocNumberFrom:        "20-12345678-6",
+		ReceptorIVACondition: models.ReceptorIVAConditionRegistered,
+		NonTaxableAmount:     built.NonTaxableAmount,
+		ExemptAmount:         built.ExemptAmount,
+	}
+	if err := invoice.Validate(); err != nil {
+		t.Fatalf("Built invoice should pass WSFE validation: %v", err)
+	}
+
+	config := client.DefaultConfig()
+	request, err := wsfe.NewService(&config, nil, nil).NewAuthorizationRequest(invoice, wsfe.Auth{})
+	if err != nil {
+		t.Fatalf("NewAuthorizationRequest() error = %v", err)
+	}
+	detail := request.Request.Details[0]
+	if detail.Amount != 1000 || detail.UntaxedAmount != 200 || detail.ExemptAmount != 500 || detail.TotalAmount != 1910 {
+		t.Errorf("ImpNeto, ImpTotConc, ImpOpEx and ImpTotal should be 1000, 200, 500 and 1910, got %v %v %v %v", detail.Amount, detail.UntaxedAmount, detail.ExemptAmount, detail.TotalAmount)
+	}
+}
+
+func TestInvoiceBuilderValidation(t *testing.T) {
+	_, err := models.NewInvoiceBuilder().
+		AddTax(models.TaxTypeIVA, models.TaxRate21).
+		Build()
+
+	var validationErrors models.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		t.Fatalf("Build() should return ValidationErrors, got %v", err)
+	}
+
+	fields := map[string]bool{}
+	for _, validationErr := range validationErrors {
+		fields[validationErr.Field] = true
+	}
+	for _, field := range []string{"invoice_type", "point_of_sale", "items", "taxes"} {
+		if !fields[field] {
+			t.Errorf("Build() should report %s, got %v", field, err)
+		}
+	}
+}
+
+func TestInvoiceBuilderClassC(t *testing.T) {
+	invoice, err := models.NewInvoiceBuilder().
+		WithType(models.InvoiceTypeC).
+		WithPointOfSale(1).
+		WithCustomer(models.DocumentTypeCUIT, "20-12345678-6").
+		AddItem("Servicio", 1, 1000).
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if invoice.TaxAmount != 0 || invoice.TotalAmount != 1000 {
+		t.Errorf("Factura C should not carry IVA, got TaxAmount %v TotalAmount %v", invoice.TaxAmount, invoice.TotalAmount)
+	}
+
+	_, err = models.NewInvoiceBuilder().
+		WithType(models.InvoiceTypeC).
+		WithPointOfSale(1).
+		AddItem("Servicio", 1, 1000).
+		AddTax(models.TaxTypeIVA, models.TaxRate21).
+		Build()
+	if err == nil {
+		t.Error("Build() should reject IVA on a Factura C")
+	}
+}
+
+func TestInvoiceBuilderCreditNote(t *testing.T) {
+	_, err := models.NewInvoiceBuilder().
+		WithType(models.InvoiceTypeCreditNoteB).
+		WithPointOfSale(1).
+		AddItem("Devolución", 1, 500).
+		Build()
+
+	var validationErrors models.ValidationErrors
+	if !errors.As(err, &validationErrors) || validationErrors[0].Field != "associated_invoices" {
+		t.Fatalf("Credit notes without associated invoices should be rejected, got %v", err)
+	}
+
+	invoice, err := models.NewInvoiceBuilder().
+		WithType(models.InvoiceTypeCreditNoteB).
+		WithPointOfSale(1).
+		WithAssociatedInvoice(models.InvoiceTypeB, 1, 120).
+		AddItem("Devolución", 1, 500).
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if len(invoice.AssociatedInvoices) != 1 || invoice.AssociatedInvoices[0].InvoiceNumber != 120 {
+		t.Errorf("Associated invoice should be kept, got %+v", invoice.AssociatedInvoices)
+	}
+}
+
+func TestInvoiceBuilderPaymentDueDate(t *testing.T) {
+	date := time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)
+	builder := func() *models.InvoiceBuilder {
+		return models.NewInvoiceBuilder().
+			WithType(models.InvoiceTypeC).
+			WithPointOfSale(1).
+			WithConcept(models.ConceptTypeServices).
+			WithDate(date).
+			AddItem("Servicio", 1, 1000)
+	}
+
+	var validationErrors models.ValidationErrors
+	_, err := builder().Build()
+	if !errors.As(err, &validationErrors) || validationErrors[0].Field != "payment_due_date" {
+		t.Errorf("Services invoices without payment due date should be rejected, got %v", err)
+	}
+
+	_, err = builder().WithPaymentDueDate(date.AddDate(0, 0, -1)).Build()
+	if !errors.As(err, &validationErrors) || validationErrors[0].Field != "payment_due_date" {
+		t.Errorf("Payment due date before the invoice date should be rejected, got %v", err)
+	}
+
+	invoice, err := builder().WithPaymentDueDate(date.AddDate(0, 0, 30)).Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if !invoice.PaymentDueDate.Equal(date.AddDate(0, 0, 30)) {
+		t.Errorf("PaymentDueDate should be kept, got %v", invoice.PaymentDueDate)
+	}
+}
+
+func TestInvoiceBuilderAssociatedPeriod(t *testing.T) {
+	from := time.Now().AddDate(0, -1, 0)
+	invoice, err := models.NewInvoiceBuilder().
+		WithType(models.InvoiceTypeCreditNoteB).
+		WithPointOfSale(1).
+		WithAssociatedPeriod(from, time.Now()).
+		AddItem("Ajuste abono", 1, 500).
+		Build()
+	if err != nil {
+		t.Fatalf("Build() should accept an associated period instead of an invoice: %v", err)
+	}
+	if invoice.AssociatedPeriod == nil || !invoice.AssociatedPeriod.From.Equal(from) {
+		t.Errorf("Build() should keep the associated period, got %+v", invoice.AssociatedPeriod)
+	}
+
+	_, err = models.NewInvoiceBuilder().
+		WithType(models.InvoiceTypeCreditNoteB).
+		WithPointOfSale(1).
+		WithAssociatedInvoice(models.InvoiceTypeB, 1, 120).
+		WithAssociatedPeriod(from, time.Now()).
+		AddItem("Ajuste abono", 1, 500).
+		Build()
+	var validationErrors models.ValidationErrors
+	if !errors.As(err, &validationErrors) || validationErrors[0].Field != "associated_period" {
+		t.Errorf("Build() should reject both an associated invoice and period, got %v", err)
+	}
+}
+
+func TestInvoiceBuilderServicePeriod(t *testing.T) {
+	date := time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)
+	builder := func() *models.InvoiceBuilder {
+		return models.NewInvoiceBuilder().
+			WithType(models.InvoiceTypeC).
+			WithPointOfSale(1).
+			WithConcept(models.ConceptTypeServices).
+			WithDate(date).
+			WithPaymentDueDate(date).
+			AddItem("Abono mensual", 1, 1000)
+	}
+
+	from := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)
+	invoice, err := builder().WithServicePeriod(from, to).Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if !invoice.ServiceFrom.Equal(from) || !invoice.ServiceTo.Equal(to) {
+		t.Errorf("Build() should keep the service period, got %v - %v", invoice.ServiceFrom, invoice.ServiceTo)
+	}
+
+	var validationErrors models.ValidationErrors
+	_, err = builder().WithServicePeriod(to, from).Build()
+	if !errors.As(err, &validationErrors) || validationErrors[0].Field != "service_to" {
+		t.Errorf("An inverted service period should be rejected, got %v", err)
+	}
+}
+
+func TestInvoiceBuilderPricesIncludeTax(t *testing.T) {
+	invoice, err := models.NewInvoiceBuilder().
+		PricesIncludeTax(true).
+		WithType(models.InvoiceTypeB).
+		WithPointOfSale(1).
+		WithCustomer(models.DocumentTypeDNI, "12345678").
+		AddItem("Producto", 2, 605).
+		AddTax(models.TaxTypeIVA, models.TaxRate21).
+		AddItem("Libro", 1, 99.99).
+		AddTax(models.TaxTypeIVA, models.TaxRate105).
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	if invoice.Items[0].TotalPrice != 1000 || invoice.Items[0].Taxes[0].Amount != 210 || invoice.Items[0].UnitPrice != 500 {
+		t.Errorf("1210 with 21%% IVA included should split into 1000 + 210, got %+v", invoice.Items[0])
+	}
+	// 99.99 / 1.105 = 90.488...: el neto redondeado por la alícuota reconstruye el precio final
+	if invoice.Items[1].TotalPrice != 90.49 || invoice.Items[1].Taxes[0].Amount != 9.5 {
+		t.Errorf("99.99 with 10.5%% IVA included should split into 90.49 + 9.50, got %+v", invoice.Items[1])
+	}
+	if invoice.TotalAmount != 1309.99 {
+		t.Errorf("TotalAmount should match the gross prices, got %v", invoice.TotalAmount)
+	}
+	if err := utils.ValidateTotalAmount(invoice.Amount, invoice.TaxAmount, invoice.TotalAmount, invoice.Taxes); err != nil {
+		t.Errorf("Built invoice should pass total validation: %v", err)
+	}
+	if err := utils.ValidateItemsTaxes(invoice.TaxAmount, invoice.Items); err != nil {
+		t.Errorf("Built invoice should carry item taxes: %v", err)
+	}
+
+	_, err = models.NewInvoiceBuilder().
+		WithType(models.InvoiceTypeB).
+		WithPointOfSale(1).
+		AddItem("Producto", 1, 121).
+		PricesIncludeTax(true).
+		Build()
+	var validationErrs models.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		t.Errorf("PricesIncludeTax() after AddItem() should fail, got %v", err)
+	}
+}
+
+func TestInvoiceBuilderPricesIncludeTaxPassesWSFEValidation(t *testing.T) {
+	for _, rate := range []models.TaxRate{models.TaxRate21, models.TaxRate105} {
+		for cents := 100; cents <= 100000; cents++ {
+			gross := float64(cents) / 100
+			built, err := models.NewInvoiceBuilder().
+				PricesIncludeTax(true).
+				WithType(models.InvoiceTypeB).
+				WithPointOfSale(1).
+				WithInvoiceNumber(1).
+				WithCustomer(models.DocumentTypeDNI, "12345678").
+				AddItem("Producto", 1, gross).
+				AddTax(models.TaxTypeIVA, rate).
+				Build()
+			if err != nil {
+				t.Fatalf("Build() with gross %.2f at %s error = %v", gross, rate, err)
+			}
+
+			invoice := &wsfe.Invoice{
+				InvoiceBase:          built.InvoiceBase,
+				DocType:              built.DocType,
+				DocNumber:            built.DocNumber,
+				DocTypeFrom:          models.DocumentTypeCUIT,
+				DocNumberFrom:        "20-12345678-6",
+				ReceptorIVACondition: models.ReceptorIVAConditionFinalConsumer,
+			}
+			if err := invoice.Validate(); err != nil {
+				t.Fatalf("Gross %.2f at %s split into %+v should pass WSFE validation: %v", gross, rate, built.Items[0], err)
+			}
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/dlarregola/arca_invoice_lib/pkg/models"
+	"github.com/dlarregola/arca_invoice_lib/pkg/testutil"
+	"github.com/dlarregola/arca_invoice_lib/pkg/wsfe"
+)
+
+// mockCAEAService simula las operaciones CAEA de AFIP
+type mockCAEAService struct {
+	registered  map[int][]*wsfe.Invoice
+	noMovement  []int
+	caeaDueDate time.Time
+}
+
+func newMockCAEAService() *mockCAEAService {
+	return &mockCAEAService{
+		registered:  make(map[int][]*wsfe.Invoice),
+		caeaDueDate: time.Now().AddDate(0, 0, 15),
+	}
+}
+
+func (m *mockCAEAService) GetCAEA(ctx context.Context, period, order, fiscalYear int) (*wsfe.CAEAResponse, error) {
+	response := &wsfe.CAEAResponse{}
+	response.Result.CAEA = "21064126523746"
+	response.Result.Period = period
+	response.Result.Order = order
+	response.Result.DueDate = wsfe.AFIPDate{Time: m.caeaDueDate}
+	response.Result.ReportDeadline = wsfe.AFIPDate{Time: m.caeaDueDate.AddDate(0, 0, 8)}
+	return response, nil
+}
+
+func (m *mockCAEAService) RegisterCAEAInvoices(ctx context.Context, caea string, invoices []*wsfe.Invoice) ([]*models.AuthorizationResult, error) {
+	results := make([]*models.AuthorizationResult, 0, len(invoices))
+	for _, invoice := range invoices {
+		m.registered[invoice.PointOfSale] = append(m.registered[invoice.PointOfSale], invoice)
+		results = append(results, &models.AuthorizationResult{
+			CAE:           caea,
+			InvoiceNumber: invoice.InvoiceNumber,
+			PointOfSale:   invoice.PointOfSale,
+			InvoiceType:   invoice.InvoiceType,
+			Status:        "A",
+		})
+	}
+	return results, nil
+}
+
+func (m *mockCAEAService) ReportCAEANoMovement(ctx context.Context, caea string, pointOfSale int) error {
+	m.noMovement = append(m.noMovement, pointOfSale)
+	return nil
+}
+
+func TestCAEASessionLifecycle(t *testing.T) {
+	ctx := context.Background()
+	service := newMockCAEAService()
+	session := wsfe.NewCAEASession(service, 202401, 1, 2024)
+
+	if session.State() != wsfe.CAEASessionNew {
+		t.Fatalf("New session should be in state %s, got %s", wsfe.CAEASessionNew, session.State())
+	}
+
+	// No se puede emitir antes de obtener el CAEA
+	if err := session.IssueInvoice(newTestWSFEInvoice()); err == nil {
+		t.Error("IssueInvoice() should fail before Start()")
+	}
+
+	if err := session.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if session.State() != wsfe.CAEASessionActive {
+		t.Fatalf("Started session should be in state %s, got %s", wsfe.CAEASessionActive, session.State())
+	}
+	if session.CAEA != "21064126523746" {
+		t.Errorf("Session should store the CAEA, got %q", session.CAEA)
+	}
+
+	for number := 1; number <= 2; number++ {
+		invoice := newTestWSFEInvoice()
+		invoice.InvoiceNumber = number
+		if err := session.IssueInvoice(invoice); err != nil {
+			t.Fatalf("IssueInvoice() error = %v", err)
+		}
+		if invoice.CAE != session.CAEA {
+			t.Errorf("Issued invoice should carry the CAEA, got %q", invoice.CAE)
+		}
+	}
+	if session.PendingCount() != 2 {
+		t.Errorf("Session should have 2 pending invoices, got %d", session.PendingCount())
+	}
+
+	results, err := session.Register(ctx)
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if len(results) != 2 || session.PendingCount() != 0 {
+		t.Errorf("Register() should report all pending invoices, got %d results and %d pending", len(results), session.PendingCount())
+	}
+
+	// El punto de venta 1 tuvo movimiento; el 2 se informa sin movimiento
+	if _, err := session.Close(ctx, 1, 2); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if len(service.noMovement) != 1 || service.noMovement[0] != 2 {
+		t.Errorf("Only point of sale 2 should be reported without movement, got %v", service.noMovement)
+	}
+	if len(service.registered[1]) != 2 {
+		t.Errorf("Point of sale 1 should have 2 registered invoices, got %d", len(service.registered[1]))
+	}
+	if session.State() != wsfe.CAEASessionClosed {
+		t.Errorf("Closed session should be in state %s, got %s", wsfe.CAEASessionClosed, session.State())
+	}
+
+	if err := session.IssueInvoice(newTestWSFEInvoice()); err == nil {
+		t.Error("IssueInvoice() should fail after Close()")
+	}
+}
+
+func TestCAEASessionExpired(t *testing.T) {
+	service := newMockCAEAService()
+	service.caeaDueDate = time.Now().AddDate(0, 0, -1)
+
+	session := wsfe.NewCAEASession(service, 202401, 1, 2024)
+	if err := session.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	if err := session.IssueInvoice(newTestWSFEInvoice()); err == nil {
+		t.Error("IssueInvoice() should fail with an expired CAEA")
+	}
+}
+
+func TestCAEAResponseValidityWindow(t *testing.T) {
+	service := newMockCAEAService()
+	response, err := service.GetCAEA(context.Background(), 202401, 2, 2024)
+	if err != nil {
+		t.Fatalf("GetCAEA() error = %v", err)
+	}
+	response.Result.ValidFrom = wsfe.AFIPDate{Time: time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC)}
+	response.Result.MaxAmount = 500000
+
+	caea := response.ToCAEAResponse()
+	if caea.CAEA != "21064126523746" || caea.Period != 202401 || caea.Order != 2 {
+		t.Errorf("ToCAEAResponse() should keep CAEA, period and order, got %+v", caea)
+	}
+	if !caea.ValidFrom.Equal(response.Result.ValidFrom.Time) || !caea.ExpirationDate.Equal(service.caeaDueDate) {
+		t.Errorf("ToCAEAResponse() should carry the validity window, got %v - %v", caea.ValidFrom, caea.ExpirationDate)
+	}
+	if caea.MaxAmount != 500000 || caea.ReportDeadline.IsZero() {
+		t.Errorf("ToCAEAResponse() should carry max amount and report deadline, got %+v", caea)
+	}
+}
+
+func TestRegisterCAEAInvoicesRequest(t *testing.T) {
+	server, _, service := newFakeAFIPService(t)
+
+	invoice := newTestWSFEInvoice()
+	invoice.IssueDate = time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	invoice.ConceptType = models.ConceptTypeServices
+	invoice.ServiceFrom = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	invoice.ServiceTo = time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	invoice.PaymentDueDate = time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC)
+	invoice.Taxes = []models.Tax{{Type: models.TaxTypeII, Base: 1000, Amount: 30}}
+	invoice.TotalAmount = 1240
+
+	results, err := service.RegisterCAEAInvoices(context.Background(), "21064126523746", []*wsfe.Invoice{invoice})
+	if err != nil {
+		t.Fatalf("RegisterCAEAInvoices() error = %v", err)
+	}
+	if len(results) != 1 || results[0].CAE != "21064126523746" || results[0].Status != models.AuthResultApproved {
+		t.Errorf("RegisterCAEAInvoices() should return the informed invoice, got %+v", results)
+	}
+
+	// El detalle informado debe llevar los mismos datos que FECAESolicitar
+	body := string(server.LastRequest(testutil.ActionFECAEARegInformativo))
+	for _, pattern := range []string{
+		`<ImpTrib>30.00</ImpTrib>`,
+		`<FchServDesde>20240101</FchServDesde>\s*<FchServHasta>20240131</FchServHasta>\s*<FchVtoPago>20240215</FchVtoPago>`,
+		`<CondicionIVAReceptorId>1</CondicionIVAReceptorId>`,
+		`<Tributos>\s*<Tributo>\s*<Id>4</Id>`,
+		`<Iva>\s*<AlicIva>\s*<Id>5</Id>\s*<BaseImp>1000.00</BaseImp>\s*<Importe>210.00</Importe>`,
+		`</Iva>\s*<CAEA>21064126523746</CAEA>\s*</FECAEADetRequest>`,
+	} {
+		if !regexp.MustCompile(pattern).MatchString(body) {
+			t.Errorf("FECAEARegInformativo request should match %s, got %s", pattern, body)
+		}
+	}
+
+	invalid := newTestWSFEInvoice()
+	invalid.IssueDate = invoice.IssueDate
+	invalid.TaxAmount = 0
+	_, err = service.RegisterCAEAInvoices(context.Background(), "21064126523746", []*wsfe.Invoice{invalid})
+	var validationErrs models.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		t.Errorf("RegisterCAEAInvoices() should validate the invoices, got %v", err)
+	}
+	if calls := server.Calls(testutil.ActionFECAEARegInformativo); calls != 1 {
+		t.Errorf("An invalid invoice should not be informed to AFIP, got %d calls", calls)
+	}
+}
@@ -3,7 +3,14 @@ package tests
 import (
 	"github.com/dlarregola/arca_invoice_lib/pkg/client"
 	"github.com/dlarregola/arca_invoice_lib/pkg/models"
+	"github.com/dlarregola/arca_invoice_lib/pkg/testutil"
+	"bytes"
 	"context"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 )
@@ -161,6 +168,12 @@ func TestConfigURLs(t *testing.T) {
 	if wsfexURL != "https://wswhomo.afip.gov.ar/wsfexv1/service.asmx" {
 		t.Errorf("WSFEX URL should be correct, got %s", wsfexURL)
 	}
+
+	// Verificar URL base fija (proxy/sandbox)
+	productionConfig.WithBaseURLOverride("http://proxy.local:8080/afip/")
+	if url := productionConfig.GetWSFEURL(); url != "http://proxy.local:8080/afip/wsfev1/service.asmx" {
+		t.Errorf("BaseURLOverride should replace the environment URL, got %s", url)
+	}
 }
 
 func TestConfigBuilder(t *testing.T) {
@@ -193,6 +206,167 @@ func TestConfigBuilder(t *testing.T) {
 	}
 }
 
+func TestConfigFromEnv(t *testing.T) {
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "cert.pem")
+	keyPath := filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certPath, []byte("test certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyPath, []byte("test private key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("ARCA_CUIT", "20-12345678-9")
+	t.Setenv("ARCA_ENVIRONMENT", "production")
+	t.Setenv("ARCA_CERT_PATH", certPath)
+	t.Setenv("ARCA_KEY_PATH", keyPath)
+	t.Setenv("ARCA_TIMEOUT", "45s")
+	t.Setenv("ARCA_RETRY_ATTEMPTS", "5")
+	t.Setenv("ARCA_RETRY_DELAY", "2")
+
+	config, err := client.ConfigFromEnv()
+	if err != nil {
+		t.Fatalf("ConfigFromEnv() error = %v", err)
+	}
+	if config.Environment != models.EnvironmentProduction || config.CUIT != "20-12345678-9" {
+		t.Errorf("ConfigFromEnv() should read environment and CUIT, got %s %s", config.Environment, config.CUIT)
+	}
+	if string(config.Certificate) != "test certificate" || string(config.PrivateKey) != "test private key" {
+		t.Errorf("ConfigFromEnv() should load the certificate and key files")
+	}
+	if config.Timeout != 45*time.Second || config.RetryAttempts != 5 || config.RetryDelay != 2*time.Second {
+		t.Errorf("ConfigFromEnv() should parse network settings, got %v %d %v", config.Timeout, config.RetryAttempts, config.RetryDelay)
+	}
+	if config.AuthCacheTTL != 23*time.Hour {
+		t.Errorf("Unset variables should keep the defaults, got AuthCacheTTL %v", config.AuthCacheTTL)
+	}
+}
+
+func TestConfigFromEnvErrors(t *testing.T) {
+	t.Setenv("ARCA_CUIT", "2012345678")
+	t.Setenv("ARCA_CERT_PATH", "")
+	t.Setenv("ARCA_KEY_PATH", filepath.Join(t.TempDir(), "missing.pem"))
+	t.Setenv("ARCA_TIMEOUT", "soon")
+
+	_, err := client.ConfigFromEnv()
+	var validationErrs models.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		t.Fatalf("ConfigFromEnv() should return ValidationErrors, got %v", err)
+	}
+
+	fields := make(map[string]int)
+	for _, validationErr := range validationErrs {
+		fields[validationErr.Field]++
+	}
+	for _, field := range []string{"cuit", "certificate", "private_key", "timeout"} {
+		if fields[field] != 1 {
+			t.Errorf("ConfigFromEnv() should report %s once, got %d in %v", field, fields[field], err)
+		}
+	}
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "cert.pem"), []byte("test certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	key := base64.StdEncoding.EncodeToString([]byte("test private key"))
+	path := filepath.Join(dir, "arca.json")
+	content := `{"environment": "production", "cuit": "20-12345678-9", "certificate": "cert.pem", "private_key": "` + key + `", "timeout": "45s", "retry_attempts": 5}`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := client.LoadConfigFile(path)
+	if err != nil {
+		t.Fatalf("LoadConfigFile() error = %v", err)
+	}
+	if config.Environment != models.EnvironmentProduction || config.CUIT != "20-12345678-9" {
+		t.Errorf("LoadConfigFile() should read environment and CUIT, got %s %s", config.Environment, config.CUIT)
+	}
+	if string(config.Certificate) != "test certificate" || string(config.PrivateKey) != "test private key" {
+		t.Errorf("LoadConfigFile() should read the certificate file and decode the base64 key")
+	}
+	if config.Timeout != 45*time.Second || config.RetryAttempts != 5 || config.AuthCacheTTL != 23*time.Hour {
+		t.Errorf("LoadConfigFile() should parse durations and keep defaults, got %v %d %v", config.Timeout, config.RetryAttempts, config.AuthCacheTTL)
+	}
+
+	content = `{"cuit": "2012345678", "certificate": "missing.pem", "private_key": "` + key + `"}`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.LoadConfigFile(path)
+	var validationErrs models.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		t.Fatalf("LoadConfigFile() should return ValidationErrors, got %v", err)
+	}
+	fields := make(map[string]int)
+	for _, validationErr := range validationErrs {
+		fields[validationErr.Field]++
+	}
+	if fields["cuit"] != 1 || fields["certificate"] != 1 || !strings.Contains(err.Error(), "missing.pem") {
+		t.Errorf("LoadConfigFile() should report the invalid CUIT and the missing certificate file, got %v", err)
+	}
+
+	if _, err := client.LoadConfigFile(filepath.Join(dir, "absent.json")); err == nil {
+		t.Error("LoadConfigFile() should fail for a missing config file")
+	}
+
+	content = `{"cuit": "20-12345678-9", "certificate": "cert.pem", "private_key": "` + key + `", "timeout": 30, "retry_delay": 1.5}`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	config, err = client.LoadConfigFile(path)
+	if err != nil {
+		t.Fatalf("LoadConfigFile() error = %v", err)
+	}
+	if config.Timeout != 30*time.Second || config.RetryDelay != 1500*time.Millisecond {
+		t.Errorf("LoadConfigFile() should read numeric durations as seconds, got %v %v", config.Timeout, config.RetryDelay)
+	}
+}
+
+func TestLoadConfigFileYAML(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "arca.yaml")
+	content := `# Configuración de producción
+environment: production
+cuit: 20-12345678-9
+certificate: |
+  -----BEGIN CERTIFICATE-----
+  MIIB
+  -----END CERTIFICATE-----
+private_key: 'dGVzdCBwcml2YXRlIGtleQ=='
+timeout: 45s # segundos
+retry_attempts: 5
+log_requests: true
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := client.LoadConfigFile(path)
+	if err != nil {
+		t.Fatalf("LoadConfigFile() error = %v", err)
+	}
+	if config.Environment != models.EnvironmentProduction || config.CUIT != "20-12345678-9" {
+		t.Errorf("LoadConfigFile() should read environment and CUIT from YAML, got %s %s", config.Environment, config.CUIT)
+	}
+	if !strings.HasPrefix(string(config.Certificate), "-----BEGIN CERTIFICATE-----\nMIIB\n") || string(config.PrivateKey) != "test private key" {
+		t.Errorf("LoadConfigFile() should read the PEM block and decode the base64 key, got %q", config.Certificate)
+	}
+	if config.Timeout != 45*time.Second || config.RetryAttempts != 5 || !config.LogRequests {
+		t.Errorf("LoadConfigFile() should convert YAML values by field type, got %v %d %v", config.Timeout, config.RetryAttempts, config.LogRequests)
+	}
+
+	if err := os.WriteFile(path, []byte("cuit: 20123456789\nproxy:\n  host: localhost\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.LoadConfigFile(path); err == nil || !strings.Contains(err.Error(), "invalid YAML") {
+		t.Errorf("LoadConfigFile() should reject nested YAML values, got %v", err)
+	}
+}
+
 func TestClientCreation(t *testing.T) {
 	// Test con configuración válida
 	validConfig := client.Config{
@@ -215,10 +389,12 @@ func TestClientCreation(t *testing.T) {
 	}
 
 	// Verificar que los servicios están disponibles
-	// Nota: Los servicios WSFE y WSFEX necesitan ser configurados después de crear el cliente
-	// Por ahora verificamos que el cliente se creó correctamente
-	if arcaClient == nil {
-		t.Error("ARCA client should not be nil")
+	if arcaClient.WSFE() == nil {
+		t.Error("WSFE service should be wired on client creation")
+	}
+
+	if arcaClient.WSFEX() == nil {
+		t.Error("WSFEX service should be wired on client creation")
 	}
 
 	// Test con configuración inválida
@@ -238,6 +414,146 @@ func TestClientCreation(t *testing.T) {
 	}
 }
 
+func TestStdLogger(t *testing.T) {
+	var out bytes.Buffer
+	logger := client.NewStdLogger(&out, "warn")
+
+	logger.Debug("debug message")
+	logger.Infof("info %d", 1)
+	logger.Warnf("warn %d", 2)
+	logger.Error("error message")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "[WARN] warn 2") || !strings.Contains(lines[1], "[ERROR] error message") {
+		t.Errorf("StdLogger should only write warn and error, got %q", out.String())
+	}
+
+	out.Reset()
+	logger.SetLevel("debug")
+	logger.Debug("debug message")
+	if !strings.Contains(out.String(), "[DEBUG] debug message") || logger.GetLevel() != "debug" {
+		t.Errorf("StdLogger should write debug after SetLevel, got %q", out.String())
+	}
+
+	// NewARCAClient usa un StdLogger con Config.LogLevel
+	config := client.DefaultConfig()
+	config.CUIT = "20-12345678-9"
+	config.Certificate = []byte("test certificate")
+	config.PrivateKey = []byte("test private key")
+	config.LogLevel = "error"
+	config.LogOutput = &out
+
+	arcaClient, err := client.NewARCAClient(config)
+	if err != nil {
+		t.Fatalf("NewARCAClient() error = %v", err)
+	}
+	if logger, ok := arcaClient.GetLogger().(*client.StdLogger); !ok || logger.GetLevel() != "error" {
+		t.Errorf("Default logger should honor Config.LogLevel, got %#v", arcaClient.GetLogger())
+	}
+
+	config.LogLevel = "verbose"
+	if _, err := client.NewARCAClient(config); err == nil {
+		t.Error("NewARCAClient() should reject an unknown log level")
+	}
+}
+
+func TestCheckCertificate(t *testing.T) {
+	tests := []struct {
+		name       string
+		notAfter   time.Time
+		wantStatus string
+		wantValid  bool
+	}{
+		{name: "valid", notAfter: time.Now().AddDate(1, 0, 0), wantStatus: models.CertStatusOK, wantValid: true},
+		{name: "about to expire", notAfter: time.Now().AddDate(0, 0, 10), wantStatus: models.CertStatusWarning, wantValid: true},
+		{name: "expired", notAfter: time.Now().Add(-time.Minute), wantStatus: models.CertStatusExpired, wantValid: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, key, err := testutil.GenerateCertificateWithExpiry("20123456786", tt.notAfter)
+			if err != nil {
+				t.Fatalf("GenerateCertificateWithExpiry() error = %v", err)
+			}
+
+			config := client.DefaultConfig()
+			config.CUIT = "20-12345678-6"
+			config.Certificate = cert
+			config.PrivateKey = key
+
+			arcaClient, err := client.NewARCAClient(config)
+			if err != nil {
+				t.Fatalf("NewARCAClient() error = %v", err)
+			}
+
+			status, err := arcaClient.CheckCertificate(context.Background())
+			if err != nil {
+				t.Fatalf("CheckCertificate() error = %v", err)
+			}
+			if status.Status != tt.wantStatus || status.Valid != tt.wantValid {
+				t.Errorf("CheckCertificate() = %s (valid %v), want %s (valid %v)", status.Status, status.Valid, tt.wantStatus, tt.wantValid)
+			}
+			if !status.NotAfter.Equal(tt.notAfter.Truncate(time.Second)) {
+				t.Errorf("NotAfter = %v, want %v", status.NotAfter, tt.notAfter)
+			}
+		})
+	}
+
+	config := client.DefaultConfig()
+	config.CUIT = "20-12345678-6"
+	config.Certificate = []byte("test certificate")
+	config.PrivateKey = []byte("test private key")
+	arcaClient, err := client.NewARCAClient(config)
+	if err != nil {
+		t.Fatalf("NewARCAClient() error = %v", err)
+	}
+	if _, err := arcaClient.CheckCertificate(context.Background()); err == nil {
+		t.Error("CheckCertificate() should fail with an invalid certificate")
+	}
+}
+
+func TestClientClose(t *testing.T) {
+	server := testutil.NewFakeAFIPServer()
+	defer server.Close()
+
+	config, err := server.Config()
+	if err != nil {
+		t.Fatalf("server.Config() error = %v", err)
+	}
+	arcaClient, err := client.NewARCAClient(config)
+	if err != nil {
+		t.Fatalf("NewARCAClient() error = %v", err)
+	}
+
+	ctx := context.Background()
+	if _, err := arcaClient.WSFE().GetTaxRates(ctx); err != nil {
+		t.Fatalf("GetTaxRates() error = %v", err)
+	}
+	if arcaClient.GetAuthCacheSize() != 1 {
+		t.Fatalf("Auth cache should hold the wsfe ticket")
+	}
+
+	if err := arcaClient.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if err := arcaClient.Close(); err != nil {
+		t.Errorf("Close() should be idempotent, got %v", err)
+	}
+	if !arcaClient.IsClosed() || arcaClient.GetAuthCacheSize() != 0 {
+		t.Errorf("Close() should mark the client closed and clear the auth cache")
+	}
+
+	if _, err := arcaClient.WSFEX().GetCountries(ctx); !errors.Is(err, client.ErrClientClosed) {
+		t.Errorf("Service calls after Close() should fail with ErrClientClosed, got %v", err)
+	}
+	if err := arcaClient.TestConnection(ctx); !errors.Is(err, client.ErrClientClosed) {
+		t.Errorf("TestConnection() after Close() should fail with ErrClientClosed, got %v", err)
+	}
+	if calls := server.Calls(testutil.ActionLoginCms); calls != 1 {
+		t.Errorf("Closed client should not call WSAA again, got %d logins", calls)
+	}
+}
+
 func TestSystemStatus(t *testing.T) {
 	config := client.Config{
 		Environment:   models.EnvironmentTesting,
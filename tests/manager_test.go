@@ -0,0 +1,258 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlarregola/arca_invoice_lib/internal/shared"
+	"github.com/dlarregola/arca_invoice_lib/pkg/client"
+	arcaerrors "github.com/dlarregola/arca_invoice_lib/pkg/errors"
+	"github.com/dlarregola/arca_invoice_lib/pkg/factory"
+	"github.com/dlarregola/arca_invoice_lib/pkg/interfaces"
+	"github.com/dlarregola/arca_invoice_lib/pkg/models"
+	"github.com/dlarregola/arca_invoice_lib/pkg/testutil"
+)
+
+// testCompanyConfig implementa interfaces.CompanyConfig para los tests
+type testCompanyConfig struct {
+	companyID string
+}
+
+func (c *testCompanyConfig) GetCUIT() string        { return "20123456786" }
+func (c *testCompanyConfig) GetCertificate() []byte { return []byte("cert") }
+func (c *testCompanyConfig) GetPrivateKey() []byte  { return []byte("key") }
+func (c *testCompanyConfig) GetEnvironment() string { return "testing" }
+func (c *testCompanyConfig) GetCompanyID() string   { return c.companyID }
+
+func TestClientManagerClose(t *testing.T) {
+	manager := factory.NewClientManagerFactory(10, time.Minute, time.Second, 1, nil).CreateManager()
+
+	ctx := context.Background()
+	for _, companyID := range []string{"company-1", "company-2"} {
+		if _, err := manager.GetClientForCompany(ctx, &testCompanyConfig{companyID: companyID}); err != nil {
+			t.Fatalf("GetClientForCompany(%s) error = %v", companyID, err)
+		}
+	}
+	if stats := manager.GetCacheStats(); stats.TotalClients != 2 {
+		t.Fatalf("Expected 2 cached clients, got %d", stats.TotalClients)
+	}
+
+	if err := manager.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if err := manager.Close(); err != nil {
+		t.Errorf("Second Close() should be a no-op, got %v", err)
+	}
+
+	if stats := manager.GetCacheStats(); stats.TotalClients != 0 {
+		t.Errorf("Close() should evict all clients, got %d", stats.TotalClients)
+	}
+
+	_, err := manager.GetClientForCompany(ctx, &testCompanyConfig{companyID: "company-1"})
+	var cacheErr *arcaerrors.ClientCacheError
+	if !errors.As(err, &cacheErr) {
+		t.Errorf("GetClientForCompany() after Close should fail with ClientCacheError, got %v", err)
+	}
+}
+
+func TestClientManagerCleanupInterval(t *testing.T) {
+	manager := factory.NewClientManagerFactory(10, 50*time.Millisecond, time.Second, 1, nil,
+		factory.WithCleanupInterval(10*time.Millisecond)).CreateManager()
+	defer manager.Close()
+
+	if _, err := manager.GetClientForCompany(context.Background(), &testCompanyConfig{companyID: "company-1"}); err != nil {
+		t.Fatalf("GetClientForCompany() error = %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for manager.GetCacheStats().TotalClients > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Janitor should evict idle clients without a manual cleanup")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if stats := manager.GetCacheStats(); stats.LastCleanup.IsZero() {
+		t.Error("Janitor should record the last cleanup")
+	}
+}
+
+func TestRotateCompanyCredentials(t *testing.T) {
+	manager := factory.NewClientManagerFactory(10, time.Minute, 20*time.Millisecond, 1, nil).CreateManager()
+	defer manager.Close()
+
+	ctx := context.Background()
+	companyConfig := &testCompanyConfig{companyID: "company-1"}
+	previous, err := manager.GetClientForCompany(ctx, companyConfig)
+	if err != nil {
+		t.Fatalf("GetClientForCompany() error = %v", err)
+	}
+
+	if err := manager.RotateCompanyCredentials("company-1", companyConfig); err != nil {
+		t.Fatalf("RotateCompanyCredentials() error = %v", err)
+	}
+	rotated, err := manager.GetClientForCompany(ctx, companyConfig)
+	if err != nil {
+		t.Fatalf("GetClientForCompany() error = %v", err)
+	}
+	if rotated == previous {
+		t.Fatal("GetClientForCompany() should return the rotated client")
+	}
+	if stats := manager.GetCacheStats(); stats.TotalClients != 1 {
+		t.Errorf("Rotation should replace the cached client, got %d clients", stats.TotalClients)
+	}
+
+	// El cliente anterior sigue abierto para las llamadas en curso
+	if err := previous.IsHealthy(ctx); err != nil && strings.Contains(err.Error(), "client is closed") {
+		t.Error("The previous client should stay open during the grace period")
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		err := previous.IsHealthy(ctx)
+		if err != nil && strings.Contains(err.Error(), "client is closed") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("The previous client should be closed after the grace period")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	err = manager.RotateCompanyCredentials("company-1", &testCompanyConfig{companyID: "company-2"})
+	var configErr *arcaerrors.CompanyConfigError
+	if !errors.As(err, &configErr) {
+		t.Errorf("RotateCompanyCredentials() should reject a config of another company, got %v", err)
+	}
+}
+
+func TestRotateCompanyCredentialsClose(t *testing.T) {
+	manager := factory.NewClientManagerFactory(10, time.Minute, time.Minute, 1, nil).CreateManager()
+
+	ctx := context.Background()
+	companyConfig := &testCompanyConfig{companyID: "company-1"}
+	previous, err := manager.GetClientForCompany(ctx, companyConfig)
+	if err != nil {
+		t.Fatalf("GetClientForCompany() error = %v", err)
+	}
+	if err := manager.RotateCompanyCredentials("company-1", companyConfig); err != nil {
+		t.Fatalf("RotateCompanyCredentials() error = %v", err)
+	}
+
+	// Close no espera el período de gracia de los clientes rotados
+	if err := manager.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if err := previous.IsHealthy(ctx); err == nil || !strings.Contains(err.Error(), "client is closed") {
+		t.Errorf("Close() should close the rotated client, got %v", err)
+	}
+}
+
+// recordingFieldLogger implementa interfaces.FieldLogger guardando los campos
+type recordingFieldLogger struct {
+	*client.StdLogger
+	fields map[string]interface{}
+}
+
+func (l *recordingFieldLogger) WithFields(fields map[string]interface{}) interfaces.Logger {
+	l.fields = fields
+	return l
+}
+
+func TestLogFields(t *testing.T) {
+	var out bytes.Buffer
+	logger := shared.WithFields(client.NewStdLogger(&out, "info"), map[string]interface{}{
+		interfaces.LogFieldCompany:       "company-1",
+		interfaces.LogFieldInvoiceNumber: 5,
+	})
+	logger.Infof("Authorizing %s", "invoice")
+	if !strings.Contains(out.String(), "Authorizing invoice company=company-1 invoice_number=5") {
+		t.Errorf("Printf loggers should get the fields as key=value, got %q", out.String())
+	}
+
+	out.Reset()
+	shared.WithFields(logger, map[string]interface{}{interfaces.LogFieldService: "wsfe"}).Warn("Retrying")
+	if !strings.Contains(out.String(), "Retrying company=company-1 invoice_number=5 service=wsfe") {
+		t.Errorf("Nested fields should be merged, got %q", out.String())
+	}
+
+	fieldLogger := &recordingFieldLogger{StdLogger: client.NewStdLogger(&out, "info")}
+	shared.WithFields(fieldLogger, map[string]interface{}{interfaces.LogFieldAction: "FECAESolicitar"}).Info("Authorizing invoice")
+	if fieldLogger.fields[interfaces.LogFieldAction] != "FECAESolicitar" {
+		t.Errorf("Structured loggers should receive the fields, got %v", fieldLogger.fields)
+	}
+}
+
+// certCompanyConfig es una testCompanyConfig con un certificado válido para
+// el servidor simulado
+type certCompanyConfig struct {
+	testCompanyConfig
+	cert, key []byte
+}
+
+func (c *certCompanyConfig) GetCertificate() []byte { return c.cert }
+func (c *certCompanyConfig) GetPrivateKey() []byte  { return c.key }
+
+// newFakeAFIPCompanyClient retorna un cliente multi-empresa que llama al
+// servidor simulado
+func newFakeAFIPCompanyClient(t *testing.T) (*testutil.FakeAFIPServer, interfaces.ARCAClient) {
+	t.Helper()
+
+	server := testutil.NewFakeAFIPServer()
+	t.Cleanup(server.Close)
+
+	cert, key, err := testutil.GenerateCertificate("20123456786")
+	if err != nil {
+		t.Fatalf("GenerateCertificate() error = %v", err)
+	}
+
+	manager := factory.NewClientManagerFactory(10, time.Minute, time.Second, 1, nil,
+		factory.WithBaseURLOverride(server.URL)).CreateManager()
+	t.Cleanup(func() { manager.Close() })
+
+	companyConfig := &certCompanyConfig{testCompanyConfig: testCompanyConfig{companyID: "company-1"}, cert: cert, key: key}
+	arcaClient, err := manager.GetClientForCompany(context.Background(), companyConfig)
+	if err != nil {
+		t.Fatalf("GetClientForCompany() error = %v", err)
+	}
+	return server, arcaClient
+}
+
+func TestCompanyClientInvoiceTypes(t *testing.T) {
+	server, arcaClient := newFakeAFIPCompanyClient(t)
+
+	invoiceTypes, err := arcaClient.WSFE().GetInvoiceTypes(context.Background())
+	if err != nil {
+		t.Fatalf("GetInvoiceTypes() error = %v", err)
+	}
+	if server.Calls(testutil.ActionFEParamGetTiposCbte) != 1 {
+		t.Errorf("GetInvoiceTypes() should query FEParamGetTiposCbte, got %d calls", server.Calls(testutil.ActionFEParamGetTiposCbte))
+	}
+	if len(invoiceTypes) == 0 || invoiceTypes[0].ID != models.InvoiceTypeA || !invoiceTypes[0].Active {
+		t.Errorf("GetInvoiceTypes() = %v, want the types returned by AFIP", invoiceTypes)
+	}
+
+	server.SetResult(testutil.ActionFEParamGetTiposCbte, `<Errors><Err><Code>600</Code><Msg>No autorizado</Msg></Err></Errors>`)
+	var arcaErr *models.ARCAError
+	if _, err := arcaClient.WSFE().GetInvoiceTypes(context.Background()); !errors.As(err, &arcaErr) || arcaErr.Code != "600" {
+		t.Errorf("GetInvoiceTypes() should return the AFIP error, got %v", err)
+	}
+}
+
+func TestCompanyClientUnitTypes(t *testing.T) {
+	server, arcaClient := newFakeAFIPCompanyClient(t)
+
+	unitTypes, err := arcaClient.WSFEX().GetUnitTypes(context.Background())
+	if err != nil {
+		t.Fatalf("GetUnitTypes() error = %v", err)
+	}
+	if server.Calls(testutil.ActionFEXGetPARAMUMed) != 1 {
+		t.Errorf("GetUnitTypes() should query FEXGetPARAM_UMed, got %d calls", server.Calls(testutil.ActionFEXGetPARAMUMed))
+	}
+	if len(unitTypes) == 0 || unitTypes[0].ID != "1" {
+		t.Errorf("GetUnitTypes() = %v, want the units returned by AFIP", unitTypes)
+	}
+}
@@ -0,0 +1,398 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlarregola/arca_invoice_lib/pkg/models"
+)
+
+func TestAuthorizationResultSummary(t *testing.T) {
+	result := &models.AuthorizationResult{
+		CAE:               "74123456789012",
+		CAEExpirationDate: time.Date(2024, 1, 25, 0, 0, 0, 0, time.UTC),
+		InvoiceNumber:     123,
+		PointOfSale:       1,
+		InvoiceType:       models.InvoiceTypeA,
+		Status:            "A",
+		Message:           "Observación de prueba",
+	}
+
+	summary := result.Summary()
+
+	for _, want := range []string{"74123456789012", "0001-00000123", "Factura A", "2024-01-25", "Observación de prueba"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("Summary() should contain %q, got %q", want, summary)
+		}
+	}
+
+	if lines := strings.Count(summary, "\n") + 1; lines != 3 {
+		t.Errorf("Summary() should have 3 lines, got %d", lines)
+	}
+}
+
+func TestAuthorizationResultStringAndJSON(t *testing.T) {
+	result := models.AuthorizationResult{
+		CAE:               "74123456789012",
+		CAEExpirationDate: time.Date(2024, 1, 25, 0, 0, 0, 0, time.UTC),
+		InvoiceNumber:     123,
+		PointOfSale:       1,
+		InvoiceType:       models.InvoiceTypeA,
+		AuthorizationDate: time.Date(2024, 1, 15, 10, 30, 0, 123456789, time.UTC),
+		Status:            models.AuthResultApproved,
+	}
+
+	if got, want := fmt.Sprint(&result), "Factura A 0001-00000123 A CAE 74123456789012 vence 2024-01-25"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	for _, want := range []string{`"cae_expiration_date":"2024-01-25T00:00:00Z"`, `"authorization_date":"2024-01-15T10:30:00Z"`, `"invoice_number":123`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("json.Marshal() should contain %s, got %s", want, data)
+		}
+	}
+
+	var decoded models.AuthorizationResult
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if !decoded.CAEExpirationDate.Equal(result.CAEExpirationDate) || decoded.CAE != result.CAE {
+		t.Errorf("JSON should round-trip, got %+v", decoded)
+	}
+
+	data, _ = json.Marshal(models.AuthorizationResult{Status: models.AuthResultRejected})
+	if strings.Contains(string(data), "_date") {
+		t.Errorf("Zero dates should be omitted, got %s", data)
+	}
+}
+
+func TestAuthorizationResultIsValid(t *testing.T) {
+	result := &models.AuthorizationResult{
+		CAE:               "74123456789012",
+		CAEExpirationDate: time.Date(2024, 1, 25, 0, 0, 0, 0, time.UTC),
+		Status:            models.AuthResultApproved,
+	}
+
+	tests := []struct {
+		name   string
+		modify func(r *models.AuthorizationResult)
+		now    time.Time
+		want   bool
+	}{
+		{"vigente", nil, time.Date(2024, 1, 20, 12, 0, 0, 0, time.UTC), true},
+		{"último día", nil, time.Date(2024, 1, 25, 23, 59, 0, 0, time.UTC), true},
+		{"vencido", nil, time.Date(2024, 1, 26, 0, 0, 0, 0, time.UTC), false},
+		{"sin CAE", func(r *models.AuthorizationResult) { r.CAE = "" }, time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC), false},
+		{"rechazado", func(r *models.AuthorizationResult) { r.Status = models.AuthResultRejected }, time.Date(2024, 1, 20, 0, 0, 0, 0, time.UTC), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := *result
+			if tt.modify != nil {
+				tt.modify(&r)
+			}
+			if got := r.IsValidAt(tt.now); got != tt.want {
+				t.Errorf("IsValidAt() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthorizationResultSummaryWithoutCAE(t *testing.T) {
+	result := &models.AuthorizationResult{
+		InvoiceNumber: 7,
+		PointOfSale:   12,
+		InvoiceType:   models.InvoiceTypeB,
+		Status:        "R",
+	}
+
+	summary := result.Summary()
+	if strings.Contains(summary, "\n") {
+		t.Errorf("Summary() without CAE or observations should be a single line, got %q", summary)
+	}
+	if !strings.Contains(summary, "0012-00000007") {
+		t.Errorf("Summary() should contain the formatted number, got %q", summary)
+	}
+}
+
+func TestParseAuthResult(t *testing.T) {
+	tests := []struct {
+		code     string
+		want     models.AuthResult
+		approved bool
+		wantErr  bool
+	}{
+		{code: "A", want: models.AuthResultApproved, approved: true},
+		{code: " r ", want: models.AuthResultRejected},
+		{code: "P", want: models.AuthResultPartial},
+		{code: "X", want: "X", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := models.ParseAuthResult(tt.code)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseAuthResult(%q) error = %v, wantErr %v", tt.code, err, tt.wantErr)
+		}
+		if got != tt.want || got.IsApproved() != tt.approved {
+			t.Errorf("ParseAuthResult(%q) = %q (approved %v), want %q (approved %v)", tt.code, got, got.IsApproved(), tt.want, tt.approved)
+		}
+	}
+}
+
+func TestMarshalResults(t *testing.T) {
+	results := []*models.AuthorizationResult{
+		{
+			CAE:               "74123456789012",
+			CAEExpirationDate: time.Date(2024, 1, 25, 0, 0, 0, 0, time.UTC),
+			InvoiceNumber:     1,
+			PointOfSale:       1,
+			InvoiceType:       models.InvoiceTypeA,
+			Status:            models.AuthResultApproved,
+			Message:           "10217: Observación, con coma",
+		},
+		nil,
+	}
+	batchErr := models.NewBatchError([]error{nil, models.NewServiceError("10016", "Número no correlativo")})
+
+	data, err := models.MarshalResultsCSV(results, batchErr)
+	if err != nil {
+		t.Fatalf("MarshalResultsCSV() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 || lines[0] != "Posicion,CbteTipo,PtoVta,CbteNro,Resultado,CodAutorizacion,CAEFchVto,FchProceso,Observaciones,Error" {
+		t.Fatalf("CSV should have a header and one row per invoice, got %q", data)
+	}
+	if lines[1] != `0,1,1,1,A,74123456789012,20240125,,"10217: Observación, con coma",` {
+		t.Errorf("CSV row should carry the authorization, got %q", lines[1])
+	}
+	if !strings.HasPrefix(lines[2], "1,,,,,,,,,") || !strings.Contains(lines[2], "10016") {
+		t.Errorf("CSV row of a failed invoice should carry the error, got %q", lines[2])
+	}
+
+	data, err = models.MarshalResultsJSON(results, batchErr)
+	if err != nil {
+		t.Fatalf("MarshalResultsJSON() error = %v", err)
+	}
+	var records []map[string]interface{}
+	if err := json.Unmarshal(data, &records); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if len(records) != 2 || records[0]["CodAutorizacion"] != "74123456789012" || records[1]["Error"] == nil {
+		t.Errorf("JSON should use the AFIP column names, got %s", data)
+	}
+}
+
+func TestFCEOptionals(t *testing.T) {
+	fce := &models.FCEData{CBU: "0110599520000001234567", Alias: "empresa.pyme", TransferType: models.FCETransferADC}
+
+	optionals := fce.Optionals(models.InvoiceTypeFCEA)
+	want := map[string]string{
+		models.OptionalIDFCECBU:      "0110599520000001234567",
+		models.OptionalIDFCEAlias:    "empresa.pyme",
+		models.OptionalIDFCETransfer: models.FCETransferADC,
+	}
+	if len(optionals) != len(want) {
+		t.Fatalf("FCE invoice should carry %d optionals, got %d", len(want), len(optionals))
+	}
+	for _, optional := range optionals {
+		if want[optional.ID] != optional.Value {
+			t.Errorf("Optional %s should be %q, got %q", optional.ID, want[optional.ID], optional.Value)
+		}
+	}
+
+	noteOptionals := (&models.FCEData{Cancelled: true}).Optionals(models.InvoiceTypeFCECreditNoteB)
+	if len(noteOptionals) != 1 || noteOptionals[0].ID != models.OptionalIDFCECancellation || noteOptionals[0].Value != "S" {
+		t.Errorf("FCE credit note should carry the cancellation optional, got %+v", noteOptionals)
+	}
+}
+
+func TestIsRetryableError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "transport error", err: models.NewNetworkError("connection reset", "", 0), want: true},
+		{name: "server error", err: models.NewNetworkError("HTTP error", "", 503), want: true},
+		{name: "client error", err: models.NewNetworkError("HTTP error", "", 400), want: false},
+		{name: "timeout", err: models.NewARCAError(models.ErrorCodeTimeout, ""), want: true},
+		{name: "wrapped service unavailable", err: fmt.Errorf("call failed: %w", models.NewARCAError(models.ErrorCodeServiceUnavailable, "")), want: true},
+		{name: "invalid invoice", err: models.NewARCAError(models.ErrorCodeInvalidInvoiceNumber, ""), want: false},
+		{name: "validation error", err: models.NewValidationError("amount", "invalid", nil), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := models.IsRetryableError(tt.err); got != tt.want {
+				t.Errorf("IsRetryableError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookupError(t *testing.T) {
+	info, ok := models.LookupError(models.ErrorCodeExpiredCertificate)
+	if !ok || info.Code != models.ErrorCodeExpiredCertificate || info.Category != models.ErrorCategoryAuth ||
+		info.Severity != models.ErrorSeverityCritical || info.Retryable {
+		t.Errorf("LookupError() for an expired certificate = %+v, %v", info, ok)
+	}
+
+	info, ok = models.LookupError(models.ErrorCodeRateLimitExceeded)
+	if !ok || info.Category != models.ErrorCategorySystem || !info.Retryable {
+		t.Errorf("LookupError() for the rate limit = %+v, %v", info, ok)
+	}
+
+	if _, ok := models.LookupError("99999"); ok {
+		t.Error("LookupError() should not find unknown codes")
+	}
+	if got := models.GetErrorMessage(models.ErrorCodeInvalidDate); got != models.ErrorMessages[models.ErrorCodeInvalidDate] || got != "Fecha inválida" {
+		t.Errorf("GetErrorMessage() should read from the catalog, got %q", got)
+	}
+}
+
+func TestEnumJSONRoundTrip(t *testing.T) {
+	person := models.Person{DocType: models.DocumentTypeCUIT, DocNumber: "20123456786"}
+
+	data, err := json.Marshal(person)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if !strings.Contains(string(data), `"doc_type":"CUIT"`) {
+		t.Errorf("DocumentType should be marshaled by name, got %s", data)
+	}
+
+	var decoded models.Person
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if decoded.DocType != models.DocumentTypeCUIT {
+		t.Errorf("DocumentType should round-trip, got %v", decoded.DocType)
+	}
+
+	var base struct {
+		InvoiceType models.InvoiceType  `json:"invoice_type"`
+		ConceptType models.ConceptType  `json:"concept_type"`
+		Rates       []models.TaxRate    `json:"rates"`
+		DocType     models.DocumentType `json:"doc_type"`
+	}
+	input := `{"invoice_type":"FacturaA","concept_type":2,"rates":["10.5%",21,"exento"],"doc_type":"dni"}`
+	if err := json.Unmarshal([]byte(input), &base); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if base.InvoiceType != models.InvoiceTypeA || base.ConceptType != models.ConceptTypeServices || base.DocType != models.DocumentTypeDNI {
+		t.Errorf("Enums should accept codes and names, got %+v", base)
+	}
+	if len(base.Rates) != 3 || base.Rates[0] != models.TaxRate105 || base.Rates[1] != models.TaxRate21 || base.Rates[2] != models.TaxRateExempt {
+		t.Errorf("TaxRate should accept codes and names, got %v", base.Rates)
+	}
+
+	data, err = json.Marshal(models.InvoiceType(999))
+	if err != nil || string(data) != "999" {
+		t.Errorf("Unknown codes should be marshaled as numbers, got %s (%v)", data, err)
+	}
+}
+
+func TestEnumJSONStrictMode(t *testing.T) {
+	models.SetStrictEnumJSON(true)
+	defer models.SetStrictEnumJSON(false)
+
+	var docType models.DocumentType
+	if err := json.Unmarshal([]byte(`"CUIT"`), &docType); err != nil || docType != models.DocumentTypeCUIT {
+		t.Errorf("Strict mode should accept canonical names, got %v (%v)", docType, err)
+	}
+	if err := json.Unmarshal([]byte(`"cuit"`), &docType); err == nil {
+		t.Error("Strict mode should reject names with different case")
+	}
+	if err := json.Unmarshal([]byte(`98`), &docType); err == nil {
+		t.Error("Strict mode should reject unknown codes")
+	}
+
+	models.SetStrictEnumJSON(false)
+	if err := json.Unmarshal([]byte(`98`), &docType); err != nil || docType != 98 {
+		t.Errorf("Lenient mode should accept unknown codes, got %v (%v)", docType, err)
+	}
+}
+
+func TestEnumString(t *testing.T) {
+	tests := []struct {
+		value    fmt.Stringer
+		expected string
+	}{
+		{models.DocumentTypeCUIT, "CUIT"},
+		{models.ConceptTypeMixed, "ProductosYServicios"},
+		{models.TaxRate105, "10.5%"},
+		{models.TaxRateExempt, "Exento"},
+		{models.InvoiceTypeB, "Factura B"},
+		{models.DocumentType(42), "DocumentType(42)"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.value.String(); got != tt.expected {
+			t.Errorf("String() = %q, expected %q", got, tt.expected)
+		}
+	}
+}
+
+func TestInvoiceTypeProperties(t *testing.T) {
+	tests := []struct {
+		invoiceType models.InvoiceType
+		credit      bool
+		debit       bool
+		letter      string
+	}{
+		{models.InvoiceTypeA, false, false, "A"},
+		{models.InvoiceTypeDebitNoteA, false, true, "A"},
+		{models.InvoiceTypeCreditNoteA, true, false, "A"},
+		{models.InvoiceTypeB, false, false, "B"},
+		{models.InvoiceTypeDebitNoteB, false, true, "B"},
+		{models.InvoiceTypeCreditNoteB, true, false, "B"},
+		{models.InvoiceTypeC, false, false, "C"},
+		{models.InvoiceTypeDebitNoteC, false, true, "C"},
+		{models.InvoiceTypeCreditNoteC, true, false, "C"},
+		{models.InvoiceTypeE, false, false, "E"},
+		{models.InvoiceTypeDebitNoteE, false, true, "E"},
+		{models.InvoiceTypeCreditNoteE, true, false, "E"},
+		{models.InvoiceTypeM, false, false, "M"},
+		{models.InvoiceTypeDebitNoteM, false, true, "M"},
+		{models.InvoiceTypeCreditNoteM, true, false, "M"},
+		{models.InvoiceTypeT, false, false, ""},
+		{models.InvoiceTypeR, false, false, ""},
+		{models.InvoiceTypeFCEA, false, false, "A"},
+		{models.InvoiceTypeFCEDebitNoteA, false, true, "A"},
+		{models.InvoiceTypeFCECreditNoteA, true, false, "A"},
+		{models.InvoiceTypeFCEB, false, false, "B"},
+		{models.InvoiceTypeFCEDebitNoteB, false, true, "B"},
+		{models.InvoiceTypeFCECreditNoteB, true, false, "B"},
+		{models.InvoiceTypeFCEC, false, false, "C"},
+		{models.InvoiceTypeFCEDebitNoteC, false, true, "C"},
+		{models.InvoiceTypeFCECreditNoteC, true, false, "C"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.invoiceType.String(), func(t *testing.T) {
+			if got := tt.invoiceType.IsCreditNote(); got != tt.credit {
+				t.Errorf("IsCreditNote() = %v, want %v", got, tt.credit)
+			}
+			if got := tt.invoiceType.IsDebitNote(); got != tt.debit {
+				t.Errorf("IsDebitNote() = %v, want %v", got, tt.debit)
+			}
+			if got := tt.invoiceType.RequiresAssociatedInvoice(); got != (tt.credit || tt.debit) {
+				t.Errorf("RequiresAssociatedInvoice() = %v, want %v", got, tt.credit || tt.debit)
+			}
+			if got := tt.invoiceType.LetterClass(); got != tt.letter {
+				t.Errorf("LetterClass() = %q, want %q", got, tt.letter)
+			}
+			if got := tt.invoiceType.IsClassC(); got != (tt.letter == "C") {
+				t.Errorf("IsClassC() = %v, want %v", got, tt.letter == "C")
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package tests
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlarregola/arca_invoice_lib/internal/soap"
+	"github.com/dlarregola/arca_invoice_lib/pkg/client"
+	"github.com/dlarregola/arca_invoice_lib/pkg/models"
+	"github.com/dlarregola/arca_invoice_lib/pkg/testutil"
+	"github.com/dlarregola/arca_invoice_lib/pkg/wsfe"
+
+	"github.com/sirupsen/logrus"
+)
+
+func TestSOAPClientGzipResponse(t *testing.T) {
+	envelope := `<?xml version="1.0" encoding="utf-8"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body>` +
+		`<FEDummyResponse xmlns="http://ar.gov.afip.dif.FEV1/"><FEDummyResult>` +
+		`<AppServer>OK</AppServer><DbServer>OK</DbServer><AuthServer>OK</AuthServer>` +
+		`</FEDummyResult></FEDummyResponse></soap:Body></soap:Envelope>`
+
+	var acceptEncoding string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		acceptEncoding = r.Header.Get("Accept-Encoding")
+
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		w.Header().Set("Content-Encoding", "gzip")
+		gzipWriter := gzip.NewWriter(w)
+		defer gzipWriter.Close()
+		gzipWriter.Write([]byte(envelope))
+	}))
+	defer server.Close()
+
+	client := soap.NewClient(server.URL, 5*time.Second, logrus.New())
+
+	var response wsfe.DummyResponse
+	if err := client.Call(context.Background(), "FEDummy", &wsfe.DummyRequest{}, &response); err != nil {
+		t.Fatalf("Call() error = %v", err)
+	}
+
+	if acceptEncoding != "gzip" {
+		t.Errorf("Request should send Accept-Encoding: gzip, got %q", acceptEncoding)
+	}
+	if response.AppServer != "OK" || response.DbServer != "OK" || response.AuthServer != "OK" {
+		t.Errorf("Gzip response should be decompressed and decoded, got %+v", response)
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	envelope := `<?xml version="1.0" encoding="utf-8"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body>` +
+		`<FEDummyResponse xmlns="http://ar.gov.afip.dif.FEV1/"><FEDummyResult>` +
+		`<AppServer>OK</AppServer><DbServer>OK</DbServer><AuthServer>OK</AuthServer>` +
+		`</FEDummyResult></FEDummyResponse></soap:Body></soap:Envelope>`
+
+	var userAgent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.Header.Get("User-Agent")
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		w.Write([]byte(envelope))
+	}))
+	defer server.Close()
+
+	config := client.DefaultConfig()
+	config.BaseURLOverride = server.URL
+
+	if _, err := wsfe.NewService(&config, nil, nil).Dummy(context.Background()); err != nil {
+		t.Fatalf("Dummy() error = %v", err)
+	}
+	if userAgent != "ARCA-Go-Client/"+client.Version {
+		t.Errorf("Default User-Agent should include the library version, got %q", userAgent)
+	}
+
+	config.UserAgent = "MiERP/2.3 (soporte@example.com)"
+	if _, err := wsfe.NewService(&config, nil, nil).Dummy(context.Background()); err != nil {
+		t.Fatalf("Dummy() error = %v", err)
+	}
+	if userAgent != config.UserAgent {
+		t.Errorf("Config.UserAgent should override the default, got %q", userAgent)
+	}
+}
+
+func TestSOAPClientRejectsIncompleteAuth(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+	}))
+	defer server.Close()
+
+	client := soap.NewClient(server.URL, 5*time.Second, logrus.New())
+
+	request := &wsfe.ParametersRequest{Auth: wsfe.Auth{Sign: "sign", CUIT: "20123456786"}}
+	var response wsfe.TaxRatesResponse
+	err := client.Call(context.Background(), "FEParamGetTiposIva", request, &response)
+
+	var authErr *models.AuthenticationError
+	if !errors.As(err, &authErr) {
+		t.Fatalf("Call() without token should return AuthenticationError, got %v", err)
+	}
+	if calls != 0 {
+		t.Errorf("Request with incomplete Auth should not be sent, got %d calls", calls)
+	}
+}
+
+func TestRequestIDLogging(t *testing.T) {
+	server := testutil.NewFakeAFIPServer()
+	defer server.Close()
+
+	config, err := server.Config()
+	if err != nil {
+		t.Fatalf("server.Config() error = %v", err)
+	}
+
+	var soapLog bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&soapLog)
+	logger.SetLevel(logrus.DebugLevel)
+
+	ctx := client.WithRequestID(context.Background(), "req-42")
+	if _, err := wsfe.NewService(&config, nil, logger).Dummy(ctx); err != nil {
+		t.Fatalf("Dummy() error = %v", err)
+	}
+	if strings.Count(soapLog.String(), "request_id=req-42") != 2 {
+		t.Errorf("SOAP request and response logs should carry the request ID, got %s", soapLog.String())
+	}
+
+	var infoLog bytes.Buffer
+	client.LogInfof(ctx, client.NewStdLogger(&infoLog, "info"), "Invoice %s authorized", "0001-00000001")
+	if !strings.Contains(infoLog.String(), "[request_id=req-42] Invoice 0001-00000001 authorized") {
+		t.Errorf("Service logs should carry the request ID, got %q", infoLog.String())
+	}
+	if client.RequestIDFromContext(context.Background()) != "" {
+		t.Error("RequestIDFromContext() without ID should be empty")
+	}
+}
+
+func TestValidateRequest(t *testing.T) {
+	valid := []byte(`<FECAESolicitar><Auth><Token>t</Token><Sign>s</Sign><Cuit>20123456786</Cuit></Auth>` +
+		`<FeCAEReq><FeCabReq><CantReg>1</CantReg></FeCabReq><FeDetReq><FECAEDetRequest/></FeDetReq></FeCAEReq></FECAESolicitar>`)
+	if err := soap.ValidateAuth(valid); err != nil {
+		t.Errorf("ValidateAuth() error = %v", err)
+	}
+	if err := soap.ValidateRequest("FECAESolicitar", valid); err != nil {
+		t.Errorf("ValidateRequest() error = %v", err)
+	}
+
+	invalid := []byte(`<FECAESolicitar><FeCAEReq><FeCabReq/><FeCabReq/></FeCAEReq></FECAESolicitar>`)
+	var validationErrs models.ValidationErrors
+	if err := soap.ValidateRequest("FECAESolicitar", invalid); !errors.As(err, &validationErrs) || len(validationErrs) != 2 {
+		t.Errorf("ValidateRequest() should report the duplicated FeCabReq and missing FeDetReq, got %v", err)
+	}
+
+	// Los métodos sin reglas y los requests sin Auth se aceptan
+	if err := soap.ValidateRequest("FEDummy", []byte(`<FEDummy/>`)); err != nil {
+		t.Errorf("ValidateRequest() without rules should pass, got %v", err)
+	}
+	if err := soap.ValidateAuth([]byte(`<FEDummy/>`)); err != nil {
+		t.Errorf("ValidateAuth() without Auth should pass, got %v", err)
+	}
+}
+
+func TestSOAPActionAndNamespace(t *testing.T) {
+	envelope := `<?xml version="1.0" encoding="utf-8"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body>` +
+		`<FEXGetLast_CMPResponse xmlns="http://ar.gov.afip.dif.fexv1/"><FEXGetLast_CMPResult>` +
+		`<FEXResult_LastCMP><Cbte_nro>7</Cbte_nro></FEXResult_LastCMP>` +
+		`</FEXGetLast_CMPResult></FEXGetLast_CMPResponse></soap:Body></soap:Envelope>`
+
+	var soapAction string
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		soapAction = r.Header.Get("SOAPAction")
+		body, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		w.Write([]byte(envelope))
+	}))
+	defer server.Close()
+
+	client := soap.NewClient(server.URL, 5*time.Second, logrus.New())
+	request := struct {
+		Auth struct {
+			Token string
+			Sign  string
+			Cuit  string
+		}
+	}{}
+	request.Auth.Token, request.Auth.Sign, request.Auth.Cuit = "t", "s", "20123456786"
+
+	var response struct{}
+	if err := client.Call(context.Background(), "FEXGetLast_CMP", &request, &response); err != nil {
+		t.Fatalf("Call() error = %v", err)
+	}
+	if soapAction != "http://ar.gov.afip.dif.fexv1/FEXGetLast_CMP" {
+		t.Errorf("SOAPAction should be the full operation URL, got %q", soapAction)
+	}
+	if !bytes.Contains(body, []byte(`<FEXGetLast_CMP xmlns="http://ar.gov.afip.dif.fexv1/">`)) {
+		t.Errorf("Request should be wrapped in the operation element of the service namespace, got %s", body)
+	}
+
+	if err := client.Call(context.Background(), "FEXUnknown", &request, &response); err == nil {
+		t.Errorf("Call() should reject unknown operations")
+	}
+
+}
@@ -0,0 +1,980 @@
+package tests
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlarregola/arca_invoice_lib/internal/utils"
+	"github.com/dlarregola/arca_invoice_lib/pkg/client"
+	"github.com/dlarregola/arca_invoice_lib/pkg/models"
+	"github.com/dlarregola/arca_invoice_lib/pkg/testutil"
+	"github.com/dlarregola/arca_invoice_lib/pkg/wsfe"
+	"github.com/dlarregola/arca_invoice_lib/pkg/wsfex"
+)
+
+func newFakeAFIPService(t *testing.T) (*testutil.FakeAFIPServer, *client.Config, *wsfe.Service) {
+	t.Helper()
+
+	server := testutil.NewFakeAFIPServer()
+	t.Cleanup(server.Close)
+
+	config, err := server.Config()
+	if err != nil {
+		t.Fatalf("server.Config() error = %v", err)
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Fake server config should be valid: %v", err)
+	}
+
+	auth := client.NewWSAAAuth(&config, nil)
+	return server, &config, wsfe.NewService(&config, auth, nil)
+}
+
+func TestFakeAFIPServerLogin(t *testing.T) {
+	server, config, _ := newFakeAFIPService(t)
+
+	auth := client.NewWSAAAuth(config, nil)
+	ticket, err := auth.GetAccessTicket(context.Background(), "wsfe")
+	if err != nil {
+		t.Fatalf("GetAccessTicket() error = %v", err)
+	}
+	if ticket.Token != testutil.FakeToken || ticket.Sign != testutil.FakeSign {
+		t.Errorf("Ticket should carry the fake credentials, got %+v", ticket)
+	}
+
+	// El segundo pedido sale del cache
+	if _, err := auth.GetAccessTicket(context.Background(), "wsfe"); err != nil {
+		t.Fatalf("GetAccessTicket() error = %v", err)
+	}
+	if calls := server.Calls(testutil.ActionLoginCms); calls != 1 {
+		t.Errorf("loginCms should be called once, got %d", calls)
+	}
+}
+
+func TestFakeAFIPServerDummy(t *testing.T) {
+	server, _, service := newFakeAFIPService(t)
+	ctx := context.Background()
+
+	status, err := service.Dummy(ctx)
+	if err != nil {
+		t.Fatalf("Dummy() error = %v", err)
+	}
+	if status.AppServer != "OK" || status.DbServer != "OK" || status.AuthServer != "OK" {
+		t.Errorf("Dummy() should report all servers OK, got %+v", status)
+	}
+
+	server.SetResult(testutil.ActionFEDummy, "<AppServer>OK</AppServer><DbServer>DOWN</DbServer><AuthServer>OK</AuthServer>")
+	status, err = service.Dummy(ctx)
+	if err != nil {
+		t.Fatalf("Dummy() error = %v", err)
+	}
+	if status.DbServer != "DOWN" {
+		t.Errorf("Dummy() should return the configured result, got %+v", status)
+	}
+}
+
+func TestFakeAFIPServerErrors(t *testing.T) {
+	server, _, service := newFakeAFIPService(t)
+	ctx := context.Background()
+
+	server.SetFault(testutil.ActionFEDummy, "soap:Server", "Servicio no disponible")
+	_, err := service.Dummy(ctx)
+	var arcaErr *models.ARCAError
+	if !errors.As(err, &arcaErr) || arcaErr.Details != "Servicio no disponible" {
+		t.Errorf("Dummy() should return the SOAP fault as ARCAError, got %v", err)
+	}
+
+	server.Reset()
+	server.SetHTTPStatus(testutil.ActionFEDummy, http.StatusServiceUnavailable)
+	_, err = service.Dummy(ctx)
+	if !models.IsRetryableError(err) {
+		t.Errorf("HTTP 503 should be a retryable error, got %v", err)
+	}
+	if calls := server.Calls(testutil.ActionFEDummy); calls != 1 {
+		t.Errorf("Reset() should clear call counters, got %d calls", calls)
+	}
+}
+
+func TestFakeAFIPServerRateLimit(t *testing.T) {
+	server, _, service := newFakeAFIPService(t)
+	ctx := context.Background()
+
+	server.SetHTTPStatus(testutil.ActionFEDummy, http.StatusTooManyRequests)
+	server.SetHeader(testutil.ActionFEDummy, "Retry-After", "120")
+	_, err := service.Dummy(ctx)
+	var rateLimitErr *models.RateLimitError
+	if !errors.As(err, &rateLimitErr) {
+		t.Fatalf("HTTP 429 should return a RateLimitError, got %v", err)
+	}
+	if rateLimitErr.RetryAfter != 2*time.Minute {
+		t.Errorf("RetryAfter should be parsed from the header, got %s", rateLimitErr.RetryAfter)
+	}
+	if !models.IsRetryableError(err) {
+		t.Errorf("Rate limit errors should be retryable")
+	}
+
+	// El ARCAError subyacente sigue disponible para quien ya lo manejaba
+	var arcaErr *models.ARCAError
+	if !errors.As(err, &arcaErr) || arcaErr.Code != models.ErrorCodeRateLimitExceeded {
+		t.Errorf("RateLimitError should unwrap to an ARCAError with code 30004, got %v", err)
+	}
+
+	server.Reset()
+	server.SetFault(testutil.ActionFEDummy, "soap:"+models.ErrorCodeRateLimitExceeded, "Limite de requests excedido")
+	if _, err := service.Dummy(ctx); !models.IsRateLimitError(err) || models.GetRetryAfter(err) != 0 {
+		t.Errorf("SOAP fault 30004 should return a RateLimitError without RetryAfter, got %v", err)
+	}
+
+	server.Reset()
+	server.SetResult(testutil.ActionFEParamGetTiposIva,
+		"<Errors><Err><Code>30004</Code><Msg>Limite de requests excedido</Msg></Err></Errors>")
+	if _, err := service.GetTaxRates(ctx); !models.IsRateLimitError(err) {
+		t.Errorf("Error 30004 in the response should return a RateLimitError, got %v", err)
+	}
+}
+
+func TestFakeAFIPServerActionFromBody(t *testing.T) {
+	server := testutil.NewFakeAFIPServer()
+	defer server.Close()
+
+	envelope := `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body>` +
+		`<FECompUltimoAutorizado xmlns="http://ar.gov.afip.dif.FEV1/"/></soap:Body></soap:Envelope>`
+
+	resp, err := http.Post(server.URL+"/wsfev1/service.asmx", "text/xml", strings.NewReader(envelope))
+	if err != nil {
+		t.Fatalf("POST error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), "<FECompUltimoAutorizadoResult>") || !strings.Contains(string(body), "<CbteNro>0</CbteNro>") {
+		t.Errorf("Server should answer the action found in the body, got %s", body)
+	}
+	if server.Calls(testutil.ActionFECompUltimoAutorizado) != 1 {
+		t.Errorf("Server should record the call")
+	}
+}
+
+func TestGetTaxRates(t *testing.T) {
+	server, _, service := newFakeAFIPService(t)
+	ctx := context.Background()
+
+	taxRates, err := service.GetTaxRates(ctx)
+	if err != nil {
+		t.Fatalf("GetTaxRates() error = %v", err)
+	}
+
+	want := map[models.TaxRate]bool{
+		models.TaxRate0: true, models.TaxRate105: true, models.TaxRate21: true,
+		models.TaxRate27: true, models.TaxRate5: true, models.TaxRate25: true,
+	}
+	if len(taxRates) != len(want) {
+		t.Fatalf("GetTaxRates() should return %d rates, got %d", len(want), len(taxRates))
+	}
+	for _, rate := range taxRates {
+		if !want[rate.ID] || !rate.Active {
+			t.Errorf("Unexpected tax rate %+v", rate)
+		}
+	}
+
+	// La lista queda en cache para la sesión
+	if _, err := service.GetTaxRates(ctx); err != nil {
+		t.Fatalf("GetTaxRates() error = %v", err)
+	}
+	if calls := server.Calls(testutil.ActionFEParamGetTiposIva); calls != 1 {
+		t.Errorf("FEParamGetTiposIva should be called once, got %d", calls)
+	}
+}
+
+func TestWarmupParameters(t *testing.T) {
+	server, config, service := newFakeAFIPService(t)
+	ctx := context.Background()
+
+	clock := testutil.NewFakeClock(time.Now())
+	config.Clock = clock
+	service.SetParametersTTL(time.Hour)
+
+	params, err := service.WarmupParameters(ctx)
+	if err != nil {
+		t.Fatalf("WarmupParameters() error = %v", err)
+	}
+	if len(params.DocumentTypes) != 4 || params.DocumentTypes[0].Description != "CUIT" {
+		t.Errorf("Parameters should include the document types, got %+v", params.DocumentTypes)
+	}
+	if len(params.InvoiceTypes) != 5 || len(params.CurrencyTypes) != 5 || len(params.TaxRates) != 6 {
+		t.Errorf("Parameters should include invoice types, currencies and tax rates, got %+v", params)
+	}
+	if len(params.ConceptTypes) != 3 || params.ConceptTypes[1].ID != models.ConceptTypeServices {
+		t.Errorf("Parameters should include the concept types, got %+v", params.ConceptTypes)
+	}
+	if len(params.PointsOfSale) != 2 || params.PointsOfSale[0].Number != 1 || params.PointsOfSale[0].Blocked || !params.PointsOfSale[1].Blocked {
+		t.Errorf("Parameters should include the points of sale, got %+v", params.PointsOfSale)
+	}
+	if calls := server.Calls(testutil.ActionLoginCms); calls != 1 {
+		t.Errorf("WarmupParameters() should request a single ticket, got %d", calls)
+	}
+
+	// Dentro del TTL se reutilizan los catálogos
+	if cached, err := service.GetParameters(ctx); err != nil || cached != params {
+		t.Errorf("GetParameters() should return the cached parameters, got %v", err)
+	}
+	if calls := server.Calls(testutil.ActionFEParamGetTiposDoc); calls != 1 {
+		t.Errorf("FEParamGetTiposDoc should be called once, got %d", calls)
+	}
+
+	clock.Advance(time.Hour)
+	if _, err := service.GetParameters(ctx); err != nil {
+		t.Fatalf("GetParameters() error = %v", err)
+	}
+	if calls := server.Calls(testutil.ActionFEParamGetTiposIva); calls != 2 {
+		t.Errorf("GetParameters() should refresh the catalogs after the TTL, got %d calls", calls)
+	}
+
+	server.SetResult(testutil.ActionFEParamGetPtosVenta, "<Errors><Err><Code>602</Code><Msg>Sin Resultados</Msg></Err></Errors>")
+	if pointsOfSale, err := service.GetPointsOfSale(ctx); err != nil || len(pointsOfSale) != 0 {
+		t.Errorf("GetPointsOfSale() should return an empty list without points of sale, got %v, %v", pointsOfSale, err)
+	}
+	if _, err := service.WarmupParameters(ctx); err != nil {
+		t.Errorf("WarmupParameters() should succeed without points of sale, got %v", err)
+	}
+}
+
+func TestAllowedInvoiceTypes(t *testing.T) {
+	server, _, service := newFakeAFIPService(t)
+	ctx := context.Background()
+
+	allowed, err := service.GetAllowedInvoiceTypes(ctx, 1)
+	if err != nil {
+		t.Fatalf("GetAllowedInvoiceTypes() error = %v", err)
+	}
+	want := []models.InvoiceType{models.InvoiceTypeA, models.InvoiceTypeCreditNoteA, models.InvoiceTypeB, models.InvoiceTypeM}
+	if len(allowed) != len(want) {
+		t.Fatalf("GetAllowedInvoiceTypes() = %v, want the active types %v", allowed, want)
+	}
+	for i := range want {
+		if allowed[i] != want[i] {
+			t.Errorf("GetAllowedInvoiceTypes()[%d] = %d, want %d", i, allowed[i], want[i])
+		}
+	}
+
+	for _, pointOfSale := range []int{2, 5} {
+		var validationErr *models.ValidationError
+		if _, err := service.GetAllowedInvoiceTypes(ctx, pointOfSale); !errors.As(err, &validationErr) || validationErr.Field != "point_of_sale" {
+			t.Errorf("GetAllowedInvoiceTypes(%d) should reject a blocked or unknown point of sale, got %v", pointOfSale, err)
+		}
+	}
+
+	// Un punto de venta CAEA habilitado no puede solicitar CAE
+	server.SetResult(testutil.ActionFEParamGetPtosVenta, `<ResultGet>`+
+		`<PtoVenta><Nro>1</Nro><EmisionTipo>CAE - Ws</EmisionTipo><Bloqueado>N</Bloqueado><FchBaja>NULL</FchBaja></PtoVenta>`+
+		`<PtoVenta><Nro>3</Nro><EmisionTipo>CAEA - Ws</EmisionTipo><Bloqueado>N</Bloqueado><FchBaja>NULL</FchBaja></PtoVenta>`+
+		`</ResultGet>`)
+	if _, err := service.WarmupParameters(ctx); err != nil {
+		t.Fatalf("WarmupParameters() error = %v", err)
+	}
+	var caeaErr *models.ValidationError
+	if _, err := service.GetAllowedInvoiceTypes(ctx, 3); !errors.As(err, &caeaErr) || caeaErr.Field != "point_of_sale" {
+		t.Errorf("GetAllowedInvoiceTypes(3) should reject a CAEA-only point of sale, got %v", err)
+	}
+
+	service.SetLivePointOfSaleValidation(true)
+	server.SetResult(testutil.ActionFEParamGetTiposCbte, `<ResultGet>`+
+		`<CbteTipo><Id>6</Id><Desc>Factura B</Desc><FchDesde>20100917</FchDesde><FchHasta>NULL</FchHasta></CbteTipo>`+
+		`</ResultGet>`)
+	if _, err := service.WarmupParameters(ctx); err != nil {
+		t.Fatalf("WarmupParameters() error = %v", err)
+	}
+
+	invoice := newTestWSFEInvoice()
+	_, err = service.AuthorizeInvoice(ctx, invoice)
+	var validationErr *models.ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Field != "invoice_type" {
+		t.Errorf("AuthorizeInvoice() should reject a type the point of sale cannot issue, got %v", err)
+	}
+	if server.Calls(testutil.ActionFECAESolicitar) != 0 {
+		t.Error("The invoice type should be rejected before calling AFIP")
+	}
+}
+
+func TestLiveTaxRateValidation(t *testing.T) {
+	server, _, service := newFakeAFIPService(t)
+
+	// AFIP retiró el 27% e incorporó una alícuota nueva del 15%
+	server.SetResult(testutil.ActionFEParamGetTiposIva, `<ResultGet>`+
+		`<IvaTipo><Id>5</Id><Desc>21%</Desc><FchDesde>20090220</FchDesde><FchHasta>NULL</FchHasta></IvaTipo>`+
+		`<IvaTipo><Id>6</Id><Desc>27%</Desc><FchDesde>20090220</FchDesde><FchHasta>20100101</FchHasta></IvaTipo>`+
+		`<IvaTipo><Id>10</Id><Desc>15%</Desc><FchDesde>20250101</FchDesde><FchHasta>NULL</FchHasta></IvaTipo>`+
+		`</ResultGet>`)
+	service.SetLiveTaxRateValidation(true)
+
+	invoice := newTestWSFEInvoice()
+	invoice.Items[0].Taxes = []models.Tax{{Type: models.TaxTypeIVA, Rate: models.TaxRate27, Base: 1000, Amount: 270}}
+	invoice.TaxAmount = 270
+	invoice.TotalAmount = 1270
+
+	_, err := service.AuthorizeInvoice(context.Background(), invoice)
+	var validationErr *models.ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Field != "tax_rate" {
+		t.Errorf("AuthorizeInvoice() should reject a retired tax rate, got %v", err)
+	}
+
+	taxRates, err := service.GetTaxRates(context.Background())
+	if err != nil {
+		t.Fatalf("GetTaxRates() error = %v", err)
+	}
+	if err := utils.ValidateTaxRate(models.TaxRate(15), taxRates...); err != nil {
+		t.Errorf("A new AFIP rate should be valid without code changes: %v", err)
+	}
+}
+
+func TestLiveTaxRateValidationExempt(t *testing.T) {
+	server, _, service := newFakeAFIPService(t)
+	service.SetLiveTaxRateValidation(true)
+
+	// FEParamGetTiposIva no lista el exento (Id 2)
+	invoice := newTestWSFEInvoice()
+	invoice.Items = append(invoice.Items, models.Item{
+		Description: "Libro",
+		Quantity:    1,
+		UnitPrice:   500,
+		TotalPrice:  500,
+		Taxes:       []models.Tax{{Type: models.TaxTypeIVA, Rate: models.TaxRateExempt, Base: 500}},
+	})
+	invoice.ExemptAmount = 500
+	invoice.TotalAmount += 500
+
+	if _, err := service.AuthorizeInvoice(context.Background(), invoice); err != nil {
+		t.Fatalf("AuthorizeInvoice() should accept exempt items with live tax rates, got %v", err)
+	}
+	if server.Calls(testutil.ActionFECAESolicitar) != 1 {
+		t.Error("The exempt invoice should be sent to AFIP")
+	}
+}
+
+func TestLiveCurrencyValidation(t *testing.T) {
+	_, _, service := newFakeAFIPService(t)
+
+	currencyTypes, err := service.GetCurrencyTypes(context.Background())
+	if err != nil {
+		t.Fatalf("GetCurrencyTypes() error = %v", err)
+	}
+	if err := utils.ValidateCurrencyType("021", currencyTypes...); err != nil {
+		t.Errorf("A currency listed by AFIP should be valid: %v", err)
+	}
+	if err := utils.ValidateCurrencyType(models.CurrencyTypeGBP); err != nil {
+		t.Errorf("Without the AFIP list any well-formed code should be valid: %v", err)
+	}
+	if err := utils.ValidateCurrencyType("USD!"); err == nil {
+		t.Error("Without the AFIP list malformed codes should be rejected")
+	}
+	for _, currency := range []models.CurrencyType{models.CurrencyTypePES, models.CurrencyTypeUSD, models.CurrencyTypeEUR, models.CurrencyTypeBRL} {
+		if err := utils.ValidateCurrencyType(currency, currencyTypes...); err != nil {
+			t.Errorf("The library's currency constants should be AFIP codes: %v", err)
+		}
+	}
+
+	service.SetLiveCurrencyValidation(true)
+
+	invoice := newTestWSFEInvoice()
+	invoice.CurrencyType = "XXX"
+
+	_, err = service.AuthorizeInvoice(context.Background(), invoice)
+	var validationErr *models.ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Field != "currency_type" {
+		t.Errorf("AuthorizeInvoice() should reject a currency not listed by AFIP, got %v", err)
+	}
+}
+
+func TestLiveOptionalValidation(t *testing.T) {
+	server, _, service := newFakeAFIPService(t)
+	ctx := context.Background()
+
+	invoice := newTestWSFEInvoice()
+	invoice.Optionals = []models.Optional{
+		{ID: models.OptionalIDFCECBU, Value: "0110-5995"},
+		{ID: "CBU", Value: "1"},
+		{ID: models.OptionalIDFCECBU, Value: "0110599520000001234567"},
+	}
+	var validationErrs models.ValidationErrors
+	if err := invoice.Validate(); !errors.As(err, &validationErrs) {
+		t.Fatalf("Validate() should reject malformed optionals, got %v", err)
+	}
+	fields := make(map[string]bool)
+	for _, validationErr := range validationErrs {
+		fields[validationErr.Field] = true
+	}
+	for _, field := range []string{"optionals[0]", "optionals[1]", "optionals[2]"} {
+		if !fields[field] {
+			t.Errorf("Validate() should report %s, got %v", field, validationErrs)
+		}
+	}
+
+	service.SetLiveOptionalValidation(true)
+
+	invoice.Optionals = []models.Optional{{ID: "2", Value: "1"}}
+	_, err := service.AuthorizeInvoice(ctx, invoice)
+	if !errors.As(err, &validationErrs) || validationErrs[0].Field != "optionals[0]" {
+		t.Fatalf("AuthorizeInvoice() should reject an optional not active in AFIP, got %v", err)
+	}
+	if server.Calls(testutil.ActionFECAESolicitar) != 0 {
+		t.Error("Invalid optionals should be rejected before calling AFIP")
+	}
+
+	invoice.Optionals = []models.Optional{{ID: models.OptionalIDFCETransfer, Value: models.FCETransferSCA}}
+	if _, err := service.AuthorizeInvoice(ctx, invoice); err != nil {
+		t.Fatalf("AuthorizeInvoice() error = %v", err)
+	}
+	if server.Calls(testutil.ActionFEParamGetTiposOpcional) != 1 {
+		t.Error("Optional types should be fetched once and cached")
+	}
+}
+
+func TestGetOptionalTypes(t *testing.T) {
+	server, _, service := newFakeAFIPService(t)
+	ctx := context.Background()
+
+	optionalTypes, err := service.GetOptionalTypes(ctx)
+	if err != nil {
+		t.Fatalf("GetOptionalTypes() error = %v", err)
+	}
+	active := make(map[string]bool)
+	for _, optionalType := range optionalTypes {
+		active[optionalType.ID] = optionalType.Active
+	}
+	if len(optionalTypes) != 5 || active["2"] || !active[models.OptionalIDFCECBU] {
+		t.Errorf("GetOptionalTypes() = %+v, want the AFIP list with RG 3668 expired", optionalTypes)
+	}
+
+	if _, err := service.GetOptionalTypes(ctx); err != nil {
+		t.Fatalf("Second GetOptionalTypes() error = %v", err)
+	}
+	if calls := server.Calls(testutil.ActionFEParamGetTiposOpcional); calls != 1 {
+		t.Errorf("Optional types should be cached, got %d calls", calls)
+	}
+}
+
+func TestOptionalsInRequest(t *testing.T) {
+	server, _, service := newFakeAFIPService(t)
+
+	invoice := newTestWSFEInvoice()
+	invoice.Optionals = []models.Optional{{ID: models.OptionalIDFCECBU, Value: "0110599520000001234567"}}
+	if _, err := service.AuthorizeInvoice(context.Background(), invoice); err != nil {
+		t.Fatalf("AuthorizeInvoice() error = %v", err)
+	}
+
+	body := server.LastRequest(testutil.ActionFECAESolicitar)
+	want := regexp.MustCompile(`<Opcionales>\s*<Opcional>\s*<Id>` + models.OptionalIDFCECBU + `</Id>\s*<Valor>0110599520000001234567</Valor>\s*</Opcional>\s*</Opcionales>`)
+	if !want.Match(body) {
+		t.Errorf("FECAESolicitar envelope should carry the optional in Opcionales>Opcional, got %s", body)
+	}
+
+	invoice = newTestWSFEInvoice()
+	invoice.InvoiceNumber++
+	if _, err := service.AuthorizeInvoice(context.Background(), invoice); err != nil {
+		t.Fatalf("AuthorizeInvoice() error = %v", err)
+	}
+	if body := string(server.LastRequest(testutil.ActionFECAESolicitar)); strings.Contains(body, "Opcionales") {
+		t.Errorf("Invoices without optionals should not send Opcionales, got %s", body)
+	}
+}
+
+// countingTransport cuenta los requests que pasan por el transporte
+type countingTransport struct {
+	requests int
+}
+
+func (c *countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	c.requests++
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestCustomHTTPClient(t *testing.T) {
+	server := testutil.NewFakeAFIPServer()
+	defer server.Close()
+
+	config, err := server.Config()
+	if err != nil {
+		t.Fatalf("server.Config() error = %v", err)
+	}
+	transport := &countingTransport{}
+	config.HTTPClient = &http.Client{Transport: transport}
+
+	auth := client.NewWSAAAuth(&config, nil)
+	if _, err := auth.GetAccessTicket(context.Background(), "wsfe"); err != nil {
+		t.Fatalf("GetAccessTicket() error = %v", err)
+	}
+	if _, err := wsfe.NewService(&config, auth, nil).Dummy(context.Background()); err != nil {
+		t.Fatalf("Dummy() error = %v", err)
+	}
+
+	if transport.requests != 2 {
+		t.Errorf("WSAA and WSFE calls should use the configured HTTP client, got %d requests", transport.requests)
+	}
+}
+
+// redirectTransport envía todos los requests al servidor simulado,
+// registrando el host al que iba cada uno
+type redirectTransport struct {
+	target string
+	hosts  []string
+}
+
+func (r *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.hosts = append(r.hosts, req.URL.Host)
+	req.URL.Scheme = "http"
+	req.URL.Host = strings.TrimPrefix(r.target, "http://")
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestEnvironmentOverride(t *testing.T) {
+	server := testutil.NewFakeAFIPServer()
+	defer server.Close()
+
+	config, err := server.Config()
+	if err != nil {
+		t.Fatalf("server.Config() error = %v", err)
+	}
+	transport := &redirectTransport{target: config.BaseURLOverride}
+	config.BaseURLOverride = ""
+	config.HTTPClient = &http.Client{Transport: transport}
+
+	var logs strings.Builder
+	logger := client.NewStdLogger(&logs, "warn")
+	auth := client.NewWSAAAuth(&config, logger)
+	service := wsfe.NewService(&config, auth, logger)
+
+	if _, err := service.Dummy(context.Background()); err != nil {
+		t.Fatalf("Dummy() error = %v", err)
+	}
+	if logs.Len() != 0 {
+		t.Errorf("Calls without override should not log warnings, got %q", logs.String())
+	}
+
+	ctx := client.WithEnvironmentOverride(context.Background(), models.EnvironmentProduction)
+	if env, ok := client.EnvironmentOverrideFromContext(ctx); !ok || env != models.EnvironmentProduction {
+		t.Errorf("EnvironmentOverrideFromContext() = %q, %v", env, ok)
+	}
+	if _, err := auth.GetAccessTicket(ctx, "wsfe"); err != nil {
+		t.Fatalf("GetAccessTicket() with override error = %v", err)
+	}
+	if _, err := service.Dummy(ctx); err != nil {
+		t.Fatalf("Dummy() with override error = %v", err)
+	}
+
+	want := []string{"wswhomo.afip.gov.ar", "servicios1.afip.gov.ar", "servicios1.afip.gov.ar"}
+	if strings.Join(transport.hosts, ",") != strings.Join(want, ",") {
+		t.Errorf("Requests went to %v, want %v", transport.hosts, want)
+	}
+	if server.Calls(testutil.ActionLoginCms) != 1 {
+		t.Errorf("Production ticket should be requested to WSAA, got %d logins", server.Calls(testutil.ActionLoginCms))
+	}
+	if !strings.Contains(logs.String(), "environment override in effect") {
+		t.Errorf("Override should log a warning, got %q", logs.String())
+	}
+
+	request, err := auth.NewLoginTicketRequest("wsfe")
+	if err != nil {
+		t.Fatalf("NewLoginTicketRequest() error = %v", err)
+	}
+	if request.Header.Destination != "cn=wsaahomo,o=afip,c=ar,serialNumber=CUIT 33693450239" {
+		t.Errorf("Login request without context should use the configured environment, got %q", request.Header.Destination)
+	}
+
+	// Los catálogos en cache se separan por ambiente
+	if _, err := service.GetCurrencyTypes(context.Background()); err != nil {
+		t.Fatalf("GetCurrencyTypes() error = %v", err)
+	}
+	if _, err := service.GetCurrencyTypes(ctx); err != nil {
+		t.Fatalf("GetCurrencyTypes() with override error = %v", err)
+	}
+	if _, err := service.GetCurrencyTypes(ctx); err != nil {
+		t.Fatalf("GetCurrencyTypes() with override error = %v", err)
+	}
+	if calls := server.Calls(testutil.ActionFEParamGetTiposMonedas); calls != 2 {
+		t.Errorf("Currencies should be cached once per environment, got %d calls", calls)
+	}
+	exportService := wsfex.NewService(&config, auth, logger)
+	for _, callCtx := range []context.Context{context.Background(), ctx, ctx} {
+		if _, err := exportService.GetUnitMeasures(callCtx); err != nil {
+			t.Fatalf("GetUnitMeasures() error = %v", err)
+		}
+	}
+	if calls := server.Calls(testutil.ActionFEXGetPARAMUMed); calls != 2 {
+		t.Errorf("Unit measures should be cached once per environment, got %d calls", calls)
+	}
+
+	// Un ambiente inválido se ignora en todas las llamadas
+	logs.Reset()
+	transport.hosts = nil
+	invalidCtx := client.WithEnvironmentOverride(context.Background(), models.Environment("prod"))
+	if _, ok := client.EnvironmentOverrideFromContext(invalidCtx); ok {
+		t.Error("EnvironmentOverrideFromContext() should ignore an invalid environment")
+	}
+	if got := config.ForContext(invalidCtx); got.Environment != models.EnvironmentTesting {
+		t.Errorf("ForContext() with an invalid override = %q, want the configured environment", got.Environment)
+	}
+	if _, err := service.Dummy(invalidCtx); err != nil {
+		t.Fatalf("Dummy() with invalid override error = %v", err)
+	}
+	if strings.Join(transport.hosts, ",") != "wswhomo.afip.gov.ar" {
+		t.Errorf("Invalid override should call the configured environment, got %v", transport.hosts)
+	}
+	if !strings.Contains(logs.String(), "ignoring invalid environment override") {
+		t.Errorf("Invalid override should log a warning, got %q", logs.String())
+	}
+}
+
+func TestProxyConfig(t *testing.T) {
+	// El servidor simulado actúa como proxy: recibe los requests dirigidos a un host inexistente
+	proxy := testutil.NewFakeAFIPServer()
+	defer proxy.Close()
+
+	config, err := proxy.Config()
+	if err != nil {
+		t.Fatalf("proxy.Config() error = %v", err)
+	}
+	config.BaseURLOverride = "http://afip.invalid"
+	config.Proxy = proxy.URL
+
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Config.Validate() should accept the proxy URL: %v", err)
+	}
+	if _, err := wsfe.NewService(&config, nil, nil).Dummy(context.Background()); err != nil {
+		t.Fatalf("Dummy() should go through the proxy: %v", err)
+	}
+	if proxy.Calls(testutil.ActionFEDummy) != 1 {
+		t.Errorf("Proxy should receive the FEDummy request")
+	}
+
+	config.Proxy = "proxy:3128"
+	if err := config.Validate(); err == nil {
+		t.Error("Config.Validate() should reject a proxy without scheme")
+	}
+}
+
+func TestARCAClientServices(t *testing.T) {
+	server := testutil.NewFakeAFIPServer()
+	defer server.Close()
+
+	config, err := server.Config()
+	if err != nil {
+		t.Fatalf("server.Config() error = %v", err)
+	}
+
+	arcaClient, err := client.NewARCAClient(config)
+	if err != nil {
+		t.Fatalf("NewARCAClient() error = %v", err)
+	}
+
+	status, err := arcaClient.WSFE().Dummy(context.Background())
+	if err != nil {
+		t.Fatalf("WSFE().Dummy() error = %v", err)
+	}
+	if status.AppServer != "OK" {
+		t.Errorf("WSFE() should reach the configured server, got %+v", status)
+	}
+}
+
+func TestTLSConfig(t *testing.T) {
+	server := testutil.NewFakeAFIPTLSServer()
+	defer server.Close()
+
+	config, err := server.Config()
+	if err != nil {
+		t.Fatalf("server.Config() error = %v", err)
+	}
+	ctx := context.Background()
+
+	// Por defecto el certificado del servidor no es de confianza
+	if _, err := wsfe.NewService(&config, nil, nil).Dummy(ctx); err == nil {
+		t.Error("Dummy() should fail against an untrusted certificate")
+	}
+
+	// CA propia, como en un proxy con inspección TLS
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	config.TLSConfig = &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}
+	if _, err := wsfe.NewService(&config, nil, nil).Dummy(ctx); err != nil {
+		t.Fatalf("Dummy() should trust the custom CA: %v", err)
+	}
+
+	// Versión mínima mayor a la que ofrece el servidor
+	server.TLS.MaxVersion = tls.VersionTLS12
+	config.TLSConfig = &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS13}
+	if _, err := wsfe.NewService(&config, nil, nil).Dummy(ctx); err == nil {
+		t.Error("Dummy() should fail when the server does not meet the minimum TLS version")
+	}
+}
+
+func TestClientTLSCert(t *testing.T) {
+	server := testutil.NewFakeAFIPTLSServer()
+	defer server.Close()
+	server.TLS.ClientAuth = tls.RequireAnyClientCert
+
+	config, err := server.Config()
+	if err != nil {
+		t.Fatalf("server.Config() error = %v", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	config.TLSConfig = &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}
+	ctx := context.Background()
+
+	// El certificado de firma de WSAA no se presenta en el handshake
+	if _, err := wsfe.NewService(&config, nil, nil).Dummy(ctx); err == nil {
+		t.Error("Dummy() should fail when the gateway requires a client certificate")
+	}
+
+	certDER, keyDER, err := testutil.GenerateCertificate("20123456786")
+	if err != nil {
+		t.Fatalf("GenerateCertificate() error = %v", err)
+	}
+	key, err := x509.ParsePKCS1PrivateKey(keyDER)
+	if err != nil {
+		t.Fatalf("ParsePKCS1PrivateKey() error = %v", err)
+	}
+	clientCert := tls.Certificate{Certificate: [][]byte{certDER}, PrivateKey: key}
+	config.WithClientTLSCert(&clientCert)
+	if _, err := wsfe.NewService(&config, nil, nil).Dummy(ctx); err != nil {
+		t.Fatalf("Dummy() should present the client certificate: %v", err)
+	}
+	if len(config.TLSConfig.Certificates) != 0 {
+		t.Error("ClientTLSCert should not modify the configured TLSConfig")
+	}
+}
+
+// recordingCapture guarda los envelopes capturados
+type recordingCapture struct {
+	requests  map[string][]byte
+	responses map[string]int
+}
+
+func (c *recordingCapture) OnRequest(action string, body []byte) {
+	c.requests[action] = body
+}
+
+func (c *recordingCapture) OnResponse(action string, status int, body []byte) {
+	c.responses[action] = status
+}
+
+func TestRequestCapture(t *testing.T) {
+	server, config, service := newFakeAFIPService(t)
+	capture := &recordingCapture{requests: map[string][]byte{}, responses: map[string]int{}}
+	config.RequestCapture = capture
+	config.LogRequests = true
+	config.LogResponses = true
+
+	if _, err := service.GetTaxRates(context.Background()); err != nil {
+		t.Fatalf("GetTaxRates() error = %v", err)
+	}
+
+	request, ok := capture.requests[testutil.ActionFEParamGetTiposIva]
+	if !ok {
+		t.Fatal("OnRequest() should receive the FEParamGetTiposIva envelope")
+	}
+	for _, secret := range []string{testutil.FakeToken, testutil.FakeSign, "20-12345678-6"} {
+		if strings.Contains(string(request), secret) {
+			t.Errorf("Captured request should redact %q, got %s", secret, request)
+		}
+	}
+	if !strings.Contains(string(server.LastRequest(testutil.ActionFEParamGetTiposIva)), testutil.FakeToken) {
+		t.Error("Redaction should only apply to the captured copy")
+	}
+	if status := capture.responses[testutil.ActionFEParamGetTiposIva]; status != http.StatusOK {
+		t.Errorf("OnResponse() should receive status 200, got %d", status)
+	}
+}
+
+func TestRequestCaptureDisabled(t *testing.T) {
+	_, config, service := newFakeAFIPService(t)
+	capture := &recordingCapture{requests: map[string][]byte{}, responses: map[string]int{}}
+	config.RequestCapture = capture
+
+	if _, err := service.Dummy(context.Background()); err != nil {
+		t.Fatalf("Dummy() error = %v", err)
+	}
+	if len(capture.requests) != 0 || len(capture.responses) != 0 {
+		t.Error("Capture should not be invoked unless LogRequests/LogResponses are set")
+	}
+}
+
+func TestInterceptors(t *testing.T) {
+	server, config, service := newFakeAFIPService(t)
+	ctx := context.Background()
+
+	var calls []string
+	config.WithInterceptor(
+		client.InterceptorFunc(func(ctx context.Context, action string, req []byte, next func([]byte) ([]byte, error)) ([]byte, error) {
+			calls = append(calls, "outer:"+action)
+			resp, err := next(req)
+			calls = append(calls, "outer:done")
+			return resp, err
+		}),
+		client.InterceptorFunc(func(ctx context.Context, action string, req []byte, next func([]byte) ([]byte, error)) ([]byte, error) {
+			calls = append(calls, "inner:"+action)
+			if action == testutil.ActionFEDummy {
+				return []byte(`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body>` +
+					`<FEDummyResponse><FEDummyResult><AppServer>FIXTURE</AppServer></FEDummyResult></FEDummyResponse>` +
+					`</soap:Body></soap:Envelope>`), nil
+			}
+			return next(req)
+		}),
+	)
+
+	dummy, err := service.Dummy(ctx)
+	if err != nil {
+		t.Fatalf("Dummy() error = %v", err)
+	}
+	if dummy.AppServer != "FIXTURE" || server.Calls(testutil.ActionFEDummy) != 0 {
+		t.Errorf("Interceptor response should replace the call to AFIP, got %+v", dummy)
+	}
+	want := []string{"outer:FEDummy", "inner:FEDummy", "outer:done"}
+	if strings.Join(calls, ",") != strings.Join(want, ",") {
+		t.Errorf("Interceptors should run in order %v, got %v", want, calls)
+	}
+
+	if _, err := service.GetTaxRates(ctx); err != nil {
+		t.Fatalf("GetTaxRates() error = %v", err)
+	}
+	if server.Calls(testutil.ActionFEParamGetTiposIva) != 1 {
+		t.Error("Interceptors calling next should reach AFIP")
+	}
+}
+
+func TestPartialSOAPResponse(t *testing.T) {
+	_, config, service := newFakeAFIPService(t)
+	ctx := context.Background()
+
+	var body []byte
+	config.WithInterceptor(client.InterceptorFunc(func(ctx context.Context, action string, req []byte, next func([]byte) ([]byte, error)) ([]byte, error) {
+		return body, nil
+	}))
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"empty", ""},
+		{"whitespace", "  \n"},
+		{"not XML", "Service Unavailable"},
+		{"truncated", `<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body><FEDummyResponse><FEDummyRes`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body = []byte(tt.body)
+
+			_, err := service.Dummy(ctx)
+			var networkErr *models.NetworkError
+			if !errors.As(err, &networkErr) {
+				t.Fatalf("Dummy() error = %v, want *models.NetworkError", err)
+			}
+			if networkErr.Status != http.StatusOK || networkErr.Body != strings.TrimSpace(tt.body) {
+				t.Errorf("NetworkError should carry status and body snippet, got %+v", networkErr)
+			}
+			if !models.IsRetryableError(err) {
+				t.Error("Partial responses should be retryable")
+			}
+		})
+	}
+
+	body = []byte(`<html><body>Mantenimiento</body></html>`)
+	_, err := service.Dummy(ctx)
+	var networkErr *models.NetworkError
+	if errors.As(err, &networkErr) || !models.IsARCAError(err) {
+		t.Errorf("Complete XML that is not a SOAP envelope should be an invalid response, got %v", err)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	server, config, service := newFakeAFIPService(t)
+	ctx := context.Background()
+
+	clock := testutil.NewFakeClock(time.Now())
+	config.Clock = clock
+	config.WithCircuitBreaker(2, time.Minute)
+
+	server.SetHTTPStatus(testutil.ActionFEDummy, http.StatusServiceUnavailable)
+	for i := 0; i < 2; i++ {
+		if _, err := service.Dummy(ctx); !models.IsRetryableError(err) {
+			t.Fatalf("HTTP 503 should be a retryable error, got %v", err)
+		}
+	}
+
+	// Con el circuito abierto la llamada falla sin llegar a AFIP
+	_, err := service.Dummy(ctx)
+	if !errors.Is(err, models.ErrCircuitOpen) || models.IsRetryableError(err) {
+		t.Errorf("Open circuit should fail fast with a non retryable ErrCircuitOpen, got %v", err)
+	}
+	if calls := server.Calls(testutil.ActionFEDummy); calls != 2 {
+		t.Errorf("Open circuit should not call AFIP, got %d calls", calls)
+	}
+
+	// Pasado el cooldown, una llamada de prueba fallida lo vuelve a abrir
+	clock.Advance(time.Minute)
+	if _, err := service.Dummy(ctx); errors.Is(err, models.ErrCircuitOpen) {
+		t.Errorf("Circuit should half-open after the cooldown, got %v", err)
+	}
+	if _, err := service.Dummy(ctx); !errors.Is(err, models.ErrCircuitOpen) {
+		t.Errorf("Failed probe should reopen the circuit, got %v", err)
+	}
+
+	// Una llamada de prueba exitosa lo cierra
+	clock.Advance(time.Minute)
+	server.Reset()
+	for i := 0; i < 3; i++ {
+		if _, err := service.Dummy(ctx); err != nil {
+			t.Fatalf("Successful probe should close the circuit, got %v", err)
+		}
+	}
+	if state := config.GetCircuitBreaker("wsfe").State(); state != client.CircuitClosed {
+		t.Errorf("Circuit should be closed, got %s", state)
+	}
+
+	// Los rechazos de AFIP no cuentan como fallas del servicio
+	server.SetFault(testutil.ActionFEDummy, "soap:Client", "Request inválido")
+	for i := 0; i < 3; i++ {
+		if _, err := service.Dummy(ctx); errors.Is(err, models.ErrCircuitOpen) {
+			t.Fatalf("SOAP faults should not open the circuit, got %v", err)
+		}
+	}
+}
+
+func TestIdempotencyGuard(t *testing.T) {
+	server, config, service := newFakeAFIPService(t)
+	config.RetryAttempts = 0
+	service.SetIdempotencyGuard(true)
+	server.SetHTTPStatus(testutil.ActionFECAESolicitar, http.StatusServiceUnavailable)
+
+	// El CAE consultado corresponde al comprobante: se retorna como aprobado
+	today := time.Now().Format("20060102")
+	authorized := func(total string) string {
+		return `<ResultGet><Concepto>1</Concepto><DocTipo>11</DocTipo><DocNro>20123456786</DocNro>` +
+			`<CbteDesde>1</CbteDesde><CbteHasta>1</CbteHasta><CbteFch>` + today + `</CbteFch><ImpTotal>` + total + `</ImpTotal>` +
+			`<MonId>PES</MonId><MonCotiz>1</MonCotiz><Resultado>A</Resultado><CodAutorizacion>74123456789012</CodAutorizacion>` +
+			`<EmisionTipo>CAE</EmisionTipo><FchVto>` + today + `</FchVto><PtoVta>1</PtoVta><CbteTipo>1</CbteTipo></ResultGet>`
+	}
+	server.SetResult(testutil.ActionFECompConsultar, authorized("1210"))
+
+	result, err := service.AuthorizeInvoice(context.Background(), newTestWSFEInvoice())
+	if err != nil {
+		t.Fatalf("AuthorizeInvoice() should recover the CAE already granted, got %v", err)
+	}
+	if result.CAE != "74123456789012" || result.Status != models.AuthResultApproved {
+		t.Errorf("AuthorizeInvoice() should return the existing CAE, got %+v", result)
+	}
+
+	// Otro proceso usó el número con otro importe: el CAE no es de esta factura
+	server.SetResult(testutil.ActionFECompConsultar, authorized("5000"))
+	result, err = service.AuthorizeInvoice(context.Background(), newTestWSFEInvoice())
+	if !errors.Is(err, models.ErrDuplicateInvoiceNumber) || result != nil {
+		t.Errorf("AuthorizeInvoice() should report a duplicate number for another invoice's CAE, got %+v, %v", result, err)
+	}
+}
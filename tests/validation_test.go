@@ -0,0 +1,328 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlarregola/arca_invoice_lib/internal/utils"
+	"github.com/dlarregola/arca_invoice_lib/pkg/models"
+)
+
+func TestValidateItemsTaxes(t *testing.T) {
+	taxlessItems := []models.Item{
+		{Description: "Producto", Quantity: 1, UnitPrice: 1000, TotalPrice: 1000},
+	}
+	taxedItems := []models.Item{
+		{
+			Description: "Producto",
+			Quantity:    1,
+			UnitPrice:   1000,
+			TotalPrice:  1000,
+			Taxes: []models.Tax{
+				{Type: models.TaxTypeIVA, Rate: models.TaxRate21, Base: 1000, Amount: 210},
+			},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		taxAmount float64
+		items     []models.Item
+		wantErr   bool
+	}{
+		{name: "tax amount with taxless items", taxAmount: 210, items: taxlessItems, wantErr: true},
+		{name: "tax amount with taxed items", taxAmount: 210, items: taxedItems, wantErr: false},
+		{name: "no tax amount with taxless items", taxAmount: 0, items: taxlessItems, wantErr: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := utils.ValidateItemsTaxes(tt.taxAmount, tt.items)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateItemsTaxes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "ítem") {
+				t.Errorf("error should point to the missing item taxes, got %q", err.Error())
+			}
+		})
+	}
+}
+
+func TestValidateTaxBreakdown(t *testing.T) {
+	newItem := func(taxes ...models.Tax) models.Item {
+		return models.Item{Description: "Producto", Quantity: 1, UnitPrice: 1000, TotalPrice: 1000, Taxes: taxes}
+	}
+
+	tests := []struct {
+		name      string
+		taxAmount float64
+		items     []models.Item
+		wantField string
+	}{
+		{
+			name:      "consistent breakdown",
+			taxAmount: 315,
+			items: []models.Item{
+				newItem(models.Tax{Type: models.TaxTypeIVA, Rate: models.TaxRate21, Base: 1000, Amount: 210}),
+				newItem(models.Tax{Type: models.TaxTypeIVA, Rate: models.TaxRate105, Base: 1000, Amount: 105}),
+			},
+		},
+		{
+			name:      "amount does not match base times rate",
+			taxAmount: 200,
+			items:     []models.Item{newItem(models.Tax{Type: models.TaxTypeIVA, Rate: models.TaxRate21, Base: 1000, Amount: 200})},
+			wantField: "items[0].taxes[0].amount",
+		},
+		{
+			name:      "sum does not match tax amount",
+			taxAmount: 210,
+			items: []models.Item{
+				newItem(models.Tax{Type: models.TaxTypeIVA, Rate: models.TaxRate21, Base: 1000, Amount: 210}),
+				newItem(models.Tax{Type: models.TaxTypeIVA, Rate: models.TaxRate25, Base: 1000, Amount: 25}),
+			},
+			wantField: "tax_amount",
+		},
+		{
+			name:      "non IVA taxes are ignored",
+			taxAmount: 0,
+			items:     []models.Item{newItem(models.Tax{Type: models.TaxTypeII, Base: 1000, Amount: 80})},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := utils.ValidateTaxBreakdown(tt.taxAmount, tt.items)
+			if tt.wantField == "" {
+				if err != nil {
+					t.Fatalf("ValidateTaxBreakdown() unexpected error = %v", err)
+				}
+				return
+			}
+			validationErr, ok := err.(*models.ValidationError)
+			if !ok || validationErr.Field != tt.wantField {
+				t.Errorf("ValidateTaxBreakdown() should fail on %s, got %v", tt.wantField, err)
+			}
+		})
+	}
+}
+
+func TestValidateFCE(t *testing.T) {
+	validFCE := &models.FCEData{CBU: "0110599520000001234567", TransferType: models.FCETransferSCA}
+
+	tests := []struct {
+		name        string
+		invoiceType models.InvoiceType
+		fce         *models.FCEData
+		wantErr     bool
+	}{
+		{name: "FCE invoice with data", invoiceType: models.InvoiceTypeFCEA, fce: validFCE, wantErr: false},
+		{name: "FCE invoice without data", invoiceType: models.InvoiceTypeFCEB, fce: nil, wantErr: true},
+		{name: "FCE invoice with short CBU", invoiceType: models.InvoiceTypeFCEA, fce: &models.FCEData{CBU: "123", TransferType: models.FCETransferSCA}, wantErr: true},
+		{name: "FCE invoice without transfer type", invoiceType: models.InvoiceTypeFCEC, fce: &models.FCEData{CBU: validFCE.CBU}, wantErr: true},
+		{name: "FCE credit note with cancellation", invoiceType: models.InvoiceTypeFCECreditNoteA, fce: &models.FCEData{Cancelled: true}, wantErr: false},
+		{name: "regular invoice with FCE data", invoiceType: models.InvoiceTypeA, fce: validFCE, wantErr: true},
+		{name: "regular invoice without FCE data", invoiceType: models.InvoiceTypeA, fce: nil, wantErr: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := utils.ValidateFCE(tt.invoiceType, tt.fce)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateFCE() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateActivityCodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		codes    []int
+		required bool
+		wantErr  bool
+	}{
+		{name: "optional and empty", codes: nil, required: false, wantErr: false},
+		{name: "required and empty", codes: nil, required: true, wantErr: true},
+		{name: "required with code", codes: []int{620100}, required: true, wantErr: false},
+		{name: "invalid code", codes: []int{0}, required: false, wantErr: true},
+		{name: "duplicated code", codes: []int{620100, 620100}, required: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := utils.ValidateActivityCodes(tt.codes, tt.required)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateActivityCodes() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateTotalAmount(t *testing.T) {
+	tributes := []models.Tax{
+		{Type: models.TaxTypeII, Base: 1000, Amount: 30},
+		{Type: models.TaxTypeIVA, Rate: models.TaxRate21, Base: 1000, Amount: 210},
+	}
+
+	tests := []struct {
+		name        string
+		amount      float64
+		taxAmount   float64
+		totalAmount float64
+		taxes       []models.Tax
+		wantErr     bool
+	}{
+		{name: "net plus IVA", amount: 1000, taxAmount: 210, totalAmount: 1210, wantErr: false},
+		{name: "rounding tolerance", amount: 1000.004, taxAmount: 210.003, totalAmount: 1210, wantErr: false},
+		{name: "with tributes", amount: 1000, taxAmount: 210, totalAmount: 1240, taxes: tributes, wantErr: false},
+		{name: "missing tributes", amount: 1000, taxAmount: 210, totalAmount: 1210, taxes: tributes, wantErr: true},
+		{name: "wrong total", amount: 1000, taxAmount: 210, totalAmount: 1200, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := utils.ValidateTotalAmount(tt.amount, tt.taxAmount, tt.totalAmount, tt.taxes)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ValidateTotalAmount() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "1210.00") && !strings.Contains(err.Error(), "1240.00") {
+				t.Errorf("error should include the expected total, got %q", err.Error())
+			}
+		})
+	}
+}
+
+func TestValidateConceptDates(t *testing.T) {
+	date := func(day int) time.Time { return time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name                 string
+		conceptType          models.ConceptType
+		serviceFrom          time.Time
+		serviceTo            time.Time
+		paymentDueDate       time.Time
+		requireServicePeriod bool
+		wantErr              bool
+	}{
+		{name: "products without dates", conceptType: models.ConceptTypeProducts, requireServicePeriod: true, wantErr: false},
+		{name: "products with service dates", conceptType: models.ConceptTypeProducts, serviceFrom: date(1), serviceTo: date(31), wantErr: true},
+		{name: "services with dates", conceptType: models.ConceptTypeServices, serviceFrom: date(1), serviceTo: date(31), paymentDueDate: date(15), requireServicePeriod: true, wantErr: false},
+		{name: "mixed without payment due date", conceptType: models.ConceptTypeMixed, serviceFrom: date(1), serviceTo: date(31), wantErr: true},
+		{name: "services without dates", conceptType: models.ConceptTypeServices, requireServicePeriod: true, wantErr: true},
+		{name: "services without required period", conceptType: models.ConceptTypeServices, paymentDueDate: date(15), requireServicePeriod: true, wantErr: true},
+		{name: "services without optional period", conceptType: models.ConceptTypeServices, paymentDueDate: date(15), wantErr: false},
+		{name: "services with inverted period", conceptType: models.ConceptTypeServices, serviceFrom: date(31), serviceTo: date(1), paymentDueDate: date(15), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := utils.ValidateConceptDates(tt.conceptType, tt.serviceFrom, tt.serviceTo, tt.paymentDueDate, tt.requireServicePeriod)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateConceptDates() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidatePaymentDueDate(t *testing.T) {
+	issueDate := time.Date(2024, 3, 15, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		conceptType    models.ConceptType
+		paymentDueDate time.Time
+		wantErr        bool
+	}{
+		{name: "same day", conceptType: models.ConceptTypeServices, paymentDueDate: time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{name: "after issue date", conceptType: models.ConceptTypeMixed, paymentDueDate: time.Date(2024, 4, 15, 0, 0, 0, 0, time.UTC)},
+		{name: "before issue date", conceptType: models.ConceptTypeServices, paymentDueDate: time.Date(2024, 3, 14, 0, 0, 0, 0, time.UTC), wantErr: true},
+		{name: "last day of the window", conceptType: models.ConceptTypeServices, paymentDueDate: issueDate.AddDate(0, 0, utils.MaxPaymentDueDays)},
+		{name: "beyond the window", conceptType: models.ConceptTypeServices, paymentDueDate: issueDate.AddDate(0, 0, utils.MaxPaymentDueDays+1), wantErr: true},
+		{name: "products", conceptType: models.ConceptTypeProducts, paymentDueDate: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := utils.ValidatePaymentDueDate(tt.conceptType, tt.paymentDueDate, issueDate)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePaymentDueDate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateConsumidorFinal(t *testing.T) {
+	if err := utils.ValidateDocumentType(models.DocumentTypeConsumidorFinal); err != nil {
+		t.Errorf("ValidateDocumentType() should accept DocTipo 99, got %v", err)
+	}
+	if err := utils.ValidateDocumentNumber(models.DocumentTypeConsumidorFinal, "0"); err != nil {
+		t.Errorf("ValidateDocumentNumber() should accept DocNro 0 for consumidor final, got %v", err)
+	}
+	for _, docNumber := range []string{"", "20123456786"} {
+		if err := utils.ValidateDocumentNumber(models.DocumentTypeConsumidorFinal, docNumber); err == nil {
+			t.Errorf("ValidateDocumentNumber() should reject DocNro %q for consumidor final", docNumber)
+		}
+	}
+
+	for _, invoiceType := range []models.InvoiceType{models.InvoiceTypeB, models.InvoiceTypeC, models.InvoiceTypeCreditNoteB} {
+		if err := utils.ValidateConsumidorFinal(invoiceType, models.DocumentTypeConsumidorFinal); err != nil {
+			t.Errorf("ValidateConsumidorFinal() should accept %s, got %v", invoiceType, err)
+		}
+	}
+	if err := utils.ValidateConsumidorFinal(models.InvoiceTypeA, models.DocumentTypeConsumidorFinal); err == nil {
+		t.Error("ValidateConsumidorFinal() should reject consumidor final on a Factura A")
+	}
+}
+
+func TestValidateExportConsistency(t *testing.T) {
+	tests := []struct {
+		name    string
+		check   func() error
+		wantErr bool
+	}{
+		{name: "factura E without IVA", check: func() error { return utils.ValidateExportTaxes(models.InvoiceTypeE, 0) }},
+		{name: "factura E with IVA", check: func() error { return utils.ValidateExportTaxes(models.InvoiceTypeE, 210) }, wantErr: true},
+		{name: "credit note E with IVA", check: func() error { return utils.ValidateExportTaxes(models.InvoiceTypeCreditNoteE, 21) }, wantErr: true},
+		{name: "foreign currency", check: func() error { return utils.ValidateExportCurrency(models.CurrencyTypeUSD, 1050.5) }},
+		{name: "PES currency", check: func() error { return utils.ValidateExportCurrency(models.CurrencyTypePES, 1) }, wantErr: true},
+		{name: "missing rate", check: func() error { return utils.ValidateExportCurrency(models.CurrencyTypeEUR, 0) }, wantErr: true},
+		{name: "AFIP country code", check: func() error { return utils.ValidateCountryCode("212", "country_from") }},
+		{name: "ISO country code", check: func() error { return utils.ValidateCountryCode("US", "country_from") }, wantErr: true},
+		{name: "Argentina as destination", check: func() error { return utils.ValidateCountryCode("200", "country_from") }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.check(); (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateDateAt(t *testing.T) {
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		date    time.Time
+		wantErr bool
+	}{
+		{"today", now, false},
+		{"tomorrow", now.AddDate(0, 0, 1), false},
+		{"fecha futura", now.AddDate(0, 0, 2), true},
+		{"within a year", now.AddDate(0, -11, 0), false},
+		{"older than a year", now.AddDate(-1, 0, -1), true},
+		{"zero", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := utils.ValidateDateAt(tt.date, "date_from", now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateDateAt() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,953 @@
+package tests
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlarregola/arca_invoice_lib/internal/soap"
+	"github.com/dlarregola/arca_invoice_lib/internal/utils"
+	"github.com/dlarregola/arca_invoice_lib/pkg/client"
+	"github.com/dlarregola/arca_invoice_lib/pkg/models"
+	"github.com/dlarregola/arca_invoice_lib/pkg/testutil"
+	"github.com/dlarregola/arca_invoice_lib/pkg/wsfe"
+)
+
+// newTestWSFEInvoice crea una factura nacional válida para tests
+func newTestWSFEInvoice() *wsfe.Invoice {
+	return &wsfe.Invoice{
+		InvoiceBase: models.InvoiceBase{
+			InvoiceType:   models.InvoiceTypeA,
+			PointOfSale:   1,
+			InvoiceNumber: 1,
+			DateFrom:      time.Now(),
+			DateTo:        time.Now(),
+			ConceptType:   models.ConceptTypeProducts,
+			CurrencyType:  models.CurrencyTypePES,
+			Amount:        1000,
+			TaxAmount:     210,
+			TotalAmount:   1210,
+			Items: []models.Item{
+				{
+					Description: "Producto",
+					Quantity:    1,
+					UnitPrice:   1000,
+					TotalPrice:  1000,
+					Taxes: []models.Tax{
+						{Type: models.TaxTypeIVA, Rate: models.TaxRate21, Base: 1000, Amount: 210},
+					},
+				},
+			},
+		},
+		DocType:              models.DocumentTypeCUIT,
+		DocNumber:            "20-12345678-6",
+		DocTypeFrom:          models.DocumentTypeCUIT,
+		DocNumberFrom:        "20-12345678-6",
+		ReceptorIVACondition: models.ReceptorIVAConditionRegistered,
+	}
+}
+
+func TestPESCurrencyRateNormalization(t *testing.T) {
+	for _, rate := range []float64{0, 1, 350.5} {
+		config := client.DefaultConfig()
+		service := wsfe.NewService(&config, nil, nil)
+
+		invoice := newTestWSFEInvoice()
+		invoice.CurrencyRate = rate
+
+		request, err := service.NewAuthorizationRequest(invoice, wsfe.Auth{})
+		if err != nil {
+			t.Fatalf("NewAuthorizationRequest() error = %v", err)
+		}
+		if request.Request.Details[0].CurrencyRate != 1 {
+			t.Errorf("PES invoice with rate %v should send MonCotiz 1, got %v", rate, request.Request.Details[0].CurrencyRate)
+		}
+	}
+}
+
+func TestPESCurrencyRateModes(t *testing.T) {
+	invoice := newTestWSFEInvoice()
+	invoice.CurrencyRate = 0
+
+	config := client.DefaultConfig()
+	config.PESCurrencyRateMode = client.CurrencyRateModeValidate
+	if _, err := wsfe.NewService(&config, nil, nil).NewAuthorizationRequest(invoice, wsfe.Auth{}); err == nil {
+		t.Error("validate mode should reject a PES invoice with rate 0")
+	}
+
+	config.PESCurrencyRateMode = client.CurrencyRateModePassthrough
+	request, err := wsfe.NewService(&config, nil, nil).NewAuthorizationRequest(invoice, wsfe.Auth{})
+	if err != nil {
+		t.Fatalf("passthrough mode should not fail: %v", err)
+	}
+	if request.Request.Details[0].CurrencyRate != 0 {
+		t.Errorf("passthrough mode should send the invoice rate, got %v", request.Request.Details[0].CurrencyRate)
+	}
+}
+
+func TestServiceDatesMapping(t *testing.T) {
+	config := client.DefaultConfig()
+	service := wsfe.NewService(&config, nil, nil)
+
+	invoice := newTestWSFEInvoice()
+	invoice.ConceptType = models.ConceptTypeServices
+	invoice.ServiceFrom = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	invoice.ServiceTo = time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	invoice.PaymentDueDate = time.Date(2024, 2, 15, 0, 0, 0, 0, time.UTC)
+
+	body, err := authorizationRequestXML(service, invoice)
+	if err != nil {
+		t.Fatalf("NewAuthorizationRequest() error = %v", err)
+	}
+	for _, want := range []string{"<FchServDesde>20240101</FchServDesde>", "<FchServHasta>20240131</FchServHasta>", "<FchVtoPago>20240215</FchVtoPago>"} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("Service invoice request should contain %s, got %s", want, body)
+		}
+	}
+
+	products := newTestWSFEInvoice()
+	body, err = authorizationRequestXML(service, products)
+	if err != nil {
+		t.Fatalf("NewAuthorizationRequest() error = %v", err)
+	}
+	if strings.Contains(string(body), "FchServDesde") || strings.Contains(string(body), "FchVtoPago") {
+		t.Errorf("Products invoice request should not send service dates, got %s", body)
+	}
+
+	invoice.ServiceTo = time.Time{}
+	var validationErrs models.ValidationErrors
+	if err := invoice.Validate(); !errors.As(err, &validationErrs) {
+		t.Errorf("Validate() should require the end of the service period, got %v", err)
+	}
+}
+
+// authorizationRequestXML serializa el request de FECAESolicitar de invoice
+// sin validarlo
+func authorizationRequestXML(service *wsfe.Service, invoice *wsfe.Invoice) ([]byte, error) {
+	request, err := service.NewAuthorizationRequest(invoice, wsfe.Auth{})
+	if err != nil {
+		return nil, err
+	}
+	return xml.Marshal(request)
+}
+
+func TestUntaxedAndExemptAmounts(t *testing.T) {
+	config := client.DefaultConfig()
+	service := wsfe.NewService(&config, nil, nil)
+
+	invoice := newTestWSFEInvoice()
+	invoice.Items = append(invoice.Items,
+		models.Item{Description: "No gravado", Quantity: 1, UnitPrice: 200, TotalPrice: 200,
+			Taxes: []models.Tax{{Type: models.TaxTypeIVA, Rate: models.TaxRate0, Base: 200}}},
+		models.Item{Description: "Exento", Quantity: 1, UnitPrice: 500, TotalPrice: 500,
+			Taxes: []models.Tax{{Type: models.TaxTypeIVA, Rate: models.TaxRateExempt, Base: 500}}},
+	)
+	invoice.TotalAmount = 1910
+	if err := invoice.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	request, err := service.NewAuthorizationRequest(invoice, wsfe.Auth{})
+	if err != nil {
+		t.Fatalf("NewAuthorizationRequest() error = %v", err)
+	}
+	detail := request.Request.Details[0]
+	if detail.UntaxedAmount != 200 || detail.ExemptAmount != 500 || detail.Amount != 1000 {
+		t.Errorf("ImpTotConc, ImpOpEx and ImpNeto should be 200, 500 and 1000, got %v %v %v", detail.UntaxedAmount, detail.ExemptAmount, detail.Amount)
+	}
+	if len(detail.IVA) != 1 || detail.IVA[0].ID != 5 {
+		t.Errorf("AlicIva should only include the taxed rates, got %+v", detail.IVA)
+	}
+
+	invoice.TotalAmount = 1210
+	var validationErrs models.ValidationErrors
+	if err := invoice.Validate(); !errors.As(err, &validationErrs) || validationErrs[0].Field != "total_amount" {
+		t.Errorf("Validate() should require the total to include untaxed and exempt amounts, got %v", err)
+	}
+
+	invoice.TotalAmount = 2010
+	invoice.ExemptAmount = 600
+	if err := invoice.Validate(); err != nil {
+		t.Errorf("Validate() should use the explicit ExemptAmount, got %v", err)
+	}
+}
+
+func TestActivityCodesMapping(t *testing.T) {
+	config := client.DefaultConfig()
+	service := wsfe.NewService(&config, nil, nil)
+
+	invoice := newTestWSFEInvoice()
+	invoice.ActivityCodes = []int{620100, 474010}
+
+	request, err := service.NewAuthorizationRequest(invoice, wsfe.Auth{})
+	if err != nil {
+		t.Fatalf("NewAuthorizationRequest() error = %v", err)
+	}
+	if len(request.Request.Details[0].Activities) != 2 || request.Request.Details[0].Activities[0].ID != 620100 || request.Request.Details[0].Activities[1].ID != 474010 {
+		t.Errorf("Request should carry the activity codes, got %+v", request.Request.Details[0].Activities)
+	}
+}
+
+func TestAuthorizationRequestXML(t *testing.T) {
+	config := client.DefaultConfig()
+	service := wsfe.NewService(&config, nil, nil)
+
+	auth := wsfe.Auth{Token: "token", Sign: "sign", CUIT: "20123456786"}
+	request, err := service.NewAuthorizationRequest(newTestWSFEInvoice(), auth)
+	if err != nil {
+		t.Fatalf("NewAuthorizationRequest() error = %v", err)
+	}
+
+	body, err := xml.Marshal(request)
+	if err != nil {
+		t.Fatalf("xml.Marshal() error = %v", err)
+	}
+	for _, want := range []string{
+		"<Auth><Token>token</Token><Sign>sign</Sign><Cuit>20123456786</Cuit></Auth>",
+		"<FeCabReq><CantReg>1</CantReg><PtoVta>1</PtoVta><CbteTipo>1</CbteTipo></FeCabReq>",
+		"<FeDetReq><FECAEDetRequest><Concepto>1</Concepto><DocTipo>",
+		"<CbteDesde>1</CbteDesde><CbteHasta>1</CbteHasta>",
+		"<ImpTotal>1210.00</ImpTotal><ImpTotConc>0.00</ImpTotConc><ImpNeto>1000.00</ImpNeto><ImpOpEx>0.00</ImpOpEx><ImpTrib>0.00</ImpTrib><ImpIVA>210.00</ImpIVA>",
+		"<MonId>PES</MonId><MonCotiz>1</MonCotiz><CondicionIVAReceptorId>1</CondicionIVAReceptorId>",
+		"<Iva><AlicIva><Id>5</Id><BaseImp>1000.00</BaseImp><Importe>210.00</Importe></AlicIva></Iva></FECAEDetRequest>",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("Request XML should contain %s, got %s", want, body)
+		}
+	}
+	if err := soap.ValidateRequest("FECAESolicitar", body); err != nil {
+		t.Errorf("Request XML should match the FECAESolicitar cardinality: %v", err)
+	}
+}
+
+func TestTributesInRequest(t *testing.T) {
+	config := client.DefaultConfig()
+	service := wsfe.NewService(&config, nil, nil)
+
+	invoice := newTestWSFEInvoice()
+	invoice.Taxes = []models.Tax{{Type: models.TaxTypeII, Base: 1000, Amount: 30}}
+	invoice.TotalAmount = 1240
+
+	body, err := authorizationRequestXML(service, invoice)
+	if err != nil {
+		t.Fatalf("NewAuthorizationRequest() error = %v", err)
+	}
+	for _, want := range []string{
+		"<ImpTrib>30.00</ImpTrib>",
+		"<Tributos><Tributo><Id>4</Id><Desc>Impuestos Internos</Desc><BaseImp>1000.00</BaseImp><Alic>3.00</Alic><Importe>30.00</Importe></Tributo></Tributos><Iva>",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("Request XML should contain %s, got %s", want, body)
+		}
+	}
+
+	body, err = authorizationRequestXML(service, newTestWSFEInvoice())
+	if err != nil {
+		t.Fatalf("NewAuthorizationRequest() error = %v", err)
+	}
+	if strings.Contains(string(body), "Tributos") {
+		t.Errorf("An invoice without tributes should not send Tributos, got %s", body)
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	tests := []struct {
+		value float64
+		want  string
+	}{
+		{1210, "1210.00"},
+		{1000000, "1000000.00"},
+		{1210.0000001, "1210.00"},
+		{0.1 + 0.2, "0.30"},
+		{10.5, "10.50"},
+		{0, "0.00"},
+	}
+
+	for _, tt := range tests {
+		if got := soap.FormatAmount(tt.value); got != tt.want {
+			t.Errorf("FormatAmount(%v) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+
+	body, err := xml.Marshal(wsfe.AlicIva{ID: 5, Base: 1e6, Amount: 210000})
+	if err != nil {
+		t.Fatalf("xml.Marshal() error = %v", err)
+	}
+	if want := "<BaseImp>1000000.00</BaseImp><Importe>210000.00</Importe>"; !strings.Contains(string(body), want) {
+		t.Errorf("Amounts should not use exponent notation, got %s", body)
+	}
+}
+
+func TestAFIPDate(t *testing.T) {
+	config := client.DefaultConfig()
+	service := wsfe.NewService(&config, nil, nil)
+
+	invoice := newTestWSFEInvoice()
+	invoice.DateFrom = time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	request, err := service.NewAuthorizationRequest(invoice, wsfe.Auth{})
+	if err != nil {
+		t.Fatalf("NewAuthorizationRequest() error = %v", err)
+	}
+	body, err := xml.Marshal(request)
+	if err != nil {
+		t.Fatalf("xml.Marshal() error = %v", err)
+	}
+	if !strings.Contains(string(body), "<CbteFch>20240115</CbteFch>") {
+		t.Errorf("Request dates should use the AAAAMMDD format, got %s", body)
+	}
+
+	var response struct {
+		Date     wsfe.AFIPDate `xml:"Date"`
+		DateTime wsfe.AFIPDate `xml:"DateTime"`
+		Null     wsfe.AFIPDate `xml:"Null"`
+		Empty    wsfe.AFIPDate `xml:"Empty"`
+	}
+	data := `<Result><Date>20240125</Date><DateTime>20240115103000</DateTime><Null>NULL</Null><Empty></Empty></Result>`
+	if err := xml.Unmarshal([]byte(data), &response); err != nil {
+		t.Fatalf("xml.Unmarshal() error = %v", err)
+	}
+	if !response.Date.Equal(time.Date(2024, 1, 25, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("AFIPDate should parse AAAAMMDD, got %v", response.Date)
+	}
+	if !response.DateTime.Equal(time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)) {
+		t.Errorf("AFIPDate should parse AAAAMMDDhhmmss, got %v", response.DateTime)
+	}
+	if !response.Null.IsZero() || !response.Empty.IsZero() {
+		t.Errorf("Empty and NULL dates should be zero, got %v %v", response.Null, response.Empty)
+	}
+
+	if err := xml.Unmarshal([]byte(`<Result><Date>2024-01-25</Date></Result>`), &response); err == nil {
+		t.Error("AFIPDate should reject other formats")
+	}
+}
+
+func TestIssueDate(t *testing.T) {
+	config := client.DefaultConfig()
+	service := wsfe.NewService(&config, nil, nil)
+
+	invoice := newTestWSFEInvoice()
+	invoice.IssueDate = time.Now().AddDate(0, 0, 3)
+	invoice.DateFrom = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	invoice.DateTo = time.Time{}
+	if err := invoice.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	request, err := service.NewAuthorizationRequest(invoice, wsfe.Auth{})
+	if err != nil {
+		t.Fatalf("NewAuthorizationRequest() error = %v", err)
+	}
+	if got := request.Request.Details[0].InvoiceDate; !got.Equal(invoice.IssueDate) {
+		t.Errorf("CbteFch should come from IssueDate, got %v", got)
+	}
+
+	var validationErrs models.ValidationErrors
+	invoice.IssueDate = time.Now().AddDate(0, 0, 6)
+	if err := invoice.Validate(); !errors.As(err, &validationErrs) || validationErrs[0].Field != "issue_date" {
+		t.Errorf("Validate() should reject a products invoice dated more than 5 days ahead, got %v", err)
+	}
+
+	invoice.ConceptType = models.ConceptTypeServices
+	invoice.IssueDate = time.Now().AddDate(0, 0, -8)
+	invoice.ServiceFrom = invoice.IssueDate
+	invoice.ServiceTo = invoice.IssueDate
+	invoice.PaymentDueDate = time.Now()
+	if err := invoice.Validate(); err != nil {
+		t.Errorf("Validate() should accept a services invoice dated up to 10 days back, got %v", err)
+	}
+	invoice.IssueDate = time.Now().AddDate(0, 0, -11)
+	if err := invoice.Validate(); !errors.As(err, &validationErrs) || validationErrs[0].Field != "issue_date" {
+		t.Errorf("Validate() should reject a services invoice dated more than 10 days back, got %v", err)
+	}
+}
+
+func TestBuildAuthorizeRequestXML(t *testing.T) {
+	server, _, service := newFakeAFIPService(t)
+
+	envelope, err := service.BuildAuthorizeRequestXML(newTestWSFEInvoice())
+	if err != nil {
+		t.Fatalf("BuildAuthorizeRequestXML() error = %v", err)
+	}
+	for _, want := range []string{"Envelope", `<FECAESolicitar xmlns="http://ar.gov.afip.dif.FEV1/">`, "<FeCabReq>", "<Cuit>20123456786</Cuit>"} {
+		if !strings.Contains(string(envelope), want) {
+			t.Errorf("Envelope should contain %s, got %s", want, envelope)
+		}
+	}
+	if server.Calls(testutil.ActionLoginCms) != 0 || server.Calls(testutil.ActionFECAESolicitar) != 0 {
+		t.Error("BuildAuthorizeRequestXML() should not call WSAA or WSFE")
+	}
+
+	invoice := newTestWSFEInvoice()
+	invoice.PointOfSale = 0
+	_, err = service.BuildAuthorizeRequestXML(invoice)
+	var validationErrs models.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		t.Errorf("BuildAuthorizeRequestXML() should validate the invoice, got %v", err)
+	}
+}
+
+func TestValidateAndBuild(t *testing.T) {
+	server, _, service := newFakeAFIPService(t)
+
+	result, err := service.ValidateAndBuild(newTestWSFEInvoice())
+	if err != nil {
+		t.Fatalf("ValidateAndBuild() error = %v", err)
+	}
+	if !result.Valid() || result.Request == nil || result.Request.Request.Header.PointOfSale != 1 {
+		t.Errorf("A valid invoice should return the request, got %+v", result)
+	}
+	if !strings.Contains(string(result.Envelope), "<FeCAEReq>") {
+		t.Errorf("Result should include the SOAP envelope, got %s", result.Envelope)
+	}
+
+	invoice := newTestWSFEInvoice()
+	invoice.PointOfSale = 0
+	invoice.TotalAmount = 1
+	result, err = service.ValidateAndBuild(invoice)
+	if err != nil {
+		t.Fatalf("ValidateAndBuild() should report validation problems in the result, got %v", err)
+	}
+	if result.Valid() || len(result.ValidationErrors) < 2 || result.Request != nil {
+		t.Errorf("Result should list every validation error, got %+v", result.ValidationErrors)
+	}
+
+	if server.Calls(testutil.ActionLoginCms) != 0 || server.Calls(testutil.ActionFECAESolicitar) != 0 {
+		t.Error("ValidateAndBuild() should not call WSAA or WSFE")
+	}
+}
+
+func TestAssociatedPeriod(t *testing.T) {
+	_, _, service := newFakeAFIPService(t)
+
+	invoice := newTestWSFEInvoice()
+	invoice.InvoiceType = models.InvoiceTypeCreditNoteA
+	invoice.AssociatedPeriod = &models.AssociatedPeriod{
+		From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC),
+	}
+	envelope, err := service.BuildAuthorizeRequestXML(invoice)
+	if err != nil {
+		t.Fatalf("BuildAuthorizeRequestXML() error = %v", err)
+	}
+	for _, want := range []string{"<PeriodoAsoc>", "<FchDesde>20240101</FchDesde>", "<FchHasta>20240131</FchHasta>"} {
+		if !strings.Contains(string(envelope), want) {
+			t.Errorf("Envelope should contain %s, got %s", want, envelope)
+		}
+	}
+	if strings.Contains(string(envelope), "CbtesAsoc") {
+		t.Errorf("Envelope should not contain CbtesAsoc without associated invoices, got %s", envelope)
+	}
+
+	invoice.AssociatedPeriod = nil
+	invoice.AssociatedInvoices = []models.AssociatedInvoice{{InvoiceType: models.InvoiceTypeA, PointOfSale: 1, InvoiceNumber: 10}}
+	envelope, err = service.BuildAuthorizeRequestXML(invoice)
+	if err != nil {
+		t.Fatalf("BuildAuthorizeRequestXML() error = %v", err)
+	}
+	for _, want := range []string{"<CbtesAsoc>", "<CbteAsoc>", "<Tipo>1</Tipo>", "<Nro>10</Nro>"} {
+		if !strings.Contains(string(envelope), want) {
+			t.Errorf("Envelope should contain %s, got %s", want, envelope)
+		}
+	}
+	if strings.Contains(string(envelope), "PeriodoAsoc") {
+		t.Errorf("Envelope should not contain PeriodoAsoc without a period, got %s", envelope)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(i *wsfe.Invoice)
+	}{
+		{"ambos", func(i *wsfe.Invoice) {
+			i.AssociatedPeriod = &models.AssociatedPeriod{From: time.Now(), To: time.Now()}
+		}},
+		{"ninguno", func(i *wsfe.Invoice) { i.AssociatedInvoices = nil }},
+		{"período invertido", func(i *wsfe.Invoice) {
+			i.AssociatedInvoices = nil
+			i.AssociatedPeriod = &models.AssociatedPeriod{From: time.Now(), To: time.Now().AddDate(0, -1, 0)}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invalid := *invoice
+			tt.modify(&invalid)
+			if err := invalid.Validate(); err == nil {
+				t.Error("Validate() should require exactly one of associated invoices or period")
+			}
+		})
+	}
+}
+
+func TestReceptorIVACondition(t *testing.T) {
+	server, _, service := newFakeAFIPService(t)
+
+	invoice := newTestWSFEInvoice()
+	request, err := service.NewAuthorizationRequest(invoice, wsfe.Auth{})
+	if err != nil {
+		t.Fatalf("NewAuthorizationRequest() error = %v", err)
+	}
+	if request.Request.Details[0].ReceptorIVACondition != models.ReceptorIVAConditionRegistered {
+		t.Errorf("Request should carry CondicionIVAReceptorId, got %d", request.Request.Details[0].ReceptorIVACondition)
+	}
+
+	invoice.ReceptorIVACondition = 0
+	_, err = service.AuthorizeInvoice(context.Background(), invoice)
+	var validationErrs models.ValidationErrors
+	if !errors.As(err, &validationErrs) || validationErrs[0].Field != "receptor_iva_condition" {
+		t.Errorf("AuthorizeInvoice() should require the receptor IVA condition, got %v", err)
+	}
+
+	conditions, err := service.GetReceptorIVAConditions(context.Background())
+	if err != nil {
+		t.Fatalf("GetReceptorIVAConditions() error = %v", err)
+	}
+	if len(conditions) != 2 || conditions[1].ID != models.ReceptorIVAConditionFinalConsumer || conditions[1].InvoiceClass != "B/C" {
+		t.Errorf("GetReceptorIVAConditions() should map the AFIP conditions, got %+v", conditions)
+	}
+	if server.Calls(testutil.ActionFEParamGetCondicionIvaReceptor) != 1 {
+		t.Errorf("GetReceptorIVAConditions() should call FEParamGetCondicionIvaReceptor")
+	}
+}
+
+func TestGetActivities(t *testing.T) {
+	server, _, service := newFakeAFIPService(t)
+
+	activities, err := service.GetActivities(context.Background())
+	if err != nil {
+		t.Fatalf("GetActivities() error = %v", err)
+	}
+	if len(activities) != 2 || activities[0].ID != 620100 || activities[0].Order != 1 || activities[0].Description == "" {
+		t.Errorf("GetActivities() should map the AFIP activities, got %+v", activities)
+	}
+	if server.Calls(testutil.ActionFEParamGetActividades) != 1 {
+		t.Errorf("GetActivities() should call FEParamGetActividades")
+	}
+}
+
+func TestGetInvoiceTypes(t *testing.T) {
+	server, _, service := newFakeAFIPService(t)
+
+	invoiceTypes, err := service.GetInvoiceTypes(context.Background())
+	if err != nil {
+		t.Fatalf("GetInvoiceTypes() error = %v", err)
+	}
+	if len(invoiceTypes) != 5 || invoiceTypes[1].ID != models.InvoiceTypeCreditNoteA || invoiceTypes[3].ID != models.InvoiceTypeM {
+		t.Fatalf("GetInvoiceTypes() should map the AFIP invoice types, got %+v", invoiceTypes)
+	}
+	if invoiceTypes[1].Description != "Nota de Crédito A" || !invoiceTypes[1].Active {
+		t.Errorf("Invoice type should keep the AFIP description, got %+v", invoiceTypes[1])
+	}
+	if invoiceTypes[4].Active {
+		t.Errorf("Invoice type with FchHasta in the past should be inactive, got %+v", invoiceTypes[4])
+	}
+	if server.Calls(testutil.ActionFEParamGetTiposCbte) != 1 {
+		t.Errorf("GetInvoiceTypes() should call FEParamGetTiposCbte")
+	}
+}
+
+func TestRequireActivities(t *testing.T) {
+	_, _, service := newFakeAFIPService(t)
+	service.SetRequireActivities(true)
+
+	_, err := service.AuthorizeInvoice(context.Background(), newTestWSFEInvoice())
+	var validationErrs models.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		t.Fatalf("AuthorizeInvoice() should fail validation without activity codes, got %v", err)
+	}
+	found := false
+	for _, validationErr := range validationErrs {
+		found = found || validationErr.Field == "activity_codes"
+	}
+	if !found {
+		t.Errorf("Validation should report activity_codes, got %v", err)
+	}
+}
+
+func TestIVABreakdownMapping(t *testing.T) {
+	config := client.DefaultConfig()
+	service := wsfe.NewService(&config, nil, nil)
+
+	invoice := newTestWSFEInvoice()
+	invoice.Items = append(invoice.Items,
+		models.Item{
+			Description: "Producto 2", Quantity: 1, UnitPrice: 500, TotalPrice: 500,
+			Taxes: []models.Tax{{Type: models.TaxTypeIVA, Rate: models.TaxRate21, Base: 500, Amount: 105}},
+		},
+		models.Item{
+			Description: "Libro", Quantity: 1, UnitPrice: 200, TotalPrice: 200,
+			Taxes: []models.Tax{{Type: models.TaxTypeIVA, Rate: models.TaxRate105, Base: 200, Amount: 21}},
+		},
+	)
+
+	request, err := service.NewAuthorizationRequest(invoice, wsfe.Auth{})
+	if err != nil {
+		t.Fatalf("NewAuthorizationRequest() error = %v", err)
+	}
+
+	want := []wsfe.AlicIva{{ID: 5, Base: 1500, Amount: 315}, {ID: 4, Base: 200, Amount: 21}}
+	if len(request.Request.Details[0].IVA) != len(want) {
+		t.Fatalf("Request should group IVA by rate, got %+v", request.Request.Details[0].IVA)
+	}
+	for i := range want {
+		if request.Request.Details[0].IVA[i] != want[i] {
+			t.Errorf("IVA[%d] = %+v, want %+v", i, request.Request.Details[0].IVA[i], want[i])
+		}
+	}
+}
+
+func TestClassCInvoice(t *testing.T) {
+	_, _, service := newFakeAFIPService(t)
+
+	invoice := newTestWSFEInvoice()
+	invoice.InvoiceType = models.InvoiceTypeC
+
+	// Con IVA discriminado se rechaza antes de llamar a AFIP
+	_, err := service.AuthorizeInvoice(context.Background(), invoice)
+	var validationErrs models.ValidationErrors
+	if !errors.As(err, &validationErrs) || !strings.Contains(err.Error(), "no discrimina IVA") {
+		t.Fatalf("Factura C with IVA should fail validation, got %v", err)
+	}
+
+	// Sin IVA el request no lleva alícuotas
+	invoice.TaxAmount = 0
+	invoice.TotalAmount = 1000
+	invoice.Items[0].Taxes = nil
+
+	request, err := service.NewAuthorizationRequest(invoice, wsfe.Auth{})
+	if err != nil {
+		t.Fatalf("NewAuthorizationRequest() error = %v", err)
+	}
+	if len(request.Request.Details[0].IVA) != 0 || request.Request.Details[0].TaxAmount != 0 {
+		t.Errorf("Factura C request should not carry IVA, got %+v (ImpIVA %v)", request.Request.Details[0].IVA, request.Request.Details[0].TaxAmount)
+	}
+}
+
+func TestUsedGoodsRegime(t *testing.T) {
+	_, _, service := newFakeAFIPService(t)
+	service.SetRegimeContext(wsfe.UsedGoodsRegime{})
+
+	// Sin vendedor y con IVA discriminado se rechaza antes de llamar a AFIP
+	_, err := service.AuthorizeInvoice(context.Background(), newTestWSFEInvoice())
+	var validationErrs models.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		t.Fatalf("AuthorizeInvoice() should apply the regime validation, got %v", err)
+	}
+	fields := map[string]bool{}
+	for _, validationErr := range validationErrs {
+		fields[validationErr.Field] = true
+	}
+	if !fields["used_goods_seller"] || !fields["tax_amount"] {
+		t.Errorf("Validation should report the seller and the IVA, got %v", err)
+	}
+
+	invoice := newTestWSFEInvoice()
+	invoice.TaxAmount = 0
+	invoice.TotalAmount = 1000
+	invoice.Items[0].Taxes = nil
+	invoice.UsedGoodsSeller = &models.UsedGoodsSeller{Name: "Juan Pérez", Nationality: "Argentina", Address: "Av. Siempreviva 742"}
+
+	if _, err := service.BuildAuthorizeRequestXML(invoice); err != nil {
+		t.Fatalf("BuildAuthorizeRequestXML() error = %v", err)
+	}
+
+	request, err := service.NewAuthorizationRequest(invoice, wsfe.Auth{})
+	if err != nil {
+		t.Fatalf("NewAuthorizationRequest() error = %v", err)
+	}
+	want := []wsfe.Optional{
+		{ID: models.OptionalIDUsedGoodsSellerName, Value: "Juan Pérez"},
+		{ID: models.OptionalIDUsedGoodsSellerNationality, Value: "Argentina"},
+		{ID: models.OptionalIDUsedGoodsSellerAddress, Value: "Av. Siempreviva 742"},
+	}
+	optionals := request.Request.Details[0].Optionals
+	if len(optionals) != len(want) {
+		t.Fatalf("Request should carry the seller optionals, got %+v", optionals)
+	}
+	for i := range want {
+		if optionals[i] != want[i] {
+			t.Errorf("Optionals[%d] = %+v, want %+v", i, optionals[i], want[i])
+		}
+	}
+}
+
+func TestInvoiceValidate(t *testing.T) {
+	invoice := newTestWSFEInvoice()
+	if err := invoice.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	invoice.PointOfSale = 0
+	invoice.TotalAmount = 1000
+	err := invoice.Validate()
+	var validationErrs models.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		t.Fatalf("Validate() should return ValidationErrors, got %v", err)
+	}
+	fields := map[string]bool{}
+	for _, validationErr := range validationErrs {
+		fields[validationErr.Field] = true
+	}
+	if !fields["point_of_sale"] || !fields["total_amount"] {
+		t.Errorf("Validate() should report point_of_sale and total_amount, got %v", err)
+	}
+
+	// AuthorizeInvoice rechaza lo mismo sin llamar a AFIP
+	server, _, service := newFakeAFIPService(t)
+	if _, err := service.AuthorizeInvoice(context.Background(), invoice); !errors.As(err, &validationErrs) {
+		t.Errorf("AuthorizeInvoice() should return the Validate() errors, got %v", err)
+	}
+	if calls := server.Calls(testutil.ActionFECAESolicitar); calls != 0 {
+		t.Errorf("Invalid invoices should not reach AFIP, got %d calls", calls)
+	}
+
+	modelInvoice := &models.Invoice{InvoiceBase: models.InvoiceBase{ConceptType: models.ConceptTypeServices}}
+	if err := modelInvoice.Validate(); !errors.As(err, &validationErrs) || len(validationErrs) != 5 {
+		t.Errorf("models.Invoice.Validate() should report the 5 missing fields, got %v", err)
+	}
+}
+
+func TestConsumidorFinalThreshold(t *testing.T) {
+	config := client.DefaultConfig()
+	service := wsfe.NewService(&config, nil, nil)
+
+	invoice := newTestWSFEInvoice()
+	invoice.InvoiceType = models.InvoiceTypeB
+	invoice.DocType = models.DocumentTypeConsumidorFinal
+	invoice.DocNumber = "0"
+	invoice.ReceptorIVACondition = models.ReceptorIVAConditionFinalConsumer
+
+	if _, err := service.BuildAuthorizeRequestXML(invoice); err != nil {
+		t.Fatalf("Anonymous consumidor final below the threshold should be accepted, got %v", err)
+	}
+
+	service.SetConsumidorFinalThreshold(1000)
+	_, err := service.BuildAuthorizeRequestXML(invoice)
+	var validationErrs models.ValidationErrors
+	if !errors.As(err, &validationErrs) || validationErrs[0].Field != "doc_type" || !strings.Contains(validationErrs[0].Message, "identificar") {
+		t.Errorf("Anonymous consumidor final above the threshold should be rejected, got %v", err)
+	}
+
+	invoice.DocType = models.DocumentTypeDNI
+	invoice.DocNumber = "12345678"
+	if _, err := service.BuildAuthorizeRequestXML(invoice); err != nil {
+		t.Errorf("Identified consumidor final above the threshold should be accepted, got %v", err)
+	}
+
+	if err := utils.ValidateConsumidorFinalAmount(models.DocumentTypeConsumidorFinal, utils.ConsumidorFinalThreshold, 0); err == nil {
+		t.Error("ValidateConsumidorFinalAmount() should default to the AFIP threshold")
+	}
+}
+
+func TestNextInvoiceNumber(t *testing.T) {
+	server, _, service := newFakeAFIPService(t)
+	server.SetResult(testutil.ActionFECompUltimoAutorizado, `<PtoVta>1</PtoVta><CbteTipo>6</CbteTipo><CbteNro>41</CbteNro>`)
+
+	next, err := service.NextInvoiceNumber(context.Background(), 1, int(models.InvoiceTypeB))
+	if err != nil {
+		t.Fatalf("NextInvoiceNumber() error = %v", err)
+	}
+	if next != 42 {
+		t.Errorf("NextInvoiceNumber() should return the last authorized + 1, got %d", next)
+	}
+
+	_, exportService := newFakeAFIPExportService(t)
+	next, err = exportService.NextInvoiceNumber(context.Background(), 1, int(models.InvoiceTypeE))
+	if err != nil {
+		t.Fatalf("NextInvoiceNumber() error = %v", err)
+	}
+	if next != 1 {
+		t.Errorf("NextInvoiceNumber() should start at 1 without authorized invoices, got %d", next)
+	}
+}
+
+func TestGetLastAuthorizedInvoiceEmpty(t *testing.T) {
+	server, _, service := newFakeAFIPService(t)
+	server.SetResult(testutil.ActionFECompUltimoAutorizado, `<PtoVta>3</PtoVta><CbteTipo>6</CbteTipo><CbteNro>0</CbteNro>`)
+
+	last, err := service.GetLastAuthorizedInvoice(context.Background(), 3, int(models.InvoiceTypeB))
+	if err != nil {
+		t.Fatalf("GetLastAuthorizedInvoice() error = %v", err)
+	}
+	if !last.IsEmpty() || last.Status != "" {
+		t.Errorf("GetLastAuthorizedInvoice() should return an empty result without status, got %+v", last)
+	}
+	if last.PointOfSale != 3 || last.InvoiceType != models.InvoiceTypeB {
+		t.Errorf("Empty result should keep the point of sale and type, got %+v", last)
+	}
+
+	server.SetResult(testutil.ActionFECompUltimoAutorizado, `<PtoVta>3</PtoVta><CbteTipo>6</CbteTipo><CbteNro>12</CbteNro>`)
+	last, err = service.GetLastAuthorizedInvoice(context.Background(), 3, int(models.InvoiceTypeB))
+	if err != nil {
+		t.Fatalf("GetLastAuthorizedInvoice() error = %v", err)
+	}
+	if last.IsEmpty() || last.InvoiceNumber != 12 || last.Status != models.AuthResultApproved {
+		t.Errorf("GetLastAuthorizedInvoice() should return the last approved invoice, got %+v", last)
+	}
+}
+
+func TestGetInvoiceNotFound(t *testing.T) {
+	server, _, service := newFakeAFIPService(t)
+	server.SetResult(testutil.ActionFECompConsultar,
+		`<Errors><Err><Code>602</Code><Msg>Sin Resultados: - en FECompConsultar</Msg></Err></Errors>`)
+
+	_, err := service.GetInvoice(context.Background(), 1, int(models.InvoiceTypeA), 99)
+	if !errors.Is(err, models.ErrInvoiceNotFound) {
+		t.Fatalf("GetInvoice() should return ErrInvoiceNotFound, got %v", err)
+	}
+	var arcaErr *models.ARCAError
+	if !errors.As(err, &arcaErr) || arcaErr.Code != models.ErrorCodeInvoiceNotFound {
+		t.Errorf("GetInvoice() should keep the AFIP error, got %v", err)
+	}
+
+	server.SetHTTPStatus(testutil.ActionFECompConsultar, http.StatusInternalServerError)
+	_, err = service.GetInvoice(context.Background(), 1, int(models.InvoiceTypeA), 99)
+	if err == nil || errors.Is(err, models.ErrInvoiceNotFound) {
+		t.Errorf("A transport failure should not be reported as not found, got %v", err)
+	}
+}
+
+func TestQueryInvoiceRange(t *testing.T) {
+	server, config, service := newFakeAFIPService(t)
+	ctx := context.Background()
+
+	// El comprobante 3 no existe en AFIP
+	config.WithInterceptor(client.InterceptorFunc(func(ctx context.Context, action string, req []byte, next func([]byte) ([]byte, error)) ([]byte, error) {
+		if action == testutil.ActionFECompConsultar && strings.Contains(string(req), "<CbteNro>3</CbteNro>") {
+			return []byte(`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body>` +
+				`<FECompConsultarResponse><FECompConsultarResult><Errors><Err><Code>602</Code><Msg>Sin Resultados</Msg></Err></Errors>` +
+				`</FECompConsultarResult></FECompConsultarResponse></soap:Body></soap:Envelope>`), nil
+		}
+		return next(req)
+	}))
+
+	invoices, err := service.QueryInvoiceRange(ctx, 1, int(models.InvoiceTypeA), 1, 5)
+	if len(invoices) != 5 || server.Calls(testutil.ActionFECompConsultar) != 4 {
+		t.Fatalf("QueryInvoiceRange() should query every number, got %d results and %d calls", len(invoices), server.Calls(testutil.ActionFECompConsultar))
+	}
+	var batchErr *models.BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Errors) != 1 || !errors.Is(batchErr.Errors[2], models.ErrInvoiceNotFound) {
+		t.Fatalf("QueryInvoiceRange() should report the gap at position 2, got %v", err)
+	}
+	for i, invoice := range invoices {
+		if (invoice == nil) != (i == 2) {
+			t.Errorf("Position %d should be nil only for the missing invoice, got %+v", i, invoice)
+		}
+	}
+
+	if _, err := service.QueryInvoiceRange(ctx, 1, int(models.InvoiceTypeA), 5, 1); err == nil {
+		t.Error("QueryInvoiceRange() should reject a reversed range")
+	}
+	if _, err := service.QueryInvoiceRange(ctx, 1, int(models.InvoiceTypeA), 1, wsfe.MaxQueryRange+1); err == nil {
+		t.Error("QueryInvoiceRange() should reject ranges over MaxQueryRange")
+	}
+}
+
+func TestIssueInvoice(t *testing.T) {
+	server, _, service := newFakeAFIPService(t)
+	server.SetResult(testutil.ActionFECompUltimoAutorizado, `<PtoVta>1</PtoVta><CbteTipo>1</CbteTipo><CbteNro>41</CbteNro>`)
+	server.SetResult(testutil.ActionFECAESolicitar, `<FeCabResp><Cuit>20123456786</Cuit><PtoVta>1</PtoVta><CbteTipo>1</CbteTipo>`+
+		`<FchProceso>20240115103000</FchProceso><CantReg>1</CantReg><Resultado>A</Resultado></FeCabResp>`+
+		`<FeDetResp><FECAEDetResponse><Concepto>1</Concepto><DocTipo>80</DocTipo><DocNro>20123456786</DocNro>`+
+		`<CbteDesde>42</CbteDesde><CbteHasta>42</CbteHasta><CbteFch>20240115</CbteFch><Resultado>A</Resultado>`+
+		`<CAE>74123456789012</CAE><CAEFchVto>20240125</CAEFchVto></FECAEDetResponse></FeDetResp>`)
+
+	invoice := newTestWSFEInvoice()
+	invoice.InvoiceNumber = 0
+	issued, err := service.IssueInvoice(context.Background(), invoice)
+	if err != nil {
+		t.Fatalf("IssueInvoice() error = %v", err)
+	}
+	if issued.InvoiceNumber != 42 || invoice.InvoiceNumber != 42 {
+		t.Errorf("IssueInvoice() should number the invoice from the last authorized, got %d", issued.InvoiceNumber)
+	}
+	if issued.CAE != "74123456789012" || invoice.CAE != issued.CAE || !issued.CAEExpirationDate.Equal(time.Date(2024, 1, 25, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("IssueInvoice() should attach the CAE, got %q %v", issued.CAE, issued.CAEExpirationDate)
+	}
+
+	payload, ok := strings.CutPrefix(issued.QRURL, models.QRBaseURL+"?p=")
+	if !ok {
+		t.Fatalf("QR URL should point to %s, got %s", models.QRBaseURL, issued.QRURL)
+	}
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		t.Fatalf("QR payload should be base64: %v", err)
+	}
+	var qr models.QRData
+	if err := json.Unmarshal(data, &qr); err != nil {
+		t.Fatalf("QR payload should be JSON: %v", err)
+	}
+	if qr.CUIT != 20123456786 || qr.InvoiceNumber != 42 || qr.AuthCode != 74123456789012 || qr.AuthType != models.QRAuthTypeCAE ||
+		qr.Amount != 1210 || qr.DocNumber != 20123456786 || qr.Date != invoice.DateFrom.Format("2006-01-02") {
+		t.Errorf("QR payload should describe the invoice, got %+v", qr)
+	}
+
+	server.SetResult(testutil.ActionFECAESolicitar, `<FeCabResp><PtoVta>1</PtoVta><CbteTipo>1</CbteTipo><Resultado>R</Resultado></FeCabResp>`+
+		`<FeDetResp><FECAEDetResponse><CbteDesde>43</CbteDesde><Resultado>R</Resultado>`+
+		`<Observaciones><Obs><Code>10016</Code><Msg>El número de comprobante no es correlativo</Msg></Obs></Observaciones>`+
+		`</FECAEDetResponse></FeDetResp>`)
+	invoice = newTestWSFEInvoice()
+	invoice.InvoiceNumber = 43
+	if _, err := service.IssueInvoice(context.Background(), invoice); err == nil || !strings.Contains(err.Error(), "no es correlativo") {
+		t.Errorf("IssueInvoice() should fail with the AFIP observations when rejected, got %v", err)
+	}
+}
+
+func TestCancelInvoice(t *testing.T) {
+	server, _, service := newFakeAFIPService(t)
+	server.SetResult(testutil.ActionFECompConsultar, `<ResultGet><Concepto>1</Concepto><DocTipo>11</DocTipo><DocNro>20123456786</DocNro>`+
+		`<CbteDesde>42</CbteDesde><CbteHasta>42</CbteHasta><CbteFch>20240115</CbteFch><ImpTotal>1210</ImpTotal><ImpTotConc>0</ImpTotConc>`+
+		`<ImpNeto>1000</ImpNeto><ImpOpEx>0</ImpOpEx><ImpTrib>0</ImpTrib><ImpIVA>210</ImpIVA><MonId>PES</MonId><MonCotiz>1</MonCotiz>`+
+		`<CondicionIVAReceptorId>1</CondicionIVAReceptorId><Iva><AlicIva><Id>5</Id><BaseImp>1000</BaseImp><Importe>210</Importe></AlicIva></Iva>`+
+		`<Resultado>A</Resultado><CodAutorizacion>74123456789012</CodAutorizacion><EmisionTipo>CAE</EmisionTipo>`+
+		`<FchVto>20240125</FchVto><PtoVta>1</PtoVta><CbteTipo>1</CbteTipo></ResultGet>`)
+	server.SetResult(testutil.ActionFECompUltimoAutorizado, `<PtoVta>1</PtoVta><CbteTipo>3</CbteTipo><CbteNro>6</CbteNro>`)
+	server.SetResult(testutil.ActionFECAESolicitar, `<FeCabResp><PtoVta>1</PtoVta><CbteTipo>3</CbteTipo><Resultado>A</Resultado></FeCabResp>`+
+		`<FeDetResp><FECAEDetResponse><CbteDesde>7</CbteDesde><Resultado>A</Resultado>`+
+		`<CAE>74123456789099</CAE><CAEFchVto>20240125</CAEFchVto></FECAEDetResponse></FeDetResp>`)
+
+	original := &models.AuthorizationResult{
+		CAE:           "74123456789012",
+		InvoiceNumber: 42,
+		PointOfSale:   1,
+		InvoiceType:   models.InvoiceTypeA,
+		Status:        models.AuthResultApproved,
+	}
+	result, err := service.CancelInvoice(context.Background(), original)
+	if err != nil {
+		t.Fatalf("CancelInvoice() error = %v", err)
+	}
+	if result.InvoiceType != models.InvoiceTypeCreditNoteA || result.InvoiceNumber != 7 || result.CAE != "74123456789099" {
+		t.Errorf("CancelInvoice() should authorize credit note A 7, got %+v", result)
+	}
+
+	request := string(server.LastRequest(testutil.ActionFECAESolicitar))
+	for _, want := range []string{
+		"<CbteTipo>3</CbteTipo>",
+		"<CbteDesde>7</CbteDesde>",
+		"<ImpTotal>1210.00</ImpTotal>",
+		"<ImpNeto>1000.00</ImpNeto>",
+		"<ImpIVA>210.00</ImpIVA>",
+		"<BaseImp>1000.00</BaseImp>",
+		"<Tipo>1</Tipo>",
+		"<Nro>42</Nro>",
+	} {
+		if !strings.Contains(request, want) {
+			t.Errorf("Credit note request should contain %s, got %s", want, request)
+		}
+	}
+
+	original.InvoiceType = models.InvoiceTypeCreditNoteA
+	if _, err := service.CancelInvoice(context.Background(), original); err == nil {
+		t.Error("CancelInvoice() should reject credit notes")
+	}
+
+	original.InvoiceType = models.InvoiceTypeA
+	original.CAE = "74123456789000"
+	if _, err := service.CancelInvoice(context.Background(), original); err == nil {
+		t.Error("CancelInvoice() should reject a CAE that does not match AFIP's")
+	}
+}
@@ -0,0 +1,603 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dlarregola/arca_invoice_lib/internal/soap"
+	"github.com/dlarregola/arca_invoice_lib/pkg/client"
+	"github.com/dlarregola/arca_invoice_lib/pkg/models"
+	"github.com/dlarregola/arca_invoice_lib/pkg/testutil"
+	"github.com/dlarregola/arca_invoice_lib/pkg/wsfex"
+)
+
+// newTestExportInvoice crea una factura de exportación de bienes válida para tests
+func newTestExportInvoice() *wsfex.ExportInvoice {
+	return &wsfex.ExportInvoice{
+		InvoiceBase: models.InvoiceBase{
+			InvoiceType:   models.InvoiceTypeE,
+			PointOfSale:   1,
+			InvoiceNumber: 1,
+			DateFrom:      time.Now(),
+			DateTo:        time.Now(),
+			ConceptType:   models.ConceptTypeProducts,
+			CurrencyType:  models.CurrencyTypeUSD,
+			CurrencyRate:  1050.5,
+			Amount:        1000,
+			TotalAmount:   1000,
+			Items: []models.Item{
+				{Description: "Producto de exportación", Quantity: 1, UnitPrice: 1000, TotalPrice: 1000},
+			},
+		},
+		DocType:       models.DocumentTypeCUIT,
+		DocNumber:     "20-12345678-6",
+		DocTypeFrom:   models.DocumentTypeCUIT,
+		DocNumberFrom: "20-12345678-6",
+		CountryFrom:   "212",
+		Incoterm:      "FOB",
+	}
+}
+
+func newFakeAFIPExportService(t *testing.T) (*testutil.FakeAFIPServer, *wsfex.Service) {
+	t.Helper()
+
+	server := testutil.NewFakeAFIPServer()
+	t.Cleanup(server.Close)
+
+	config, err := server.Config()
+	if err != nil {
+		t.Fatalf("server.Config() error = %v", err)
+	}
+
+	auth := client.NewWSAAAuth(&config, nil)
+	return server, wsfex.NewService(&config, auth, nil)
+}
+
+func TestExportGetCurrencyRate(t *testing.T) {
+	server, service := newFakeAFIPExportService(t)
+	ctx := context.Background()
+
+	rate, err := service.GetCurrencyRate(ctx, "DOL")
+	if err != nil {
+		t.Fatalf("GetCurrencyRate() error = %v", err)
+	}
+	if rate != 1050.5 {
+		t.Errorf("GetCurrencyRate() should return the AFIP rate, got %v", rate)
+	}
+	if calls := server.Calls(testutil.ActionFEXGetPARAMCtz); calls != 1 {
+		t.Errorf("FEXGetPARAM_Ctz should be called once, got %d", calls)
+	}
+
+	if _, err := service.GetCurrencyRate(ctx, ""); err == nil {
+		t.Error("GetCurrencyRate() should reject an empty currency")
+	}
+}
+
+func TestExportCountriesAndIncoterms(t *testing.T) {
+	_, service := newFakeAFIPExportService(t)
+	ctx := context.Background()
+
+	countries, err := service.GetCountries(ctx)
+	if err != nil {
+		t.Fatalf("GetCountries() error = %v", err)
+	}
+	if len(countries) != 3 || countries[2].ID != "212" || countries[2].Description != "ESTADOS UNIDOS" {
+		t.Errorf("GetCountries() should map the AFIP destinations, got %+v", countries)
+	}
+
+	incoterms, err := service.GetIncoterms(ctx)
+	if err != nil {
+		t.Fatalf("GetIncoterms() error = %v", err)
+	}
+	if len(incoterms) != 3 || incoterms[1].ID != "FOB" || !incoterms[1].Active {
+		t.Errorf("GetIncoterms() should map the AFIP incoterms, got %+v", incoterms)
+	}
+}
+
+func TestExportAuthorizationRequestExportData(t *testing.T) {
+	config := client.DefaultConfig()
+	service := wsfex.NewService(&config, nil, nil)
+
+	invoice := newTestExportInvoice()
+	invoice.IncotermDescription = "Free On Board"
+	invoice.Permits = []models.ExportPermit{{ID: "24001EC01000123X", DestinationCountry: "212"}}
+	invoice.AssociatedInvoices = []models.AssociatedInvoice{
+		{InvoiceType: models.InvoiceTypeE, PointOfSale: 1, InvoiceNumber: 10, CUIT: "20123456786"},
+	}
+
+	request := service.NewAuthorizationRequest(invoice, wsfex.Auth{}).Request
+	if request.Incoterm != "FOB" || request.IncotermDescription != "Free On Board" {
+		t.Errorf("Request should carry the incoterm, got %q %q", request.Incoterm, request.IncotermDescription)
+	}
+	if request.PermitExists != "S" || len(request.Permits) != 1 || request.Permits[0].DestinationCountry != "212" {
+		t.Errorf("Request should carry the export permits, got %q %+v", request.PermitExists, request.Permits)
+	}
+	if len(request.AssociatedInvoices) != 1 || request.AssociatedInvoices[0].InvoiceType != int(models.InvoiceTypeE) || request.AssociatedInvoices[0].InvoiceNumber != 10 {
+		t.Errorf("Request should carry the associated invoices, got %+v", request.AssociatedInvoices)
+	}
+
+	invoice.Permits = nil
+	if request := service.NewAuthorizationRequest(invoice, wsfex.Auth{}).Request; request.PermitExists != "N" {
+		t.Errorf("Goods export without permits should send Permiso_existente N, got %q", request.PermitExists)
+	}
+}
+
+func TestExportAuthorizationRequestXML(t *testing.T) {
+	config := client.DefaultConfig()
+	service := wsfex.NewService(&config, nil, nil)
+
+	invoice := newTestExportInvoice()
+	invoice.CustomerName = "ACME Inc."
+	invoice.CustomerAddress = "1 Main St, Miami"
+
+	auth := wsfex.Auth{Token: "token", Sign: "sign", CUIT: "20123456786"}
+	body, err := xml.Marshal(service.NewAuthorizationRequest(invoice, auth))
+	if err != nil {
+		t.Fatalf("xml.Marshal() error = %v", err)
+	}
+	for _, want := range []string{
+		"<Auth><Token>token</Token><Sign>sign</Sign><Cuit>20123456786</Cuit></Auth>",
+		"<Cmp><Id>1</Id><Fecha_cbte>",
+		"<Cbte_Tipo>19</Cbte_Tipo><Punto_vta>1</Punto_vta><Cbte_nro>1</Cbte_nro><Tipo_expo>1</Tipo_expo><Permiso_existente>N</Permiso_existente>",
+		"<Dst_cmp>212</Dst_cmp><Cliente>ACME Inc.</Cliente>",
+		"<Moneda_ctz>1050.5</Moneda_ctz><Imp_total>1000.00</Imp_total><Incoterms>",
+		"<Incoterms>FOB</Incoterms><Idioma_cbte>1</Idioma_cbte>",
+		"<Items><Item><Pro_codigo></Pro_codigo><Pro_ds>Producto de exportación</Pro_ds><Pro_qty>1</Pro_qty>",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("Request XML should contain %s, got %s", want, body)
+		}
+	}
+	if err := soap.ValidateRequest("FEXAuthorize", body); err != nil {
+		t.Errorf("Request XML should match the FEXAuthorize cardinality: %v", err)
+	}
+}
+
+func TestExportBuildAuthorizeRequestXML(t *testing.T) {
+	server, service := newFakeAFIPExportService(t)
+
+	envelope, err := service.BuildAuthorizeRequestXML(newTestExportInvoice())
+	if err != nil {
+		t.Fatalf("BuildAuthorizeRequestXML() error = %v", err)
+	}
+	if !strings.Contains(string(envelope), "Envelope") || !strings.Contains(string(envelope), "<Cmp>") {
+		t.Errorf("Envelope should wrap the FEXAuthorize request, got %s", envelope)
+	}
+	if server.Calls(testutil.ActionLoginCms) != 0 {
+		t.Error("BuildAuthorizeRequestXML() should not call WSAA")
+	}
+
+	invoice := newTestExportInvoice()
+	invoice.Incoterm = ""
+	_, err = service.BuildAuthorizeRequestXML(invoice)
+	var validationErrs models.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		t.Errorf("BuildAuthorizeRequestXML() should validate the invoice, got %v", err)
+	}
+}
+
+func TestExportValidateAndBuild(t *testing.T) {
+	server, service := newFakeAFIPExportService(t)
+
+	result, err := service.ValidateAndBuild(newTestExportInvoice())
+	if err != nil {
+		t.Fatalf("ValidateAndBuild() error = %v", err)
+	}
+	if !result.Valid() || result.Request == nil || !strings.Contains(string(result.Envelope), "<Cmp>") {
+		t.Errorf("A valid export invoice should return the request and envelope, got %+v", result)
+	}
+
+	invoice := newTestExportInvoice()
+	invoice.Incoterm = ""
+	result, err = service.ValidateAndBuild(invoice)
+	if err != nil || result.Valid() || result.ValidationErrors[0].Field != "incoterm" {
+		t.Errorf("ValidateAndBuild() should report the missing Incoterm in the result, got %+v, %v", result, err)
+	}
+
+	if server.Calls(testutil.ActionLoginCms) != 0 || server.Calls(testutil.ActionFEXAuthorize) != 0 {
+		t.Error("ValidateAndBuild() should not call WSAA or WSFEX")
+	}
+}
+
+func TestExportCreditNoteAssociatedInvoices(t *testing.T) {
+	_, service := newFakeAFIPExportService(t)
+
+	invoice := newTestExportInvoice()
+	invoice.InvoiceType = models.InvoiceTypeCreditNoteE
+	result, err := service.ValidateAndBuild(invoice)
+	if err != nil || result.Valid() || result.ValidationErrors[0].Field != "associated_invoices" {
+		t.Errorf("Export credit notes without Cmps_asoc should be rejected, got %+v, %v", result, err)
+	}
+
+	invoice.AssociatedInvoices = []models.AssociatedInvoice{{InvoiceType: models.InvoiceTypeA, PointOfSale: 1, InvoiceNumber: 10}}
+	result, err = service.ValidateAndBuild(invoice)
+	if err != nil || result.Valid() || result.ValidationErrors[0].Field != "associated_invoices" {
+		t.Errorf("Export credit notes should reference export invoices, got %+v, %v", result, err)
+	}
+
+	invoice.AssociatedInvoices = []models.AssociatedInvoice{{InvoiceType: models.InvoiceTypeE, PointOfSale: 1, InvoiceNumber: 10}}
+	result, err = service.ValidateAndBuild(invoice)
+	if err != nil || !result.Valid() {
+		t.Fatalf("Export credit note with Cmps_asoc should be valid, got %+v, %v", result, err)
+	}
+	envelope := string(result.Envelope)
+	for _, want := range []string{"<Cmps_asoc>", "<Cmp_asoc>", "<Cbte_tipo>19</Cbte_tipo>", "<Cbte_nro>10</Cbte_nro>"} {
+		if !strings.Contains(envelope, want) {
+			t.Errorf("Request should include %s for the associated invoice", want)
+		}
+	}
+
+	modelInvoice := &models.ExportInvoice{
+		InvoiceBase: invoice.InvoiceBase,
+		Destination: "212",
+		ExportType:  models.ExportTypeGoods,
+	}
+	var validationErrs models.ValidationErrors
+	if err := modelInvoice.Validate(); !errors.As(err, &validationErrs) || validationErrs[0].Field != "associated_invoices" {
+		t.Errorf("models.ExportInvoice.Validate() should require associated invoices on credit notes, got %v", err)
+	}
+}
+
+func TestExportInvoiceRequiresIncoterm(t *testing.T) {
+	config := client.DefaultConfig()
+	service := wsfex.NewService(&config, nil, nil)
+
+	invoice := newTestExportInvoice()
+	invoice.Incoterm = ""
+
+	_, err := service.AuthorizeExportInvoice(context.Background(), invoice)
+	var validationErrs models.ValidationErrors
+	if !errors.As(err, &validationErrs) || validationErrs[0].Field != "incoterm" {
+		t.Errorf("AuthorizeExportInvoice() should reject goods exports without incoterm, got %v", err)
+	}
+
+	invoice.Incoterm = "FOB"
+	invoice.ConceptType = models.ConceptTypeServices
+	invoice.Permits = []models.ExportPermit{{ID: "24001EC01000123X", DestinationCountry: "212"}}
+	_, err = service.AuthorizeExportInvoice(context.Background(), invoice)
+	if !errors.As(err, &validationErrs) || validationErrs[0].Field != "permits" {
+		t.Errorf("AuthorizeExportInvoice() should reject permits on service exports, got %v", err)
+	}
+}
+
+func TestExportBuyers(t *testing.T) {
+	config := client.DefaultConfig()
+	service := wsfex.NewService(&config, nil, nil)
+
+	invoice := newTestExportInvoice()
+	invoice.Buyers = []models.ExportBuyer{
+		{DocType: models.DocumentTypeCUIT, DocNumber: "55000002206", Percentage: 60},
+		{DocType: models.DocumentTypeCUIT, DocNumber: "55000002214", Percentage: 40},
+	}
+	if err := invoice.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	body, err := xml.Marshal(service.NewAuthorizationRequest(invoice, wsfex.Auth{}))
+	if err != nil {
+		t.Fatalf("xml.Marshal() error = %v", err)
+	}
+	want := "<Compradores><Comprador><Tipo_doc>11</Tipo_doc><Nro_doc>55000002206</Nro_doc><Porcentaje>60.00</Porcentaje></Comprador>" +
+		"<Comprador><Tipo_doc>11</Tipo_doc><Nro_doc>55000002214</Nro_doc><Porcentaje>40.00</Porcentaje></Comprador></Compradores>"
+	if !strings.Contains(string(body), want) {
+		t.Errorf("Request XML should contain %s, got %s", want, body)
+	}
+
+	invoice.Buyers[1].Percentage = 30
+	var validationErrs models.ValidationErrors
+	if err := invoice.Validate(); !errors.As(err, &validationErrs) || validationErrs[0].Field != "buyers" {
+		t.Errorf("Validate() should reject buyer shares that do not add up to 100, got %v", err)
+	}
+
+	invoice.Buyers = nil
+	body, _ = xml.Marshal(service.NewAuthorizationRequest(invoice, wsfex.Auth{}))
+	if strings.Contains(string(body), "Compradores") {
+		t.Errorf("Request without buyers should not send Compradores, got %s", body)
+	}
+}
+
+func TestExportServiceDates(t *testing.T) {
+	config := client.DefaultConfig()
+	service := wsfex.NewService(&config, nil, nil)
+
+	invoice := newTestExportInvoice()
+	invoice.ConceptType = models.ConceptTypeServices
+	var validationErrs models.ValidationErrors
+	if err := invoice.Validate(); !errors.As(err, &validationErrs) || validationErrs[0].Field != "service_dates" {
+		t.Errorf("Services invoice without dates should fail on service_dates, got %v", err)
+	}
+
+	// FEXAuthorize no recibe el período del servicio, sólo la fecha de pago
+	today := time.Now()
+	invoice.PaymentDueDate = today
+	if err := invoice.Validate(); err != nil {
+		t.Fatalf("Validate() without service period error = %v", err)
+	}
+	invoice.ServiceFrom = today
+	invoice.ServiceTo = today
+	if err := invoice.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	body, err := xml.Marshal(service.NewAuthorizationRequest(invoice, wsfex.Auth{}))
+	if err != nil {
+		t.Fatalf("xml.Marshal() error = %v", err)
+	}
+	if want := "<Fecha_pago>" + today.Format("20060102") + "</Fecha_pago>"; !strings.Contains(string(body), want) {
+		t.Errorf("Request XML should contain %s, got %s", want, body)
+	}
+
+	invoice.ConceptType = models.ConceptTypeProducts
+	if err := invoice.Validate(); !errors.As(err, &validationErrs) || validationErrs[0].Field != "service_dates" {
+		t.Errorf("Products invoice with dates should fail on service_dates, got %v", err)
+	}
+}
+
+func TestExportType(t *testing.T) {
+	config := client.DefaultConfig()
+	service := wsfex.NewService(&config, nil, nil)
+
+	if models.ExportTypeServices.String() != "Servicios" || models.ExportType(3).String() != "ExportType(3)" {
+		t.Errorf("ExportType.String() should use the canonical names, got %s and %s", models.ExportTypeServices, models.ExportType(3))
+	}
+	data, err := json.Marshal(models.ExportTypeGoods)
+	if err != nil || string(data) != `"Bienes"` {
+		t.Errorf("ExportType should marshal to its name, got %s (%v)", data, err)
+	}
+
+	invoice := newTestExportInvoice()
+	body, _ := xml.Marshal(service.NewAuthorizationRequest(invoice, wsfex.Auth{}))
+	if !strings.Contains(string(body), "<Tipo_expo>1</Tipo_expo>") {
+		t.Errorf("Products export should default to Tipo_expo 1, got %s", body)
+	}
+
+	invoice.ExportType = models.ExportTypeServices
+	var validationErrs models.ValidationErrors
+	if err := invoice.Validate(); !errors.As(err, &validationErrs) || validationErrs[0].Field != "export_type" {
+		t.Errorf("Validate() should reject an export type that does not match the concept, got %v", err)
+	}
+
+	invoice.ExportType = models.ExportType(3)
+	if err := invoice.Validate(); !errors.As(err, &validationErrs) || validationErrs[0].Field != "export_type" {
+		t.Errorf("Validate() should reject unknown export types, got %v", err)
+	}
+}
+
+func TestExportGetLastAuthorized(t *testing.T) {
+	server, service := newFakeAFIPExportService(t)
+	ctx := context.Background()
+
+	last, err := service.GetLastAuthorizedExportInvoice(ctx, 3, int(models.InvoiceTypeE))
+	if err != nil {
+		t.Fatalf("GetLastAuthorizedExportInvoice() error = %v", err)
+	}
+	if last.InvoiceNumber != 0 || !last.Date.IsZero() {
+		t.Errorf("Point of sale without invoices should return number 0 and no date, got %+v", last)
+	}
+
+	server.SetResult(testutil.ActionFEXGetLastCMP,
+		"<FEXResult_LastCMP><Cbte_nro>15</Cbte_nro><Cbte_fecha>20240115</Cbte_fecha></FEXResult_LastCMP>")
+	last, err = service.GetLastAuthorizedExportInvoice(ctx, 3, int(models.InvoiceTypeE))
+	if err != nil {
+		t.Fatalf("GetLastAuthorizedExportInvoice() error = %v", err)
+	}
+	if last.InvoiceNumber != 15 || last.PointOfSale != 3 || last.InvoiceType != models.InvoiceTypeE {
+		t.Errorf("Last authorized should map number, point of sale and type, got %+v", last)
+	}
+	if want := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC); !last.Date.Equal(want) {
+		t.Errorf("Last authorized date should be %v, got %v", want, last.Date)
+	}
+
+	body := string(server.LastRequest(testutil.ActionFEXGetLastCMP))
+	if !strings.Contains(body, "<Pto_venta>3</Pto_venta>") || !strings.Contains(body, "<Cbte_Tipo>19</Cbte_Tipo>") {
+		t.Errorf("Request should carry Pto_venta and Cbte_Tipo in Auth, got %s", body)
+	}
+}
+
+func TestAuthorizeExportInvoices(t *testing.T) {
+	server, service := newFakeAFIPExportService(t)
+	server.SetResult(testutil.ActionFEXAuthorize, `<FEXResultAuth><Cae>74123456789012</Cae><Fch_venc_Cae>20240125</Fch_venc_Cae>`+
+		`<Fch_cbte>20240115</Fch_cbte><Resultado>A</Resultado><Punto_vta>3</Punto_vta><Cbte_nro>1</Cbte_nro><Cbte_tipo>19</Cbte_tipo></FEXResultAuth>`)
+
+	invoices := make([]*wsfex.ExportInvoice, 5)
+	for i := range invoices {
+		invoices[i] = newTestExportInvoice()
+		invoices[i].InvoiceNumber = i + 1
+	}
+	invoices[2].Incoterm = ""
+
+	results, err := service.AuthorizeExportInvoices(context.Background(), invoices, 2)
+	var batchErr *models.BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Errors) != 1 || batchErr.Errors[2] == nil {
+		t.Fatalf("AuthorizeExportInvoices() should report only the invalid invoice, got %v", err)
+	}
+	if len(results) != len(invoices) || results[2] != nil {
+		t.Fatalf("AuthorizeExportInvoices() should keep a nil result for the failed invoice, got %v", results)
+	}
+	for i, result := range results {
+		if i == 2 {
+			continue
+		}
+		if result == nil || result.CAE != "74123456789012" || result.PointOfSale != 3 || result.InvoiceNumber != 1 || result.InvoiceType != models.InvoiceTypeE {
+			t.Errorf("Result %d should be authorized, got %+v", i, result)
+		} else if !result.CAEExpirationDate.Equal(time.Date(2024, 1, 25, 0, 0, 0, 0, result.CAEExpirationDate.Location())) {
+			t.Errorf("Result %d should decode Fch_venc_Cae, got %v", i, result.CAEExpirationDate)
+		}
+	}
+	if calls := server.Calls(testutil.ActionFEXAuthorize); calls != 4 {
+		t.Errorf("FEXAuthorize should be called once per valid invoice, got %d", calls)
+	}
+}
+
+func TestExportInvoiceValidate(t *testing.T) {
+	invoice := newTestExportInvoice()
+	if err := invoice.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	invoice.CountryFrom = "200"
+	var validationErrs models.ValidationErrors
+	if err := invoice.Validate(); !errors.As(err, &validationErrs) || validationErrs[0].Field != "country_from" {
+		t.Errorf("Validate() should reject Argentina as destination, got %v", err)
+	}
+
+	modelInvoice := &models.ExportInvoice{}
+	if err := modelInvoice.Validate(); !errors.As(err, &validationErrs) || len(validationErrs) != 6 {
+		t.Errorf("models.ExportInvoice.Validate() should report the 6 missing fields, got %v", err)
+	}
+}
+
+func TestGetExportInvoice(t *testing.T) {
+	server, service := newFakeAFIPExportService(t)
+	server.SetResult(testutil.ActionFEXGetCMP, `<FEXResultGet><Id>7</Id><Fecha_cbte>20240115</Fecha_cbte>`+
+		`<Cbte_tipo>19</Cbte_tipo><Punto_vta>3</Punto_vta><Cbte_nro>7</Cbte_nro><Tipo_expo>1</Tipo_expo>`+
+		`<Permiso_existente>S</Permiso_existente><Permisos><Permiso><Id_permiso>24001EC01000123X</Id_permiso>`+
+		`<Dst_merc>212</Dst_merc></Permiso></Permisos><Dst_cmp>212</Dst_cmp><Cliente>ACME Inc.</Cliente>`+
+		`<Cuit_pais_cliente>50000000016</Cuit_pais_cliente><Domicilio_cliente>1 Main St, Miami</Domicilio_cliente>`+
+		`<Id_impositivo>99-1234567</Id_impositivo><Moneda_Id>DOL</Moneda_Id><Moneda_ctz>1050.5</Moneda_ctz>`+
+		`<Imp_total>1000</Imp_total><Incoterms>FOB</Incoterms><Items><Item><Pro_codigo>P1</Pro_codigo>`+
+		`<Pro_ds>Producto de exportación</Pro_ds><Pro_qty>2</Pro_qty><Pro_umed>7</Pro_umed>`+
+		`<Pro_precio_uni>500</Pro_precio_uni><Pro_total_item>1000</Pro_total_item></Item></Items>`+
+		`<Cae>74123456789012</Cae><Fch_venc_Cae>20240125</Fch_venc_Cae><Resultado>A</Resultado></FEXResultGet>`)
+
+	invoice, err := service.GetExportInvoice(context.Background(), 3, int(models.InvoiceTypeE), 7)
+	if err != nil {
+		t.Fatalf("GetExportInvoice() error = %v", err)
+	}
+
+	if invoice.CAE != "74123456789012" || !invoice.CAEDueDate.Equal(time.Date(2024, 1, 25, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("CAE should be mapped, got %s %v", invoice.CAE, invoice.CAEDueDate)
+	}
+	if invoice.InvoiceNumber != 7 || invoice.PointOfSale != 3 || invoice.TotalAmount != 1000 ||
+		!invoice.DateFrom.Equal(time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Invoice header should be mapped, got %+v", invoice.InvoiceBase)
+	}
+	if invoice.CountryFrom != "212" || invoice.CustomerName != "ACME Inc." || invoice.DocNumber != "99-1234567" {
+		t.Errorf("Destination and customer should be mapped, got %s %s %s", invoice.CountryFrom, invoice.CustomerName, invoice.DocNumber)
+	}
+	if invoice.ConceptType != models.ConceptTypeProducts || invoice.Incoterm != "FOB" || len(invoice.Permits) != 1 {
+		t.Errorf("Export data should be mapped, got %v %s %v", invoice.ConceptType, invoice.Incoterm, invoice.Permits)
+	}
+	if len(invoice.Items) != 1 || invoice.Items[0].ProductCode != "P1" || invoice.Items[0].Quantity != 2 {
+		t.Errorf("Items should be mapped, got %+v", invoice.Items)
+	}
+}
+
+func TestExportUnitMeasures(t *testing.T) {
+	server, service := newFakeAFIPExportService(t)
+	ctx := context.Background()
+
+	units, err := service.GetUnitMeasures(ctx)
+	if err != nil {
+		t.Fatalf("GetUnitMeasures() error = %v", err)
+	}
+	if len(units) != 3 || units[1].ID != "7" || !units[1].Active || units[2].Active {
+		t.Errorf("GetUnitMeasures() should map the AFIP units, got %+v", units)
+	}
+	if _, err := service.GetUnitMeasures(ctx); err != nil || server.Calls(testutil.ActionFEXGetPARAMUMed) != 1 {
+		t.Errorf("GetUnitMeasures() should cache the AFIP list, got %d calls (%v)", server.Calls(testutil.ActionFEXGetPARAMUMed), err)
+	}
+
+	for value, want := range map[string]string{"kg": "1", "Unidades": "7", "07": "7", " UN ": "7"} {
+		if code, ok := models.ParseUnitMeasure(value); !ok || code != want {
+			t.Errorf("ParseUnitMeasure(%q) = %q, %v, want %q", value, code, ok, want)
+		}
+	}
+
+	invoice := newTestExportInvoice()
+	invoice.Items[0].UnitMeasure = "kg"
+	body, err := service.BuildAuthorizeRequestXML(invoice)
+	if err != nil {
+		t.Fatalf("BuildAuthorizeRequestXML() error = %v", err)
+	}
+	if !strings.Contains(string(body), "<Pro_umed>1</Pro_umed>") {
+		t.Errorf("Unit measure should be sent as the Umed code, got %s", body)
+	}
+
+	invoice.Items[0].UnitMeasure = "bolsa"
+	var validationErrs models.ValidationErrors
+	if err := invoice.Validate(); !errors.As(err, &validationErrs) || validationErrs[0].Field != "unit_measure" {
+		t.Errorf("Validate() should reject unknown unit measures, got %v", err)
+	}
+
+	// Sin la lista de AFIP se acepta cualquier código Umed
+	invoice.Items[0].UnitMeasure = "99"
+	if err := invoice.Validate(); err != nil {
+		t.Errorf("Validate() should accept numeric codes missing from the local catalog, got %v", err)
+	}
+
+	// Con validación en vivo se usa la lista de AFIP: quilates está dado de baja
+	service.SetLiveUnitMeasureValidation(true)
+	invoice.Items[0].UnitMeasure = "10"
+	var validationErr *models.ValidationError
+	if _, err := service.AuthorizeExportInvoice(ctx, invoice); !errors.As(err, &validationErr) || validationErr.Field != "items[0].unit_measure" {
+		t.Errorf("AuthorizeExportInvoice() should reject units not active in AFIP, got %v", err)
+	}
+}
+
+func TestCheckExportPermit(t *testing.T) {
+	server, service := newFakeAFIPExportService(t)
+	ctx := context.Background()
+
+	ok, err := service.CheckExportPermit(ctx, "24001EC01000123A", "212")
+	if err != nil || !ok {
+		t.Fatalf("CheckExportPermit() should accept an existing permit, got %v, %v", ok, err)
+	}
+	request := string(server.LastRequest(testutil.ActionFEXCheckPermiso))
+	for _, want := range []string{"<ID_Permiso>24001EC01000123A</ID_Permiso>", "<Dst_merc>212</Dst_merc>"} {
+		if !strings.Contains(request, want) {
+			t.Errorf("Request should contain %s, got %s", want, request)
+		}
+	}
+
+	server.SetResult(testutil.ActionFEXCheckPermiso, `<FEXResultGet><Status>NO</Status></FEXResultGet>`)
+	if ok, err := service.CheckExportPermit(ctx, "24001EC01000999Z", "212"); err != nil || ok {
+		t.Errorf("CheckExportPermit() should report a non-existent permit as false, got %v, %v", ok, err)
+	}
+
+	if _, err := service.CheckExportPermit(ctx, "", "212"); err == nil {
+		t.Error("CheckExportPermit() should reject an empty permit")
+	}
+	if _, err := service.CheckExportPermit(ctx, "24001EC01000123A", "200"); err == nil {
+		t.Error("CheckExportPermit() should reject Argentina as destination")
+	}
+}
+
+func TestGetExportParameters(t *testing.T) {
+	server, service := newFakeAFIPExportService(t)
+
+	params, err := service.GetExportParameters(context.Background())
+	if err != nil {
+		t.Fatalf("GetExportParameters() error = %v", err)
+	}
+	if len(params.InvoiceTypes) != 3 || params.InvoiceTypes[0].ID != models.InvoiceTypeE {
+		t.Errorf("Parameters should include the export invoice types, got %+v", params.InvoiceTypes)
+	}
+	if len(params.CurrencyTypes) != 2 || params.CurrencyTypes[0].ID != "DOL" {
+		t.Errorf("Parameters should include the currencies, got %+v", params.CurrencyTypes)
+	}
+	if len(params.ConceptTypes) != 0 {
+		t.Errorf("Export types should not be reported as concept types, got %+v", params.ConceptTypes)
+	}
+	if len(params.Countries) != 3 || len(params.Incoterms) != 3 {
+		t.Errorf("Parameters should include countries and incoterms, got %+v", params)
+	}
+	if len(params.ExportTypes) != 3 || params.ExportTypes[2].ID != models.ExportTypeOther {
+		t.Errorf("Parameters should include the typed export types, got %+v", params.ExportTypes)
+	}
+	if len(params.UnitMeasures) != 3 || params.UnitMeasures[2].Active {
+		t.Errorf("Parameters should include the unit measures, got %+v", params.UnitMeasures)
+	}
+
+	for _, action := range []string{testutil.ActionFEXGetPARAMTipoCbte, testutil.ActionFEXGetPARAMMON, testutil.ActionFEXGetPARAMTipoExpo,
+		testutil.ActionFEXGetPARAMDSTPais, testutil.ActionFEXGetPARAMIncoterms, testutil.ActionFEXGetPARAMUMed} {
+		if calls := server.Calls(action); calls != 1 {
+			t.Errorf("%s should be called once, got %d", action, calls)
+		}
+	}
+}